@@ -0,0 +1,315 @@
+// Package client is a Go client of the price calculator HTTP API. Requests are traced with
+// otelhttp, so the trace context of the caller is propagated to the calculator, and failed
+// requests are retried when that is safe.
+package client
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Defaults of the options
+const (
+	defaultRetries = 2
+	defaultBackoff = 100 * time.Millisecond
+	maxBackoff     = 5 * time.Second
+)
+
+// Client calls a price calculator, safe for concurrent use
+type Client struct {
+	baseURL    *url.URL
+	httpClient *http.Client
+	apiKey     string
+	tenant     string
+	retries    int
+	backoff    time.Duration
+	userAgent  string
+}
+
+// Option configures a Client
+type Option func(*options)
+
+// options collects the settings applied by the options before the client is created
+type options struct {
+	httpClient     *http.Client
+	apiKey         string
+	tenant         string
+	retries        int
+	backoff        time.Duration
+	userAgent      string
+	tracerProvider trace.TracerProvider
+	propagators    propagation.TextMapPropagator
+}
+
+// WithAPIKey sends the key in the X-API-Key header of every request
+func WithAPIKey(key string) Option {
+	return func(o *options) { o.apiKey = key }
+}
+
+// WithTenant prices with the configuration of a tenant, calling the pricing endpoints under /tenants/{tenant}
+func WithTenant(tenant string) Option {
+	return func(o *options) { o.tenant = tenant }
+}
+
+// WithHTTPClient makes the requests with the given client, its transport wrapped by otelhttp
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(o *options) { o.httpClient = httpClient }
+}
+
+// WithRetries sets how many times a failed request is retried, 2 by default, 0 disables retries
+func WithRetries(retries int) Option {
+	return func(o *options) { o.retries = retries }
+}
+
+// WithBackoff sets the wait before the first retry, doubled for every further retry. 100ms by default.
+func WithBackoff(backoff time.Duration) Option {
+	return func(o *options) { o.backoff = backoff }
+}
+
+// WithUserAgent sets the User-Agent header of every request
+func WithUserAgent(userAgent string) Option {
+	return func(o *options) { o.userAgent = userAgent }
+}
+
+// WithTracerProvider creates the client spans with the given provider instead of the global one
+func WithTracerProvider(provider trace.TracerProvider) Option {
+	return func(o *options) { o.tracerProvider = provider }
+}
+
+// WithPropagators injects the trace context with the given propagators instead of the global ones
+func WithPropagators(propagators propagation.TextMapPropagator) Option {
+	return func(o *options) { o.propagators = propagators }
+}
+
+// New creates a client of the calculator at baseURL, such as http://localhost:8080
+func New(baseURL string, opts ...Option) (*Client, error) {
+	parsed, err := url.Parse(strings.TrimRight(baseURL, "/"))
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return nil, fmt.Errorf("invalid base URL %q", baseURL)
+	}
+	o := options{retries: defaultRetries, backoff: defaultBackoff, userAgent: "pricecalculator-go-client"}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.retries < 0 || o.backoff < 0 {
+		return nil, fmt.Errorf("retries and backoff must not be negative")
+	}
+
+	// Trace every attempt as a client span named after the route, propagating the trace context
+	httpClient := http.Client{Timeout: 30 * time.Second}
+	if o.httpClient != nil {
+		httpClient = *o.httpClient
+	}
+	transportOptions := []otelhttp.Option{otelhttp.WithSpanNameFormatter(func(_ string, r *http.Request) string {
+		if route, ok := r.Context().Value(routeKey{}).(string); ok {
+			return r.Method + " " + route
+		}
+		return "HTTP " + r.Method
+	})}
+	if o.tracerProvider != nil {
+		transportOptions = append(transportOptions, otelhttp.WithTracerProvider(o.tracerProvider))
+	}
+	if o.propagators != nil {
+		transportOptions = append(transportOptions, otelhttp.WithPropagators(o.propagators))
+	}
+	httpClient.Transport = otelhttp.NewTransport(httpClient.Transport, transportOptions...)
+
+	return &Client{
+		baseURL:    parsed,
+		httpClient: &httpClient,
+		apiKey:     o.apiKey,
+		tenant:     o.tenant,
+		retries:    o.retries,
+		backoff:    o.backoff,
+		userAgent:  o.userAgent,
+	}, nil
+}
+
+// Error is a failed request, decoded from the problem details of the response
+type Error struct {
+	Status   int    `json:"status"`
+	Type     string `json:"type,omitempty"`
+	Title    string `json:"title,omitempty"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"`
+}
+
+func (e *Error) Error() string {
+	if e.Detail != "" {
+		return fmt.Sprintf("price calculator: %d %s: %s", e.Status, e.Title, e.Detail)
+	}
+	return fmt.Sprintf("price calculator: %d %s", e.Status, e.Title)
+}
+
+// IsNotFound reports whether err is a 404 answer of the calculator
+func IsNotFound(err error) bool {
+	var apiErr *Error
+	return errors.As(err, &apiErr) && apiErr.Status == http.StatusNotFound
+}
+
+// Context key of the route template of a request, naming its client span
+type routeKey struct{}
+
+// Returns the path of a pricing endpoint, under /tenants/{tenant} for a client of a tenant
+func (c *Client) tenantPath(path string) string {
+	if c.tenant == "" {
+		return path
+	}
+	return "/tenants/" + url.PathEscape(c.tenant) + path
+}
+
+// Do sends a request to the path and decodes the JSON response into out, unless out is nil.
+// The body, unless nil, is encoded as JSON. route names the client span, such as "/quotes/{id}".
+// Requests failing with a network error, 429, 502, 503 or 504 are retried, POST and PATCH
+// requests with an Idempotency-Key header so that they are not applied twice.
+func (c *Client) Do(ctx context.Context, method, route, path string, query url.Values, body, out any) error {
+	data, err := c.doRaw(ctx, method, route, path, query, body, "")
+	if err != nil || out == nil {
+		return err
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("failed to decode response of %s %s: %v", method, route, err)
+	}
+	return nil
+}
+
+// Sends a request, returning the body of a 2xx response and retrying failed attempts
+func (c *Client) doRaw(ctx context.Context, method, route, path string, query url.Values, body any, accept string) ([]byte, error) {
+	var payload []byte
+	if body != nil {
+		var err error
+		if payload, err = json.Marshal(body); err != nil {
+			return nil, fmt.Errorf("failed to encode request of %s %s: %v", method, route, err)
+		}
+	}
+	target := *c.baseURL
+	target.Path += path
+	target.RawQuery = query.Encode()
+	ctx = context.WithValue(ctx, routeKey{}, c.tenantRoute(route, path))
+
+	var idempotencyKey string
+	if method == http.MethodPost || method == http.MethodPatch {
+		idempotencyKey = randomKey()
+	}
+	backoff := c.backoff
+	for attempt := 0; ; attempt++ {
+		data, retryAfter, err := c.attempt(ctx, method, target.String(), payload, idempotencyKey, accept)
+		if err == nil || attempt >= c.retries || !retryable(err) {
+			return data, err
+		}
+		wait := backoff
+		if retryAfter > 0 {
+			wait = retryAfter
+		}
+		select {
+		case <-time.After(min(wait, maxBackoff)):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		backoff *= 2
+	}
+}
+
+// Returns the route of a request for its span, prefixed for a client of a tenant
+func (c *Client) tenantRoute(route, path string) string {
+	if c.tenant != "" && strings.HasPrefix(path, "/tenants/") && !strings.HasPrefix(route, "/tenants/") {
+		return "/tenants/{tenant}" + route
+	}
+	return route
+}
+
+// Makes a single attempt of a request, returning the wait the server asked for before a retry
+func (c *Client) attempt(ctx context.Context, method, target string, payload []byte, idempotencyKey, accept string) ([]byte, time.Duration, error) {
+	var body io.Reader
+	if payload != nil {
+		body = bytes.NewReader(payload)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target, body)
+	if err != nil {
+		return nil, 0, err
+	}
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if accept == "" {
+		accept = "application/json"
+	}
+	req.Header.Set("Accept", accept)
+	req.Header.Set("User-Agent", c.userAgent)
+	if c.apiKey != "" {
+		req.Header.Set("X-API-Key", c.apiKey)
+	}
+	if idempotencyKey != "" {
+		req.Header.Set("Idempotency-Key", idempotencyKey)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, 0, &networkError{err}
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, 0, &networkError{err}
+	}
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return data, 0, nil
+	}
+
+	apiErr := &Error{}
+	if json.Unmarshal(data, apiErr) != nil || apiErr.Status == 0 {
+		apiErr = &Error{Title: http.StatusText(resp.StatusCode), Detail: strings.TrimSpace(string(data))}
+	}
+	apiErr.Status = resp.StatusCode
+	var retryAfter time.Duration
+	if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds > 0 {
+		retryAfter = time.Duration(seconds) * time.Second
+	}
+	return nil, retryAfter, apiErr
+}
+
+// networkError is a request that got no response, such as a refused connection
+type networkError struct {
+	err error
+}
+
+func (e *networkError) Error() string { return e.err.Error() }
+func (e *networkError) Unwrap() error { return e.err }
+
+// Reports whether a failed attempt may succeed when retried
+func retryable(err error) bool {
+	var netErr *networkError
+	if errors.As(err, &netErr) {
+		return !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+	}
+	var apiErr *Error
+	if errors.As(err, &apiErr) {
+		switch apiErr.Status {
+		case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			return true
+		}
+	}
+	return false
+}
+
+// Returns a random idempotency key, shared by the attempts of a request
+func randomKey() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
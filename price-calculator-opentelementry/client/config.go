@@ -0,0 +1,189 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"otpl/pricecalculator/pricing"
+)
+
+// GetConfig returns the default configuration
+func (c *Client) GetConfig(ctx context.Context) (pricing.Config, error) {
+	var cfg pricing.Config
+	err := c.Do(ctx, http.MethodGet, "/v1/config", "/v1/config", nil, nil, &cfg)
+	return cfg, err
+}
+
+// ReplaceConfig replaces the default configuration. The base price and tax rate are required,
+// an omitted currency or rounding mode is reset to the default.
+func (c *Client) ReplaceConfig(ctx context.Context, update ConfigUpdate) (pricing.Config, error) {
+	var cfg pricing.Config
+	err := c.Do(ctx, http.MethodPut, "/v1/config", "/v1/config", nil, update, &cfg)
+	return cfg, err
+}
+
+// UpdateConfig changes the fields of the default configuration set in update
+func (c *Client) UpdateConfig(ctx context.Context, update ConfigUpdate) (pricing.Config, error) {
+	var cfg pricing.Config
+	err := c.Do(ctx, http.MethodPatch, "/v1/config", "/v1/config", nil, update, &cfg)
+	return cfg, err
+}
+
+// BulkUpdateConfig changes the fields set in update in a single update, recording which ones changed
+func (c *Client) BulkUpdateConfig(ctx context.Context, update ConfigUpdate) (pricing.Config, error) {
+	var cfg pricing.Config
+	err := c.Do(ctx, http.MethodPost, "/config/bulk", "/config/bulk", nil, update, &cfg)
+	return cfg, err
+}
+
+// ListConfigVersions returns the configuration versions, newest first
+func (c *Client) ListConfigVersions(ctx context.Context) ([]ConfigVersion, error) {
+	var versions []ConfigVersion
+	err := c.Do(ctx, http.MethodGet, "/config/versions", "/config/versions", nil, nil, &versions)
+	return versions, err
+}
+
+// GetConfigVersion returns a configuration version
+func (c *Client) GetConfigVersion(ctx context.Context, n int) (ConfigVersion, error) {
+	var version ConfigVersion
+	err := c.Do(ctx, http.MethodGet, "/config/versions/{n}", "/config/versions/"+strconv.Itoa(n), nil, nil, &version)
+	return version, err
+}
+
+// RollbackConfig restores a configuration version, returning the new version it created
+func (c *Client) RollbackConfig(ctx context.Context, n int) (ConfigVersion, error) {
+	var version ConfigVersion
+	err := c.Do(ctx, http.MethodPost, "/config/rollback/{n}", "/config/rollback/"+strconv.Itoa(n), nil, nil, &version)
+	return version, err
+}
+
+// ListScheduledChanges returns the scheduled changes by effective time, those with the status when it is not empty
+func (c *Client) ListScheduledChanges(ctx context.Context, status string) ([]ScheduledChange, error) {
+	var query url.Values
+	if status != "" {
+		query = url.Values{"status": {status}}
+	}
+	var changes []ScheduledChange
+	err := c.Do(ctx, http.MethodGet, "/config/schedule", "/config/schedule", query, nil, &changes)
+	return changes, err
+}
+
+// ScheduleChange schedules a change of the default base price, tax rate or both
+func (c *Client) ScheduleChange(ctx context.Context, request ScheduleRequest) (ScheduledChange, error) {
+	var change ScheduledChange
+	err := c.Do(ctx, http.MethodPost, "/config/schedule", "/config/schedule", nil, request, &change)
+	return change, err
+}
+
+// GetScheduledChange returns a scheduled change
+func (c *Client) GetScheduledChange(ctx context.Context, id string) (ScheduledChange, error) {
+	var change ScheduledChange
+	err := c.Do(ctx, http.MethodGet, "/config/schedule/{id}", "/config/schedule/"+url.PathEscape(id), nil, nil, &change)
+	return change, err
+}
+
+// CancelScheduledChange cancels a pending scheduled change
+func (c *Client) CancelScheduledChange(ctx context.Context, id string) (ScheduledChange, error) {
+	var change ScheduledChange
+	err := c.Do(ctx, http.MethodPost, "/config/schedule/{id}/cancel", "/config/schedule/"+url.PathEscape(id)+"/cancel", nil, nil, &change)
+	return change, err
+}
+
+// ListTaxRateHistory returns the default tax rates by the time they took effect
+func (c *Client) ListTaxRateHistory(ctx context.Context) ([]TaxRatePeriod, error) {
+	var periods []TaxRatePeriod
+	err := c.Do(ctx, http.MethodGet, "/config/tax-rates", "/config/tax-rates", nil, nil, &periods)
+	return periods, err
+}
+
+// RecordTaxRate records a default tax rate in effect from a past time
+func (c *Client) RecordTaxRate(ctx context.Context, taxRate float64, effectiveFrom time.Time) (TaxRatePeriod, error) {
+	var period TaxRatePeriod
+	body := map[string]any{"tax_rate": taxRate, "effective_from": effectiveFrom}
+	err := c.Do(ctx, http.MethodPost, "/config/tax-rates", "/config/tax-rates", nil, body, &period)
+	return period, err
+}
+
+// ListHistory returns a page of the recorded calculations, newest first
+func (c *Client) ListHistory(ctx context.Context, filter HistoryFilter) (HistoryResponse, error) {
+	query := url.Values{}
+	if !filter.From.IsZero() {
+		query.Set("from", filter.From.Format(time.RFC3339Nano))
+	}
+	if !filter.To.IsZero() {
+		query.Set("to", filter.To.Format(time.RFC3339Nano))
+	}
+	if filter.Limit > 0 {
+		query.Set("limit", strconv.Itoa(filter.Limit))
+	}
+	if filter.Offset > 0 {
+		query.Set("offset", strconv.Itoa(filter.Offset))
+	}
+	var page HistoryResponse
+	err := c.Do(ctx, http.MethodGet, "/history", "/history", query, nil, &page)
+	return page, err
+}
+
+// ListAudit returns a page of the audit log of configuration changes, newest first
+func (c *Client) ListAudit(ctx context.Context, filter AuditFilter) (AuditResponse, error) {
+	query := url.Values{}
+	for name, value := range map[string]string{"actor": filter.Actor, "action": filter.Action, "resource": filter.Resource,
+		"tenant": filter.Tenant, "trace_id": filter.TraceID} {
+		if value != "" {
+			query.Set(name, value)
+		}
+	}
+	if !filter.From.IsZero() {
+		query.Set("from", filter.From.Format(time.RFC3339Nano))
+	}
+	if !filter.To.IsZero() {
+		query.Set("to", filter.To.Format(time.RFC3339Nano))
+	}
+	if filter.Limit > 0 {
+		query.Set("limit", strconv.Itoa(filter.Limit))
+	}
+	if filter.Offset > 0 {
+		query.Set("offset", strconv.Itoa(filter.Offset))
+	}
+	var page AuditResponse
+	err := c.Do(ctx, http.MethodGet, "/audit", "/audit", query, nil, &page)
+	return page, err
+}
+
+// Status returns the health of the calculator's dependencies
+func (c *Client) Status(ctx context.Context) (StatusResponse, error) {
+	var status StatusResponse
+	err := c.Do(ctx, http.MethodGet, "/status", "/status", nil, nil, &status)
+	return status, err
+}
+
+// ListFlags returns the feature flags
+func (c *Client) ListFlags(ctx context.Context) ([]FeatureFlag, error) {
+	var flags []FeatureFlag
+	err := c.Do(ctx, http.MethodGet, "/admin/flags", "/admin/flags", nil, nil, &flags)
+	return flags, err
+}
+
+// GetFlag returns a feature flag
+func (c *Client) GetFlag(ctx context.Context, name string) (FeatureFlag, error) {
+	var flag FeatureFlag
+	err := c.Do(ctx, http.MethodGet, "/admin/flags/{name}", "/admin/flags/"+url.PathEscape(name), nil, nil, &flag)
+	return flag, err
+}
+
+// SetFlag overrides a feature flag at runtime
+func (c *Client) SetFlag(ctx context.Context, name string, enabled bool) (FeatureFlag, error) {
+	var flag FeatureFlag
+	err := c.Do(ctx, http.MethodPut, "/admin/flags/{name}", "/admin/flags/"+url.PathEscape(name), nil, map[string]bool{"enabled": enabled}, &flag)
+	return flag, err
+}
+
+// ResetFlag removes the runtime override of a feature flag
+func (c *Client) ResetFlag(ctx context.Context, name string) (FeatureFlag, error) {
+	var flag FeatureFlag
+	err := c.Do(ctx, http.MethodDelete, "/admin/flags/{name}", "/admin/flags/"+url.PathEscape(name), nil, nil, &flag)
+	return flag, err
+}
@@ -0,0 +1,191 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+
+	"otpl/pricecalculator/pricing"
+)
+
+// Locale query of a request formatting its amounts, none without a locale
+func localeQuery(locale string) url.Values {
+	if locale == "" {
+		return nil
+	}
+	return url.Values{"locale": {locale}}
+}
+
+// Calculate prices a request with the calculator's defaults and rules
+func (c *Client) Calculate(ctx context.Context, request pricing.PriceRequest) (pricing.PriceResponse, error) {
+	var response pricing.PriceResponse
+	err := c.Do(ctx, http.MethodPost, "/calculate", c.tenantPath("/calculate"), nil, request, &response)
+	return response, err
+}
+
+// CalculateFormatted prices a request and adds its amounts formatted in a BCP 47 locale such as de-DE
+func (c *Client) CalculateFormatted(ctx context.Context, request pricing.PriceRequest, locale string) (pricing.PriceResponse, error) {
+	var response pricing.PriceResponse
+	err := c.Do(ctx, http.MethodPost, "/calculate", c.tenantPath("/calculate"), localeQuery(locale), request, &response)
+	return response, err
+}
+
+// CalculateExplained prices a request and adds a step by step explanation of the calculation
+func (c *Client) CalculateExplained(ctx context.Context, request pricing.PriceRequest) (pricing.PriceResponse, error) {
+	var response pricing.PriceResponse
+	err := c.Do(ctx, http.MethodPost, "/calculate", c.tenantPath("/calculate"), url.Values{"explain": {"true"}}, request, &response)
+	return response, err
+}
+
+// CalculateCart prices the line items of a cart
+func (c *Client) CalculateCart(ctx context.Context, request pricing.CartRequest) (pricing.CartResponse, error) {
+	var response pricing.CartResponse
+	err := c.Do(ctx, http.MethodPost, "/calculate/cart", c.tenantPath("/calculate/cart"), nil, request, &response)
+	return response, err
+}
+
+// CalculateV2 prices a request in the v2 shapes
+func (c *Client) CalculateV2(ctx context.Context, request pricing.PriceRequestV2) (pricing.PriceResponseV2, error) {
+	var response pricing.PriceResponseV2
+	err := c.Do(ctx, http.MethodPost, "/v2/calculate", c.tenantPath("/v2/calculate"), nil, request, &response)
+	return response, err
+}
+
+// CalculateCartV2 prices the lines of a cart in the v2 shapes
+func (c *Client) CalculateCartV2(ctx context.Context, request pricing.CartRequestV2) (pricing.CartResponseV2, error) {
+	var response pricing.CartResponseV2
+	err := c.Do(ctx, http.MethodPost, "/v2/calculate/cart", c.tenantPath("/v2/calculate/cart"), nil, request, &response)
+	return response, err
+}
+
+// CalculateSubscription prices a subscription change with its proration
+func (c *Client) CalculateSubscription(ctx context.Context, request pricing.SubscriptionRequest) (pricing.SubscriptionResponse, error) {
+	var response pricing.SubscriptionResponse
+	err := c.Do(ctx, http.MethodPost, "/calculate/subscription", c.tenantPath("/calculate/subscription"), nil, request, &response)
+	return response, err
+}
+
+// CalculateBatch prices up to 100 requests, returning one result per request in the same order
+func (c *Client) CalculateBatch(ctx context.Context, requests []pricing.PriceRequest) ([]BatchResult, error) {
+	var results []BatchResult
+	err := c.Do(ctx, http.MethodPost, "/calculate/batch", c.tenantPath("/calculate/batch"), nil, requests, &results)
+	return results, err
+}
+
+// CalculateSellingPrice derives the selling price from a cost and a target margin or markup
+func (c *Client) CalculateSellingPrice(ctx context.Context, request pricing.SellingPriceRequest) (pricing.MarginResponse, error) {
+	var response pricing.MarginResponse
+	err := c.Do(ctx, http.MethodPost, "/calculate/selling-price", c.tenantPath("/calculate/selling-price"), nil, request, &response)
+	return response, err
+}
+
+// CalculateMargin reports the margin and markup of a price over its cost
+func (c *Client) CalculateMargin(ctx context.Context, request pricing.MarginRequest) (pricing.MarginResponse, error) {
+	var response pricing.MarginResponse
+	err := c.Do(ctx, http.MethodPost, "/calculate/margin", c.tenantPath("/calculate/margin"), nil, request, &response)
+	return response, err
+}
+
+// CalculateUnitPrice prices a quantity of units and reports the effective unit price
+func (c *Client) CalculateUnitPrice(ctx context.Context, request pricing.UnitPriceRequest) (pricing.UnitPriceResponse, error) {
+	var response pricing.UnitPriceResponse
+	err := c.Do(ctx, http.MethodPost, "/calculate/unit", c.tenantPath("/calculate/unit"), nil, request, &response)
+	return response, err
+}
+
+// CalculateRefund prices a refund of a calculation in the history by the units returned or a credit amount
+func (c *Client) CalculateRefund(ctx context.Context, request pricing.RefundRequest) (pricing.RefundResponse, error) {
+	var response pricing.RefundResponse
+	err := c.Do(ctx, http.MethodPost, "/calculate/refund", c.tenantPath("/calculate/refund"), nil, request, &response)
+	return response, err
+}
+
+// Simulate prices a base request and what-if scenarios without redeeming or recording anything
+func (c *Client) Simulate(ctx context.Context, request SimulationRequest) (SimulationResponse, error) {
+	var response SimulationResponse
+	err := c.Do(ctx, http.MethodPost, "/simulate", c.tenantPath("/simulate"), nil, request, &response)
+	return response, err
+}
+
+// Convert converts an amount between currencies
+func (c *Client) Convert(ctx context.Context, from, to string, amount pricing.Money) (ConversionResponse, error) {
+	var response ConversionResponse
+	query := url.Values{"from": {from}, "to": {to}, "amount": {amount.String()}}
+	err := c.Do(ctx, http.MethodGet, "/convert", "/convert", query, nil, &response)
+	return response, err
+}
+
+// Rates returns the exchange rates stored by the scheduled refresh with its last and next runs
+func (c *Client) Rates(ctx context.Context) (RatesResponse, error) {
+	var response RatesResponse
+	err := c.Do(ctx, http.MethodGet, "/rates", "/rates", nil, nil, &response)
+	return response, err
+}
+
+// CreateQuote prices a request and stores it as a quote, returning the existing quote for the same request
+func (c *Client) CreateQuote(ctx context.Context, request pricing.PriceRequest) (Quote, error) {
+	var quote Quote
+	err := c.Do(ctx, http.MethodPost, "/quotes", c.tenantPath("/quotes"), nil, request, &quote)
+	return quote, err
+}
+
+// GetQuote returns a quote
+func (c *Client) GetQuote(ctx context.Context, id string) (Quote, error) {
+	var quote Quote
+	err := c.Do(ctx, http.MethodGet, "/quotes/{id}", c.tenantPath("/quotes/"+url.PathEscape(id)), nil, nil, &quote)
+	return quote, err
+}
+
+// FinalizeQuote locks an open quote
+func (c *Client) FinalizeQuote(ctx context.Context, id string) (Quote, error) {
+	var quote Quote
+	err := c.Do(ctx, http.MethodPost, "/quotes/{id}/finalize", c.tenantPath("/quotes/"+url.PathEscape(id)+"/finalize"), nil, nil, &quote)
+	return quote, err
+}
+
+// RenderQuote renders a finalized quote as an invoice, format html or pdf, with its amounts
+// formatted in locale, en-US when empty
+func (c *Client) RenderQuote(ctx context.Context, id, format, locale string) ([]byte, error) {
+	query := url.Values{"format": {format}}
+	if locale != "" {
+		query.Set("locale", locale)
+	}
+	accept := "text/html"
+	if format == "pdf" {
+		accept = "application/pdf"
+	}
+	return c.doRaw(ctx, http.MethodPost, "/quotes/{id}/render", c.tenantPath("/quotes/"+url.PathEscape(id)+"/render"), query, nil, accept)
+}
+
+// ListDiscounts returns the discount rules
+func (c *Client) ListDiscounts(ctx context.Context) ([]pricing.Discount, error) {
+	var discounts []pricing.Discount
+	err := c.Do(ctx, http.MethodGet, "/discounts", c.tenantPath("/discounts"), nil, nil, &discounts)
+	return discounts, err
+}
+
+// CreateDiscount adds a discount rule, returned with its ID
+func (c *Client) CreateDiscount(ctx context.Context, discount pricing.Discount) (pricing.Discount, error) {
+	var created pricing.Discount
+	err := c.Do(ctx, http.MethodPost, "/discounts", c.tenantPath("/discounts"), nil, discount, &created)
+	return created, err
+}
+
+// GetDiscount returns a discount rule
+func (c *Client) GetDiscount(ctx context.Context, id string) (pricing.Discount, error) {
+	var discount pricing.Discount
+	err := c.Do(ctx, http.MethodGet, "/discounts/{id}", c.tenantPath("/discounts/"+url.PathEscape(id)), nil, nil, &discount)
+	return discount, err
+}
+
+// UpdateDiscount replaces a discount rule
+func (c *Client) UpdateDiscount(ctx context.Context, id string, discount pricing.Discount) (pricing.Discount, error) {
+	var updated pricing.Discount
+	err := c.Do(ctx, http.MethodPut, "/discounts/{id}", c.tenantPath("/discounts/"+url.PathEscape(id)), nil, discount, &updated)
+	return updated, err
+}
+
+// DeleteDiscount removes a discount rule
+func (c *Client) DeleteDiscount(ctx context.Context, id string) error {
+	return c.Do(ctx, http.MethodDelete, "/discounts/{id}", c.tenantPath("/discounts/"+url.PathEscape(id)), nil, nil, nil)
+}
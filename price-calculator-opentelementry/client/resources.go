@@ -0,0 +1,233 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+
+	"otpl/pricecalculator/pricing"
+)
+
+// ListCoupons returns the coupons
+func (c *Client) ListCoupons(ctx context.Context) ([]pricing.Coupon, error) {
+	var coupons []pricing.Coupon
+	err := c.Do(ctx, http.MethodGet, "/coupons", "/coupons", nil, nil, &coupons)
+	return coupons, err
+}
+
+// CreateCoupon adds a coupon, returned with its ID
+func (c *Client) CreateCoupon(ctx context.Context, coupon pricing.Coupon) (pricing.Coupon, error) {
+	var created pricing.Coupon
+	err := c.Do(ctx, http.MethodPost, "/coupons", "/coupons", nil, coupon, &created)
+	return created, err
+}
+
+// GetCoupon returns a coupon
+func (c *Client) GetCoupon(ctx context.Context, id string) (pricing.Coupon, error) {
+	var coupon pricing.Coupon
+	err := c.Do(ctx, http.MethodGet, "/coupons/{id}", "/coupons/"+url.PathEscape(id), nil, nil, &coupon)
+	return coupon, err
+}
+
+// UpdateCoupon replaces a coupon
+func (c *Client) UpdateCoupon(ctx context.Context, id string, coupon pricing.Coupon) (pricing.Coupon, error) {
+	var updated pricing.Coupon
+	err := c.Do(ctx, http.MethodPut, "/coupons/{id}", "/coupons/"+url.PathEscape(id), nil, coupon, &updated)
+	return updated, err
+}
+
+// DeleteCoupon removes a coupon
+func (c *Client) DeleteCoupon(ctx context.Context, id string) error {
+	return c.Do(ctx, http.MethodDelete, "/coupons/{id}", "/coupons/"+url.PathEscape(id), nil, nil, nil)
+}
+
+// ListTaxRules returns the tax rules
+func (c *Client) ListTaxRules(ctx context.Context) ([]pricing.TaxRule, error) {
+	var rules []pricing.TaxRule
+	err := c.Do(ctx, http.MethodGet, "/tax/rules", "/tax/rules", nil, nil, &rules)
+	return rules, err
+}
+
+// CreateTaxRule adds a tax rule, returned with its ID
+func (c *Client) CreateTaxRule(ctx context.Context, rule pricing.TaxRule) (pricing.TaxRule, error) {
+	var created pricing.TaxRule
+	err := c.Do(ctx, http.MethodPost, "/tax/rules", "/tax/rules", nil, rule, &created)
+	return created, err
+}
+
+// GetTaxRule returns a tax rule
+func (c *Client) GetTaxRule(ctx context.Context, id string) (pricing.TaxRule, error) {
+	var rule pricing.TaxRule
+	err := c.Do(ctx, http.MethodGet, "/tax/rules/{id}", "/tax/rules/"+url.PathEscape(id), nil, nil, &rule)
+	return rule, err
+}
+
+// UpdateTaxRule replaces a tax rule
+func (c *Client) UpdateTaxRule(ctx context.Context, id string, rule pricing.TaxRule) (pricing.TaxRule, error) {
+	var updated pricing.TaxRule
+	err := c.Do(ctx, http.MethodPut, "/tax/rules/{id}", "/tax/rules/"+url.PathEscape(id), nil, rule, &updated)
+	return updated, err
+}
+
+// DeleteTaxRule removes a tax rule
+func (c *Client) DeleteTaxRule(ctx context.Context, id string) error {
+	return c.Do(ctx, http.MethodDelete, "/tax/rules/{id}", "/tax/rules/"+url.PathEscape(id), nil, nil, nil)
+}
+
+// ListSurcharges returns the surcharge rules
+func (c *Client) ListSurcharges(ctx context.Context) ([]pricing.SurchargeRule, error) {
+	var rules []pricing.SurchargeRule
+	err := c.Do(ctx, http.MethodGet, "/surcharges", "/surcharges", nil, nil, &rules)
+	return rules, err
+}
+
+// CreateSurcharge adds a surcharge rule, returned with its ID
+func (c *Client) CreateSurcharge(ctx context.Context, rule pricing.SurchargeRule) (pricing.SurchargeRule, error) {
+	var created pricing.SurchargeRule
+	err := c.Do(ctx, http.MethodPost, "/surcharges", "/surcharges", nil, rule, &created)
+	return created, err
+}
+
+// GetSurcharge returns a surcharge rule
+func (c *Client) GetSurcharge(ctx context.Context, id string) (pricing.SurchargeRule, error) {
+	var rule pricing.SurchargeRule
+	err := c.Do(ctx, http.MethodGet, "/surcharges/{id}", "/surcharges/"+url.PathEscape(id), nil, nil, &rule)
+	return rule, err
+}
+
+// UpdateSurcharge replaces a surcharge rule
+func (c *Client) UpdateSurcharge(ctx context.Context, id string, rule pricing.SurchargeRule) (pricing.SurchargeRule, error) {
+	var updated pricing.SurchargeRule
+	err := c.Do(ctx, http.MethodPut, "/surcharges/{id}", "/surcharges/"+url.PathEscape(id), nil, rule, &updated)
+	return updated, err
+}
+
+// DeleteSurcharge removes a surcharge rule
+func (c *Client) DeleteSurcharge(ctx context.Context, id string) error {
+	return c.Do(ctx, http.MethodDelete, "/surcharges/{id}", "/surcharges/"+url.PathEscape(id), nil, nil, nil)
+}
+
+// ListProducts returns the catalog products
+func (c *Client) ListProducts(ctx context.Context) ([]pricing.Product, error) {
+	var products []pricing.Product
+	err := c.Do(ctx, http.MethodGet, "/products", "/products", nil, nil, &products)
+	return products, err
+}
+
+// CreateProduct adds a catalog product
+func (c *Client) CreateProduct(ctx context.Context, product pricing.Product) (pricing.Product, error) {
+	var created pricing.Product
+	err := c.Do(ctx, http.MethodPost, "/products", "/products", nil, product, &created)
+	return created, err
+}
+
+// GetProduct returns a catalog product
+func (c *Client) GetProduct(ctx context.Context, sku string) (pricing.Product, error) {
+	var product pricing.Product
+	err := c.Do(ctx, http.MethodGet, "/products/{sku}", "/products/"+url.PathEscape(sku), nil, nil, &product)
+	return product, err
+}
+
+// UpdateProduct replaces a catalog product
+func (c *Client) UpdateProduct(ctx context.Context, sku string, product pricing.Product) (pricing.Product, error) {
+	var updated pricing.Product
+	err := c.Do(ctx, http.MethodPut, "/products/{sku}", "/products/"+url.PathEscape(sku), nil, product, &updated)
+	return updated, err
+}
+
+// DeleteProduct removes a catalog product
+func (c *Client) DeleteProduct(ctx context.Context, sku string) error {
+	return c.Do(ctx, http.MethodDelete, "/products/{sku}", "/products/"+url.PathEscape(sku), nil, nil, nil)
+}
+
+// ListTenants returns the tenants
+func (c *Client) ListTenants(ctx context.Context) ([]Tenant, error) {
+	var tenants []Tenant
+	err := c.Do(ctx, http.MethodGet, "/tenants", "/tenants", nil, nil, &tenants)
+	return tenants, err
+}
+
+// CreateTenant adds a tenant
+func (c *Client) CreateTenant(ctx context.Context, tenant Tenant) (Tenant, error) {
+	var created Tenant
+	err := c.Do(ctx, http.MethodPost, "/tenants", "/tenants", nil, tenant, &created)
+	return created, err
+}
+
+// GetTenant returns a tenant
+func (c *Client) GetTenant(ctx context.Context, id string) (Tenant, error) {
+	var tenant Tenant
+	err := c.Do(ctx, http.MethodGet, "/tenants/{id}", "/tenants/"+url.PathEscape(id), nil, nil, &tenant)
+	return tenant, err
+}
+
+// UpdateTenant replaces the settings of a tenant
+func (c *Client) UpdateTenant(ctx context.Context, id string, tenant Tenant) (Tenant, error) {
+	var updated Tenant
+	err := c.Do(ctx, http.MethodPut, "/tenants/{id}", "/tenants/"+url.PathEscape(id), nil, tenant, &updated)
+	return updated, err
+}
+
+// DeleteTenant removes a tenant with its discount rules
+func (c *Client) DeleteTenant(ctx context.Context, id string) error {
+	return c.Do(ctx, http.MethodDelete, "/tenants/{id}", "/tenants/"+url.PathEscape(id), nil, nil, nil)
+}
+
+// ListCustomers returns the customers
+func (c *Client) ListCustomers(ctx context.Context) ([]Customer, error) {
+	var customers []Customer
+	err := c.Do(ctx, http.MethodGet, "/customers", "/customers", nil, nil, &customers)
+	return customers, err
+}
+
+// CreateCustomer adds a customer
+func (c *Client) CreateCustomer(ctx context.Context, customer Customer) (Customer, error) {
+	var created Customer
+	err := c.Do(ctx, http.MethodPost, "/customers", "/customers", nil, customer, &created)
+	return created, err
+}
+
+// GetCustomer returns a customer
+func (c *Client) GetCustomer(ctx context.Context, id string) (Customer, error) {
+	var customer Customer
+	err := c.Do(ctx, http.MethodGet, "/customers/{id}", "/customers/"+url.PathEscape(id), nil, nil, &customer)
+	return customer, err
+}
+
+// UpdateCustomer replaces a customer
+func (c *Client) UpdateCustomer(ctx context.Context, id string, customer Customer) (Customer, error) {
+	var updated Customer
+	err := c.Do(ctx, http.MethodPut, "/customers/{id}", "/customers/"+url.PathEscape(id), nil, customer, &updated)
+	return updated, err
+}
+
+// DeleteCustomer removes a customer
+func (c *Client) DeleteCustomer(ctx context.Context, id string) error {
+	return c.Do(ctx, http.MethodDelete, "/customers/{id}", "/customers/"+url.PathEscape(id), nil, nil, nil)
+}
+
+// ListWebhooks returns the registered webhooks
+func (c *Client) ListWebhooks(ctx context.Context) ([]Webhook, error) {
+	var webhooks []Webhook
+	err := c.Do(ctx, http.MethodGet, "/webhooks", "/webhooks", nil, nil, &webhooks)
+	return webhooks, err
+}
+
+// CreateWebhook registers a webhook, returned with its ID
+func (c *Client) CreateWebhook(ctx context.Context, webhook Webhook) (Webhook, error) {
+	var created Webhook
+	err := c.Do(ctx, http.MethodPost, "/webhooks", "/webhooks", nil, webhook, &created)
+	return created, err
+}
+
+// GetWebhook returns a webhook
+func (c *Client) GetWebhook(ctx context.Context, id string) (Webhook, error) {
+	var webhook Webhook
+	err := c.Do(ctx, http.MethodGet, "/webhooks/{id}", "/webhooks/"+url.PathEscape(id), nil, nil, &webhook)
+	return webhook, err
+}
+
+// DeleteWebhook removes a webhook
+func (c *Client) DeleteWebhook(ctx context.Context, id string) error {
+	return c.Do(ctx, http.MethodDelete, "/webhooks/{id}", "/webhooks/"+url.PathEscape(id), nil, nil, nil)
+}
@@ -0,0 +1,256 @@
+package client
+
+import (
+	"encoding/json"
+	"time"
+
+	"otpl/pricecalculator/pricing"
+)
+
+// Types of the resources served by the calculator besides those of the pricing package,
+// with the same JSON encoding as the server's
+
+// BatchResult structure for the outcome of one request in a batch
+type BatchResult struct {
+	Result *pricing.PriceResponse `json:"result,omitempty"`
+	Error  string                 `json:"error,omitempty"`
+}
+
+// Scenario structure for a what-if change to the base request of a simulation,
+// omitted fields keep the base request's values
+type Scenario struct {
+	Name       string         `json:"name"`
+	BasePrice  *pricing.Money `json:"base_price,omitempty"`
+	TaxRate    *float64       `json:"tax_rate,omitempty"`
+	Quantity   int            `json:"quantity,omitempty"`
+	CouponCode *string        `json:"coupon_code,omitempty"` // An empty code removes the base request's coupon
+	Currency   string         `json:"currency,omitempty"`    // Converts the base request's base_price at the current exchange rate
+}
+
+// SimulationRequest structure for the input of /simulate
+type SimulationRequest struct {
+	Base      pricing.PriceRequest `json:"base"`
+	Scenarios []Scenario           `json:"scenarios"`
+}
+
+// ScenarioResult structure for the outcome of one scenario in a simulation
+type ScenarioResult struct {
+	Name       string                 `json:"name"`
+	Result     *pricing.PriceResponse `json:"result,omitempty"`
+	Difference *pricing.Money         `json:"difference,omitempty"`
+	Error      string                 `json:"error,omitempty"`
+}
+
+// SimulationResponse structure for the output of /simulate
+type SimulationResponse struct {
+	Base      pricing.PriceResponse `json:"base"`
+	Scenarios []ScenarioResult      `json:"scenarios"`
+}
+
+// ConversionResponse structure for the response of /convert
+type ConversionResponse struct {
+	From      string        `json:"from"`
+	To        string        `json:"to"`
+	Amount    pricing.Money `json:"amount"`
+	Rate      float64       `json:"rate"`
+	Converted pricing.Money `json:"converted"`
+	Source    string        `json:"source"` // refreshed, provider, cache or static
+}
+
+// ExchangeRate structure for a rate stored by the scheduled refresh
+type ExchangeRate struct {
+	Base      string    `json:"base"`
+	Currency  string    `json:"currency"`
+	Rate      float64   `json:"rate"` // Units of the currency per unit of the base currency
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// RateRefresh structure for a run of the scheduled exchange rate refresh
+type RateRefresh struct {
+	StartedAt         time.Time `json:"started_at"`
+	RecordsUpdated    int       `json:"records_updated"`
+	ProviderLatencyMS float64   `json:"provider_latency_ms"`
+	Error             string    `json:"error,omitempty"`
+	TraceID           string    `json:"trace_id,omitempty"`
+}
+
+// RatesResponse structure for the response of GET /rates
+type RatesResponse struct {
+	Base        string         `json:"base"`
+	Refresh     string         `json:"refresh,omitempty"`
+	LastRefresh *RateRefresh   `json:"last_refresh,omitempty"`
+	NextRefresh *time.Time     `json:"next_refresh,omitempty"`
+	Rates       []ExchangeRate `json:"rates"`
+}
+
+// Quote structure for a persisted calculation with its full breakdown
+type Quote struct {
+	ID          string                `json:"id"`
+	Tenant      string                `json:"tenant,omitempty"`
+	Status      string                `json:"status"` // open or finalized
+	Request     pricing.PriceRequest  `json:"request"`
+	Response    pricing.PriceResponse `json:"response"`
+	TraceID     string                `json:"trace_id,omitempty"`
+	CreatedAt   time.Time             `json:"created_at"`
+	FinalizedAt *time.Time            `json:"finalized_at,omitempty"`
+}
+
+// Tenant structure for a tenant with its own pricing configuration
+type Tenant struct {
+	ID        string        `json:"id"`
+	Name      string        `json:"name,omitempty"`
+	Currency  string        `json:"currency,omitempty"`
+	BasePrice pricing.Money `json:"base_price"`
+	TaxRate   float64       `json:"tax_rate"`
+}
+
+// Customer structure for a customer with negotiated prices or a tax exemption
+type Customer struct {
+	ID        string                   `json:"id"`
+	Name      string                   `json:"name,omitempty"`
+	BasePrice *pricing.Money           `json:"base_price,omitempty"`
+	Prices    map[string]pricing.Money `json:"prices,omitempty"` // Negotiated unit prices by catalog SKU
+	TaxExempt bool                     `json:"tax_exempt,omitempty"`
+}
+
+// ConfigUpdate structure for changing the default configuration, nil fields are left unchanged
+type ConfigUpdate struct {
+	BasePrice *pricing.Money        `json:"base_price,omitempty"`
+	TaxRate   *float64              `json:"tax_rate,omitempty"`
+	Currency  *string               `json:"currency,omitempty"`
+	Rounding  *pricing.RoundingMode `json:"rounding,omitempty"`
+}
+
+// ConfigVersion structure for a snapshot of the default pricing configuration
+type ConfigVersion struct {
+	Version   int                  `json:"version"`
+	Timestamp time.Time            `json:"timestamp"`
+	Author    string               `json:"author"`
+	Change    string               `json:"change"`
+	BasePrice pricing.Money        `json:"base_price"`
+	TaxRate   float64              `json:"tax_rate"`
+	Currency  string               `json:"currency,omitempty"`
+	Rounding  pricing.RoundingMode `json:"rounding,omitempty"`
+	Discounts []pricing.Discount   `json:"discounts"`
+}
+
+// ScheduleRequest structure for scheduling a change of the default base price, tax rate or both
+type ScheduleRequest struct {
+	BasePrice   *pricing.Money `json:"base_price,omitempty"`
+	TaxRate     *float64       `json:"tax_rate,omitempty"`
+	EffectiveAt time.Time      `json:"effective_at"` // Must be in the future
+}
+
+// ScheduledChange structure for a scheduled change and its status
+type ScheduledChange struct {
+	ID          string         `json:"id"`
+	BasePrice   *pricing.Money `json:"base_price,omitempty"`
+	TaxRate     *float64       `json:"tax_rate,omitempty"`
+	EffectiveAt time.Time      `json:"effective_at"`
+	Status      string         `json:"status"` // pending, applied, cancelled or failed
+	Error       string         `json:"error,omitempty"`
+	Author      string         `json:"author"`
+	TraceID     string         `json:"trace_id,omitempty"`
+	SpanID      string         `json:"span_id,omitempty"`
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+}
+
+// TaxRatePeriod structure for a default tax rate and the time it took effect
+type TaxRatePeriod struct {
+	TaxRate       float64   `json:"tax_rate"`
+	EffectiveFrom time.Time `json:"effective_from"`
+	Author        string    `json:"author,omitempty"`
+	CreatedAt     time.Time `json:"created_at,omitempty"`
+}
+
+// HistoryRecord structure for a recorded calculation
+type HistoryRecord struct {
+	ID        int                   `json:"id"`
+	Timestamp time.Time             `json:"timestamp"`
+	TraceID   string                `json:"trace_id,omitempty"`
+	Request   pricing.PriceRequest  `json:"request"`
+	Response  pricing.PriceResponse `json:"response"`
+}
+
+// HistoryResponse structure for a page of the calculation history
+type HistoryResponse struct {
+	Records []HistoryRecord `json:"records"`
+	Total   int             `json:"total"`
+	Limit   int             `json:"limit"`
+	Offset  int             `json:"offset"`
+}
+
+// HistoryFilter structure for selecting a page of the calculation history, zero fields are not sent
+type HistoryFilter struct {
+	From   time.Time
+	To     time.Time
+	Limit  int
+	Offset int
+}
+
+// AuditEntry structure for a change to the configuration
+type AuditEntry struct {
+	ID        int64           `json:"id"`
+	Timestamp time.Time       `json:"timestamp"`
+	Actor     string          `json:"actor"`
+	Action    string          `json:"action"`   // e.g. prices.updated or discount.deleted
+	Resource  string          `json:"resource"` // e.g. prices or discounts/3
+	Tenant    string          `json:"tenant,omitempty"`
+	Old       json.RawMessage `json:"old,omitempty"` // Unset when the resource was created
+	New       json.RawMessage `json:"new,omitempty"` // Unset when the resource was deleted
+	TraceID   string          `json:"trace_id,omitempty"`
+	SpanID    string          `json:"span_id,omitempty"`
+}
+
+// AuditResponse structure for a page of the audit log
+type AuditResponse struct {
+	Entries []AuditEntry `json:"entries"`
+	Total   int          `json:"total"`
+	Limit   int          `json:"limit"`
+	Offset  int          `json:"offset"`
+}
+
+// AuditFilter structure for selecting a page of the audit log, zero fields are not sent
+type AuditFilter struct {
+	Actor    string
+	Action   string
+	Resource string // Ending in / for every resource under it, such as discounts/
+	Tenant   string
+	TraceID  string
+	From     time.Time
+	To       time.Time
+	Limit    int
+	Offset   int
+}
+
+// DependencyStatus structure for the health of one dependency
+type DependencyStatus struct {
+	Name        string     `json:"name"`
+	Status      string     `json:"status"` // ok, down or disabled
+	LatencyMS   float64    `json:"latency_ms"`
+	LastError   string     `json:"last_error,omitempty"`
+	LastErrorAt *time.Time `json:"last_error_at,omitempty"`
+}
+
+// StatusResponse structure for the health of the calculator's dependencies
+type StatusResponse struct {
+	Status       string             `json:"status"` // ok or degraded
+	Dependencies []DependencyStatus `json:"dependencies"`
+}
+
+// Webhook structure for a URL notified of configuration changes
+type Webhook struct {
+	ID     string   `json:"id,omitempty"`
+	URL    string   `json:"url"`
+	Secret string   `json:"secret,omitempty"` // Only sent, never returned
+	Events []string `json:"events,omitempty"`
+}
+
+// FeatureFlag structure for the state of a feature flag
+type FeatureFlag struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Enabled     bool   `json:"enabled"`
+	Source      string `json:"source"` // default, config or override
+}
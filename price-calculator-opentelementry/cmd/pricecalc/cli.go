@@ -0,0 +1,342 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+
+	"otpl/pricecalculator/internal/config"
+	"otpl/pricecalculator/internal/httpapi"
+	"otpl/pricecalculator/internal/telemetry"
+	"otpl/pricecalculator/pricing"
+)
+
+// Tracer of the client commands, set up by initClientTracing
+var tracer trace.Tracer
+
+// Server the client commands call unless --server or PRICECALC_SERVER names another
+const defaultServerURL = "http://localhost:8080"
+
+// Timeout of a client command's call to the server
+const clientTimeout = 30 * time.Second
+
+// Creates the pricecalc command line: serve runs the server, the other commands call one.
+// Without a command the server runs, so existing deployments keep working.
+func newRootCommand() *cobra.Command {
+	var opts httpapi.ListenOptions
+	root := &cobra.Command{
+		Use:           "pricecalc",
+		Short:         "Price calculator service and client",
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		Args:          cobra.NoArgs,
+		Run:           func(cmd *cobra.Command, args []string) { httpapi.Serve(opts) },
+	}
+	addListenFlags(root, &opts)
+	server := os.Getenv("PRICECALC_SERVER")
+	if server == "" {
+		server = defaultServerURL
+	}
+	root.PersistentFlags().String("server", server, "URL of the server called by the client commands (PRICECALC_SERVER)")
+	root.PersistentFlags().String("api-key", os.Getenv("PRICECALC_API_KEY"), "API key sent with the client commands (PRICECALC_API_KEY)")
+	root.PersistentFlags().String("tenant", "", "Tenant the client commands act for")
+	root.AddCommand(newServeCommand(), newCalculateCommand(), newConfigCommand(), newLoadTestCommand(), newWorkerCommand())
+	return root
+}
+
+// Creates the serve command running the HTTP and gRPC servers
+func newServeCommand() *cobra.Command {
+	var opts httpapi.ListenOptions
+	cmd := &cobra.Command{
+		Use:   "serve",
+		Short: "Run the HTTP and gRPC servers",
+		Example: "  pricecalc serve --port 3000\n" +
+			"  pricecalc serve --bind 127.0.0.1\n" +
+			"  pricecalc serve --bind unix:/run/pricecalc.sock",
+		Args: cobra.NoArgs,
+		Run:  func(cmd *cobra.Command, args []string) { httpapi.Serve(opts) },
+	}
+	addListenFlags(cmd, &opts)
+	return cmd
+}
+
+// Adds the --bind and --port flags, defaulting to BIND_ADDR and PORT
+func addListenFlags(cmd *cobra.Command, opts *httpapi.ListenOptions) {
+	cmd.Flags().StringVar(&opts.Bind, "bind", os.Getenv("BIND_ADDR"), "Address the HTTP server binds, or unix:<path> for a Unix domain socket (BIND_ADDR)")
+	cmd.Flags().StringVar(&opts.Port, "port", os.Getenv("PORT"), "Port of the HTTP server (PORT)")
+}
+
+// Creates the worker command pricing the requests consumed from a Kafka topic
+func newWorkerCommand() *cobra.Command {
+	var brokers string
+	var opts httpapi.WorkerOptions
+	cmd := &cobra.Command{
+		Use:   "worker",
+		Short: "Price the requests consumed from a Kafka topic and publish the results",
+		Long: "Consumes PriceRequest messages from a Kafka topic, prices them like POST /calculate and\n" +
+			"publishes a reply with the result or error to the reply topic, or to the topic named by the\n" +
+			"request's reply-to header. The trace context in the request headers is continued, and the\n" +
+			"message key and correlation-id header are copied to the reply.",
+		Example: "  pricecalc worker --brokers localhost:9092 --topic price-requests --reply-topic price-responses",
+		Args:    cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if brokers == "" {
+				return fmt.Errorf("--brokers or KAFKA_BROKERS is required")
+			}
+			opts.Brokers = strings.Split(brokers, ",")
+			return httpapi.RunWorker(cmd.Context(), opts)
+		},
+	}
+	cmd.Flags().StringVar(&brokers, "brokers", os.Getenv("KAFKA_BROKERS"), "Comma separated Kafka brokers (KAFKA_BROKERS)")
+	cmd.Flags().StringVar(&opts.Topic, "topic", "price-requests", "Topic the requests are consumed from")
+	cmd.Flags().StringVar(&opts.Group, "group", "pricecalc-worker", "Consumer group the workers share the requests in")
+	cmd.Flags().StringVar(&opts.ReplyTopic, "reply-topic", "price-responses", "Topic the replies are published to")
+	return cmd
+}
+
+// Creates the calculate command pricing a request on the server
+func newCalculateCommand() *cobra.Command {
+	var request pricing.PriceRequest
+	var basePrice string
+	var taxRate float64
+	cmd := &cobra.Command{
+		Use:   "calculate",
+		Short: "Calculate a total price on the server",
+		Example: "  pricecalc calculate --base 100 --tax 18\n" +
+			"  pricecalc calculate --sku BOOK-1 --quantity 2 --currency EUR",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if cmd.Flags().Changed("base") {
+				parsed, err := pricing.ParseMoney(basePrice)
+				if err != nil {
+					return fmt.Errorf("invalid base price %q", basePrice)
+				}
+				request.BasePrice = &parsed
+			}
+			if cmd.Flags().Changed("tax") {
+				request.TaxRate = &taxRate
+			}
+			var response pricing.PriceResponse
+			return runClient(cmd, "calculate", func(ctx context.Context, c *apiClient) error {
+				if err := c.call(ctx, http.MethodPost, "/calculate", request, &response); err != nil {
+					return err
+				}
+				return printJSON(cmd.OutOrStdout(), response)
+			})
+		},
+	}
+	cmd.Flags().StringVar(&basePrice, "base", "", "Base price, the server default when omitted")
+	cmd.Flags().Float64Var(&taxRate, "tax", 0, "Tax rate in percent, the server default when omitted")
+	cmd.Flags().StringVar(&request.Currency, "currency", "", "ISO 4217 currency code")
+	cmd.Flags().IntVar(&request.Quantity, "quantity", 0, "Number of units")
+	cmd.Flags().StringVar(&request.SKU, "sku", "", "Catalog product to price instead of a base price")
+	cmd.Flags().StringVar(&request.CouponCode, "coupon", "", "Coupon code to redeem")
+	cmd.Flags().StringVar((*string)(&request.Rounding), "rounding", "", "Rounding mode, the server's configured mode when omitted")
+	return cmd
+}
+
+// Creates the config command reading and changing the server's default prices
+func newConfigCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "config",
+		Short: "Show or change the default base price and tax rate",
+	}
+	cmd.AddCommand(&cobra.Command{
+		Use:   "get",
+		Short: "Show the default base price and tax rate",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var cfg pricing.Config
+			return runClient(cmd, "config get", func(ctx context.Context, c *apiClient) error {
+				if err := c.call(ctx, http.MethodGet, "/v1/config", nil, &cfg); err != nil {
+					return err
+				}
+				return printJSON(cmd.OutOrStdout(), cfg)
+			})
+		},
+	})
+
+	var basePrice string
+	var taxRate float64
+	set := &cobra.Command{
+		Use:     "set",
+		Short:   "Change the default base price, tax rate or both",
+		Example: "  pricecalc config set --base 19.99 --tax 8.5",
+		Args:    cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var update httpapi.ConfigUpdate
+			if cmd.Flags().Changed("base") {
+				parsed, err := pricing.ParseMoney(basePrice)
+				if err != nil {
+					return fmt.Errorf("invalid base price %q", basePrice)
+				}
+				update.BasePrice = &parsed
+			}
+			if cmd.Flags().Changed("tax") {
+				update.TaxRate = &taxRate
+			}
+			if update.BasePrice == nil && update.TaxRate == nil {
+				return fmt.Errorf("--base or --tax is required")
+			}
+			var cfg pricing.Config
+			return runClient(cmd, "config set", func(ctx context.Context, c *apiClient) error {
+				if err := c.call(ctx, http.MethodPatch, "/v1/config", update, &cfg); err != nil {
+					return err
+				}
+				return printJSON(cmd.OutOrStdout(), cfg)
+			})
+		},
+	}
+	set.Flags().StringVar(&basePrice, "base", "", "New default base price")
+	set.Flags().Float64Var(&taxRate, "tax", 0, "New default tax rate in percent")
+	cmd.AddCommand(set)
+	return cmd
+}
+
+// apiClient calls the price calculator HTTP API
+type apiClient struct {
+	server string
+	apiKey string
+	tenant string
+	client *http.Client
+}
+
+// Runs a client command in its own span, so the server's spans join the command's trace.
+// The spans are exported when OTEL_EXPORTER_OTLP_ENDPOINT is set.
+func runClient(cmd *cobra.Command, name string, fn func(ctx context.Context, c *apiClient) error) error {
+	ctx := cmd.Context()
+	shutdown, err := initClientTracing(ctx)
+	if err != nil {
+		return err
+	}
+	defer shutdown()
+
+	flags := cmd.Flags()
+	c := &apiClient{client: &http.Client{Transport: otelhttp.NewTransport(http.DefaultTransport), Timeout: clientTimeout}}
+	c.server, _ = flags.GetString("server")
+	c.apiKey, _ = flags.GetString("api-key")
+	c.tenant, _ = flags.GetString("tenant")
+
+	ctx, span := tracer.Start(ctx, "pricecalc "+name)
+	defer span.End()
+	if err := fn(ctx, c); err != nil {
+		telemetry.RecordError(span, err)
+		return fmt.Errorf("%v (trace %s)", err, traceID(ctx))
+	}
+	return nil
+}
+
+// Sets up the tracer and propagators of the client commands, exporting the spans
+// only when a collector is configured. Returns a function flushing the spans.
+func initClientTracing(ctx context.Context) (func(), error) {
+	file := config.Config{}
+	if path := os.Getenv("CONFIG_FILE"); path != "" {
+		var err error
+		if file, err = config.Load(path); err != nil {
+			return nil, err
+		}
+	}
+	res, err := telemetry.NewResource(ctx, "pricecalc-cli")
+	if err != nil {
+		return nil, err
+	}
+	options := []sdktrace.TracerProviderOption{sdktrace.WithResource(res)}
+	if os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT") != "" {
+		otlpCfg, err := telemetry.LoadOTLPConfig(file.OTLP)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load OTLP configuration: %v", err)
+		}
+		exporter, err := telemetry.NewTraceExporter(ctx, otlpCfg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create OTLP exporter: %v", err)
+		}
+		options = append(options, sdktrace.WithBatcher(exporter))
+	}
+	tp := sdktrace.NewTracerProvider(options...)
+	otel.SetTracerProvider(tp)
+	propagator, err := telemetry.NewPropagator()
+	if err != nil {
+		return nil, fmt.Errorf("failed to configure propagators: %v", err)
+	}
+	otel.SetTextMapPropagator(propagator)
+	tracer = tp.Tracer("pricecalc")
+
+	return func() {
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+		defer cancel()
+		if err := tp.Shutdown(ctx); err != nil {
+			fmt.Fprintln(os.Stderr, "Error exporting spans:", err)
+		}
+	}, nil
+}
+
+// Calls an API endpoint with body encoded as JSON, decoding the response into out.
+// Problem responses are returned as errors.
+func (c *apiClient) call(ctx context.Context, method, path string, body, out any) error {
+	if c.tenant != "" {
+		path = "/tenants/" + url.PathEscape(c.tenant) + path
+	}
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to encode request: %v", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimSuffix(c.server, "/")+path, reader)
+	if err != nil {
+		return fmt.Errorf("invalid server URL: %v", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.apiKey != "" {
+		req.Header.Set(httpapi.APIKeyHeader, c.apiKey)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to call server: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= http.StatusBadRequest {
+		var problem httpapi.Problem
+		if err := json.NewDecoder(resp.Body).Decode(&problem); err != nil || problem.Title == "" {
+			return fmt.Errorf("server answered %s", resp.Status)
+		}
+		if problem.Detail == "" {
+			return fmt.Errorf("%s", problem.Title)
+		}
+		return fmt.Errorf("%s: %s", problem.Title, problem.Detail)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("invalid response: %v", err)
+	}
+	return nil
+}
+
+// Writes v as indented JSON
+func printJSON(w io.Writer, v any) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+// Returns the ID of the trace a client command runs in, reported with its errors
+func traceID(ctx context.Context) string {
+	return trace.SpanContextFromContext(ctx).TraceID().String()
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/spf13/cobra"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"otpl/pricecalculator/internal/telemetry"
+)
+
+// LoadTestReport structure for the results of a load test
+type LoadTestReport struct {
+	Requests int     `json:"requests"`
+	Errors   int     `json:"errors"`
+	Duration string  `json:"duration"`
+	RPS      float64 `json:"rps"` // Achieved requests per second
+	P50      string  `json:"p50"`
+	P90      string  `json:"p90"`
+	P99      string  `json:"p99"`
+	Max      string  `json:"max"`
+}
+
+// Creates the loadtest command driving an endpoint of the server
+func newLoadTestCommand() *cobra.Command {
+	var path, body string
+	var rps float64
+	var concurrency int
+	var duration time.Duration
+	cmd := &cobra.Command{
+		Use:   "loadtest",
+		Short: "Drive an endpoint at a request rate and report the latency percentiles",
+		Example: "  pricecalc loadtest --rps 100 --concurrency 10 --duration 30s\n" +
+			"  pricecalc loadtest --path /calculate/batch --body '[{}, {}]'",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if rps < 0 || concurrency < 1 || duration <= 0 {
+				return fmt.Errorf("--rps must not be negative, --concurrency and --duration must be positive")
+			}
+			var payload json.RawMessage
+			if err := json.Unmarshal([]byte(body), &payload); err != nil {
+				return fmt.Errorf("invalid --body: %v", err)
+			}
+			return runClient(cmd, "loadtest", func(ctx context.Context, c *apiClient) error {
+				report := runLoadTest(ctx, c, path, payload, rps, concurrency, duration)
+				trace.SpanFromContext(ctx).SetAttributes(
+					attribute.Int("loadtest.requests", report.Requests),
+					attribute.Int("loadtest.errors", report.Errors),
+				)
+				return printJSON(cmd.OutOrStdout(), report)
+			})
+		},
+	}
+	cmd.Flags().StringVar(&path, "path", "/calculate", "Endpoint to POST to")
+	cmd.Flags().StringVar(&body, "body", "{}", "JSON request body")
+	cmd.Flags().Float64Var(&rps, "rps", 10, "Requests per second, 0 for as fast as the workers go")
+	cmd.Flags().IntVar(&concurrency, "concurrency", 4, "Number of concurrent workers")
+	cmd.Flags().DurationVar(&duration, "duration", 10*time.Second, "How long to send requests")
+	return cmd
+}
+
+// Sends requests until the duration is over, paced to rps, and measures their latency.
+// Each request is traced as its own root span linked to the load test's span, so the
+// server spans of a request are found by its trace rather than in one huge trace.
+func runLoadTest(ctx context.Context, c *apiClient, path string, payload json.RawMessage, rps float64, concurrency int, duration time.Duration) LoadTestReport {
+	ctx, cancel := context.WithTimeout(ctx, duration)
+	defer cancel()
+	link := trace.LinkFromContext(ctx)
+
+	// Hand out the requests at the target rate
+	ticks := make(chan struct{})
+	go func() {
+		defer close(ticks)
+		var pace <-chan time.Time
+		if rps > 0 {
+			ticker := time.NewTicker(time.Duration(float64(time.Second) / rps))
+			defer ticker.Stop()
+			pace = ticker.C
+		}
+		for {
+			if pace != nil {
+				select {
+				case <-pace:
+				case <-ctx.Done():
+					return
+				}
+			}
+			select {
+			case ticks <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	var mu sync.Mutex
+	var latencies []time.Duration
+	errors := 0
+	start := time.Now()
+	var wg sync.WaitGroup
+	for range concurrency {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range ticks {
+				reqCtx, span := tracer.Start(context.WithoutCancel(ctx), "loadtest request", trace.WithNewRoot(), trace.WithLinks(link))
+				began := time.Now()
+				err := c.call(reqCtx, http.MethodPost, path, payload, &json.RawMessage{})
+				elapsed := time.Since(began)
+				if err != nil {
+					telemetry.RecordError(span, err)
+				}
+				span.End()
+
+				mu.Lock()
+				latencies = append(latencies, elapsed)
+				if err != nil {
+					errors++
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	elapsed := time.Since(start)
+
+	slices.Sort(latencies)
+	return LoadTestReport{
+		Requests: len(latencies),
+		Errors:   errors,
+		Duration: elapsed.Round(time.Millisecond).String(),
+		RPS:      float64(len(latencies)) / elapsed.Seconds(),
+		P50:      percentile(latencies, 50).String(),
+		P90:      percentile(latencies, 90).String(),
+		P99:      percentile(latencies, 99).String(),
+		Max:      percentile(latencies, 100).String(),
+	}
+}
+
+// Returns the p-th percentile of sorted latencies, zero when there are none
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	i := int(float64(len(sorted))*p/100+0.5) - 1
+	return sorted[min(max(i, 0), len(sorted)-1)]
+}
@@ -0,0 +1,210 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: pricecalculator/v1/price_calculator.proto
+
+package pricecalculatorv1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	PriceCalculatorService_Calculate_FullMethodName    = "/pricecalculator.v1.PriceCalculatorService/Calculate"
+	PriceCalculatorService_SetBasePrice_FullMethodName = "/pricecalculator.v1.PriceCalculatorService/SetBasePrice"
+	PriceCalculatorService_SetTaxRate_FullMethodName   = "/pricecalculator.v1.PriceCalculatorService/SetTaxRate"
+)
+
+// PriceCalculatorServiceClient is the client API for PriceCalculatorService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// PriceCalculatorService exposes the price calculator over gRPC.
+// Amounts are decimal strings such as "107.99" so no precision is lost.
+type PriceCalculatorServiceClient interface {
+	// Calculate computes the total price, omitted fields fall back to the stored defaults.
+	Calculate(ctx context.Context, in *CalculateRequest, opts ...grpc.CallOption) (*CalculateResponse, error)
+	// SetBasePrice stores the default base price.
+	SetBasePrice(ctx context.Context, in *SetBasePriceRequest, opts ...grpc.CallOption) (*SetBasePriceResponse, error)
+	// SetTaxRate stores the default tax rate.
+	SetTaxRate(ctx context.Context, in *SetTaxRateRequest, opts ...grpc.CallOption) (*SetTaxRateResponse, error)
+}
+
+type priceCalculatorServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewPriceCalculatorServiceClient(cc grpc.ClientConnInterface) PriceCalculatorServiceClient {
+	return &priceCalculatorServiceClient{cc}
+}
+
+func (c *priceCalculatorServiceClient) Calculate(ctx context.Context, in *CalculateRequest, opts ...grpc.CallOption) (*CalculateResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CalculateResponse)
+	err := c.cc.Invoke(ctx, PriceCalculatorService_Calculate_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *priceCalculatorServiceClient) SetBasePrice(ctx context.Context, in *SetBasePriceRequest, opts ...grpc.CallOption) (*SetBasePriceResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SetBasePriceResponse)
+	err := c.cc.Invoke(ctx, PriceCalculatorService_SetBasePrice_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *priceCalculatorServiceClient) SetTaxRate(ctx context.Context, in *SetTaxRateRequest, opts ...grpc.CallOption) (*SetTaxRateResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SetTaxRateResponse)
+	err := c.cc.Invoke(ctx, PriceCalculatorService_SetTaxRate_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// PriceCalculatorServiceServer is the server API for PriceCalculatorService service.
+// All implementations must embed UnimplementedPriceCalculatorServiceServer
+// for forward compatibility.
+//
+// PriceCalculatorService exposes the price calculator over gRPC.
+// Amounts are decimal strings such as "107.99" so no precision is lost.
+type PriceCalculatorServiceServer interface {
+	// Calculate computes the total price, omitted fields fall back to the stored defaults.
+	Calculate(context.Context, *CalculateRequest) (*CalculateResponse, error)
+	// SetBasePrice stores the default base price.
+	SetBasePrice(context.Context, *SetBasePriceRequest) (*SetBasePriceResponse, error)
+	// SetTaxRate stores the default tax rate.
+	SetTaxRate(context.Context, *SetTaxRateRequest) (*SetTaxRateResponse, error)
+	mustEmbedUnimplementedPriceCalculatorServiceServer()
+}
+
+// UnimplementedPriceCalculatorServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedPriceCalculatorServiceServer struct{}
+
+func (UnimplementedPriceCalculatorServiceServer) Calculate(context.Context, *CalculateRequest) (*CalculateResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Calculate not implemented")
+}
+func (UnimplementedPriceCalculatorServiceServer) SetBasePrice(context.Context, *SetBasePriceRequest) (*SetBasePriceResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetBasePrice not implemented")
+}
+func (UnimplementedPriceCalculatorServiceServer) SetTaxRate(context.Context, *SetTaxRateRequest) (*SetTaxRateResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetTaxRate not implemented")
+}
+func (UnimplementedPriceCalculatorServiceServer) mustEmbedUnimplementedPriceCalculatorServiceServer() {
+}
+func (UnimplementedPriceCalculatorServiceServer) testEmbeddedByValue() {}
+
+// UnsafePriceCalculatorServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to PriceCalculatorServiceServer will
+// result in compilation errors.
+type UnsafePriceCalculatorServiceServer interface {
+	mustEmbedUnimplementedPriceCalculatorServiceServer()
+}
+
+func RegisterPriceCalculatorServiceServer(s grpc.ServiceRegistrar, srv PriceCalculatorServiceServer) {
+	// If the following call pancis, it indicates UnimplementedPriceCalculatorServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&PriceCalculatorService_ServiceDesc, srv)
+}
+
+func _PriceCalculatorService_Calculate_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CalculateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PriceCalculatorServiceServer).Calculate(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PriceCalculatorService_Calculate_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PriceCalculatorServiceServer).Calculate(ctx, req.(*CalculateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PriceCalculatorService_SetBasePrice_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetBasePriceRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PriceCalculatorServiceServer).SetBasePrice(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PriceCalculatorService_SetBasePrice_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PriceCalculatorServiceServer).SetBasePrice(ctx, req.(*SetBasePriceRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PriceCalculatorService_SetTaxRate_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetTaxRateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PriceCalculatorServiceServer).SetTaxRate(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PriceCalculatorService_SetTaxRate_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PriceCalculatorServiceServer).SetTaxRate(ctx, req.(*SetTaxRateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// PriceCalculatorService_ServiceDesc is the grpc.ServiceDesc for PriceCalculatorService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var PriceCalculatorService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "pricecalculator.v1.PriceCalculatorService",
+	HandlerType: (*PriceCalculatorServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Calculate",
+			Handler:    _PriceCalculatorService_Calculate_Handler,
+		},
+		{
+			MethodName: "SetBasePrice",
+			Handler:    _PriceCalculatorService_SetBasePrice_Handler,
+		},
+		{
+			MethodName: "SetTaxRate",
+			Handler:    _PriceCalculatorService_SetTaxRate_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "pricecalculator/v1/price_calculator.proto",
+}
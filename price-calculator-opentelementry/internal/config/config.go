@@ -0,0 +1,668 @@
+// Package config reads the optional YAML configuration file of the price calculator
+// and watches it for changes.
+package config
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"gopkg.in/yaml.v3"
+
+	"otpl/pricecalculator/internal/cron"
+
+	"otpl/pricecalculator/pricing"
+)
+
+// Time to wait for a burst of file events to settle before reloading,
+// editors often write a file in several steps
+const reloadDelay = 100 * time.Millisecond
+
+// Config structure for the configuration file, unset fields keep their defaults
+type Config struct {
+	Server    Server           `yaml:"server"`
+	OTLP      OTLP             `yaml:"otlp"`
+	Pricing   Pricing          `yaml:"pricing"`
+	LogLevel  string           `yaml:"log_level"`
+	Features  map[string]bool  `yaml:"features"` // Features are enabled unless set to false
+	APIKeys   []string         `yaml:"api_keys"` // Keys accepted by the set and admin endpoints
+	RateLimit RateLimit        `yaml:"rate_limit"`
+	Chaos     map[string]Fault `yaml:"chaos"` // Faults by operation name, "*" for every endpoint
+	Timeouts  Timeouts         `yaml:"timeouts"`
+	Rounding  Rounding         `yaml:"rounding"`
+	Payloads  PayloadCapture   `yaml:"payload_capture"`
+	AccessLog AccessLog        `yaml:"access_log"`
+	CORS      CORS             `yaml:"cors"`
+	UI        UI               `yaml:"ui"`
+	Invoice   Invoice          `yaml:"invoice"`
+	Cart      Cart             `yaml:"cart"`
+	SLO       SLO              `yaml:"slo"`
+
+	ExchangeRates ExchangeRates `yaml:"exchange_rates"`
+
+	// Span exporters, each with its own span processor, defaults to OTLP only. Changes require a restart
+	TraceExporters []TraceExporter `yaml:"trace_exporters"`
+
+	// Certificates of the HTTP and gRPC servers, TLS is disabled without them. Changes require a restart
+	TLS TLS `yaml:"tls"`
+
+	// Where the configuration, catalog, quotes, coupons and history are kept. Changes require a restart
+	Storage Storage `yaml:"storage"`
+}
+
+// TLS structure for the server certificates, either certificate files or Let's Encrypt.
+// Overridden by TLS_CERT_FILE, TLS_KEY_FILE and TLS_AUTOCERT_DOMAINS.
+type TLS struct {
+	CertFile string   `yaml:"cert_file"` // PEM certificate chain, reloaded when it changes and on SIGHUP
+	KeyFile  string   `yaml:"key_file"`  // PEM private key
+	Autocert Autocert `yaml:"autocert"`
+}
+
+// Autocert structure for obtaining certificates from Let's Encrypt
+type Autocert struct {
+	Domains  []string `yaml:"domains"`   // Hosts certificates are obtained for
+	Email    string   `yaml:"email"`     // Contact for expiry notices from Let's Encrypt
+	CacheDir string   `yaml:"cache_dir"` // Where obtained certificates are kept, defaults to autocert
+}
+
+// Validate checks that the certificate and key are set together and not combined with Let's Encrypt
+func (t TLS) Validate() error {
+	if (t.CertFile == "") != (t.KeyFile == "") {
+		return fmt.Errorf("tls cert_file and key_file must be set together")
+	}
+	if t.CertFile != "" && len(t.Autocert.Domains) > 0 {
+		return fmt.Errorf("tls cert_file and autocert domains are mutually exclusive")
+	}
+	return nil
+}
+
+// Storage structure for the persistence settings, overridden by STORAGE_DRIVER and STORAGE_PATH
+type Storage struct {
+	Driver string `yaml:"driver"` // memory, file (default), database or sqlite
+	Path   string `yaml:"path"`   // Configuration file of the file and sqlite drivers
+}
+
+// Validate checks the storage driver
+func (s Storage) Validate() error {
+	switch s.Driver {
+	case "", "memory", "file", "database", "sqlite":
+		return nil
+	default:
+		return fmt.Errorf("unknown storage driver %q", s.Driver)
+	}
+}
+
+// Supported span exporter types
+const (
+	ExporterOTLP   = "otlp"   // The collector configured in the otlp section
+	ExporterStdout = "stdout" // Standard output
+	ExporterFile   = "file"   // Appended to a file, one JSON span per line
+)
+
+// TraceExporter structure for a destination of the spans
+type TraceExporter struct {
+	Type        string `yaml:"type"`
+	Path        string `yaml:"path"`         // File the file exporter appends to
+	PrettyPrint bool   `yaml:"pretty_print"` // Indent the JSON written by the stdout and file exporters
+
+	// Stops calling an unreachable collector, only used by the otlp exporter
+	CircuitBreaker CircuitBreaker `yaml:"circuit_breaker"`
+
+	// Batching of the spans before they are exported
+	Batch BatchSpanProcessor `yaml:"batch"`
+}
+
+// BatchSpanProcessor structure for tuning the batch span processor of an exporter. Overridden for
+// every exporter by OTEL_BSP_MAX_QUEUE_SIZE, OTEL_BSP_MAX_EXPORT_BATCH_SIZE, OTEL_BSP_SCHEDULE_DELAY
+// and OTEL_BSP_EXPORT_TIMEOUT.
+type BatchSpanProcessor struct {
+	MaxQueueSize       int           `yaml:"max_queue_size"`        // Spans waiting for export, more are dropped, defaults to 2048
+	MaxExportBatchSize int           `yaml:"max_export_batch_size"` // Spans sent per export, defaults to 512
+	BatchTimeout       time.Duration `yaml:"batch_timeout"`         // Longest wait before a partial batch is sent, defaults to 5s
+	ExportTimeout      time.Duration `yaml:"export_timeout"`        // Timeout of an export, defaults to 30s
+}
+
+// Validate checks that the settings are not negative and a batch fits in the queue
+func (b BatchSpanProcessor) Validate() error {
+	if b.MaxQueueSize < 0 || b.MaxExportBatchSize < 0 || b.BatchTimeout < 0 || b.ExportTimeout < 0 {
+		return fmt.Errorf("batch settings must not be negative")
+	}
+	if b.MaxQueueSize > 0 && b.MaxExportBatchSize > b.MaxQueueSize {
+		return fmt.Errorf("batch max_export_batch_size must not exceed max_queue_size")
+	}
+	return nil
+}
+
+// CircuitBreaker structure for the circuit breaker of an OTLP exporter
+type CircuitBreaker struct {
+	FailureThreshold int           `yaml:"failure_threshold"` // Consecutive failed exports opening the circuit, defaults to 3
+	OpenTimeout      time.Duration `yaml:"open_timeout"`      // Time before a trial export while open, defaults to 30s
+	FallbackPath     string        `yaml:"fallback_path"`     // File the spans are appended to while open, dropped when empty
+}
+
+// Validate checks the exporter type and that file exporters have a path
+func (e TraceExporter) Validate() error {
+	switch e.Type {
+	case ExporterOTLP, ExporterStdout:
+	case ExporterFile:
+		if e.Path == "" {
+			return fmt.Errorf("file exporter requires a path")
+		}
+	default:
+		return fmt.Errorf("unknown trace exporter type %q", e.Type)
+	}
+	if e.CircuitBreaker.FailureThreshold < 0 || e.CircuitBreaker.OpenTimeout < 0 {
+		return fmt.Errorf("circuit breaker settings must not be negative")
+	}
+	return e.Batch.Validate()
+}
+
+// PayloadCapture structure for recording request and response bodies on the spans, disabled by default
+type PayloadCapture struct {
+	Enabled    bool     `yaml:"enabled"`
+	MaxBytes   int      `yaml:"max_bytes"`  // Longer bodies are truncated, defaults to 4096
+	Redact     []string `yaml:"redact"`     // JSON fields whose values are replaced, in addition to the built-in ones
+	Operations []string `yaml:"operations"` // Operation names captured, every endpoint when empty
+}
+
+// Validate checks that the size cap is not negative
+func (p PayloadCapture) Validate() error {
+	if p.MaxBytes < 0 {
+		return fmt.Errorf("payload capture max_bytes must not be negative")
+	}
+	return nil
+}
+
+// AccessLog structure for logging a line per API request, disabled by default
+type AccessLog struct {
+	Enabled    bool    `yaml:"enabled"`
+	SampleRate float64 `yaml:"sample_rate"` // Share of the successful requests logged, defaults to 1. Failed requests are always logged
+}
+
+// Validate checks that the sample rate is a share
+func (a AccessLog) Validate() error {
+	if a.SampleRate < 0 || a.SampleRate > 1 {
+		return fmt.Errorf("access log sample_rate must be between 0 and 1")
+	}
+	return nil
+}
+
+// UI structure for the settings of the demo web UI
+type UI struct {
+	TraceURL string `yaml:"trace_url"` // Link to a trace in the tracing backend, {trace_id} is replaced
+}
+
+// Invoice structure for rendering finalized quotes as invoices
+type Invoice struct {
+	TemplateDir string `yaml:"template_dir"` // invoice.html and invoice.txt here override the built-in templates
+}
+
+// Cart structure for the concurrent calculation of the lines of large carts
+type Cart struct {
+	Parallelism      int `yaml:"parallelism"`        // Lines calculated concurrently, defaults to the number of CPUs, 1 is sequential
+	ParallelMinLines int `yaml:"parallel_min_lines"` // Carts with fewer lines are calculated sequentially, defaults to 100
+}
+
+// Validate checks that the settings are not negative
+func (c Cart) Validate() error {
+	if c.Parallelism < 0 || c.ParallelMinLines < 0 {
+		return fmt.Errorf("cart parallelism settings must not be negative")
+	}
+	return nil
+}
+
+// ExchangeRates structure for the scheduled refresh of the exchange rates from the provider named by EXCHANGE_RATE_URL
+type ExchangeRates struct {
+	Refresh    string   `yaml:"refresh"`    // Cron schedule such as "0 * * * *" or "@every 15m", no scheduled refresh when empty
+	Base       string   `yaml:"base"`       // Currency the rates are fetched for, defaults to USD
+	Currencies []string `yaml:"currencies"` // Currencies fetched, defaults to those of the static rates
+}
+
+// Validate checks that the refresh schedule is valid and runs
+func (e ExchangeRates) Validate() error {
+	if e.Refresh == "" {
+		return nil
+	}
+	schedule, err := cron.Parse(e.Refresh)
+	if err != nil {
+		return fmt.Errorf("invalid exchange rate refresh: %v", err)
+	}
+	if schedule.Next(time.Now()).IsZero() {
+		return fmt.Errorf("exchange rate refresh %q never runs", e.Refresh)
+	}
+	return nil
+}
+
+// Types of service level objectives
+const (
+	ObjectiveLatency = "latency" // Requests slower than the threshold are bad
+	ObjectiveErrors  = "errors"  // Requests answered with a 5xx status are bad
+)
+
+// SLO structure for the service level objectives evaluated from the request duration metric
+type SLO struct {
+	Windows    []time.Duration `yaml:"windows"` // Windows of the burn rates, defaults to 5m, 1h and 6h
+	Objectives []Objective     `yaml:"objectives"`
+}
+
+// Objective structure for a latency or error rate objective of the requests to an endpoint, a tenant or both
+type Objective struct {
+	Name      string        `yaml:"name"`
+	Type      string        `yaml:"type"`      // latency or errors
+	Operation string        `yaml:"operation"` // Operation name such as CalculatePrice, every endpoint when empty
+	Tenant    string        `yaml:"tenant"`    // Tenant ID, every request when empty
+	Threshold time.Duration `yaml:"threshold"` // Latency objectives only
+	Target    float64       `yaml:"target"`    // Fraction of good requests, e.g. 0.99
+}
+
+// Validate checks the windows and that every objective is complete for its type, with a unique name
+func (s SLO) Validate() error {
+	for _, window := range s.Windows {
+		if window <= 0 {
+			return fmt.Errorf("SLO windows must be positive")
+		}
+	}
+	names := map[string]bool{}
+	for _, o := range s.Objectives {
+		if o.Name == "" {
+			return fmt.Errorf("SLO objective requires a name")
+		}
+		if names[o.Name] {
+			return fmt.Errorf("duplicate SLO objective %q", o.Name)
+		}
+		names[o.Name] = true
+		switch o.Type {
+		case ObjectiveLatency:
+			if o.Threshold <= 0 {
+				return fmt.Errorf("latency objective %q requires a positive threshold", o.Name)
+			}
+		case ObjectiveErrors:
+		default:
+			return fmt.Errorf("unknown type %q of SLO objective %q", o.Type, o.Name)
+		}
+		if o.Target <= 0 || o.Target >= 1 {
+			return fmt.Errorf("target of SLO objective %q must be between 0 and 1", o.Name)
+		}
+	}
+	return nil
+}
+
+// CORS structure for the cross-origin requests browsers may make, disabled without allowed origins
+type CORS struct {
+	AllowedOrigins   []string `yaml:"allowed_origins"`   // Origins such as https://demo.example.com, "*" for any
+	AllowedMethods   []string `yaml:"allowed_methods"`   // Defaults to GET, POST, PUT, PATCH and DELETE
+	AllowedHeaders   []string `yaml:"allowed_headers"`   // Defaults to the API and trace context request headers
+	ExposedHeaders   []string `yaml:"exposed_headers"`   // Defaults to the API response headers
+	MaxAge           int      `yaml:"max_age"`           // Seconds browsers cache a preflight response, defaults to 600
+	AllowCredentials bool     `yaml:"allow_credentials"` // Allow cookies and HTTP authentication
+}
+
+// Validate checks the preflight cache time and that credentials are not allowed for any origin
+func (c CORS) Validate() error {
+	if c.MaxAge < 0 {
+		return fmt.Errorf("cors max_age must not be negative")
+	}
+	if c.AllowCredentials && slices.Contains(c.AllowedOrigins, "*") {
+		return fmt.Errorf("cors allow_credentials cannot be combined with the \"*\" origin")
+	}
+	return nil
+}
+
+// Rounding structure for the rounding mode of calculated amounts
+type Rounding struct {
+	Mode       pricing.RoundingMode            `yaml:"mode"`       // Defaults to half_even
+	Currencies map[string]pricing.RoundingMode `yaml:"currencies"` // By ISO 4217 code, overriding Mode
+}
+
+// Validate checks the rounding modes and currency codes
+func (r Rounding) Validate() error {
+	if _, err := pricing.ParseRoundingMode(string(r.Mode)); err != nil {
+		return err
+	}
+	for code, mode := range r.Currencies {
+		if _, err := pricing.LookupCurrency(code); err != nil {
+			return err
+		}
+		if _, err := pricing.ParseRoundingMode(string(mode)); err != nil {
+			return fmt.Errorf("rounding of %s: %v", code, err)
+		}
+	}
+	return nil
+}
+
+// Timeouts structure for the time a request may take before it is answered with 503
+type Timeouts struct {
+	Request   time.Duration            `yaml:"request"`   // Every endpoint, defaults to 30s
+	Endpoints map[string]time.Duration `yaml:"endpoints"` // By operation name, overriding Request
+}
+
+// Validate checks that the timeouts are not negative
+func (t Timeouts) Validate() error {
+	if t.Request < 0 {
+		return fmt.Errorf("request timeout must not be negative")
+	}
+	for operation, timeout := range t.Endpoints {
+		if timeout <= 0 {
+			return fmt.Errorf("timeout of %s must be positive", operation)
+		}
+	}
+	return nil
+}
+
+// Fault structure for the faults injected into an endpoint for tracing demos
+type Fault struct {
+	Latency     time.Duration `yaml:"latency"`      // Added to every request
+	Jitter      time.Duration `yaml:"jitter"`       // Random extra latency up to this much
+	ErrorRate   float64       `yaml:"error_rate"`   // Fraction of requests failed with 500
+	TimeoutRate float64       `yaml:"timeout_rate"` // Fraction of requests failed with 504 after a timed out downstream call
+	Timeout     time.Duration `yaml:"timeout"`      // How long the downstream call waits, defaults to 2s
+}
+
+// Validate checks that the durations are not negative and the rates are fractions
+func (f Fault) Validate() error {
+	if f.Latency < 0 || f.Jitter < 0 || f.Timeout < 0 {
+		return fmt.Errorf("fault durations must not be negative")
+	}
+	if f.ErrorRate < 0 || f.ErrorRate > 1 || f.TimeoutRate < 0 || f.TimeoutRate > 1 {
+		return fmt.Errorf("fault rates must be between 0 and 1")
+	}
+	return nil
+}
+
+// RateLimit structure for the per client rate limit, disabled when the rate is zero
+type RateLimit struct {
+	RequestsPerSecond float64 `yaml:"requests_per_second"`
+	Burst             int     `yaml:"burst"` // Defaults to the rate
+}
+
+// Server structure for the listen addresses, changes require a restart
+type Server struct {
+	HTTPAddr     string `yaml:"http_addr"` // host:port, or unix:<path> for a Unix domain socket
+	GRPCAddr     string `yaml:"grpc_addr"`
+	MaxBodyBytes int64  `yaml:"max_body_bytes"` // Limit of a request body after decompression, defaults to 1 MiB
+	Gzip         *bool  `yaml:"gzip"`           // Decompresses gzip request bodies and compresses responses, enabled by default
+}
+
+// OTLP structure for the collector connection, changes require a restart
+type OTLP struct {
+	Endpoint string    `yaml:"endpoint"`
+	Protocol string    `yaml:"protocol"`
+	Insecure *bool     `yaml:"insecure"`
+	Retry    OTLPRetry `yaml:"retry"`
+}
+
+// OTLPRetry structure for retrying the exports a collector failed with exponential backoff
+type OTLPRetry struct {
+	Enabled         *bool         `yaml:"enabled"`          // Enabled by default
+	InitialInterval time.Duration `yaml:"initial_interval"` // Wait before the first retry, doubled for every further retry, defaults to 5s
+	MaxInterval     time.Duration `yaml:"max_interval"`     // Longest wait between retries, defaults to 30s
+	MaxElapsedTime  time.Duration `yaml:"max_elapsed_time"` // Time after which a failed export is given up, defaults to 1m
+}
+
+// Validate checks that the intervals are not negative and the first one does not exceed the longest
+func (r OTLPRetry) Validate() error {
+	if r.InitialInterval < 0 || r.MaxInterval < 0 || r.MaxElapsedTime < 0 {
+		return fmt.Errorf("OTLP retry intervals must not be negative")
+	}
+	if r.InitialInterval > 0 && r.MaxInterval > 0 && r.InitialInterval > r.MaxInterval {
+		return fmt.Errorf("OTLP retry initial_interval must not exceed max_interval")
+	}
+	return nil
+}
+
+// Pricing structure for the default base price, tax rate and volume price breaks
+type Pricing struct {
+	BasePrice *string  `yaml:"base_price"` // Decimal amount, kept as text to avoid float rounding
+	TaxRate   *float64 `yaml:"tax_rate"`
+	Tiers     []Tier   `yaml:"tiers"` // Applied to products without tiers of their own
+
+	Guardrails Guardrails       `yaml:"guardrails"`
+	Stacking   DiscountStacking `yaml:"discount_stacking"`
+}
+
+// DiscountStacking structure for the policy combining eligible discount rules and coupons
+type DiscountStacking struct {
+	Policy string `yaml:"policy"` // stack_all (default), best_single, stack_up_to_n or priority
+	Limit  int    `yaml:"limit"`  // Most discounts applied by stack_up_to_n and priority
+}
+
+// Guardrails structure for the limits of calculated prices, unchecked when no limit is set
+type Guardrails struct {
+	MinTotal  *string  `yaml:"min_total"` // Decimal amounts, kept as text to avoid float rounding
+	MaxTotal  *string  `yaml:"max_total"`
+	MinMargin *float64 `yaml:"min_margin"` // Percentage of the pre-tax price left over the product's unit cost
+	MaxMargin *float64 `yaml:"max_margin"`
+	Action    string   `yaml:"action"` // reject (default) or clamp, totals only
+}
+
+// Tier structure for a volume price break, percentage off the unit price from min_quantity units
+type Tier struct {
+	MinQuantity int     `yaml:"min_quantity"`
+	Percentage  float64 `yaml:"percentage"`
+}
+
+// Setting returns the environment variable if it is set, the value from the configuration file otherwise
+func Setting(env string, fileValue string) string {
+	if value := os.Getenv(env); value != "" {
+		return value
+	}
+	return fileValue
+}
+
+// Reads and validates the configuration file at path
+func Load(path string) (Config, error) {
+	var cfg Config
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return cfg, fmt.Errorf("failed to read config file: %v", err)
+	}
+
+	// Reject unknown keys so typos are not silently ignored
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&cfg); err != nil && !errors.Is(err, io.EOF) {
+		return cfg, fmt.Errorf("failed to parse config file: %v", err)
+	}
+	if err := cfg.Validate(); err != nil {
+		return cfg, fmt.Errorf("invalid config file: %v", err)
+	}
+	return cfg, nil
+}
+
+// Validate checks the values that are not checked by the YAML decoding
+func (c Config) Validate() error {
+	switch c.OTLP.Protocol {
+	case "", "http/protobuf", "grpc":
+	default:
+		return fmt.Errorf("unsupported OTLP protocol %q", c.OTLP.Protocol)
+	}
+	if err := c.OTLP.Retry.Validate(); err != nil {
+		return err
+	}
+	if c.RateLimit.RequestsPerSecond < 0 || c.RateLimit.Burst < 0 {
+		return fmt.Errorf("rate limit must not be negative")
+	}
+	if _, err := c.Pricing.BasePriceMoney(); err != nil {
+		return err
+	}
+	if _, err := c.Pricing.PriceGuardrails(); err != nil {
+		return err
+	}
+	if err := c.Pricing.StackingPolicy().Validate(); err != nil {
+		return err
+	}
+	if c.Pricing.TaxRate != nil {
+		if err := pricing.ValidateTaxRate(*c.Pricing.TaxRate); err != nil {
+			return err
+		}
+	}
+	if err := pricing.ValidateTiers(c.Pricing.PriceTiers()); err != nil {
+		return fmt.Errorf("pricing tiers: %v", err)
+	}
+	for operation, fault := range c.Chaos {
+		if err := fault.Validate(); err != nil {
+			return fmt.Errorf("chaos %s: %v", operation, err)
+		}
+	}
+	if err := c.Timeouts.Validate(); err != nil {
+		return err
+	}
+	if err := c.Rounding.Validate(); err != nil {
+		return err
+	}
+	if err := c.Payloads.Validate(); err != nil {
+		return err
+	}
+	if err := c.AccessLog.Validate(); err != nil {
+		return err
+	}
+	if c.Server.MaxBodyBytes < 0 {
+		return fmt.Errorf("server max_body_bytes must not be negative")
+	}
+	if err := c.ExchangeRates.Validate(); err != nil {
+		return err
+	}
+	if err := c.SLO.Validate(); err != nil {
+		return err
+	}
+	if err := c.Cart.Validate(); err != nil {
+		return err
+	}
+	if err := c.CORS.Validate(); err != nil {
+		return err
+	}
+	if err := c.TLS.Validate(); err != nil {
+		return err
+	}
+	if err := c.Storage.Validate(); err != nil {
+		return err
+	}
+	for _, exporter := range c.TraceExporters {
+		if err := exporter.Validate(); err != nil {
+			return err
+		}
+	}
+	if c.LogLevel != "" {
+		var level slog.Level
+		if err := level.UnmarshalText([]byte(strings.ToUpper(c.LogLevel))); err != nil {
+			return fmt.Errorf("invalid log level %q", c.LogLevel)
+		}
+	}
+	return nil
+}
+
+// BasePriceMoney parses the configured base price, nil when it is not set
+func (p Pricing) BasePriceMoney() (*pricing.Money, error) {
+	if p.BasePrice == nil {
+		return nil, nil
+	}
+	basePrice, err := pricing.ParseMoney(*p.BasePrice)
+	if err != nil {
+		return nil, fmt.Errorf("invalid base price %q", *p.BasePrice)
+	}
+	if err := pricing.ValidateBasePrice(basePrice); err != nil {
+		return nil, err
+	}
+	return &basePrice, nil
+}
+
+// PriceGuardrails parses the configured guardrails, nil when no limit is set
+func (p Pricing) PriceGuardrails() (*pricing.Guardrails, error) {
+	g := p.Guardrails
+	if g.MinTotal == nil && g.MaxTotal == nil && g.MinMargin == nil && g.MaxMargin == nil {
+		return nil, nil
+	}
+	guardrails := &pricing.Guardrails{MinMargin: g.MinMargin, MaxMargin: g.MaxMargin, Action: g.Action}
+	for _, limit := range []struct {
+		text *string
+		dst  **pricing.Money
+	}{{g.MinTotal, &guardrails.MinTotal}, {g.MaxTotal, &guardrails.MaxTotal}} {
+		if limit.text == nil {
+			continue
+		}
+		amount, err := pricing.ParseMoney(*limit.text)
+		if err != nil {
+			return nil, fmt.Errorf("invalid guardrail total %q", *limit.text)
+		}
+		*limit.dst = &amount
+	}
+	if err := guardrails.Validate(); err != nil {
+		return nil, err
+	}
+	return guardrails, nil
+}
+
+// StackingPolicy returns the configured discount stacking policy
+func (p Pricing) StackingPolicy() pricing.StackingPolicy {
+	return pricing.StackingPolicy{Policy: p.Stacking.Policy, Limit: p.Stacking.Limit}
+}
+
+// PriceTiers returns the configured volume price breaks
+func (p Pricing) PriceTiers() []pricing.DiscountTier {
+	tiers := make([]pricing.DiscountTier, len(p.Tiers))
+	for i, tier := range p.Tiers {
+		tiers[i] = pricing.DiscountTier{MinQuantity: tier.MinQuantity, Percentage: tier.Percentage}
+	}
+	return tiers
+}
+
+// FeatureEnabled reports whether a feature is enabled, features are on unless disabled
+func (c Config) FeatureEnabled(name string) bool {
+	enabled, ok := c.Features[name]
+	return !ok || enabled
+}
+
+// Watch calls onChange with the reloaded configuration, or the error that prevented
+// loading it, whenever the file at path changes, until ctx is cancelled.
+// The directory is watched so files replaced by a rename are picked up too.
+func Watch(ctx context.Context, path string, onChange func(Config, error)) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to create config watcher: %v", err)
+	}
+	path = filepath.Clean(path)
+	if err := watcher.Add(filepath.Dir(path)); err != nil {
+		watcher.Close()
+		return fmt.Errorf("failed to watch config file: %v", err)
+	}
+
+	go func() {
+		defer watcher.Close()
+		var timer *time.Timer
+		for {
+			select {
+			case <-ctx.Done():
+				if timer != nil {
+					timer.Stop()
+				}
+				return
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if filepath.Clean(event.Name) != path || event.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Rename) == 0 {
+					continue
+				}
+				if timer != nil {
+					timer.Stop()
+				}
+				timer = time.AfterFunc(reloadDelay, func() { onChange(Load(path)) })
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				slog.WarnContext(ctx, "Config watcher error", "error", err)
+			}
+		}
+	}()
+	return nil
+}
@@ -0,0 +1,92 @@
+package httpapi
+
+import (
+	"log/slog"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+)
+
+// accessLogWriter records the status and size of a response while writing it to the client
+type accessLogWriter struct {
+	http.ResponseWriter
+	status int
+	bytes  int
+}
+
+func (w *accessLogWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *accessLogWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(b)
+	w.bytes += n
+	return n, err
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer
+func (w *accessLogWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// Logs a line per request when the access log is enabled in the configuration file, with the
+// attribute names of the OpenTelemetry HTTP semantic conventions. Successful requests are sampled
+// at the configured rate, failed ones always logged. Runs inside the request span, so the log
+// handler adds its trace and span IDs.
+func logAccess(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		cfg := fileConfig.Load().AccessLog
+		if !cfg.Enabled {
+			handler(w, r)
+			return
+		}
+		start := time.Now()
+		rec := &accessLogWriter{ResponseWriter: w}
+		handler(rec, r)
+		duration := time.Since(start)
+
+		status := max(rec.status, http.StatusOK) // Nothing written is an empty 200
+		sampleRate := cfg.SampleRate
+		if sampleRate == 0 {
+			sampleRate = 1
+		}
+		if status < http.StatusBadRequest && rand.Float64() >= sampleRate {
+			return
+		}
+
+		route := r.URL.Path
+		if current := mux.CurrentRoute(r); current != nil {
+			if template, err := current.GetPathTemplate(); err == nil {
+				route = template
+			}
+		}
+		client, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			client = r.RemoteAddr
+		}
+		level := slog.LevelInfo
+		if status >= http.StatusInternalServerError {
+			level = slog.LevelError
+		}
+		slog.Log(r.Context(), level, "HTTP request",
+			string(semconv.HTTPRequestMethodKey), r.Method,
+			string(semconv.HTTPRouteKey), route,
+			string(semconv.URLPathKey), r.URL.Path,
+			string(semconv.HTTPResponseStatusCodeKey), status,
+			"http.server.request.duration", duration.Seconds(),
+			string(semconv.HTTPResponseBodySizeKey), rec.bytes,
+			string(semconv.ClientAddressKey), client,
+			string(semconv.UserAgentOriginalKey), r.UserAgent(),
+		)
+	}
+}
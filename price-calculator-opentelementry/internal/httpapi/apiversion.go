@@ -0,0 +1,88 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"log/slog"
+	"mime"
+	"net/http"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"otpl/pricecalculator/pricing"
+)
+
+// Media type selecting the v2 shapes on the unversioned routes, and of the v2 responses
+const mediaTypeV2 = "application/vnd.pricecalc.v2+json"
+
+// Serves a route in the shapes of the API version the request asks for: v2 when its
+// Content-Type or Accept header is the v2 media type, v1 otherwise
+func negotiateVersion(v1, v2 http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if isMediaTypeV2(r.Header.Get("Content-Type")) || isMediaTypeV2(r.Header.Get("Accept")) {
+			v2(w, r)
+			return
+		}
+		trace.SpanFromContext(r.Context()).SetAttributes(attribute.Int("api.version", 1))
+		v1(w, r)
+	}
+}
+
+// Reports whether a Content-Type or Accept header names the v2 media type
+func isMediaTypeV2(header string) bool {
+	for _, value := range strings.Split(header, ",") {
+		if mediaType, _, err := mime.ParseMediaType(value); err == nil && mediaType == mediaTypeV2 {
+			return true
+		}
+	}
+	return false
+}
+
+// Writes a v2 response with the v2 media type
+func writeJSONV2(w http.ResponseWriter, r *http.Request, v any) {
+	trace.SpanFromContext(r.Context()).SetAttributes(attribute.Int("api.version", 2))
+	w.Header().Set("Content-Type", mediaTypeV2)
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		slog.ErrorContext(r.Context(), "Error encoding response", "error", err)
+	}
+}
+
+// Calculates a price from a v2 request, answering in the v2 shape
+func calculatePriceV2(w http.ResponseWriter, r *http.Request) {
+	var requestV2 pricing.PriceRequestV2
+	if !decodeJSON(w, r, &requestV2) {
+		return
+	}
+	request, err := requestV2.V1()
+	if err != nil {
+		writeOperationError(w, r, err, "Error calculating price")
+		return
+	}
+	response, err := calculate(r.Context(), request)
+	if err != nil {
+		writeOperationError(w, r, err, "Error calculating price")
+		return
+	}
+	writeJSONV2(w, r, pricing.NewPriceResponseV2(response))
+}
+
+// Calculates a v2 cart, answering in the v2 shape
+func calculateCartV2(w http.ResponseWriter, r *http.Request) {
+	var requestV2 pricing.CartRequestV2
+	if !decodeJSON(w, r, &requestV2) {
+		return
+	}
+	request, err := requestV2.V1()
+	if err != nil {
+		writeOperationError(w, r, err, "Error calculating cart")
+		return
+	}
+	response, err := priceCart(r.Context(), request)
+	if err != nil {
+		writeOperationError(w, r, err, "Error calculating cart")
+		return
+	}
+	writeJSONV2(w, r, pricing.NewCartResponseV2(response))
+}
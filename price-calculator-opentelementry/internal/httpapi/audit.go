@@ -0,0 +1,140 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Page sizes of the audit log
+const (
+	defaultAuditLimit = 50  // Page size when no limit is given
+	maxAuditLimit     = 500 // Largest page size a client may request
+)
+
+// Audited actions besides the webhook events, which are audited under their own names
+const (
+	auditConfigRolledBack = "config.rolled_back"
+	auditTaxRuleCreated   = "tax_rule.created"
+	auditTaxRuleUpdated   = "tax_rule.updated"
+	auditTaxRuleDeleted   = "tax_rule.deleted"
+	auditSurchargeCreated = "surcharge.created"
+	auditSurchargeUpdated = "surcharge.updated"
+	auditSurchargeDeleted = "surcharge.deleted"
+	auditFlagUpdated      = "flag.updated"
+	auditFlagReset        = "flag.reset"
+	auditTenantCreated    = "tenant.created"
+	auditTenantUpdated    = "tenant.updated"
+	auditTenantDeleted    = "tenant.deleted"
+	auditProductCreated   = "product.created"
+	auditProductUpdated   = "product.updated"
+	auditProductDeleted   = "product.deleted"
+	auditCouponCreated    = "coupon.created"
+	auditCouponUpdated    = "coupon.updated"
+	auditCouponDeleted    = "coupon.deleted"
+)
+
+// AuditResponse structure for a page of the audit log
+type AuditResponse struct {
+	Entries []AuditEntry `json:"entries"`
+	Total   int          `json:"total"` // Number of entries matching the filters
+	Limit   int          `json:"limit"`
+	Offset  int          `json:"offset"`
+}
+
+// Appends a change to the audit log with its author, the request's tenant and trace.
+// The old value is nil for created resources and the new one nil for deleted resources.
+// Runs once the change is applied, so a failure to record it does not fail the request: it is
+// logged, recorded on the span and counted in price_calculator.audit.failures.
+func recordAudit(ctx context.Context, action, resource string, oldValue, newValue any) {
+	entry := AuditEntry{
+		Timestamp: time.Now().UTC(),
+		Actor:     author(ctx),
+		Action:    action,
+		Resource:  resource,
+		Tenant:    tenantID(ctx),
+		Old:       auditValue(ctx, oldValue),
+		New:       auditValue(ctx, newValue),
+	}
+	span := trace.SpanFromContext(ctx)
+	if sc := span.SpanContext(); sc.IsValid() {
+		entry.TraceID = sc.TraceID().String()
+		entry.SpanID = sc.SpanID().String()
+	}
+	id, err := auditLog.Append(ctx, entry)
+	if err != nil {
+		span.AddEvent("audit.failed", trace.WithAttributes(attribute.String("audit.action", action), attribute.String("error.message", err.Error())))
+		slog.ErrorContext(ctx, "Failed to record audit entry", "action", action, "resource", resource, "error", err)
+		auditFailures.Add(ctx, 1, metric.WithAttributes(attribute.String("action", action)))
+		return
+	}
+	span.AddEvent("audit.recorded", trace.WithAttributes(attribute.Int64("audit.id", id), attribute.String("audit.action", action)))
+}
+
+// Returns a value encoded as JSON, nil for a nil value
+func auditValue(ctx context.Context, v any) json.RawMessage {
+	if v == nil {
+		return nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		slog.WarnContext(ctx, "Failed to encode audited value", "error", err)
+		return nil
+	}
+	return data
+}
+
+// Lists the audit log, newest first.
+// Supports the actor, action, resource, tenant and trace_id filters, the from and to (RFC 3339)
+// time filters and limit/offset pagination.
+func listAudit(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter := AuditFilter{
+		Actor:    query.Get("actor"),
+		Action:   query.Get("action"),
+		Resource: query.Get("resource"),
+		Tenant:   query.Get("tenant"),
+		TraceID:  query.Get("trace_id"),
+		Limit:    defaultAuditLimit,
+	}
+
+	var err error
+	if value := query.Get("from"); value != "" {
+		if filter.From, err = time.Parse(time.RFC3339, value); err != nil {
+			writeProblem(w, r, "Invalid from time, expected RFC 3339", http.StatusBadRequest)
+			return
+		}
+	}
+	if value := query.Get("to"); value != "" {
+		if filter.To, err = time.Parse(time.RFC3339, value); err != nil {
+			writeProblem(w, r, "Invalid to time, expected RFC 3339", http.StatusBadRequest)
+			return
+		}
+	}
+	if value := query.Get("limit"); value != "" {
+		if filter.Limit, err = strconv.Atoi(value); err != nil || filter.Limit < 1 || filter.Limit > maxAuditLimit {
+			writeProblem(w, r, "Invalid limit, expected 1 to 500", http.StatusBadRequest)
+			return
+		}
+	}
+	if value := query.Get("offset"); value != "" {
+		if filter.Offset, err = strconv.Atoi(value); err != nil || filter.Offset < 0 {
+			writeProblem(w, r, "Invalid offset", http.StatusBadRequest)
+			return
+		}
+	}
+
+	entries, total, err := auditLog.List(r.Context(), filter)
+	if err != nil {
+		writeOperationError(w, r, err, "Error listing audit log")
+		return
+	}
+	writeJSON(w, http.StatusOK, AuditResponse{Entries: entries, Total: total, Limit: filter.Limit, Offset: filter.Offset})
+}
@@ -0,0 +1,211 @@
+package httpapi
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// AuditEntry structure for a change to the configuration, never modified once appended
+type AuditEntry struct {
+	ID        int64           `json:"id"`
+	Timestamp time.Time       `json:"timestamp"`
+	Actor     string          `json:"actor"`            // API key ID, "config-file", "system" or "anonymous"
+	Action    string          `json:"action"`           // What changed, e.g. prices.updated or discount.deleted
+	Resource  string          `json:"resource"`         // What was changed, e.g. prices or discounts/3
+	Tenant    string          `json:"tenant,omitempty"` // Tenant whose configuration changed, unset for the defaults
+	Old       json.RawMessage `json:"old,omitempty"`    // Unset when the resource was created
+	New       json.RawMessage `json:"new,omitempty"`    // Unset when the resource was deleted
+	TraceID   string          `json:"trace_id,omitempty"`
+	SpanID    string          `json:"span_id,omitempty"`
+}
+
+// AuditFilter structure for selecting a page of the audit log, empty fields match every entry
+type AuditFilter struct {
+	Actor    string
+	Action   string
+	Resource string // The resource or, ending in /, every resource under it such as discounts/
+	Tenant   string
+	TraceID  string
+	From     time.Time // Zero for no lower bound
+	To       time.Time // Zero for no upper bound
+	Limit    int
+	Offset   int
+}
+
+// Reports whether an entry matches the filter
+func (f AuditFilter) matches(entry AuditEntry) bool {
+	return (f.Actor == "" || entry.Actor == f.Actor) &&
+		(f.Action == "" || entry.Action == f.Action) &&
+		(f.Resource == "" || entry.Resource == f.Resource || strings.HasSuffix(f.Resource, "/") && strings.HasPrefix(entry.Resource, f.Resource)) &&
+		(f.Tenant == "" || entry.Tenant == f.Tenant) &&
+		(f.TraceID == "" || entry.TraceID == f.TraceID) &&
+		(f.From.IsZero() || !entry.Timestamp.Before(f.From)) && (f.To.IsZero() || !entry.Timestamp.After(f.To))
+}
+
+// AuditStore persists the audit log, entries can only be appended
+type AuditStore interface {
+	// Append records an entry under a new ID, returning the ID
+	Append(ctx context.Context, entry AuditEntry) (int64, error)
+	// List returns a page of the matching entries, newest first, and the number of matching entries
+	List(ctx context.Context, filter AuditFilter) ([]AuditEntry, int, error)
+}
+
+// Audit store opened on startup
+var auditLog AuditStore
+
+// memoryAuditStore keeps the audit log in memory, oldest first, appended to a JSON lines file when it has one
+type memoryAuditStore struct {
+	mu      sync.RWMutex
+	entries []AuditEntry
+	file    *jsonLines
+}
+
+// Creates the in-memory audit store, loading the entries from file
+func newMemoryAuditStore(file *jsonLines) (*memoryAuditStore, error) {
+	s := &memoryAuditStore{file: file}
+	if err := loadJSONLines(file, &s.entries); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *memoryAuditStore) Append(ctx context.Context, entry AuditEntry) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entry.ID = 1
+	if len(s.entries) > 0 {
+		entry.ID = s.entries[len(s.entries)-1].ID + 1
+	}
+	if err := s.file.append(entry); err != nil {
+		return 0, err
+	}
+	s.entries = append(s.entries, entry)
+	return entry.ID, nil
+}
+
+func (s *memoryAuditStore) List(ctx context.Context, filter AuditFilter) ([]AuditEntry, int, error) {
+	s.mu.RLock()
+	matches := make([]AuditEntry, 0)
+	for i := len(s.entries) - 1; i >= 0; i-- {
+		if filter.matches(s.entries[i]) {
+			matches = append(matches, s.entries[i])
+		}
+	}
+	s.mu.RUnlock()
+
+	if filter.Offset >= len(matches) {
+		return []AuditEntry{}, len(matches), nil
+	}
+	return matches[filter.Offset:min(filter.Offset+filter.Limit, len(matches))], len(matches), nil
+}
+
+// sqlAuditStore keeps the audit log in a SQLite or PostgreSQL table, with the old and new
+// values as JSON and the time in Unix nanoseconds
+type sqlAuditStore struct {
+	db *sql.DB
+}
+
+// Creates the audit store in db, adding its table if needed
+func newSQLAuditStore(db *sql.DB) (*sqlAuditStore, error) {
+	err := createWithGeneratedID(db, "audit_log", `CREATE TABLE IF NOT EXISTS audit_log (
+		id          %s,
+		recorded_at BIGINT NOT NULL,
+		actor       TEXT NOT NULL,
+		action      TEXT NOT NULL,
+		resource    TEXT NOT NULL,
+		tenant      TEXT NOT NULL DEFAULT '',
+		old_value   TEXT NOT NULL DEFAULT '',
+		new_value   TEXT NOT NULL DEFAULT '',
+		trace_id    TEXT NOT NULL DEFAULT '',
+		span_id     TEXT NOT NULL DEFAULT ''
+	)`)
+	if err != nil {
+		return nil, err
+	}
+	return &sqlAuditStore{db: db}, nil
+}
+
+func (s *sqlAuditStore) Append(ctx context.Context, entry AuditEntry) (int64, error) {
+	var id int64
+	err := s.db.QueryRowContext(ctx, `INSERT INTO audit_log (recorded_at, actor, action, resource, tenant, old_value, new_value, trace_id, span_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) RETURNING id`,
+		entry.Timestamp.UnixNano(), entry.Actor, entry.Action, entry.Resource, entry.Tenant,
+		string(entry.Old), string(entry.New), entry.TraceID, entry.SpanID).Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("failed to append audit entry: %v", err)
+	}
+	return id, nil
+}
+
+func (s *sqlAuditStore) List(ctx context.Context, filter AuditFilter) ([]AuditEntry, int, error) {
+	var conditions []string
+	var args []any
+	condition := func(clause string, value any) {
+		args = append(args, value)
+		conditions = append(conditions, fmt.Sprintf(clause, len(args)))
+	}
+	for _, field := range []struct{ column, value string }{
+		{"actor", filter.Actor}, {"action", filter.Action}, {"tenant", filter.Tenant}, {"trace_id", filter.TraceID},
+	} {
+		if field.value != "" {
+			condition(field.column+" = $%d", field.value)
+		}
+	}
+	if filter.Resource != "" {
+		if strings.HasSuffix(filter.Resource, "/") {
+			condition("substr(resource, 1, "+fmt.Sprint(len(filter.Resource))+") = $%d", filter.Resource)
+		} else {
+			condition("resource = $%d", filter.Resource)
+		}
+	}
+	if !filter.From.IsZero() {
+		condition("recorded_at >= $%d", filter.From.UnixNano())
+	}
+	if !filter.To.IsZero() {
+		condition("recorded_at <= $%d", filter.To.UnixNano())
+	}
+	where := ""
+	if len(conditions) > 0 {
+		where = " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	var total int
+	if err := s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM audit_log`+where, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count audit entries: %v", err)
+	}
+	args = append(args, filter.Limit, filter.Offset)
+	rows, err := s.db.QueryContext(ctx, fmt.Sprintf(`SELECT id, recorded_at, actor, action, resource, tenant, old_value, new_value, trace_id, span_id
+		FROM audit_log%s ORDER BY id DESC LIMIT $%d OFFSET $%d`, where, len(args)-1, len(args)), args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list audit entries: %v", err)
+	}
+	defer rows.Close()
+
+	entries := []AuditEntry{}
+	for rows.Next() {
+		var entry AuditEntry
+		var recordedAt int64
+		var oldValue, newValue string
+		if err := rows.Scan(&entry.ID, &recordedAt, &entry.Actor, &entry.Action, &entry.Resource, &entry.Tenant,
+			&oldValue, &newValue, &entry.TraceID, &entry.SpanID); err != nil {
+			return nil, 0, fmt.Errorf("failed to read audit entry: %v", err)
+		}
+		entry.Timestamp = time.Unix(0, recordedAt).UTC()
+		if oldValue != "" {
+			entry.Old = json.RawMessage(oldValue)
+		}
+		if newValue != "" {
+			entry.New = json.RawMessage(newValue)
+		}
+		entries = append(entries, entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("failed to list audit entries: %v", err)
+	}
+	return entries, total, nil
+}
@@ -0,0 +1,150 @@
+package httpapi
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	grpccodes "google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	pricecalculatorv1 "otpl/pricecalculator/gen/pricecalculator/v1"
+)
+
+// APIKeyHeader is the header carrying the API key, also used as the gRPC metadata key
+const APIKeyHeader = "X-API-Key"
+
+// Authentication failures
+var (
+	errMissingAPIKey = errors.New("missing API key")
+	errInvalidAPIKey = errors.New("invalid API key")
+)
+
+// Context key of the author of the changes made by a request
+type authorKey struct{}
+
+// Returns a context attributing the changes made with it to author
+func withAuthor(ctx context.Context, author string) context.Context {
+	return context.WithValue(ctx, authorKey{}, author)
+}
+
+// Returns the author of the changes made with ctx: the ID of the request's API key,
+// a component such as the configuration file, or "anonymous"
+func author(ctx context.Context) string {
+	if author, ok := ctx.Value(authorKey{}).(string); ok {
+		return author
+	}
+	return "anonymous"
+}
+
+// Returns the context of an authenticated call, attributing its changes to the API key
+func authenticatedContext(ctx context.Context, key string) context.Context {
+	if key == "" {
+		return ctx
+	}
+	return withAuthor(ctx, apiKeyID(key))
+}
+
+// Returns the accepted API keys from the comma separated API_KEYS,
+// or the configuration file. Authentication is disabled when there are none.
+func apiKeys() []string {
+	if value := os.Getenv("API_KEYS"); value != "" {
+		var keys []string
+		for _, key := range strings.Split(value, ",") {
+			if key = strings.TrimSpace(key); key != "" {
+				keys = append(keys, key)
+			}
+		}
+		return keys
+	}
+	return fileConfig.Load().APIKeys
+}
+
+// Identifies an API key in traces and logs without revealing it
+func apiKeyID(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:8])
+}
+
+// Checks an API key against the accepted keys, recording its ID on the active span
+func authenticate(ctx context.Context, key string) error {
+	keys := apiKeys()
+	if len(keys) == 0 {
+		return nil
+	}
+	if key == "" {
+		return errMissingAPIKey
+	}
+
+	id := apiKeyID(key)
+	trace.SpanFromContext(ctx).SetAttributes(attribute.String("auth.key_id", id))
+	if !acceptedAPIKey(keys, key) {
+		slog.WarnContext(ctx, "Rejected API key", "key_id", id)
+		return errInvalidAPIKey
+	}
+	return nil
+}
+
+// Reports whether the key is one of the accepted keys
+func acceptedAPIKey(keys []string, key string) bool {
+	for _, accepted := range keys {
+		if subtle.ConstantTimeCompare([]byte(key), []byte(accepted)) == 1 {
+			return true
+		}
+	}
+	return false
+}
+
+// Requires a valid X-API-Key header, answering 401 when it is missing and 403 when it is not accepted
+func requireAPIKey(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(APIKeyHeader)
+		switch err := authenticate(r.Context(), key); err {
+		case nil:
+			handler(w, r.WithContext(authenticatedContext(r.Context(), key)))
+		case errMissingAPIKey:
+			w.Header().Set("WWW-Authenticate", APIKeyHeader)
+			writeProblem(w, r, err.Error(), http.StatusUnauthorized)
+		default:
+			writeProblem(w, r, err.Error(), http.StatusForbidden)
+		}
+	}
+}
+
+// Methods of the gRPC API that require an API key, matching the HTTP set endpoints
+var authenticatedMethods = map[string]bool{
+	pricecalculatorv1.PriceCalculatorService_SetBasePrice_FullMethodName: true,
+	pricecalculatorv1.PriceCalculatorService_SetTaxRate_FullMethodName:   true,
+}
+
+// Requires a valid x-api-key metadata entry on the authenticated gRPC methods
+func apiKeyInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	if !authenticatedMethods[info.FullMethod] {
+		return handler(ctx, req)
+	}
+
+	var key string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(APIKeyHeader); len(values) > 0 {
+			key = values[0]
+		}
+	}
+	switch err := authenticate(ctx, key); err {
+	case nil:
+		return handler(authenticatedContext(ctx, key), req)
+	case errMissingAPIKey:
+		return nil, status.Error(grpccodes.Unauthenticated, err.Error())
+	default:
+		return nil, status.Error(grpccodes.PermissionDenied, err.Error())
+	}
+}
@@ -0,0 +1,64 @@
+package httpapi
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/baggage"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Request headers carrying the customer and tenant identifiers, and the baggage members they are stored in
+var baggageHeaders = map[string]string{
+	"X-Customer-ID": "customer_id",
+	"X-Tenant-ID":   "tenant_id",
+}
+
+// Adds the customer and tenant identifiers from the request headers to the baggage,
+// next to any baggage propagated by the caller, and to the server span
+func withRequestBaggage(r *http.Request) *http.Request {
+	ctx := r.Context()
+	bag := baggage.FromContext(ctx)
+	span := trace.SpanFromContext(ctx)
+	changed := false
+	for header, key := range baggageHeaders {
+		value := r.Header.Get(header)
+		if value == "" {
+			continue
+		}
+		member, err := baggage.NewMemberRaw(key, value)
+		if err == nil {
+			bag, err = bag.SetMember(member)
+		}
+		if err != nil {
+			slog.WarnContext(ctx, "Ignoring invalid baggage header", "header", header, "error", err)
+			continue
+		}
+		span.SetAttributes(attribute.String(key, value))
+		changed = true
+	}
+	if !changed {
+		return r
+	}
+	return r.WithContext(baggage.ContextWithBaggage(ctx, bag))
+}
+
+// baggageSpanProcessor copies the customer and tenant identifiers from the baggage
+// onto every span started in the request, so spans can be sliced by tenant
+type baggageSpanProcessor struct{}
+
+func (baggageSpanProcessor) OnStart(ctx context.Context, span sdktrace.ReadWriteSpan) {
+	bag := baggage.FromContext(ctx)
+	for _, key := range baggageHeaders {
+		if member := bag.Member(key); member.Value() != "" {
+			span.SetAttributes(attribute.String(key, member.Value()))
+		}
+	}
+}
+
+func (baggageSpanProcessor) OnEnd(sdktrace.ReadOnlySpan)      {}
+func (baggageSpanProcessor) Shutdown(context.Context) error   { return nil }
+func (baggageSpanProcessor) ForceFlush(context.Context) error { return nil }
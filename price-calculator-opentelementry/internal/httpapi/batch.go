@@ -0,0 +1,90 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"sync"
+
+	"go.opentelemetry.io/otel/attribute"
+
+	"otpl/pricecalculator/pricing"
+)
+
+// Limits for batch calculations
+const (
+	maxBatchSize = 100 // Maximum number of requests in a batch
+	batchWorkers = 8   // Number of requests calculated concurrently
+)
+
+// BatchResult structure for the outcome of one request in a batch
+type BatchResult struct {
+	Result *pricing.PriceResponse `json:"result,omitempty"`
+	Error  string                 `json:"error,omitempty"`
+}
+
+// Calculates a batch of price requests with a bounded worker pool,
+// returning the results in request order
+func calculateBatch(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	// Start a parent span for the batch, each item gets its own calculation span
+	ctx, span := tracer.Start(ctx, "CalculateBatch")
+	defer span.End()
+
+	// Decode the batch
+	var requests []pricing.PriceRequest
+	if !decodeJSON(w, r, &requests) {
+		return
+	}
+	span.SetAttributes(attribute.Int("batch.size", len(requests)))
+	if len(requests) == 0 {
+		writeProblem(w, r, "batch must contain at least one request", http.StatusBadRequest)
+		return
+	}
+	if len(requests) > maxBatchSize {
+		writeProblem(w, r, "batch must not contain more than 100 requests", http.StatusBadRequest)
+		return
+	}
+	formatter, ok := requestFormatter(w, r)
+	if !ok {
+		return
+	}
+
+	// Feed the request indexes to the workers, each result is written to its own slot
+	results := make([]BatchResult, len(requests))
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for range min(batchWorkers, len(requests)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				response, err := calculate(ctx, requests[i])
+				if err != nil {
+					slog.WarnContext(ctx, "Error calculating batch item", "index", i, "error", err)
+					results[i].Error = err.Error()
+					continue
+				}
+				formatPrices(r, formatter, &response)
+				results[i].Result = &response
+			}
+		}()
+	}
+	for i := range requests {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+	if writeContextError(w, r) {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(results); err != nil {
+		slog.ErrorContext(ctx, "Error encoding response", "error", err)
+		return
+	}
+	slog.InfoContext(ctx, "Calculated batch", "batch_size", len(requests))
+}
@@ -0,0 +1,136 @@
+package httpapi
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/extra/redisotel/v9"
+	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+
+	"otpl/pricecalculator/pricing"
+)
+
+// Time a cached result is served for unless CACHE_TTL sets one
+const defaultCacheTTL = 5 * time.Minute
+
+// Prefix of the cache keys, so the cache can share a Redis database
+const cacheKeyPrefix = "pricecalculator:calculation:"
+
+// Cache of calculation results, nil unless REDIS_URL is set
+var resultCache *redisCache
+
+// redisCache stores calculation results in Redis
+type redisCache struct {
+	client *redis.Client
+	ttl    time.Duration
+}
+
+// Connects to the Redis server at REDIS_URL, returning nil when caching is disabled.
+// Redis commands are traced and the client pool statistics exported as metrics.
+func openResultCache() (*redisCache, error) {
+	url := os.Getenv("REDIS_URL")
+	if url == "" {
+		return nil, nil
+	}
+	options, err := redis.ParseURL(url)
+	if err != nil {
+		return nil, fmt.Errorf("invalid REDIS_URL: %v", err)
+	}
+	ttl := defaultCacheTTL
+	if value := os.Getenv("CACHE_TTL"); value != "" {
+		ttl, err = time.ParseDuration(value)
+		if err != nil || ttl <= 0 {
+			return nil, fmt.Errorf("invalid CACHE_TTL %q", value)
+		}
+	}
+
+	client := redis.NewClient(options)
+	if err := redisotel.InstrumentTracing(client); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("failed to instrument redis tracing: %v", err)
+	}
+	if err := redisotel.InstrumentMetrics(client); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("failed to instrument redis metrics: %v", err)
+	}
+	return &redisCache{client: client, ttl: ttl}, nil
+}
+
+// Derives the cache key of a calculation from everything its result depends on:
+// the normalized request, the defaults, the rules and the rounding mode it is evaluated with.
+// Returns an empty key, bypassing the cache, if the request cannot be encoded.
+func calculationCacheKey(ctx context.Context, request pricing.PriceRequest, defaults pricing.Config, rules pricing.Rules, mode pricing.RoundingMode) string {
+	request.Currency = strings.ToUpper(request.Currency)
+	request.Quantity = max(request.Quantity, 1)
+	data, err := json.Marshal(struct {
+		Request  pricing.PriceRequest
+		Defaults pricing.Config
+		Rules    pricing.Rules
+		Mode     pricing.RoundingMode
+	}{request, defaults, rules, mode})
+	if err != nil {
+		slog.WarnContext(ctx, "Error deriving calculation cache key", "error", err)
+		return ""
+	}
+	sum := sha256.Sum256(data)
+	return cacheKeyPrefix + hex.EncodeToString(sum[:])
+}
+
+// Returns the cached result for key. Cache errors are logged and treated as a miss,
+// so an unavailable Redis only slows calculations down.
+func (c *redisCache) Get(ctx context.Context, key string) (pricing.PriceResponse, bool) {
+	var response pricing.PriceResponse
+	data, err := c.client.Get(ctx, key).Bytes()
+	switch {
+	case errors.Is(err, redis.Nil):
+		recordCacheResult(ctx, "miss")
+		return response, false
+	case err != nil:
+		slog.WarnContext(ctx, "Error reading calculation cache", "error", err)
+		recordDependencyError(dependencyCache, err)
+		recordCacheResult(ctx, "error")
+		return response, false
+	}
+	if err := json.Unmarshal(data, &response); err != nil {
+		slog.WarnContext(ctx, "Invalid cached calculation", "error", err)
+		recordCacheResult(ctx, "error")
+		return response, false
+	}
+	recordCacheResult(ctx, "hit")
+	return response, true
+}
+
+// Stores a result for the cache TTL
+func (c *redisCache) Set(ctx context.Context, key string, response pricing.PriceResponse) {
+	data, err := json.Marshal(response)
+	if err != nil {
+		slog.WarnContext(ctx, "Error encoding calculation for the cache", "error", err)
+		return
+	}
+	if err := c.client.Set(ctx, key, data, c.ttl).Err(); err != nil {
+		slog.WarnContext(ctx, "Error writing calculation cache", "error", err)
+		recordDependencyError(dependencyCache, err)
+	}
+}
+
+// Close closes the connections to Redis
+func (c *redisCache) Close() error {
+	return c.client.Close()
+}
+
+// Counts a cache lookup and marks the calculation span as served from the cache or not
+func recordCacheResult(ctx context.Context, result string) {
+	cacheRequests.Add(ctx, 1, metric.WithAttributes(attribute.String("result", result)))
+	trace.SpanFromContext(ctx).SetAttributes(attribute.Bool("cache.hit", result == "hit"))
+}
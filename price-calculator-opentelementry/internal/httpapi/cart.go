@@ -0,0 +1,72 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"runtime"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+
+	"otpl/pricecalculator/internal/telemetry"
+	"otpl/pricecalculator/pricing"
+)
+
+// Returns the cart calculation settings of the configuration file, the lines of large carts
+// calculated by as many goroutines as there are CPUs unless the file sets another parallelism
+func cartOptions() pricing.CartOptions {
+	cfg := fileConfig.Load().Cart
+	opts := pricing.CartOptions{Rates: exchangeRate, Parallelism: cfg.Parallelism, ParallelMinLines: cfg.ParallelMinLines}
+	if opts.Parallelism == 0 {
+		opts.Parallelism = runtime.GOMAXPROCS(0)
+	}
+	return opts
+}
+
+// Calculates the totals for a cart of line items
+func calculateCart(w http.ResponseWriter, r *http.Request) {
+	// Decode the cart
+	var request pricing.CartRequest
+	if !decodeJSON(w, r, &request) {
+		return
+	}
+
+	response, err := priceCart(r.Context(), request)
+	if err != nil {
+		writeOperationError(w, r, err, "Error calculating cart")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		slog.ErrorContext(r.Context(), "Error encoding response", "error", err)
+		writeProblem(w, r, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+}
+
+// Calculates a cart with the default tax rate and currency of the request's tenant,
+// converting lines in other currencies at the rates given or fetched
+func priceCart(ctx context.Context, request pricing.CartRequest) (pricing.CartResponse, error) {
+	// Start a new span for the cart calculation
+	ctx, span := tracer.Start(ctx, "CalculateCart")
+	defer span.End()
+
+	defaults, currency := tenantDefaults(ctx)
+	if request.Currency == "" {
+		request.Currency = currency
+	}
+	response, err := pricing.CalculateCart(ctx, request, defaults, roundingMode(defaults, request.Currency), cartOptions())
+	if err != nil {
+		telemetry.RecordError(span, err)
+		return response, err
+	}
+
+	// Record the cart total alongside the single price calculations
+	totalPriceCounter.Add(ctx, response.GrandTotal.Float64(), metric.WithAttributes(attribute.String("currency", response.Currency)))
+	slog.InfoContext(ctx, "Calculated cart grand total", "grand_total", response.GrandTotal.String(), "currency", response.Currency)
+	return response, nil
+}
@@ -0,0 +1,445 @@
+package httpapi
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"sort"
+	"sync"
+
+	"github.com/XSAM/otelsql"
+	"github.com/gorilla/mux"
+	_ "github.com/jackc/pgx/v5/stdlib" // Registers the "pgx" PostgreSQL driver
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.18.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"otpl/pricecalculator/pricing"
+)
+
+// Catalog stores the products that price requests can reference by SKU
+type Catalog interface {
+	// List returns all products in SKU order
+	List(ctx context.Context) ([]pricing.Product, error)
+	// Get returns the product with the SKU, false if there is none
+	Get(ctx context.Context, sku string) (pricing.Product, bool, error)
+	// Create adds a product, false if the SKU is taken
+	Create(ctx context.Context, product pricing.Product) (bool, error)
+	// Update replaces a product, false if there is none with its SKU
+	Update(ctx context.Context, product pricing.Product) (bool, error)
+	// Delete removes a product, false if there is none with the SKU
+	Delete(ctx context.Context, sku string) (bool, error)
+}
+
+// Catalog opened on startup
+var catalog Catalog
+
+// Opens the database of the database storage driver selected by the CATALOG_DRIVER and CATALOG_DSN
+// environment variables. Queries are traced with otelsql and the connection pool statistics
+// are exported as metrics.
+func openDatabase() (*sql.DB, error) {
+	driver := os.Getenv("CATALOG_DRIVER")
+	dsn := os.Getenv("CATALOG_DSN")
+	var system attribute.KeyValue
+	switch driver {
+	case "", "sqlite":
+		driver, system = "sqlite", semconv.DBSystemSqlite
+		if dsn == "" {
+			dsn = "catalog.db"
+		}
+	case "postgres":
+		driver, system = "pgx", semconv.DBSystemPostgreSQL
+		if dsn == "" {
+			return nil, fmt.Errorf("CATALOG_DSN is required for the postgres catalog")
+		}
+	default:
+		return nil, fmt.Errorf("unknown catalog driver %q", driver)
+	}
+
+	db, err := otelsql.Open(driver, dsn, otelsql.WithAttributes(system))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %v", err)
+	}
+	if driver == "sqlite" {
+		db.SetMaxOpenConns(1) // SQLite allows a single writer
+	}
+	if err := otelsql.RegisterDBStatsMetrics(db, otelsql.WithAttributes(system)); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to register database metrics: %v", err)
+	}
+	return db, nil
+}
+
+// Creates the catalog in db, adding its table if needed
+func newSQLCatalog(db *sql.DB) (*sqlCatalog, error) {
+	_, err := db.Exec(`CREATE TABLE IF NOT EXISTS products (
+		sku          TEXT PRIMARY KEY,
+		name         TEXT NOT NULL,
+		unit_price   TEXT NOT NULL,
+		tax_category TEXT NOT NULL DEFAULT ''
+	)`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create products table: %v", err)
+	}
+	if err := addColumn(db, "products", "unit_cost", "TEXT"); err != nil {
+		return nil, err
+	}
+	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS product_tiers (
+		sku          TEXT NOT NULL,
+		min_quantity INTEGER NOT NULL,
+		percentage   DOUBLE PRECISION NOT NULL,
+		PRIMARY KEY (sku, min_quantity)
+	)`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create product tiers table: %v", err)
+	}
+	return &sqlCatalog{db: db}, nil
+}
+
+// sqlCatalog keeps the products in a SQLite or PostgreSQL table, with their tiers in a table of their own
+type sqlCatalog struct {
+	db *sql.DB
+}
+
+// Loads the tiers selected by query by SKU, in min_quantity order
+func (c *sqlCatalog) tiers(ctx context.Context, query string, args ...any) (map[string][]pricing.DiscountTier, error) {
+	rows, err := c.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load product tiers: %v", err)
+	}
+	defer rows.Close()
+
+	tiers := map[string][]pricing.DiscountTier{}
+	for rows.Next() {
+		var sku string
+		var tier pricing.DiscountTier
+		if err := rows.Scan(&sku, &tier.MinQuantity, &tier.Percentage); err != nil {
+			return nil, fmt.Errorf("failed to read product tier: %v", err)
+		}
+		tiers[sku] = append(tiers[sku], tier)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to load product tiers: %v", err)
+	}
+	return tiers, nil
+}
+
+// Writes a product and replaces its tiers in one transaction, false if the query affected no product
+func (c *sqlCatalog) write(ctx context.Context, p pricing.Product, query string, args ...any) (bool, error) {
+	tx, err := c.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, query, args...)
+	if err != nil {
+		return false, err
+	}
+	if ok, err := affected(result); !ok || err != nil {
+		return false, err
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM product_tiers WHERE sku = $1`, p.SKU); err != nil {
+		return false, err
+	}
+	for _, tier := range p.Tiers {
+		if _, err := tx.ExecContext(ctx, `INSERT INTO product_tiers (sku, min_quantity, percentage) VALUES ($1, $2, $3)`,
+			p.SKU, tier.MinQuantity, tier.Percentage); err != nil {
+			return false, err
+		}
+	}
+	return true, tx.Commit()
+}
+
+func (c *sqlCatalog) List(ctx context.Context) ([]pricing.Product, error) {
+	rows, err := c.db.QueryContext(ctx, `SELECT sku, name, unit_price, unit_cost, tax_category FROM products ORDER BY sku`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list products: %v", err)
+	}
+	defer rows.Close()
+
+	products := []pricing.Product{}
+	for rows.Next() {
+		var p pricing.Product
+		if err := rows.Scan(&p.SKU, &p.Name, &p.UnitPrice, &p.UnitCost, &p.TaxCategory); err != nil {
+			return nil, fmt.Errorf("failed to read product: %v", err)
+		}
+		products = append(products, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list products: %v", err)
+	}
+
+	tiers, err := c.tiers(ctx, `SELECT sku, min_quantity, percentage FROM product_tiers ORDER BY sku, min_quantity`)
+	if err != nil {
+		return nil, err
+	}
+	for i := range products {
+		products[i].Tiers = tiers[products[i].SKU]
+	}
+	return products, nil
+}
+
+func (c *sqlCatalog) Get(ctx context.Context, sku string) (pricing.Product, bool, error) {
+	var p pricing.Product
+	err := c.db.QueryRowContext(ctx, `SELECT sku, name, unit_price, unit_cost, tax_category FROM products WHERE sku = $1`, sku).
+		Scan(&p.SKU, &p.Name, &p.UnitPrice, &p.UnitCost, &p.TaxCategory)
+	if errors.Is(err, sql.ErrNoRows) {
+		return p, false, nil
+	}
+	if err != nil {
+		return p, false, fmt.Errorf("failed to load product: %v", err)
+	}
+	tiers, err := c.tiers(ctx, `SELECT sku, min_quantity, percentage FROM product_tiers WHERE sku = $1 ORDER BY min_quantity`, sku)
+	if err != nil {
+		return p, false, err
+	}
+	p.Tiers = tiers[sku]
+	return p, true, nil
+}
+
+func (c *sqlCatalog) Create(ctx context.Context, p pricing.Product) (bool, error) {
+	ok, err := c.write(ctx, p, `INSERT INTO products (sku, name, unit_price, unit_cost, tax_category) VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (sku) DO NOTHING`, p.SKU, p.Name, p.UnitPrice, p.UnitCost, p.TaxCategory)
+	if err != nil {
+		return false, fmt.Errorf("failed to create product: %v", err)
+	}
+	return ok, nil
+}
+
+func (c *sqlCatalog) Update(ctx context.Context, p pricing.Product) (bool, error) {
+	ok, err := c.write(ctx, p, `UPDATE products SET name = $2, unit_price = $3, unit_cost = $4, tax_category = $5 WHERE sku = $1`,
+		p.SKU, p.Name, p.UnitPrice, p.UnitCost, p.TaxCategory)
+	if err != nil {
+		return false, fmt.Errorf("failed to update product: %v", err)
+	}
+	return ok, nil
+}
+
+func (c *sqlCatalog) Delete(ctx context.Context, sku string) (bool, error) {
+	if _, err := c.db.ExecContext(ctx, `DELETE FROM product_tiers WHERE sku = $1`, sku); err != nil {
+		return false, fmt.Errorf("failed to delete product tiers: %v", err)
+	}
+	result, err := c.db.ExecContext(ctx, `DELETE FROM products WHERE sku = $1`, sku)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete product: %v", err)
+	}
+	return affected(result)
+}
+
+// memoryCatalog keeps the products in memory, saved to a JSON file when it has one
+type memoryCatalog struct {
+	mu       sync.RWMutex
+	products map[string]pricing.Product
+	file     *jsonFile
+}
+
+// Creates the in-memory catalog, loading the products from file
+func newMemoryCatalog(file *jsonFile) (*memoryCatalog, error) {
+	c := &memoryCatalog{products: map[string]pricing.Product{}, file: file}
+	if err := file.load(&c.products); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *memoryCatalog) List(ctx context.Context) ([]pricing.Product, error) {
+	c.mu.RLock()
+	products := make([]pricing.Product, 0, len(c.products))
+	for _, p := range c.products {
+		products = append(products, p)
+	}
+	c.mu.RUnlock()
+	sort.Slice(products, func(i, j int) bool { return products[i].SKU < products[j].SKU })
+	return products, nil
+}
+
+func (c *memoryCatalog) Get(ctx context.Context, sku string) (pricing.Product, bool, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	p, ok := c.products[sku]
+	return p, ok, nil
+}
+
+func (c *memoryCatalog) Create(ctx context.Context, p pricing.Product) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.products[p.SKU]; ok {
+		return false, nil
+	}
+	c.products[p.SKU] = p
+	return true, c.file.persist(c.products)
+}
+
+func (c *memoryCatalog) Update(ctx context.Context, p pricing.Product) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.products[p.SKU]; !ok {
+		return false, nil
+	}
+	c.products[p.SKU] = p
+	return true, c.file.persist(c.products)
+}
+
+func (c *memoryCatalog) Delete(ctx context.Context, sku string) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.products[sku]; !ok {
+		return false, nil
+	}
+	delete(c.products, sku)
+	return true, c.file.persist(c.products)
+}
+
+// Reports whether a statement changed any row
+func affected(result sql.Result) (bool, error) {
+	n, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to read affected rows: %v", err)
+	}
+	return n > 0, nil
+}
+
+// Prices a request from the catalog product of its SKU and returns the product. The product's
+// tax category is used to resolve the tax rule when the jurisdiction does not name one.
+func priceFromCatalog(ctx context.Context, request *pricing.PriceRequest) (pricing.Product, error) {
+	if request.BasePrice != nil {
+		return pricing.Product{}, &pricing.ValidationError{Message: "base_price and sku are mutually exclusive"}
+	}
+	product, ok, err := catalog.Get(ctx, request.SKU)
+	if err != nil {
+		return product, err
+	}
+	if !ok {
+		return product, &pricing.ValidationError{Message: fmt.Sprintf("unknown sku %q", request.SKU)}
+	}
+	trace.SpanFromContext(ctx).SetAttributes(attribute.String("product.sku", product.SKU))
+
+	request.BasePrice = &product.UnitPrice
+	if request.Jurisdiction != nil && request.Jurisdiction.Category == "" {
+		jurisdiction := *request.Jurisdiction
+		jurisdiction.Category = product.TaxCategory
+		request.Jurisdiction = &jurisdiction
+	}
+	return product, nil
+}
+
+// Lists all products in SKU order
+func listProducts(w http.ResponseWriter, r *http.Request) {
+	products, err := catalog.List(r.Context())
+	if err != nil {
+		writeOperationError(w, r, err, "Error listing products")
+		return
+	}
+	writeJSON(w, http.StatusOK, products)
+}
+
+// Adds a product to the catalog
+func createProduct(w http.ResponseWriter, r *http.Request) {
+	var product pricing.Product
+	if !decodeJSON(w, r, &product) {
+		return
+	}
+	if err := product.Validate(); err != nil {
+		slog.WarnContext(r.Context(), "Invalid product", "error", err)
+		writeProblem(w, r, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	created, err := catalog.Create(r.Context(), product)
+	if err != nil {
+		writeOperationError(w, r, err, "Error creating product")
+		return
+	}
+	if !created {
+		writeProblem(w, r, "Product already exists", http.StatusConflict)
+		return
+	}
+
+	trace.SpanFromContext(r.Context()).SetAttributes(attribute.String("product.sku", product.SKU))
+	recordAudit(r.Context(), auditProductCreated, "products/"+product.SKU, nil, product)
+	writeJSON(w, http.StatusCreated, product)
+	slog.InfoContext(r.Context(), "Product created", "sku", product.SKU)
+}
+
+// Returns a single product
+func getProduct(w http.ResponseWriter, r *http.Request) {
+	sku := mux.Vars(r)["sku"]
+
+	product, ok, err := catalog.Get(r.Context(), sku)
+	if err != nil {
+		writeOperationError(w, r, err, "Error loading product")
+		return
+	}
+	if !ok {
+		writeProblem(w, r, "Product not found", http.StatusNotFound)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, product)
+}
+
+// Replaces a product
+func updateProduct(w http.ResponseWriter, r *http.Request) {
+	sku := mux.Vars(r)["sku"]
+
+	var product pricing.Product
+	if !decodeJSON(w, r, &product) {
+		return
+	}
+	product.SKU = sku
+	if err := product.Validate(); err != nil {
+		slog.WarnContext(r.Context(), "Invalid product", "error", err)
+		writeProblem(w, r, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	previous, _, err := catalog.Get(r.Context(), sku)
+	if err != nil {
+		writeOperationError(w, r, err, "Error loading product")
+		return
+	}
+	updated, err := catalog.Update(r.Context(), product)
+	if err != nil {
+		writeOperationError(w, r, err, "Error updating product")
+		return
+	}
+	if !updated {
+		writeProblem(w, r, "Product not found", http.StatusNotFound)
+		return
+	}
+
+	trace.SpanFromContext(r.Context()).SetAttributes(attribute.String("product.sku", sku))
+	recordAudit(r.Context(), auditProductUpdated, "products/"+sku, previous, product)
+	writeJSON(w, http.StatusOK, product)
+	slog.InfoContext(r.Context(), "Product updated", "sku", sku)
+}
+
+// Removes a product from the catalog
+func deleteProduct(w http.ResponseWriter, r *http.Request) {
+	sku := mux.Vars(r)["sku"]
+
+	previous, _, err := catalog.Get(r.Context(), sku)
+	if err != nil {
+		writeOperationError(w, r, err, "Error loading product")
+		return
+	}
+	deleted, err := catalog.Delete(r.Context(), sku)
+	if err != nil {
+		writeOperationError(w, r, err, "Error deleting product")
+		return
+	}
+	if !deleted {
+		writeProblem(w, r, "Product not found", http.StatusNotFound)
+		return
+	}
+
+	trace.SpanFromContext(r.Context()).SetAttributes(attribute.String("product.sku", sku))
+	recordAudit(r.Context(), auditProductDeleted, "products/"+sku, previous, nil)
+	w.WriteHeader(http.StatusNoContent)
+	slog.InfoContext(r.Context(), "Product deleted", "sku", sku)
+}
@@ -0,0 +1,143 @@
+package httpapi
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"otpl/pricecalculator/internal/config"
+	"otpl/pricecalculator/internal/telemetry"
+)
+
+// How long a simulated downstream call waits unless the fault sets a timeout
+const defaultFaultTimeout = 2 * time.Second
+
+// Faults injected into every endpoint, set from the CHAOS_* environment variables on startup.
+// Nil when none are set, the chaos section of the configuration file applies then.
+var envFault *config.Fault
+
+// Loads the faults injected into every endpoint from CHAOS_LATENCY, CHAOS_JITTER,
+// CHAOS_ERROR_RATE, CHAOS_TIMEOUT_RATE and CHAOS_TIMEOUT
+func loadChaosFromEnv() (*config.Fault, error) {
+	var fault config.Fault
+	set := false
+	for _, d := range []struct {
+		env    string
+		target *time.Duration
+	}{
+		{"CHAOS_LATENCY", &fault.Latency},
+		{"CHAOS_JITTER", &fault.Jitter},
+		{"CHAOS_TIMEOUT", &fault.Timeout},
+	} {
+		if value := os.Getenv(d.env); value != "" {
+			duration, err := time.ParseDuration(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s: %v", d.env, err)
+			}
+			*d.target, set = duration, true
+		}
+	}
+	for _, r := range []struct {
+		env    string
+		target *float64
+	}{
+		{"CHAOS_ERROR_RATE", &fault.ErrorRate},
+		{"CHAOS_TIMEOUT_RATE", &fault.TimeoutRate},
+	} {
+		if value := os.Getenv(r.env); value != "" {
+			rate, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s: %v", r.env, err)
+			}
+			*r.target, set = rate, true
+		}
+	}
+	if !set {
+		return nil, nil
+	}
+	if err := fault.Validate(); err != nil {
+		return nil, err
+	}
+	return &fault, nil
+}
+
+// Returns the faults injected into an operation: the environment settings,
+// or the configuration file entry for the operation, or its "*" entry
+func faultFor(operation string) (config.Fault, bool) {
+	if envFault != nil {
+		return *envFault, true
+	}
+	faults := fileConfig.Load().Chaos
+	if fault, ok := faults[operation]; ok {
+		return fault, true
+	}
+	fault, ok := faults["*"]
+	return fault, ok
+}
+
+// Injects the configured latency and failures into a request, recording them on its span.
+// Returns true if the request was failed and must not be handled.
+func injectFault(w http.ResponseWriter, r *http.Request, operation string) bool {
+	fault, ok := faultFor(operation)
+	if !ok || !featureEnabled(r.Context(), "chaos") {
+		return false
+	}
+	ctx := r.Context()
+	span := trace.SpanFromContext(ctx)
+
+	delay := fault.Latency
+	if fault.Jitter > 0 {
+		delay += rand.N(fault.Jitter)
+	}
+	if delay > 0 {
+		span.AddEvent("chaos.latency", trace.WithAttributes(attribute.Int64("chaos.latency_ms", delay.Milliseconds())))
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			writeContextError(w, r)
+			return true
+		}
+	}
+
+	if fault.TimeoutRate > 0 && rand.Float64() < fault.TimeoutRate {
+		span.SetAttributes(attribute.String("chaos.fault", "timeout"))
+		err := callTimedOutDownstream(ctx, fault.Timeout)
+		slog.WarnContext(ctx, "Injected downstream timeout", "operation", operation, "error", err)
+		writeProblem(w, r, "Downstream service timed out", http.StatusGatewayTimeout)
+		return true
+	}
+	if fault.ErrorRate > 0 && rand.Float64() < fault.ErrorRate {
+		span.SetAttributes(attribute.String("chaos.fault", "error"))
+		slog.WarnContext(ctx, "Injected error", "operation", operation)
+		writeProblem(w, r, "Injected fault", http.StatusInternalServerError)
+		return true
+	}
+	return false
+}
+
+// Simulates a call to a downstream service that does not answer before the timeout,
+// traced as a failed client span
+func callTimedOutDownstream(ctx context.Context, timeout time.Duration) error {
+	if timeout == 0 {
+		timeout = defaultFaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	ctx, span := tracer.Start(ctx, "DownstreamCall", trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(
+		attribute.String("peer.service", "chaos-downstream"),
+		attribute.Bool("chaos.injected", true),
+	))
+	defer span.End()
+
+	<-ctx.Done()
+	telemetry.RecordError(span, ctx.Err())
+	return ctx.Err()
+}
@@ -0,0 +1,177 @@
+package httpapi
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Limit of a request body unless MAX_BODY_BYTES or the configuration file sets one
+const defaultMaxBodyBytes = 1 << 20
+
+// Returns the limit of a request body: MAX_BODY_BYTES, or server.max_body_bytes in the configuration file, or the default
+func maxBodyBytes() (int64, error) {
+	if value := os.Getenv("MAX_BODY_BYTES"); value != "" {
+		limit, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || limit <= 0 {
+			return 0, fmt.Errorf("invalid MAX_BODY_BYTES %q", value)
+		}
+		return limit, nil
+	}
+	if limit := fileConfig.Load().Server.MaxBodyBytes; limit > 0 {
+		return limit, nil
+	}
+	return defaultMaxBodyBytes, nil
+}
+
+// Reports whether gzip compression is enabled, unless server.gzip is false in the configuration file
+func gzipEnabled() bool {
+	enabled := fileConfig.Load().Server.Gzip
+	return enabled == nil || *enabled
+}
+
+// Middleware limiting request bodies, decompressing gzip request bodies and compressing the
+// responses of clients accepting gzip. The limit applies to the decompressed body, so a small
+// compressed body cannot expand without bound; bodies over it are answered with 413 when read.
+// Other content encodings are answered with 415.
+func limitRequestBody(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		limit, err := maxBodyBytes()
+		if err != nil {
+			limit = defaultMaxBodyBytes // Validated on startup
+		}
+		if !gzipEnabled() {
+			r.Body = http.MaxBytesReader(w, r.Body, limit)
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		switch encoding := strings.ToLower(r.Header.Get("Content-Encoding")); encoding {
+		case "", "identity":
+		case "gzip":
+			body, err := gzip.NewReader(r.Body)
+			if err != nil {
+				slog.WarnContext(r.Context(), "Invalid gzip request body", "error", err)
+				writeProblem(w, r, "Invalid gzip request body", http.StatusBadRequest)
+				return
+			}
+			r.Body = gzipBody{Reader: body, raw: r.Body}
+			r.ContentLength = -1
+		default:
+			writeProblem(w, r, fmt.Sprintf("Unsupported content encoding %q", encoding), http.StatusUnsupportedMediaType)
+			return
+		}
+		r.Body = http.MaxBytesReader(w, r.Body, limit)
+
+		// Compress the response here only, the handlers must not compress it again
+		if !acceptsGzip(r) || r.Method == http.MethodHead {
+			next.ServeHTTP(w, r)
+			return
+		}
+		r.Header.Del("Accept-Encoding")
+		gw := &gzipResponseWriter{ResponseWriter: w}
+		w.Header().Set("Content-Encoding", "gzip")
+		w.Header().Add("Vary", "Accept-Encoding")
+		defer gw.Close()
+		next.ServeHTTP(gw, r)
+	})
+}
+
+// Reports whether the client accepts gzip encoded responses
+func acceptsGzip(r *http.Request) bool {
+	for _, value := range r.Header.Values("Accept-Encoding") {
+		for _, coding := range strings.Split(value, ",") {
+			name, params, _ := strings.Cut(strings.TrimSpace(coding), ";")
+			if strings.EqualFold(strings.TrimSpace(name), "gzip") && strings.ReplaceAll(strings.TrimSpace(params), " ", "") != "q=0" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Records the content encodings of the request and response on the request span
+func recordContentEncoding(w http.ResponseWriter, r *http.Request) {
+	span := trace.SpanFromContext(r.Context())
+	if encoding := r.Header.Get("Content-Encoding"); encoding != "" {
+		span.SetAttributes(attribute.String("http.request.header.content-encoding", encoding))
+	}
+	if encoding := w.Header().Get("Content-Encoding"); encoding != "" {
+		span.SetAttributes(attribute.String("http.response.header.content-encoding", encoding))
+	}
+}
+
+// gzipBody is a decompressed request body, closing the compressed body with it
+type gzipBody struct {
+	*gzip.Reader
+	raw io.ReadCloser
+}
+
+func (b gzipBody) Close() error {
+	b.Reader.Close()
+	return b.raw.Close()
+}
+
+// gzipResponseWriter compresses a response while writing it. Responses without a body are
+// written uncompressed, the Content-Encoding header is set before the handler runs.
+type gzipResponseWriter struct {
+	http.ResponseWriter
+	gz          *gzip.Writer
+	wroteHeader bool
+}
+
+func (w *gzipResponseWriter) WriteHeader(status int) {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+	if status == http.StatusNoContent || status == http.StatusNotModified || status < http.StatusOK {
+		w.Header().Del("Content-Encoding")
+	} else {
+		w.Header().Del("Content-Length") // The length of the uncompressed body
+		w.gz = gzip.NewWriter(w.ResponseWriter)
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *gzipResponseWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.gz == nil {
+		return w.ResponseWriter.Write(b)
+	}
+	return w.gz.Write(b)
+}
+
+// Flush sends the compressed data written so far to the client
+func (w *gzipResponseWriter) Flush() {
+	if w.gz != nil {
+		_ = w.gz.Flush()
+	}
+	http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer
+func (w *gzipResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// Close finishes the compressed stream, an unwritten response is left without a body
+func (w *gzipResponseWriter) Close() {
+	if !w.wroteHeader {
+		w.Header().Del("Content-Encoding")
+		return
+	}
+	if w.gz != nil {
+		_ = w.gz.Close()
+	}
+}
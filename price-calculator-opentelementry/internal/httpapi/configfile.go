@@ -0,0 +1,178 @@
+package httpapi
+
+import (
+	"context"
+	"log/slog"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"otpl/pricecalculator/internal/config"
+	"otpl/pricecalculator/internal/telemetry"
+	"otpl/pricecalculator/pricing"
+)
+
+// Contents of the configuration file named by CONFIG_FILE, empty when there is none
+var fileConfig atomic.Pointer[config.Config]
+
+// Serializes reloads of the configuration file
+var reloadMu sync.Mutex
+
+// Loads the configuration file named by CONFIG_FILE, if any
+func loadConfigFile() error {
+	cfg := config.Config{}
+	if path := os.Getenv("CONFIG_FILE"); path != "" {
+		var err error
+		if cfg, err = config.Load(path); err != nil {
+			return err
+		}
+	}
+	fileConfig.Store(&cfg)
+	return nil
+}
+
+// Returns the rounding mode of amounts in a currency priced with defaults: the currency's mode
+// from the configuration file, then the stored rounding mode, then ROUNDING_MODE, then the file's default mode
+func roundingMode(defaults pricing.Config, currency string) pricing.RoundingMode {
+	if currency == "" {
+		currency = pricing.DefaultCurrency
+	}
+	rounding := fileConfig.Load().Rounding
+	for code, mode := range rounding.Currencies {
+		if strings.EqualFold(code, currency) {
+			return mode
+		}
+	}
+	if defaults.Rounding != "" {
+		return defaults.Rounding
+	}
+	mode, _ := pricing.ParseRoundingMode(config.Setting("ROUNDING_MODE", string(rounding.Mode))) // Validated on startup and by config.Load
+	return mode
+}
+
+// Watches the configuration file named by CONFIG_FILE and applies changes until ctx is cancelled
+func watchConfigFile(ctx context.Context) error {
+	path := os.Getenv("CONFIG_FILE")
+	if path == "" {
+		return nil
+	}
+	return config.Watch(ctx, path, func(cfg config.Config, err error) {
+		reloadConfigFile(ctx, cfg, err)
+	})
+}
+
+// Applies a reloaded configuration file.
+// The log level, default prices, feature flags, API keys, rate limit, chaos faults and the other
+// request handling settings change at runtime, listen addresses, OTLP settings, trace exporters,
+// TLS and storage settings are only reported because they need a restart.
+func reloadConfigFile(ctx context.Context, cfg config.Config, err error) {
+	reloadMu.Lock()
+	defer reloadMu.Unlock()
+
+	// Each reload is traced as its own root span
+	ctx, span := tracer.Start(withAuthor(ctx, "config-file"), "ReloadConfig", trace.WithNewRoot())
+	defer span.End()
+
+	if err == nil {
+		err = withCORSOrigins(cfg.CORS).Validate()
+	}
+	if err != nil {
+		telemetry.RecordError(span, err)
+		slog.ErrorContext(ctx, "Failed to reload config file, keeping the current configuration", "error", err)
+		return
+	}
+	old := fileConfig.Load()
+
+	var changed []string
+	if cfg.LogLevel != old.LogLevel && os.Getenv("LOG_LEVEL") == "" {
+		level := slog.LevelInfo
+		if cfg.LogLevel != "" {
+			_ = level.UnmarshalText([]byte(strings.ToUpper(cfg.LogLevel))) // Validated by config.Load
+		}
+		telemetry.LogLevel.Set(level)
+		changed = append(changed, "log_level")
+	}
+	if basePrice, _ := cfg.Pricing.BasePriceMoney(); basePrice != nil && !equalPtr(cfg.Pricing.BasePrice, old.Pricing.BasePrice) {
+		if _, err := updateBasePrice(ctx, *basePrice); err != nil {
+			telemetry.RecordError(span, err)
+			slog.ErrorContext(ctx, "Failed to apply base price from config file", "error", err)
+		} else {
+			changed = append(changed, "pricing.base_price")
+		}
+	}
+	if cfg.Pricing.TaxRate != nil && !equalPtr(cfg.Pricing.TaxRate, old.Pricing.TaxRate) {
+		if _, err := updateTaxRate(ctx, *cfg.Pricing.TaxRate); err != nil {
+			telemetry.RecordError(span, err)
+			slog.ErrorContext(ctx, "Failed to apply tax rate from config file", "error", err)
+		} else {
+			changed = append(changed, "pricing.tax_rate")
+		}
+	}
+	if !slices.Equal(cfg.Pricing.Tiers, old.Pricing.Tiers) {
+		changed = append(changed, "pricing.tiers")
+	}
+	if !equalFeatures(cfg.Features, old.Features) {
+		changed = append(changed, "features")
+	}
+	if !slices.Equal(cfg.APIKeys, old.APIKeys) {
+		changed = append(changed, "api_keys")
+	}
+	if cfg.RateLimit != old.RateLimit {
+		changed = append(changed, "rate_limit")
+	}
+	if !maps.Equal(cfg.Chaos, old.Chaos) {
+		changed = append(changed, "chaos")
+	}
+	if cfg.Timeouts.Request != old.Timeouts.Request || !maps.Equal(cfg.Timeouts.Endpoints, old.Timeouts.Endpoints) {
+		changed = append(changed, "timeouts")
+	}
+	if cfg.Payloads.Enabled != old.Payloads.Enabled || cfg.Payloads.MaxBytes != old.Payloads.MaxBytes ||
+		!slices.Equal(cfg.Payloads.Redact, old.Payloads.Redact) || !slices.Equal(cfg.Payloads.Operations, old.Payloads.Operations) {
+		changed = append(changed, "payload_capture")
+	}
+	if !slices.Equal(cfg.CORS.AllowedOrigins, old.CORS.AllowedOrigins) || !slices.Equal(cfg.CORS.AllowedMethods, old.CORS.AllowedMethods) ||
+		!slices.Equal(cfg.CORS.AllowedHeaders, old.CORS.AllowedHeaders) || !slices.Equal(cfg.CORS.ExposedHeaders, old.CORS.ExposedHeaders) ||
+		cfg.CORS.MaxAge != old.CORS.MaxAge || cfg.CORS.AllowCredentials != old.CORS.AllowCredentials {
+		changed = append(changed, "cors")
+	}
+	if cfg.UI != old.UI {
+		changed = append(changed, "ui")
+	}
+	if cfg.Rounding.Mode != old.Rounding.Mode || !maps.Equal(cfg.Rounding.Currencies, old.Rounding.Currencies) {
+		changed = append(changed, "rounding")
+	}
+	if cfg.Server != old.Server || cfg.OTLP.Endpoint != old.OTLP.Endpoint || cfg.OTLP.Protocol != old.OTLP.Protocol || !equalPtr(cfg.OTLP.Insecure, old.OTLP.Insecure) ||
+		!slices.Equal(cfg.TraceExporters, old.TraceExporters) || cfg.Storage != old.Storage ||
+		cfg.TLS.CertFile != old.TLS.CertFile || cfg.TLS.KeyFile != old.TLS.KeyFile || cfg.TLS.Autocert.Email != old.TLS.Autocert.Email ||
+		cfg.TLS.Autocert.CacheDir != old.TLS.Autocert.CacheDir || !slices.Equal(cfg.TLS.Autocert.Domains, old.TLS.Autocert.Domains) {
+		slog.WarnContext(ctx, "Server, OTLP, trace exporter, TLS and storage settings in the config file apply after a restart")
+	}
+	fileConfig.Store(&cfg)
+
+	span.AddEvent("config.reloaded", trace.WithAttributes(attribute.StringSlice("config.changed", changed)))
+	slog.InfoContext(ctx, "Reloaded config file", "changed", changed)
+}
+
+// Compares two optional values
+func equalPtr[T comparable](a, b *T) bool {
+	return (a == nil && b == nil) || (a != nil && b != nil && *a == *b)
+}
+
+// Compares two sets of feature flags by their effective values
+func equalFeatures(a, b map[string]bool) bool {
+	x, y := config.Config{Features: a}, config.Config{Features: b}
+	for _, features := range []map[string]bool{a, b} {
+		for name := range features {
+			if x.FeatureEnabled(name) != y.FeatureEnabled(name) {
+				return false
+			}
+		}
+	}
+	return true
+}
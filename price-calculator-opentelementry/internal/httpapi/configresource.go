@@ -0,0 +1,111 @@
+package httpapi
+
+import (
+	"net/http"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"otpl/pricecalculator/pricing"
+)
+
+// ConfigUpdate structure for the body of PUT and PATCH /v1/config and POST /config/bulk.
+// An empty currency or rounding resets it to the default, as an omitted one does for PUT.
+type ConfigUpdate struct {
+	BasePrice *pricing.Money        `json:"base_price"`
+	TaxRate   *float64              `json:"tax_rate"`
+	Currency  *string               `json:"currency,omitempty"`
+	Rounding  *pricing.RoundingMode `json:"rounding,omitempty"`
+}
+
+// Reports whether the update sets nothing
+func (u ConfigUpdate) empty() bool {
+	return u.BasePrice == nil && u.TaxRate == nil && u.Currency == nil && u.Rounding == nil
+}
+
+// Returns the current base price and tax rate
+func getConfigV1(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, prices.Get())
+}
+
+// Replaces the whole configuration: the base price and tax rate are required, and an
+// omitted currency or rounding is reset to the default like an empty one
+func putConfigV1(w http.ResponseWriter, r *http.Request) {
+	var update ConfigUpdate
+	if !decodeJSON(w, r, &update) {
+		return
+	}
+	if update.BasePrice == nil || update.TaxRate == nil {
+		writeProblem(w, r, "base_price and tax_rate are required", http.StatusBadRequest)
+		return
+	}
+	if update.Currency == nil {
+		update.Currency = new(string)
+	}
+	if update.Rounding == nil {
+		update.Rounding = new(pricing.RoundingMode)
+	}
+	applyConfigUpdate(w, r, update)
+}
+
+// Updates the fields present in the body, at least one is required
+func patchConfigV1(w http.ResponseWriter, r *http.Request) {
+	var update ConfigUpdate
+	if !decodeJSON(w, r, &update) {
+		return
+	}
+	if update.empty() {
+		writeProblem(w, r, "base_price, tax_rate, currency or rounding is required", http.StatusBadRequest)
+		return
+	}
+	applyConfigUpdate(w, r, update)
+}
+
+// Sets any of the base price, tax rate, currency and rounding mode in a single update, so no
+// calculation is priced with some of the new values and some of the old ones
+func bulkUpdateConfig(w http.ResponseWriter, r *http.Request) {
+	var update ConfigUpdate
+	if !decodeJSON(w, r, &update) {
+		return
+	}
+	if update.empty() {
+		writeProblem(w, r, "base_price, tax_rate, currency or rounding is required", http.StatusBadRequest)
+		return
+	}
+	var fields []string
+	if update.BasePrice != nil {
+		fields = append(fields, "base_price")
+	}
+	if update.TaxRate != nil {
+		fields = append(fields, "tax_rate")
+	}
+	if update.Currency != nil {
+		fields = append(fields, "currency")
+	}
+	if update.Rounding != nil {
+		fields = append(fields, "rounding")
+	}
+	trace.SpanFromContext(r.Context()).SetAttributes(attribute.StringSlice("config.fields", fields))
+	applyConfigUpdate(w, r, update)
+}
+
+// Persists a configuration update and responds with the resulting configuration
+func applyConfigUpdate(w http.ResponseWriter, r *http.Request, update ConfigUpdate) {
+	cfg, err := updateConfig(r.Context(), update)
+	if err != nil {
+		writeOperationError(w, r, err, "Error saving configuration")
+		return
+	}
+	writeJSON(w, http.StatusOK, cfg)
+}
+
+// Serves a route replaced by the /v1/config resource while the legacy_routes feature is enabled,
+// announcing its deprecation and successor in the response headers
+func deprecatedRoute(handler http.HandlerFunc) http.HandlerFunc {
+	return requireFeature("legacy_routes", func(w http.ResponseWriter, r *http.Request) {
+		trace.SpanFromContext(r.Context()).SetAttributes(attribute.Bool("http.deprecated_route", true))
+		w.Header().Set("Deprecation", "true")
+		w.Header().Set("Link", `</v1/config>; rel="successor-version"`)
+		handler(w, r)
+	})
+}
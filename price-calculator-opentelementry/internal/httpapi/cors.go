@@ -0,0 +1,100 @@
+package httpapi
+
+import (
+	"net/http"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"otpl/pricecalculator/internal/config"
+)
+
+// CORS defaults used when the cors section leaves a list empty
+var (
+	defaultCORSMethods        = []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete}
+	defaultCORSHeaders        = []string{"Content-Type", APIKeyHeader, idempotencyHeader, requestIDHeader, "traceparent", "tracestate", "baggage"}
+	defaultCORSExposedHeaders = []string{requestIDHeader, traceResponseHeader, traceIDHeader, traceURLHeader, "Location", "Retry-After", "Idempotent-Replayed", "Deprecation", "Link"}
+)
+
+// Preflight cache time unless cors sets max_age
+const defaultCORSMaxAge = 600
+
+// Returns the CORS settings of the configuration file, with the allowed origins
+// overridden by the comma separated CORS_ALLOWED_ORIGINS
+func corsConfig() config.CORS {
+	return withCORSOrigins(fileConfig.Load().CORS)
+}
+
+// Returns the CORS settings of a configuration file with the allowed origins of CORS_ALLOWED_ORIGINS.
+// The result is validated on startup and reload, as the origins can allow credentials for any origin.
+func withCORSOrigins(cfg config.CORS) config.CORS {
+	if value := os.Getenv("CORS_ALLOWED_ORIGINS"); value != "" {
+		cfg.AllowedOrigins = nil
+		for _, origin := range strings.Split(value, ",") {
+			if origin = strings.TrimSpace(origin); origin != "" {
+				cfg.AllowedOrigins = append(cfg.AllowedOrigins, origin)
+			}
+		}
+	}
+	return cfg
+}
+
+// Reports whether browsers on the origin may call the API
+func corsOriginAllowed(cfg config.CORS, origin string) bool {
+	return slices.ContainsFunc(cfg.AllowedOrigins, func(allowed string) bool {
+		return allowed == "*" || strings.EqualFold(allowed, origin)
+	})
+}
+
+// Middleware adding the CORS headers for allowed origins and answering their preflight requests.
+// It wraps the whole router, as preflight OPTIONS requests match none of the API routes.
+func handleCORS(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		cfg := corsConfig()
+		if origin == "" || !corsOriginAllowed(cfg, origin) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		header := w.Header()
+		header.Add("Vary", "Origin")
+		if slices.Contains(cfg.AllowedOrigins, "*") {
+			header.Set("Access-Control-Allow-Origin", "*")
+		} else {
+			header.Set("Access-Control-Allow-Origin", origin)
+		}
+		if cfg.AllowCredentials {
+			header.Set("Access-Control-Allow-Credentials", "true")
+		}
+
+		// Answer preflight requests without reaching the API
+		if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+			methods, headers, maxAge := cfg.AllowedMethods, cfg.AllowedHeaders, cfg.MaxAge
+			if len(methods) == 0 {
+				methods = defaultCORSMethods
+			}
+			if len(headers) == 0 {
+				headers = defaultCORSHeaders
+			}
+			if maxAge == 0 {
+				maxAge = defaultCORSMaxAge
+			}
+			header.Add("Vary", "Access-Control-Request-Method")
+			header.Add("Vary", "Access-Control-Request-Headers")
+			header.Set("Access-Control-Allow-Methods", strings.Join(methods, ", "))
+			header.Set("Access-Control-Allow-Headers", strings.Join(headers, ", "))
+			header.Set("Access-Control-Max-Age", strconv.Itoa(maxAge))
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		exposed := cfg.ExposedHeaders
+		if len(exposed) == 0 {
+			exposed = defaultCORSExposedHeaders
+		}
+		header.Set("Access-Control-Expose-Headers", strings.Join(exposed, ", "))
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,351 @@
+package httpapi
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"otpl/pricecalculator/pricing"
+)
+
+// Returned when a coupon would take the code of another coupon
+var errCouponCodeTaken = errors.New("coupon code already exists")
+
+// CouponStore persists the coupons and counts their redemptions
+type CouponStore interface {
+	// List returns all coupons in ID order
+	List(ctx context.Context) ([]pricing.Coupon, error)
+	// Get returns the coupon with the ID, false if there is none
+	Get(ctx context.Context, id string) (pricing.Coupon, bool, error)
+	// GetByCode returns the coupon with the normalized code, false if there is none
+	GetByCode(ctx context.Context, code string) (pricing.Coupon, bool, error)
+	// Create adds a coupon under a new ID, errCouponCodeTaken if its code is in use
+	Create(ctx context.Context, coupon pricing.Coupon) (pricing.Coupon, error)
+	// Update replaces a coupon keeping its redemption count, false if there is none with its ID
+	Update(ctx context.Context, coupon pricing.Coupon) (pricing.Coupon, bool, error)
+	// Delete removes a coupon, false if there is none with the ID
+	Delete(ctx context.Context, id string) (bool, error)
+	// Redeem checks and counts a redemption of the coupon with the code in one step, so
+	// concurrent calculations cannot exceed the usage limit. False if the code is unknown.
+	Redeem(ctx context.Context, code string, now time.Time) (pricing.Coupon, bool, error)
+	// Release gives back a redemption, false if there was none to give back
+	Release(ctx context.Context, id string) (bool, error)
+}
+
+// Coupon store opened on startup
+var coupons CouponStore
+
+// memoryCouponStore keeps the coupons in memory, saved to a JSON file when it has one
+type memoryCouponStore struct {
+	mu      sync.Mutex
+	coupons map[string]pricing.Coupon
+	nextID  int
+	file    *jsonFile
+}
+
+// Creates the in-memory coupon store, loading the coupons from file
+func newMemoryCouponStore(file *jsonFile) (*memoryCouponStore, error) {
+	s := &memoryCouponStore{coupons: map[string]pricing.Coupon{}, file: file}
+	if err := file.load(&s.coupons); err != nil {
+		return nil, err
+	}
+	for id := range s.coupons {
+		s.nextID = max(s.nextID, discountID(id))
+	}
+	return s, nil
+}
+
+// Finds a coupon by its code, mu must be held
+func (s *memoryCouponStore) byCode(code string) (pricing.Coupon, bool) {
+	for _, c := range s.coupons {
+		if c.Code == code {
+			return c, true
+		}
+	}
+	return pricing.Coupon{}, false
+}
+
+func (s *memoryCouponStore) List(ctx context.Context) ([]pricing.Coupon, error) {
+	s.mu.Lock()
+	list := make([]pricing.Coupon, 0, len(s.coupons))
+	for _, c := range s.coupons {
+		list = append(list, c)
+	}
+	s.mu.Unlock()
+	sort.Slice(list, func(i, j int) bool { return discountID(list[i].ID) < discountID(list[j].ID) })
+	return list, nil
+}
+
+func (s *memoryCouponStore) Get(ctx context.Context, id string) (pricing.Coupon, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c, ok := s.coupons[id]
+	return c, ok, nil
+}
+
+func (s *memoryCouponStore) GetByCode(ctx context.Context, code string) (pricing.Coupon, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	coupon, ok := s.byCode(code)
+	return coupon, ok, nil
+}
+
+func (s *memoryCouponStore) Create(ctx context.Context, coupon pricing.Coupon) (pricing.Coupon, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, taken := s.byCode(coupon.Code); taken {
+		return coupon, errCouponCodeTaken
+	}
+	s.nextID++
+	coupon.ID = strconv.Itoa(s.nextID)
+	s.coupons[coupon.ID] = coupon
+	return coupon, s.file.persist(s.coupons)
+}
+
+func (s *memoryCouponStore) Update(ctx context.Context, coupon pricing.Coupon) (pricing.Coupon, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	existing, ok := s.coupons[coupon.ID]
+	if !ok {
+		return coupon, false, nil
+	}
+	if other, taken := s.byCode(coupon.Code); taken && other.ID != coupon.ID {
+		return coupon, true, errCouponCodeTaken
+	}
+	coupon.Redemptions = existing.Redemptions
+	s.coupons[coupon.ID] = coupon
+	return coupon, true, s.file.persist(s.coupons)
+}
+
+func (s *memoryCouponStore) Delete(ctx context.Context, id string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.coupons[id]; !ok {
+		return false, nil
+	}
+	delete(s.coupons, id)
+	return true, s.file.persist(s.coupons)
+}
+
+func (s *memoryCouponStore) Redeem(ctx context.Context, code string, now time.Time) (pricing.Coupon, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	coupon, ok := s.byCode(code)
+	if !ok {
+		return coupon, false, nil
+	}
+	if err := coupon.Redeemable(now); err != nil {
+		return coupon, true, err
+	}
+	coupon.Redemptions++
+	s.coupons[coupon.ID] = coupon
+	return coupon, true, s.file.persist(s.coupons)
+}
+
+func (s *memoryCouponStore) Release(ctx context.Context, id string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	coupon, ok := s.coupons[id]
+	if !ok || coupon.Redemptions == 0 {
+		return false, nil
+	}
+	coupon.Redemptions--
+	s.coupons[id] = coupon
+	return true, s.file.persist(s.coupons)
+}
+
+// sqlCouponStore keeps the coupons in a SQLite or PostgreSQL table, with
+// the expiry in Unix nanoseconds
+type sqlCouponStore struct {
+	db *sql.DB
+}
+
+// Creates the coupon store in db, adding its table if needed
+func newSQLCouponStore(db *sql.DB) (*sqlCouponStore, error) {
+	_, err := db.Exec(`CREATE TABLE IF NOT EXISTS coupons (
+		id              BIGINT PRIMARY KEY,
+		code            TEXT NOT NULL UNIQUE,
+		type            TEXT NOT NULL,
+		value           DOUBLE PRECISION NOT NULL,
+		expires_at      BIGINT,
+		max_redemptions BIGINT NOT NULL DEFAULT 0,
+		redemptions     BIGINT NOT NULL DEFAULT 0
+	)`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create coupons table: %v", err)
+	}
+	return &sqlCouponStore{db: db}, nil
+}
+
+// Columns of a coupon in the order scanCoupon reads them
+const couponColumns = `id, code, type, value, expires_at, max_redemptions, redemptions`
+
+// Reads a coupon selected with couponColumns
+func scanCoupon(row interface{ Scan(...any) error }) (pricing.Coupon, error) {
+	var c pricing.Coupon
+	var id int64
+	var expiresAt sql.NullInt64
+	if err := row.Scan(&id, &c.Code, &c.Type, &c.Value, &expiresAt, &c.MaxRedemptions, &c.Redemptions); err != nil {
+		return c, err
+	}
+	c.ID = strconv.FormatInt(id, 10)
+	if expiresAt.Valid {
+		at := time.Unix(0, expiresAt.Int64).UTC()
+		c.ExpiresAt = &at
+	}
+	return c, nil
+}
+
+// Returns the stored form of a coupon expiry
+func couponExpiry(c pricing.Coupon) sql.NullInt64 {
+	if c.ExpiresAt == nil {
+		return sql.NullInt64{}
+	}
+	return sql.NullInt64{Int64: c.ExpiresAt.UnixNano(), Valid: true}
+}
+
+func (s *sqlCouponStore) List(ctx context.Context) ([]pricing.Coupon, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT `+couponColumns+` FROM coupons ORDER BY id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list coupons: %v", err)
+	}
+	defer rows.Close()
+
+	list := []pricing.Coupon{}
+	for rows.Next() {
+		c, err := scanCoupon(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read coupon: %v", err)
+		}
+		list = append(list, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list coupons: %v", err)
+	}
+	return list, nil
+}
+
+func (s *sqlCouponStore) Get(ctx context.Context, id string) (pricing.Coupon, bool, error) {
+	n, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return pricing.Coupon{}, false, nil
+	}
+	c, err := scanCoupon(s.db.QueryRowContext(ctx, `SELECT `+couponColumns+` FROM coupons WHERE id = $1`, n))
+	if errors.Is(err, sql.ErrNoRows) {
+		return c, false, nil
+	}
+	if err != nil {
+		return c, false, fmt.Errorf("failed to load coupon: %v", err)
+	}
+	return c, true, nil
+}
+
+func (s *sqlCouponStore) GetByCode(ctx context.Context, code string) (pricing.Coupon, bool, error) {
+	c, err := scanCoupon(s.db.QueryRowContext(ctx, `SELECT `+couponColumns+` FROM coupons WHERE code = $1`, code))
+	if errors.Is(err, sql.ErrNoRows) {
+		return c, false, nil
+	}
+	if err != nil {
+		return c, false, fmt.Errorf("failed to load coupon: %v", err)
+	}
+	return c, true, nil
+}
+
+func (s *sqlCouponStore) Create(ctx context.Context, coupon pricing.Coupon) (pricing.Coupon, error) {
+	// The WHERE clause lets SQLite parse the ON CONFLICT clause after a SELECT
+	var id int64
+	err := s.db.QueryRowContext(ctx, `INSERT INTO coupons (`+couponColumns+`)
+		SELECT COALESCE(MAX(id), 0) + 1, CAST($1 AS TEXT), CAST($2 AS TEXT), CAST($3 AS DOUBLE PRECISION),
+			CAST($4 AS BIGINT), CAST($5 AS BIGINT), 0 FROM coupons WHERE true
+		ON CONFLICT (code) DO NOTHING RETURNING id`,
+		coupon.Code, coupon.Type, coupon.Value, couponExpiry(coupon), coupon.MaxRedemptions).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return coupon, errCouponCodeTaken
+	}
+	if err != nil {
+		return coupon, fmt.Errorf("failed to create coupon: %v", err)
+	}
+	coupon.ID = strconv.FormatInt(id, 10)
+	return coupon, nil
+}
+
+func (s *sqlCouponStore) Update(ctx context.Context, coupon pricing.Coupon) (pricing.Coupon, bool, error) {
+	id, err := strconv.ParseInt(coupon.ID, 10, 64)
+	if err != nil {
+		return coupon, false, nil
+	}
+	var other int64
+	err = s.db.QueryRowContext(ctx, `SELECT id FROM coupons WHERE code = $1 AND id <> $2`, coupon.Code, id).Scan(&other)
+	if err == nil {
+		return coupon, true, errCouponCodeTaken
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return coupon, false, fmt.Errorf("failed to update coupon: %v", err)
+	}
+
+	err = s.db.QueryRowContext(ctx, `UPDATE coupons SET code = $2, type = $3, value = $4, expires_at = $5, max_redemptions = $6
+		WHERE id = $1 RETURNING redemptions`,
+		id, coupon.Code, coupon.Type, coupon.Value, couponExpiry(coupon), coupon.MaxRedemptions).Scan(&coupon.Redemptions)
+	if errors.Is(err, sql.ErrNoRows) {
+		return coupon, false, nil
+	}
+	if err != nil {
+		return coupon, false, fmt.Errorf("failed to update coupon: %v", err)
+	}
+	return coupon, true, nil
+}
+
+func (s *sqlCouponStore) Delete(ctx context.Context, id string) (bool, error) {
+	n, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return false, nil
+	}
+	result, err := s.db.ExecContext(ctx, `DELETE FROM coupons WHERE id = $1`, n)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete coupon: %v", err)
+	}
+	return affected(result)
+}
+
+func (s *sqlCouponStore) Redeem(ctx context.Context, code string, now time.Time) (pricing.Coupon, bool, error) {
+	// Count the redemption only while the coupon is redeemable, in a single statement
+	c, err := scanCoupon(s.db.QueryRowContext(ctx, `UPDATE coupons SET redemptions = redemptions + 1
+		WHERE code = $1 AND (expires_at IS NULL OR expires_at > $2) AND (max_redemptions = 0 OR redemptions < max_redemptions)
+		RETURNING `+couponColumns, code, now.UnixNano()))
+	if err == nil {
+		return c, true, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return c, false, fmt.Errorf("failed to redeem coupon: %v", err)
+	}
+
+	// Nothing was redeemed, find out whether the code is unknown or why the coupon was rejected
+	c, err = scanCoupon(s.db.QueryRowContext(ctx, `SELECT `+couponColumns+` FROM coupons WHERE code = $1`, code))
+	if errors.Is(err, sql.ErrNoRows) {
+		return c, false, nil
+	}
+	if err != nil {
+		return c, false, fmt.Errorf("failed to load coupon: %v", err)
+	}
+	if err := c.Redeemable(now); err != nil {
+		return c, true, err
+	}
+	return c, true, &pricing.ValidationError{Message: fmt.Sprintf("coupon %s could not be redeemed", c.Code)}
+}
+
+func (s *sqlCouponStore) Release(ctx context.Context, id string) (bool, error) {
+	n, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return false, nil
+	}
+	result, err := s.db.ExecContext(ctx, `UPDATE coupons SET redemptions = redemptions - 1 WHERE id = $1 AND redemptions > 0`, n)
+	if err != nil {
+		return false, fmt.Errorf("failed to release coupon: %v", err)
+	}
+	return affected(result)
+}
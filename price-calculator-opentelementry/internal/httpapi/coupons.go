@@ -0,0 +1,193 @@
+package httpapi
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"otpl/pricecalculator/pricing"
+)
+
+// Redeems a coupon for a calculation, checking and counting the redemption in one step
+// so concurrent calculations cannot exceed the usage limit
+func redeemCoupon(ctx context.Context, code string) (pricing.Coupon, error) {
+	span := trace.SpanFromContext(ctx)
+
+	code = pricing.NormalizeCouponCode(code)
+	coupon, ok, err := coupons.Redeem(ctx, code, time.Now())
+	if !ok && err == nil {
+		span.AddEvent("coupon.rejected", trace.WithAttributes(attribute.String("coupon.code", code)))
+		return coupon, &pricing.ValidationError{Message: "unknown coupon code"}
+	}
+	if err != nil {
+		var validationErr *pricing.ValidationError
+		if errors.As(err, &validationErr) {
+			span.AddEvent("coupon.rejected", trace.WithAttributes(attribute.String("coupon.id", coupon.ID)))
+		}
+		return coupon, err
+	}
+
+	span.AddEvent("coupon.redeemed", trace.WithAttributes(
+		attribute.String("coupon.id", coupon.ID),
+		attribute.Int("coupon.redemptions", coupon.Redemptions),
+	))
+	return coupon, nil
+}
+
+// Looks up a coupon for a simulation, checking that it is redeemable without counting a redemption
+func previewCoupon(ctx context.Context, code string) (pricing.Coupon, error) {
+	code = pricing.NormalizeCouponCode(code)
+	coupon, ok, err := coupons.GetByCode(ctx, code)
+	if err != nil {
+		return coupon, err
+	}
+	if !ok {
+		return coupon, &pricing.ValidationError{Message: "unknown coupon code"}
+	}
+	if err := coupon.Redeemable(time.Now()); err != nil {
+		return coupon, err
+	}
+	trace.SpanFromContext(ctx).AddEvent("coupon.previewed", trace.WithAttributes(attribute.String("coupon.id", coupon.ID)))
+	return coupon, nil
+}
+
+// Gives back a redemption when the calculation using the coupon failed
+func releaseCoupon(ctx context.Context, id string) {
+	released, err := coupons.Release(ctx, id)
+	if err != nil {
+		slog.WarnContext(ctx, "Failed to release coupon", "coupon_id", id, "error", err)
+		return
+	}
+	if released {
+		trace.SpanFromContext(ctx).AddEvent("coupon.released", trace.WithAttributes(attribute.String("coupon.id", id)))
+	}
+}
+
+// Lists all coupons in ID order
+func listCoupons(w http.ResponseWriter, r *http.Request) {
+	list, err := coupons.List(r.Context())
+	if err != nil {
+		writeOperationError(w, r, err, "Error listing coupons")
+		return
+	}
+	writeJSON(w, http.StatusOK, list)
+}
+
+// Creates a coupon
+func createCoupon(w http.ResponseWriter, r *http.Request) {
+	var coupon pricing.Coupon
+	if !decodeJSON(w, r, &coupon) {
+		return
+	}
+	if err := coupon.Validate(); err != nil {
+		slog.WarnContext(r.Context(), "Invalid coupon", "error", err)
+		writeProblem(w, r, err.Error(), http.StatusBadRequest)
+		return
+	}
+	coupon.Code = pricing.NormalizeCouponCode(coupon.Code)
+	coupon.Redemptions = 0
+
+	coupon, err := coupons.Create(r.Context(), coupon)
+	if errors.Is(err, errCouponCodeTaken) {
+		writeProblem(w, r, "Coupon code already exists", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		writeOperationError(w, r, err, "Error creating coupon")
+		return
+	}
+
+	trace.SpanFromContext(r.Context()).SetAttributes(attribute.String("coupon.id", coupon.ID))
+	recordAudit(r.Context(), auditCouponCreated, "coupons/"+coupon.ID, nil, coupon)
+	writeJSON(w, http.StatusCreated, coupon)
+	slog.InfoContext(r.Context(), "Coupon created", "coupon_id", coupon.ID)
+}
+
+// Returns a single coupon
+func getCoupon(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	coupon, ok, err := coupons.Get(r.Context(), id)
+	if err != nil {
+		writeOperationError(w, r, err, "Error loading coupon")
+		return
+	}
+	if !ok {
+		writeProblem(w, r, "Coupon not found", http.StatusNotFound)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, coupon)
+}
+
+// Replaces a coupon, keeping its redemption count
+func updateCoupon(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	var coupon pricing.Coupon
+	if !decodeJSON(w, r, &coupon) {
+		return
+	}
+	if err := coupon.Validate(); err != nil {
+		slog.WarnContext(r.Context(), "Invalid coupon", "error", err)
+		writeProblem(w, r, err.Error(), http.StatusBadRequest)
+		return
+	}
+	coupon.ID = id
+	coupon.Code = pricing.NormalizeCouponCode(coupon.Code)
+
+	previous, _, err := coupons.Get(r.Context(), id)
+	if err != nil {
+		writeOperationError(w, r, err, "Error loading coupon")
+		return
+	}
+	coupon, ok, err := coupons.Update(r.Context(), coupon)
+	if errors.Is(err, errCouponCodeTaken) {
+		writeProblem(w, r, "Coupon code already exists", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		writeOperationError(w, r, err, "Error updating coupon")
+		return
+	}
+	if !ok {
+		writeProblem(w, r, "Coupon not found", http.StatusNotFound)
+		return
+	}
+
+	trace.SpanFromContext(r.Context()).SetAttributes(attribute.String("coupon.id", id))
+	recordAudit(r.Context(), auditCouponUpdated, "coupons/"+id, previous, coupon)
+	writeJSON(w, http.StatusOK, coupon)
+	slog.InfoContext(r.Context(), "Coupon updated", "coupon_id", id)
+}
+
+// Deletes a coupon
+func deleteCoupon(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	previous, _, err := coupons.Get(r.Context(), id)
+	if err != nil {
+		writeOperationError(w, r, err, "Error loading coupon")
+		return
+	}
+	ok, err := coupons.Delete(r.Context(), id)
+	if err != nil {
+		writeOperationError(w, r, err, "Error deleting coupon")
+		return
+	}
+	if !ok {
+		writeProblem(w, r, "Coupon not found", http.StatusNotFound)
+		return
+	}
+
+	trace.SpanFromContext(r.Context()).SetAttributes(attribute.String("coupon.id", id))
+	recordAudit(r.Context(), auditCouponDeleted, "coupons/"+id, previous, nil)
+	w.WriteHeader(http.StatusNoContent)
+	slog.InfoContext(r.Context(), "Coupon deleted", "coupon_id", id)
+}
@@ -0,0 +1,222 @@
+package httpapi
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"otpl/pricecalculator/pricing"
+)
+
+// CustomerStore persists the customers and their negotiated prices
+type CustomerStore interface {
+	// List returns all customers in ID order
+	List(ctx context.Context) ([]Customer, error)
+	// Get returns the customer with the ID, false if there is none
+	Get(ctx context.Context, id string) (Customer, bool, error)
+	// Create adds a customer, false if there is one with its ID already
+	Create(ctx context.Context, customer Customer) (bool, error)
+	// Update replaces the overrides of a customer, false if there is none with its ID
+	Update(ctx context.Context, customer Customer) (bool, error)
+	// Delete removes a customer, false if there is none with the ID
+	Delete(ctx context.Context, id string) (bool, error)
+}
+
+// Customer store opened on startup
+var customers CustomerStore
+
+// memoryCustomerStore keeps the customers in memory, saved to a JSON file when it has one
+type memoryCustomerStore struct {
+	mu        sync.Mutex
+	customers map[string]Customer
+	file      *jsonFile
+}
+
+// Creates the in-memory customer store, loading the customers from file
+func newMemoryCustomerStore(file *jsonFile) (*memoryCustomerStore, error) {
+	s := &memoryCustomerStore{customers: map[string]Customer{}, file: file}
+	if err := file.load(&s.customers); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *memoryCustomerStore) List(ctx context.Context) ([]Customer, error) {
+	s.mu.Lock()
+	list := make([]Customer, 0, len(s.customers))
+	for _, c := range s.customers {
+		list = append(list, c)
+	}
+	s.mu.Unlock()
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+	return list, nil
+}
+
+func (s *memoryCustomerStore) Get(ctx context.Context, id string) (Customer, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c, ok := s.customers[id]
+	return c, ok, nil
+}
+
+func (s *memoryCustomerStore) Create(ctx context.Context, customer Customer) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, exists := s.customers[customer.ID]; exists {
+		return false, nil
+	}
+	s.customers[customer.ID] = customer
+	return true, s.file.persist(s.customers)
+}
+
+func (s *memoryCustomerStore) Update(ctx context.Context, customer Customer) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.customers[customer.ID]; !ok {
+		return false, nil
+	}
+	s.customers[customer.ID] = customer
+	return true, s.file.persist(s.customers)
+}
+
+func (s *memoryCustomerStore) Delete(ctx context.Context, id string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.customers[id]; !ok {
+		return false, nil
+	}
+	delete(s.customers, id)
+	return true, s.file.persist(s.customers)
+}
+
+// sqlCustomerStore keeps the customers in a SQLite or PostgreSQL table, with the
+// negotiated prices by SKU encoded as JSON
+type sqlCustomerStore struct {
+	db *sql.DB
+}
+
+// Creates the customer store in db, adding its table if needed
+func newSQLCustomerStore(db *sql.DB) (*sqlCustomerStore, error) {
+	_, err := db.Exec(`CREATE TABLE IF NOT EXISTS customers (
+		id         TEXT PRIMARY KEY,
+		name       TEXT NOT NULL DEFAULT '',
+		base_price TEXT,
+		prices     TEXT NOT NULL DEFAULT '{}',
+		tax_exempt BOOLEAN NOT NULL DEFAULT FALSE
+	)`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create customers table: %v", err)
+	}
+	return &sqlCustomerStore{db: db}, nil
+}
+
+// Columns of a customer in the order scanCustomer reads them
+const customerColumns = `id, name, base_price, prices, tax_exempt`
+
+// Reads a customer selected with customerColumns
+func scanCustomer(row interface{ Scan(...any) error }) (Customer, error) {
+	var c Customer
+	var basePrice sql.NullString
+	var prices string
+	if err := row.Scan(&c.ID, &c.Name, &basePrice, &prices, &c.TaxExempt); err != nil {
+		return c, err
+	}
+	if basePrice.Valid {
+		price, err := pricing.ParseMoney(basePrice.String)
+		if err != nil {
+			return c, fmt.Errorf("invalid stored base price: %v", err)
+		}
+		c.BasePrice = &price
+	}
+	if err := json.Unmarshal([]byte(prices), &c.Prices); err != nil {
+		return c, fmt.Errorf("invalid stored customer prices: %v", err)
+	}
+	if len(c.Prices) == 0 {
+		c.Prices = nil
+	}
+	return c, nil
+}
+
+// Returns the stored form of a customer's base price and negotiated prices
+func customerValues(c Customer) (sql.NullString, string, error) {
+	var basePrice sql.NullString
+	if c.BasePrice != nil {
+		basePrice = sql.NullString{String: c.BasePrice.String(), Valid: true}
+	}
+	prices, err := json.Marshal(c.Prices)
+	if err != nil {
+		return basePrice, "", fmt.Errorf("failed to encode customer prices: %v", err)
+	}
+	return basePrice, string(prices), nil
+}
+
+func (s *sqlCustomerStore) List(ctx context.Context) ([]Customer, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT `+customerColumns+` FROM customers ORDER BY id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list customers: %v", err)
+	}
+	defer rows.Close()
+
+	list := []Customer{}
+	for rows.Next() {
+		c, err := scanCustomer(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read customer: %v", err)
+		}
+		list = append(list, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list customers: %v", err)
+	}
+	return list, nil
+}
+
+func (s *sqlCustomerStore) Get(ctx context.Context, id string) (Customer, bool, error) {
+	c, err := scanCustomer(s.db.QueryRowContext(ctx, `SELECT `+customerColumns+` FROM customers WHERE id = $1`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return c, false, nil
+	}
+	if err != nil {
+		return c, false, fmt.Errorf("failed to load customer: %v", err)
+	}
+	return c, true, nil
+}
+
+func (s *sqlCustomerStore) Create(ctx context.Context, customer Customer) (bool, error) {
+	basePrice, prices, err := customerValues(customer)
+	if err != nil {
+		return false, err
+	}
+	result, err := s.db.ExecContext(ctx, `INSERT INTO customers (`+customerColumns+`) VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (id) DO NOTHING`,
+		customer.ID, customer.Name, basePrice, prices, customer.TaxExempt)
+	if err != nil {
+		return false, fmt.Errorf("failed to create customer: %v", err)
+	}
+	return affected(result)
+}
+
+func (s *sqlCustomerStore) Update(ctx context.Context, customer Customer) (bool, error) {
+	basePrice, prices, err := customerValues(customer)
+	if err != nil {
+		return false, err
+	}
+	result, err := s.db.ExecContext(ctx, `UPDATE customers SET name = $2, base_price = $3, prices = $4, tax_exempt = $5 WHERE id = $1`,
+		customer.ID, customer.Name, basePrice, prices, customer.TaxExempt)
+	if err != nil {
+		return false, fmt.Errorf("failed to update customer: %v", err)
+	}
+	return affected(result)
+}
+
+func (s *sqlCustomerStore) Delete(ctx context.Context, id string) (bool, error) {
+	result, err := s.db.ExecContext(ctx, `DELETE FROM customers WHERE id = $1`, id)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete customer: %v", err)
+	}
+	return affected(result)
+}
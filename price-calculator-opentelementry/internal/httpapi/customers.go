@@ -0,0 +1,197 @@
+package httpapi
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/trace"
+
+	"otpl/pricecalculator/pricing"
+)
+
+// Overrides a customer can match, recorded in the customer.overrides span attribute
+const (
+	overrideUnitPrice = "unit_price" // Negotiated price of the requested SKU
+	overrideBasePrice = "base_price" // Negotiated price replacing the default base price
+	overrideTaxExempt = "tax_exempt"
+)
+
+// Customer structure for a customer with negotiated prices or a tax exemption
+type Customer struct {
+	ID        string                   `json:"id"`
+	Name      string                   `json:"name,omitempty"`
+	BasePrice *pricing.Money           `json:"base_price,omitempty"` // Used when a request has neither base_price nor sku
+	Prices    map[string]pricing.Money `json:"prices,omitempty"`     // Negotiated unit prices by catalog SKU
+	TaxExempt bool                     `json:"tax_exempt,omitempty"`
+}
+
+// Validate checks the customer ID and prices
+func (c Customer) Validate() error {
+	if c.ID == "" || len(c.ID) > 64 {
+		return fmt.Errorf("customer id must be 1 to 64 characters")
+	}
+	if c.BasePrice != nil {
+		if err := pricing.ValidateBasePrice(*c.BasePrice); err != nil {
+			return err
+		}
+	}
+	for sku, price := range c.Prices {
+		if price.IsNegative() {
+			return fmt.Errorf("invalid price for sku %q", sku)
+		}
+	}
+	return nil
+}
+
+// Applies the overrides of the request's customer, named by customer_id or else by the
+// customer_id baggage. An unknown customer_id is rejected, an unknown baggage customer ignored.
+// Runs after the catalog price of the SKU is resolved, which a negotiated price replaces.
+func applyCustomerOverrides(ctx context.Context, request *pricing.PriceRequest) error {
+	id, fromBaggage := request.CustomerID, false
+	if id == "" {
+		id, fromBaggage = baggage.FromContext(ctx).Member("customer_id").Value(), true
+	}
+	if id == "" {
+		return nil
+	}
+	customer, ok, err := customers.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		if fromBaggage {
+			return nil
+		}
+		return &pricing.ValidationError{Message: fmt.Sprintf("unknown customer %q", id)}
+	}
+
+	var overrides []string
+	if price, ok := customer.Prices[request.SKU]; ok && request.SKU != "" {
+		request.BasePrice = &price
+		overrides = append(overrides, overrideUnitPrice)
+	} else if customer.BasePrice != nil && request.BasePrice == nil {
+		request.BasePrice = customer.BasePrice
+		overrides = append(overrides, overrideBasePrice)
+	}
+	if customer.TaxExempt {
+		exempt := 0.0
+		request.TaxRate, request.Taxes, request.Jurisdiction = &exempt, nil, nil
+		overrides = append(overrides, overrideTaxExempt)
+	}
+	if overrides == nil {
+		overrides = []string{}
+	}
+	trace.SpanFromContext(ctx).SetAttributes(
+		attribute.String("customer.id", customer.ID),
+		attribute.StringSlice("customer.overrides", overrides),
+	)
+	return nil
+}
+
+// Lists all customers in ID order
+func listCustomers(w http.ResponseWriter, r *http.Request) {
+	list, err := customers.List(r.Context())
+	if err != nil {
+		writeOperationError(w, r, err, "Error listing customers")
+		return
+	}
+	writeJSON(w, http.StatusOK, list)
+}
+
+// Creates a customer
+func createCustomer(w http.ResponseWriter, r *http.Request) {
+	var customer Customer
+	if !decodeJSON(w, r, &customer) {
+		return
+	}
+	if err := customer.Validate(); err != nil {
+		slog.WarnContext(r.Context(), "Invalid customer", "error", err)
+		writeProblem(w, r, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	created, err := customers.Create(r.Context(), customer)
+	if err != nil {
+		writeOperationError(w, r, err, "Error creating customer")
+		return
+	}
+	if !created {
+		writeProblem(w, r, "Customer already exists", http.StatusConflict)
+		return
+	}
+
+	trace.SpanFromContext(r.Context()).SetAttributes(attribute.String("customer.id", customer.ID))
+	writeJSON(w, http.StatusCreated, customer)
+	slog.InfoContext(r.Context(), "Customer created", "customer_id", customer.ID)
+}
+
+// Returns a single customer
+func getCustomer(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	customer, ok, err := customers.Get(r.Context(), id)
+	if err != nil {
+		writeOperationError(w, r, err, "Error loading customer")
+		return
+	}
+	if !ok {
+		writeProblem(w, r, "Customer not found", http.StatusNotFound)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, customer)
+}
+
+// Replaces the overrides of a customer
+func updateCustomer(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	var customer Customer
+	if !decodeJSON(w, r, &customer) {
+		return
+	}
+	customer.ID = id
+	if err := customer.Validate(); err != nil {
+		slog.WarnContext(r.Context(), "Invalid customer", "error", err)
+		writeProblem(w, r, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ok, err := customers.Update(r.Context(), customer)
+	if err != nil {
+		writeOperationError(w, r, err, "Error updating customer")
+		return
+	}
+	if !ok {
+		writeProblem(w, r, "Customer not found", http.StatusNotFound)
+		return
+	}
+
+	trace.SpanFromContext(r.Context()).SetAttributes(attribute.String("customer.id", id))
+	writeJSON(w, http.StatusOK, customer)
+	slog.InfoContext(r.Context(), "Customer updated", "customer_id", id)
+}
+
+// Deletes a customer
+func deleteCustomer(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	ok, err := customers.Delete(r.Context(), id)
+	if err != nil {
+		writeOperationError(w, r, err, "Error deleting customer")
+		return
+	}
+	if !ok {
+		writeProblem(w, r, "Customer not found", http.StatusNotFound)
+		return
+	}
+
+	trace.SpanFromContext(r.Context()).SetAttributes(attribute.String("customer.id", id))
+	w.WriteHeader(http.StatusNoContent)
+	slog.InfoContext(r.Context(), "Customer deleted", "customer_id", id)
+}
@@ -0,0 +1,47 @@
+package httpapi
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"otpl/pricecalculator/pricing"
+)
+
+// Name of the calculation step added by SIMULATED_DELAY
+const stepSimulatedDelay = "simulated_delay"
+
+// simulatedDelayStep waits before pricing, simulating a slow pricing backend so the
+// calculation spans stand out in traces of the demo server
+type simulatedDelayStep struct {
+	delay time.Duration
+}
+
+func (simulatedDelayStep) Name() string { return stepSimulatedDelay }
+
+// Waits for the delay, giving up when the calculation is cancelled
+func (s simulatedDelayStep) Apply(ctx context.Context, calc *pricing.Calculation) error {
+	select {
+	case <-time.After(s.delay):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Adds the delay step after the base step when SIMULATED_DELAY is set, e.g. to 100ms
+func loadSimulatedDelay() error {
+	value := os.Getenv("SIMULATED_DELAY")
+	if value == "" {
+		return nil
+	}
+	delay, err := time.ParseDuration(value)
+	if err != nil || delay < 0 {
+		return fmt.Errorf("invalid SIMULATED_DELAY %q", value)
+	}
+	if delay > 0 {
+		registerPricingStep(pricing.StepTiers, simulatedDelayStep{delay: delay})
+	}
+	return nil
+}
@@ -0,0 +1,283 @@
+package httpapi
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+
+	"otpl/pricecalculator/pricing"
+)
+
+// DiscountStore persists the discount rules of every tenant, the default configuration under ""
+type DiscountStore interface {
+	// List returns the discount rules of a tenant in ID order
+	List(ctx context.Context, tenant string) ([]pricing.Discount, error)
+	// Get returns a discount rule of a tenant, false if there is none with the ID
+	Get(ctx context.Context, tenant, id string) (pricing.Discount, bool, error)
+	// Create adds a discount rule to a tenant under a new ID
+	Create(ctx context.Context, tenant string, discount pricing.Discount) (pricing.Discount, error)
+	// Update replaces a discount rule of a tenant, false if there is none with its ID
+	Update(ctx context.Context, tenant string, discount pricing.Discount) (bool, error)
+	// Delete removes a discount rule of a tenant, false if there is none with the ID
+	Delete(ctx context.Context, tenant, id string) (bool, error)
+	// Replace replaces all discount rules of a tenant, keeping their IDs
+	Replace(ctx context.Context, tenant string, rules []pricing.Discount) error
+	// DeleteTenant removes the discount rules of a deleted tenant
+	DeleteTenant(ctx context.Context, tenant string) error
+}
+
+// Discount store opened on startup
+var discounts DiscountStore
+
+// Sorts discount rules in ID order
+func sortDiscounts(rules []pricing.Discount) {
+	sort.Slice(rules, func(i, j int) bool { return discountID(rules[i].ID) < discountID(rules[j].ID) })
+}
+
+// memoryDiscountStore keeps the discount rules in memory by tenant and ID, saved to a JSON file when it has one
+type memoryDiscountStore struct {
+	mu      sync.Mutex
+	tenants map[string]map[string]pricing.Discount
+	nextID  map[string]int
+	file    *jsonFile
+}
+
+// Creates the in-memory discount store, loading the rules from file
+func newMemoryDiscountStore(file *jsonFile) (*memoryDiscountStore, error) {
+	s := &memoryDiscountStore{tenants: map[string]map[string]pricing.Discount{}, nextID: map[string]int{}, file: file}
+	if err := file.load(&s.tenants); err != nil {
+		return nil, err
+	}
+	for tenant, rules := range s.tenants {
+		for id := range rules {
+			s.nextID[tenant] = max(s.nextID[tenant], discountID(id))
+		}
+	}
+	return s, nil
+}
+
+func (s *memoryDiscountStore) List(ctx context.Context, tenant string) ([]pricing.Discount, error) {
+	s.mu.Lock()
+	rules := make([]pricing.Discount, 0, len(s.tenants[tenant]))
+	for _, d := range s.tenants[tenant] {
+		rules = append(rules, d)
+	}
+	s.mu.Unlock()
+	sortDiscounts(rules)
+	return rules, nil
+}
+
+func (s *memoryDiscountStore) Get(ctx context.Context, tenant, id string) (pricing.Discount, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	d, ok := s.tenants[tenant][id]
+	return d, ok, nil
+}
+
+func (s *memoryDiscountStore) Create(ctx context.Context, tenant string, discount pricing.Discount) (pricing.Discount, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.tenants[tenant] == nil {
+		s.tenants[tenant] = map[string]pricing.Discount{}
+	}
+	s.nextID[tenant]++
+	discount.ID = strconv.Itoa(s.nextID[tenant])
+	s.tenants[tenant][discount.ID] = discount
+	return discount, s.file.persist(s.tenants)
+}
+
+func (s *memoryDiscountStore) Update(ctx context.Context, tenant string, discount pricing.Discount) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.tenants[tenant][discount.ID]; !ok {
+		return false, nil
+	}
+	s.tenants[tenant][discount.ID] = discount
+	return true, s.file.persist(s.tenants)
+}
+
+func (s *memoryDiscountStore) Delete(ctx context.Context, tenant, id string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.tenants[tenant][id]; !ok {
+		return false, nil
+	}
+	delete(s.tenants[tenant], id)
+	return true, s.file.persist(s.tenants)
+}
+
+func (s *memoryDiscountStore) Replace(ctx context.Context, tenant string, rules []pricing.Discount) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	set := make(map[string]pricing.Discount, len(rules))
+	for _, d := range rules {
+		set[d.ID] = d
+		s.nextID[tenant] = max(s.nextID[tenant], discountID(d.ID))
+	}
+	s.tenants[tenant] = set
+	return s.file.persist(s.tenants)
+}
+
+func (s *memoryDiscountStore) DeleteTenant(ctx context.Context, tenant string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.tenants[tenant]; !ok {
+		return nil
+	}
+	delete(s.tenants, tenant)
+	delete(s.nextID, tenant)
+	return s.file.persist(s.tenants)
+}
+
+// sqlDiscountStore keeps the discount rules in a SQLite or PostgreSQL table, each rule
+// encoded as JSON under its tenant and numeric ID
+type sqlDiscountStore struct {
+	db *sql.DB
+}
+
+// Creates the discount store in db, adding its table if needed
+func newSQLDiscountStore(db *sql.DB) (*sqlDiscountStore, error) {
+	_, err := db.Exec(`CREATE TABLE IF NOT EXISTS discounts (
+		tenant TEXT NOT NULL,
+		id     BIGINT NOT NULL,
+		rule   TEXT NOT NULL,
+		PRIMARY KEY (tenant, id)
+	)`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create discounts table: %v", err)
+	}
+	return &sqlDiscountStore{db: db}, nil
+}
+
+// Reads a discount rule selected as id, rule
+func scanDiscount(row interface{ Scan(...any) error }) (pricing.Discount, error) {
+	var d pricing.Discount
+	var id int64
+	var rule string
+	if err := row.Scan(&id, &rule); err != nil {
+		return d, err
+	}
+	if err := json.Unmarshal([]byte(rule), &d); err != nil {
+		return d, fmt.Errorf("invalid stored discount: %v", err)
+	}
+	d.ID = strconv.FormatInt(id, 10)
+	return d, nil
+}
+
+func (s *sqlDiscountStore) List(ctx context.Context, tenant string) ([]pricing.Discount, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT id, rule FROM discounts WHERE tenant = $1 ORDER BY id`, tenant)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list discounts: %v", err)
+	}
+	defer rows.Close()
+
+	rules := []pricing.Discount{}
+	for rows.Next() {
+		d, err := scanDiscount(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read discount: %v", err)
+		}
+		rules = append(rules, d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list discounts: %v", err)
+	}
+	return rules, nil
+}
+
+func (s *sqlDiscountStore) Get(ctx context.Context, tenant, id string) (pricing.Discount, bool, error) {
+	n, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return pricing.Discount{}, false, nil
+	}
+	d, err := scanDiscount(s.db.QueryRowContext(ctx, `SELECT id, rule FROM discounts WHERE tenant = $1 AND id = $2`, tenant, n))
+	if errors.Is(err, sql.ErrNoRows) {
+		return d, false, nil
+	}
+	if err != nil {
+		return d, false, fmt.Errorf("failed to load discount: %v", err)
+	}
+	return d, true, nil
+}
+
+func (s *sqlDiscountStore) Create(ctx context.Context, tenant string, discount pricing.Discount) (pricing.Discount, error) {
+	discount.ID = ""
+	rule, err := json.Marshal(discount)
+	if err != nil {
+		return discount, fmt.Errorf("failed to encode discount: %v", err)
+	}
+	var id int64
+	err = s.db.QueryRowContext(ctx, `INSERT INTO discounts (tenant, id, rule)
+		SELECT CAST($1 AS TEXT), COALESCE(MAX(id), 0) + 1, CAST($2 AS TEXT) FROM discounts WHERE tenant = $1
+		RETURNING id`, tenant, string(rule)).Scan(&id)
+	if err != nil {
+		return discount, fmt.Errorf("failed to create discount: %v", err)
+	}
+	discount.ID = strconv.FormatInt(id, 10)
+	return discount, nil
+}
+
+func (s *sqlDiscountStore) Update(ctx context.Context, tenant string, discount pricing.Discount) (bool, error) {
+	id, err := strconv.ParseInt(discount.ID, 10, 64)
+	if err != nil {
+		return false, nil
+	}
+	rule, err := json.Marshal(discount)
+	if err != nil {
+		return false, fmt.Errorf("failed to encode discount: %v", err)
+	}
+	result, err := s.db.ExecContext(ctx, `UPDATE discounts SET rule = $3 WHERE tenant = $1 AND id = $2`, tenant, id, string(rule))
+	if err != nil {
+		return false, fmt.Errorf("failed to update discount: %v", err)
+	}
+	return affected(result)
+}
+
+func (s *sqlDiscountStore) Delete(ctx context.Context, tenant, id string) (bool, error) {
+	n, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return false, nil
+	}
+	result, err := s.db.ExecContext(ctx, `DELETE FROM discounts WHERE tenant = $1 AND id = $2`, tenant, n)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete discount: %v", err)
+	}
+	return affected(result)
+}
+
+func (s *sqlDiscountStore) Replace(ctx context.Context, tenant string, rules []pricing.Discount) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+	if _, err := tx.ExecContext(ctx, `DELETE FROM discounts WHERE tenant = $1`, tenant); err != nil {
+		return fmt.Errorf("failed to replace discounts: %v", err)
+	}
+	for _, d := range rules {
+		rule, err := json.Marshal(d)
+		if err != nil {
+			return fmt.Errorf("failed to encode discount: %v", err)
+		}
+		if _, err := tx.ExecContext(ctx, `INSERT INTO discounts (tenant, id, rule) VALUES ($1, $2, $3)`,
+			tenant, discountID(d.ID), string(rule)); err != nil {
+			return fmt.Errorf("failed to replace discounts: %v", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to replace discounts: %v", err)
+	}
+	return nil
+}
+
+func (s *sqlDiscountStore) DeleteTenant(ctx context.Context, tenant string) error {
+	if _, err := s.db.ExecContext(ctx, `DELETE FROM discounts WHERE tenant = $1`, tenant); err != nil {
+		return fmt.Errorf("failed to delete discounts: %v", err)
+	}
+	return nil
+}
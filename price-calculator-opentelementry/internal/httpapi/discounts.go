@@ -0,0 +1,149 @@
+package httpapi
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"otpl/pricecalculator/pricing"
+)
+
+// Publishes a change to a discount rule, versioning the default configuration when it changed.
+// The previous rule is nil for a created rule.
+func discountChanged(ctx context.Context, event string, previous *pricing.Discount, discount pricing.Discount) {
+	if tenantID(ctx) == "" {
+		recordConfigVersion(ctx, prices.Get(), event)
+	}
+	switch {
+	case previous == nil:
+		recordAudit(ctx, event, "discounts/"+discount.ID, nil, discount)
+	case event == eventDiscountDeleted:
+		recordAudit(ctx, event, "discounts/"+discount.ID, *previous, nil)
+	default:
+		recordAudit(ctx, event, "discounts/"+discount.ID, *previous, discount)
+	}
+	notifyWebhooks(ctx, event, discount)
+}
+
+// Parses the numeric part of a discount ID for ordering
+func discountID(id string) int {
+	n, _ := strconv.Atoi(id)
+	return n
+}
+
+// Lists all discount rules
+func listDiscounts(w http.ResponseWriter, r *http.Request) {
+	rules, err := discounts.List(r.Context(), tenantID(r.Context()))
+	if err != nil {
+		writeOperationError(w, r, err, "Error listing discounts")
+		return
+	}
+	writeJSON(w, http.StatusOK, rules)
+}
+
+// Creates a discount rule
+func createDiscount(w http.ResponseWriter, r *http.Request) {
+	var discount pricing.Discount
+	if !decodeJSON(w, r, &discount) {
+		return
+	}
+	if err := discount.Validate(); err != nil {
+		slog.WarnContext(r.Context(), "Invalid discount", "error", err)
+		writeProblem(w, r, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	discount, err := discounts.Create(r.Context(), tenantID(r.Context()), discount)
+	if err != nil {
+		writeOperationError(w, r, err, "Error creating discount")
+		return
+	}
+
+	trace.SpanFromContext(r.Context()).SetAttributes(attribute.String("discount.id", discount.ID))
+	discountChanged(r.Context(), eventDiscountCreated, nil, discount)
+	writeJSON(w, http.StatusCreated, discount)
+	slog.InfoContext(r.Context(), "Discount created", "discount_id", discount.ID)
+}
+
+// Returns a single discount rule
+func getDiscount(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	discount, ok, err := discounts.Get(r.Context(), tenantID(r.Context()), id)
+	if err != nil {
+		writeOperationError(w, r, err, "Error loading discount")
+		return
+	}
+	if !ok {
+		writeProblem(w, r, "Discount not found", http.StatusNotFound)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, discount)
+}
+
+// Replaces a discount rule
+func updateDiscount(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	var discount pricing.Discount
+	if !decodeJSON(w, r, &discount) {
+		return
+	}
+	if err := discount.Validate(); err != nil {
+		slog.WarnContext(r.Context(), "Invalid discount", "error", err)
+		writeProblem(w, r, err.Error(), http.StatusBadRequest)
+		return
+	}
+	discount.ID = id
+
+	previous, _, err := discounts.Get(r.Context(), tenantID(r.Context()), id)
+	if err != nil {
+		writeOperationError(w, r, err, "Error loading discount")
+		return
+	}
+	ok, err := discounts.Update(r.Context(), tenantID(r.Context()), discount)
+	if err != nil {
+		writeOperationError(w, r, err, "Error updating discount")
+		return
+	}
+	if !ok {
+		writeProblem(w, r, "Discount not found", http.StatusNotFound)
+		return
+	}
+
+	trace.SpanFromContext(r.Context()).SetAttributes(attribute.String("discount.id", id))
+	discountChanged(r.Context(), eventDiscountUpdated, &previous, discount)
+	writeJSON(w, http.StatusOK, discount)
+	slog.InfoContext(r.Context(), "Discount updated", "discount_id", id)
+}
+
+// Deletes a discount rule
+func deleteDiscount(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	discount, _, err := discounts.Get(r.Context(), tenantID(r.Context()), id)
+	if err != nil {
+		writeOperationError(w, r, err, "Error loading discount")
+		return
+	}
+	ok, err := discounts.Delete(r.Context(), tenantID(r.Context()), id)
+	if err != nil {
+		writeOperationError(w, r, err, "Error deleting discount")
+		return
+	}
+	if !ok {
+		writeProblem(w, r, "Discount not found", http.StatusNotFound)
+		return
+	}
+
+	trace.SpanFromContext(r.Context()).SetAttributes(attribute.String("discount.id", id))
+	discountChanged(r.Context(), eventDiscountDeleted, &discount, discount)
+	w.WriteHeader(http.StatusNoContent)
+	slog.InfoContext(r.Context(), "Discount deleted", "discount_id", id)
+}
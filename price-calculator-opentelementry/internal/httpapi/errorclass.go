@@ -0,0 +1,94 @@
+package httpapi
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"otpl/pricecalculator/pricing"
+)
+
+// Classes of the errors requests fail with, so alerts can tell spikes of client errors from outages
+const (
+	errorClassValidation  = "validation"   // The client sent an invalid, unauthorized or unknown request
+	errorClassRateLimited = "rate_limited" // The client exceeded its rate limit
+	errorClassDependency  = "dependency"   // A dependency failed or the request timed out
+	errorClassInternal    = "internal"     // The calculator failed
+)
+
+// Returns the class of an error response by its status
+func errorClass(status int) string {
+	switch {
+	case status == http.StatusTooManyRequests:
+		return errorClassRateLimited
+	case status < http.StatusInternalServerError:
+		return errorClassValidation
+	case status == http.StatusBadGateway, status == http.StatusServiceUnavailable, status == http.StatusGatewayTimeout:
+		return errorClassDependency
+	}
+	return errorClassInternal
+}
+
+// Returns the class of an error returned by a shared operation, as writeOperationError answers it
+func operationErrorClass(ctx context.Context, err error) string {
+	var validationErr *pricing.ValidationError
+	var guardrailErr *pricing.GuardrailError
+	switch {
+	case errors.As(err, &validationErr), errors.As(err, &guardrailErr):
+		return errorClassValidation
+	case errors.Is(err, context.DeadlineExceeded), databaseUnreachable(ctx):
+		return errorClassDependency
+	}
+	return errorClassInternal
+}
+
+// Context key of the class of the error a request failed with
+type errorClassKey struct{}
+
+// errorClassHolder holds the class of the error a request failed with, empty while it has not failed
+type errorClassHolder struct {
+	mu    sync.Mutex
+	class string
+}
+
+// Returns the request with room for the class of the error it fails with,
+// and the function returning the class, empty unless the request failed
+func withErrorClass(r *http.Request) (*http.Request, func() string) {
+	holder := &errorClassHolder{}
+	return r.WithContext(context.WithValue(r.Context(), errorClassKey{}, holder)), func() string {
+		holder.mu.Lock()
+		defer holder.mu.Unlock()
+		return holder.class
+	}
+}
+
+// Records the class of the error a request fails with as the error.class span attribute
+// and for the request counter, replacing any class recorded before
+func setErrorClass(ctx context.Context, class string) {
+	trace.SpanFromContext(ctx).SetAttributes(attribute.String("error.class", class))
+	if holder, ok := ctx.Value(errorClassKey{}).(*errorClassHolder); ok {
+		holder.mu.Lock()
+		holder.class = class
+		holder.mu.Unlock()
+	}
+}
+
+// Reports whether the database of the stores is configured and does not answer,
+// which makes an internal error a dependency failure
+func databaseUnreachable(ctx context.Context) bool {
+	db := storeDB
+	if db == nil {
+		return false
+	}
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), dependencyProbeTimeout)
+	defer cancel()
+	if err := db.PingContext(ctx); err != nil {
+		recordDependencyError(dependencyDatabase, err)
+		return true
+	}
+	return false
+}
@@ -0,0 +1,158 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	semconv "go.opentelemetry.io/otel/semconv/v1.18.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"otpl/pricecalculator/internal/telemetry"
+	"otpl/pricecalculator/pricing"
+)
+
+// Type of the events published for completed calculations
+const eventPriceCalculated = "PriceCalculated"
+
+// Topic the events are published to unless KAFKA_TOPIC is set
+const defaultKafkaTopic = "price-calculated"
+
+// PriceCalculatedEvent structure for the event published after each calculation
+type PriceCalculatedEvent struct {
+	Type      string                `json:"type"`
+	Timestamp time.Time             `json:"timestamp"`
+	Tenant    string                `json:"tenant,omitempty"`
+	TraceID   string                `json:"trace_id,omitempty"` // Also propagated in the message headers
+	Request   pricing.PriceRequest  `json:"request"`
+	Response  pricing.PriceResponse `json:"response"`
+}
+
+// Producer of the calculation events, nil when KAFKA_BROKERS is not set
+var eventWriter *kafka.Writer
+
+// Creates the producer for the brokers in the comma separated KAFKA_BROKERS. Messages are
+// sent in batches in the background, so a slow or unavailable Kafka never delays a calculation.
+func openEventWriter() *kafka.Writer {
+	brokers := os.Getenv("KAFKA_BROKERS")
+	if brokers == "" {
+		return nil
+	}
+	topic := os.Getenv("KAFKA_TOPIC")
+	if topic == "" {
+		topic = defaultKafkaTopic
+	}
+	return &kafka.Writer{
+		Addr:                   kafka.TCP(strings.Split(brokers, ",")...),
+		Topic:                  topic,
+		Balancer:               &kafka.Hash{}, // Events of a tenant stay in order on one partition
+		BatchTimeout:           50 * time.Millisecond,
+		Async:                  true,
+		AllowAutoTopicCreation: true,
+		Completion: func(messages []kafka.Message, err error) {
+			ctx := context.Background()
+			if err != nil {
+				eventsPublished.Add(ctx, int64(len(messages)), metric.WithAttributes(attribute.String("result", "failed")))
+				slog.ErrorContext(ctx, "Failed to publish events", "topic", topic, "events", len(messages), "error", err)
+				return
+			}
+			eventsPublished.Add(ctx, int64(len(messages)), metric.WithAttributes(attribute.String("result", "published")))
+		},
+	}
+}
+
+// Publishes a PriceCalculated event for a completed calculation. The publish is traced as a
+// producer span whose context travels in the message headers, so consumers continue the trace.
+func publishCalculation(ctx context.Context, request pricing.PriceRequest, response pricing.PriceResponse) {
+	if eventWriter == nil {
+		return
+	}
+	tenant := tenantID(ctx)
+	ctx, span := tracer.Start(context.WithoutCancel(ctx), eventWriter.Topic+" publish",
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(
+			semconv.MessagingSystem("kafka"),
+			semconv.MessagingOperationPublish,
+			semconv.MessagingDestinationName(eventWriter.Topic),
+		),
+	)
+
+	event := PriceCalculatedEvent{
+		Type:      eventPriceCalculated,
+		Timestamp: time.Now().UTC(),
+		Tenant:    tenant,
+		TraceID:   span.SpanContext().TraceID().String(),
+		Request:   request,
+		Response:  response,
+	}
+	value, err := json.Marshal(event)
+	if err != nil {
+		telemetry.RecordError(span, err)
+		span.End()
+		slog.ErrorContext(ctx, "Error encoding event", "event", eventPriceCalculated, "error", err)
+		return
+	}
+	message := kafka.Message{
+		Value: value,
+		Headers: []kafka.Header{
+			{Key: "content-type", Value: []byte("application/json")},
+			{Key: "event-type", Value: []byte(eventPriceCalculated)},
+		},
+	}
+	if tenant != "" {
+		message.Key = []byte(tenant)
+		span.SetAttributes(semconv.MessagingKafkaMessageKey(tenant))
+	}
+	otel.GetTextMapPropagator().Inject(ctx, kafkaHeaderCarrier{&message.Headers})
+	span.SetAttributes(semconv.MessagingMessagePayloadSizeBytes(len(value)))
+
+	// Queue the message in the background, the writer looks up the topic's partitions on the
+	// first write. Delivery failures are reported by the writer's completion callback.
+	go func() {
+		defer span.End()
+		if err := eventWriter.WriteMessages(ctx, message); err != nil {
+			telemetry.RecordError(span, err)
+			eventsPublished.Add(ctx, 1, metric.WithAttributes(attribute.String("result", "failed")))
+			slog.ErrorContext(ctx, "Failed to queue event", "event", eventPriceCalculated, "error", err)
+		}
+	}()
+}
+
+// kafkaHeaderCarrier adapts the headers of a Kafka message for trace context propagation
+type kafkaHeaderCarrier struct {
+	headers *[]kafka.Header
+}
+
+func (c kafkaHeaderCarrier) Get(key string) string {
+	for _, h := range *c.headers {
+		if h.Key == key {
+			return string(h.Value)
+		}
+	}
+	return ""
+}
+
+func (c kafkaHeaderCarrier) Set(key, value string) {
+	for i, h := range *c.headers {
+		if h.Key == key {
+			(*c.headers)[i].Value = []byte(value)
+			return
+		}
+	}
+	*c.headers = append(*c.headers, kafka.Header{Key: key, Value: []byte(value)})
+}
+
+func (c kafkaHeaderCarrier) Keys() []string {
+	keys := make([]string, len(*c.headers))
+	for i, h := range *c.headers {
+		keys[i] = h.Key
+	}
+	return keys
+}
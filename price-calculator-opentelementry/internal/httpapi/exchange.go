@@ -0,0 +1,201 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel/attribute"
+
+	"otpl/pricecalculator/internal/telemetry"
+	"otpl/pricecalculator/pricing"
+)
+
+// Settings for the exchange-rate provider
+const (
+	exchangeRateTTL     = 10 * time.Minute // How long a fetched rate is reused
+	exchangeRateTimeout = 5 * time.Second  // Timeout of a provider call
+)
+
+// Sources of an exchange rate
+const (
+	rateSourceProvider = "provider"
+	rateSourceCache    = "cache"
+	rateSourceStatic   = "static"
+	rateSourceRefresh  = "refreshed" // Stored by the scheduled refresh
+)
+
+// HTTP client for the exchange-rate provider, instrumented so each call is a client span
+var exchangeClient = &http.Client{
+	Transport: otelhttp.NewTransport(http.DefaultTransport),
+	Timeout:   exchangeRateTimeout,
+}
+
+// Fallback rates in US dollars per unit, used when no provider is configured or it fails
+var staticRates = map[string]float64{
+	"USD": 1,
+	"EUR": 1.08,
+	"GBP": 1.27,
+	"JPY": 0.0067,
+	"CAD": 0.73,
+	"AUD": 0.66,
+	"CHF": 1.13,
+	"CNY": 0.14,
+	"INR": 0.012,
+}
+
+// Cached provider rates by currency pair, guarded by exchangeRatesMu
+var exchangeRates = map[string]cachedRate{}
+var exchangeRatesMu sync.Mutex
+
+// cachedRate holds a provider rate and when it was fetched
+type cachedRate struct {
+	rate    float64
+	fetched time.Time
+}
+
+// ConversionResponse structure for the response of /convert
+type ConversionResponse struct {
+	From      string        `json:"from"`
+	To        string        `json:"to"`
+	Amount    pricing.Money `json:"amount"`
+	Rate      float64       `json:"rate"`
+	Converted pricing.Money `json:"converted"`
+	Source    string        `json:"source"` // refreshed, provider, cache or static
+}
+
+// Converts an amount between currencies
+func convertCurrency(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	from, err := pricing.LookupCurrency(query.Get("from"))
+	if err != nil {
+		writeProblem(w, r, err.Error(), http.StatusBadRequest)
+		return
+	}
+	to, err := pricing.LookupCurrency(query.Get("to"))
+	if err != nil {
+		writeProblem(w, r, err.Error(), http.StatusBadRequest)
+		return
+	}
+	amount, err := pricing.ParseMoney(query.Get("amount"))
+	if err != nil {
+		writeProblem(w, r, "Invalid amount", http.StatusBadRequest)
+		return
+	}
+
+	rate, source, err := exchangeRate(r.Context(), from.Code, to.Code)
+	if err != nil {
+		slog.WarnContext(r.Context(), "No exchange rate", "from", from.Code, "to", to.Code, "error", err)
+		writeProblem(w, r, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, ConversionResponse{
+		From:      from.Code,
+		To:        to.Code,
+		Amount:    amount,
+		Rate:      rate,
+		Converted: to.Round(amount.MulRate(rate), roundingMode(prices.Get(), to.Code)),
+		Source:    source,
+	})
+}
+
+// Returns the rate from one currency to another, from the rates of the scheduled refresh, the cache,
+// the provider named by EXCHANGE_RATE_URL or the static rates, in that order
+func exchangeRate(ctx context.Context, from, to string) (float64, string, error) {
+	ctx, span := tracer.Start(ctx, "GetExchangeRate")
+	defer span.End()
+	span.SetAttributes(attribute.String("exchange.from", from), attribute.String("exchange.to", to))
+
+	rate, source, err := lookupExchangeRate(ctx, from, to)
+	if err != nil {
+		telemetry.RecordError(span, err)
+		return 0, "", err
+	}
+	span.SetAttributes(attribute.String("exchange.source", source), attribute.Float64("exchange.rate", rate))
+	return rate, source, nil
+}
+
+// Looks up a rate without tracing, see exchangeRate
+func lookupExchangeRate(ctx context.Context, from, to string) (float64, string, error) {
+	if from == to {
+		return 1, rateSourceStatic, nil
+	}
+	if rate, ok := refreshedRate(from, to); ok {
+		return rate, rateSourceRefresh, nil
+	}
+
+	pair := from + "/" + to
+	exchangeRatesMu.Lock()
+	cached, ok := exchangeRates[pair]
+	exchangeRatesMu.Unlock()
+	if ok && time.Since(cached.fetched) < exchangeRateTTL {
+		return cached.rate, rateSourceCache, nil
+	}
+
+	if provider := os.Getenv("EXCHANGE_RATE_URL"); provider != "" {
+		rate, err := fetchExchangeRate(ctx, provider, from, to)
+		if err == nil {
+			exchangeRatesMu.Lock()
+			exchangeRates[pair] = cachedRate{rate: rate, fetched: time.Now()}
+			exchangeRatesMu.Unlock()
+			return rate, rateSourceProvider, nil
+		}
+		slog.WarnContext(ctx, "Exchange-rate provider failed, using static rates", "error", err)
+		recordDependencyError(dependencyExchangeRate, err)
+	}
+
+	fromUSD, fromOK := staticRates[from]
+	toUSD, toOK := staticRates[to]
+	if !fromOK || !toOK {
+		return 0, "", fmt.Errorf("no exchange rate from %s to %s", from, to)
+	}
+	return fromUSD / toUSD, rateSourceStatic, nil
+}
+
+// Fetches a rate from a Frankfurter compatible provider,
+// GET {provider}/latest?from=USD&to=EUR answering {"rates": {"EUR": 0.92}}
+func fetchExchangeRate(ctx context.Context, provider, from, to string) (float64, error) {
+	rates, err := fetchExchangeRates(ctx, provider, from, []string{to})
+	if err != nil {
+		return 0, err
+	}
+	rate, ok := rates[to]
+	if !ok || rate <= 0 {
+		return 0, fmt.Errorf("exchange-rate provider has no rate from %s to %s", from, to)
+	}
+	return rate, nil
+}
+
+// Fetches the rates from one currency to several in a single provider call, to=EUR,GBP
+func fetchExchangeRates(ctx context.Context, provider, from string, to []string) (map[string]float64, error) {
+	endpoint := strings.TrimSuffix(provider, "/") + "/latest?" + url.Values{"from": {from}, "to": {strings.Join(to, ",")}}.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := exchangeClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("exchange-rate provider returned %s", resp.Status)
+	}
+
+	var body struct {
+		Rates map[string]float64 `json:"rates"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("invalid exchange-rate response: %v", err)
+	}
+	return body.Rates, nil
+}
@@ -0,0 +1,29 @@
+package httpapi
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"otpl/pricecalculator/pricing"
+)
+
+// Returns the context of a calculation explained step by step when the request asks for it
+// with ?explain=true, and the explanation to return. Writes a problem and returns false when
+// the parameter is invalid.
+func requestExplanation(w http.ResponseWriter, r *http.Request) (context.Context, *pricing.Explanation, bool) {
+	value := r.URL.Query().Get("explain")
+	if value == "" {
+		return r.Context(), nil, true
+	}
+	explain, err := strconv.ParseBool(value)
+	if err != nil {
+		writeProblem(w, r, "Invalid explain, expected true or false", http.StatusBadRequest)
+		return nil, nil, false
+	}
+	if !explain {
+		return r.Context(), nil, true
+	}
+	ctx, explanation := pricing.WithExplanation(r.Context())
+	return ctx, explanation, true
+}
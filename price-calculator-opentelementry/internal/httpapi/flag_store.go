@@ -0,0 +1,107 @@
+package httpapi
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// FlagStore persists the runtime overrides of the feature flags
+type FlagStore interface {
+	// Get returns the override of a feature, false if it is not overridden
+	Get(ctx context.Context, name string) (bool, bool, error)
+	// Set overrides a feature
+	Set(ctx context.Context, name string, enabled bool) error
+	// Delete removes the override of a feature
+	Delete(ctx context.Context, name string) error
+}
+
+// Feature flag override store opened on startup
+var flagOverrides FlagStore
+
+// memoryFlagStore keeps the overrides in memory, saved to a JSON file when it has one
+type memoryFlagStore struct {
+	mu        sync.RWMutex
+	overrides map[string]bool
+	file      *jsonFile
+}
+
+// Creates the in-memory flag store, loading the overrides from file
+func newMemoryFlagStore(file *jsonFile) (*memoryFlagStore, error) {
+	s := &memoryFlagStore{overrides: map[string]bool{}, file: file}
+	if err := file.load(&s.overrides); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *memoryFlagStore) Get(ctx context.Context, name string) (bool, bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	enabled, ok := s.overrides[name]
+	return enabled, ok, nil
+}
+
+func (s *memoryFlagStore) Set(ctx context.Context, name string, enabled bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.overrides[name] = enabled
+	return s.file.persist(s.overrides)
+}
+
+func (s *memoryFlagStore) Delete(ctx context.Context, name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.overrides[name]; !ok {
+		return nil
+	}
+	delete(s.overrides, name)
+	return s.file.persist(s.overrides)
+}
+
+// sqlFlagStore keeps the overrides in a SQLite or PostgreSQL table
+type sqlFlagStore struct {
+	db *sql.DB
+}
+
+// Creates the flag store in db, adding its table if needed
+func newSQLFlagStore(db *sql.DB) (*sqlFlagStore, error) {
+	_, err := db.Exec(`CREATE TABLE IF NOT EXISTS flag_overrides (
+		name    TEXT PRIMARY KEY,
+		enabled BOOLEAN NOT NULL
+	)`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create flag_overrides table: %v", err)
+	}
+	return &sqlFlagStore{db: db}, nil
+}
+
+func (s *sqlFlagStore) Get(ctx context.Context, name string) (bool, bool, error) {
+	var enabled bool
+	err := s.db.QueryRowContext(ctx, `SELECT enabled FROM flag_overrides WHERE name = $1`, name).Scan(&enabled)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, false, nil
+	}
+	if err != nil {
+		return false, false, fmt.Errorf("failed to load flag override: %v", err)
+	}
+	return enabled, true, nil
+}
+
+func (s *sqlFlagStore) Set(ctx context.Context, name string, enabled bool) error {
+	_, err := s.db.ExecContext(ctx, `INSERT INTO flag_overrides (name, enabled) VALUES ($1, $2)
+		ON CONFLICT (name) DO UPDATE SET enabled = excluded.enabled`, name, enabled)
+	if err != nil {
+		return fmt.Errorf("failed to save flag override: %v", err)
+	}
+	return nil
+}
+
+func (s *sqlFlagStore) Delete(ctx context.Context, name string) error {
+	if _, err := s.db.ExecContext(ctx, `DELETE FROM flag_overrides WHERE name = $1`, name); err != nil {
+		return fmt.Errorf("failed to delete flag override: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,184 @@
+package httpapi
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"sort"
+
+	"github.com/gorilla/mux"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Sources of a feature flag's state
+const (
+	flagSourceDefault  = "default"  // Features are enabled unless turned off
+	flagSourceConfig   = "config"   // The features section of the configuration file
+	flagSourceOverride = "override" // Set at runtime through /admin/flags
+)
+
+// Feature flags with what they control
+var featureFlags = map[string]string{
+	"batch":            "POST /calculate/batch",
+	"cart":             "POST /calculate/cart",
+	"chaos":            "Fault injection configured by CHAOS_* or the chaos section",
+	"history":          "Recording calculations and GET /history",
+	"legacy_routes":    "Deprecated /setBasePrice, /setTaxRate and /config",
+	"price_attributes": "Calculation inputs and totals as CalculateTotalPrice span attributes",
+	"subscription":     "POST /calculate/subscription",
+	"ui":               "Demo web UI at /",
+}
+
+// FeatureFlag structure for the state of a feature flag
+type FeatureFlag struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Enabled     bool   `json:"enabled"`
+	Source      string `json:"source"` // default, config or override
+}
+
+// FlagUpdate structure for overriding a feature flag at runtime
+type FlagUpdate struct {
+	Enabled *bool `json:"enabled"`
+}
+
+// Returns the state of a feature flag: its runtime override, else the configuration file, else enabled.
+// When the override cannot be loaded, the error is returned with the state the flag has without one.
+func featureFlag(ctx context.Context, name string) (FeatureFlag, error) {
+	flag := FeatureFlag{Name: name, Description: featureFlags[name], Enabled: true, Source: flagSourceDefault}
+	if enabled, ok := fileConfig.Load().Features[name]; ok {
+		flag.Enabled, flag.Source = enabled, flagSourceConfig
+	}
+	enabled, overridden, err := flagOverrides.Get(ctx, name)
+	if err != nil {
+		return flag, err
+	}
+	if overridden {
+		flag.Enabled, flag.Source = enabled, flagSourceOverride
+	}
+	return flag, nil
+}
+
+// Reports whether a feature is enabled, recording the evaluation as a feature_flag event on the span
+func featureEnabled(ctx context.Context, name string) bool {
+	flag, err := featureFlag(ctx, name)
+	if err != nil {
+		slog.WarnContext(ctx, "Failed to load feature flag override", "flag", name, "error", err)
+	}
+	variant := "off"
+	if flag.Enabled {
+		variant = "on"
+	}
+	trace.SpanFromContext(ctx).AddEvent("feature_flag", trace.WithAttributes(
+		attribute.String("feature_flag.key", name),
+		attribute.String("feature_flag.provider_name", "pricecalculator"),
+		attribute.String("feature_flag.variant", variant),
+		attribute.String("feature_flag.source", flag.Source),
+	))
+	return flag.Enabled
+}
+
+// Responds with 404 while the feature is disabled
+func requireFeature(name string, handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !featureEnabled(r.Context(), name) {
+			writeProblem(w, r, "Feature disabled", http.StatusNotFound)
+			return
+		}
+		handler(w, r)
+	}
+}
+
+// Lists the feature flags in name order
+func listFlags(w http.ResponseWriter, r *http.Request) {
+	flags := make([]FeatureFlag, 0, len(featureFlags))
+	for name := range featureFlags {
+		flag, err := featureFlag(r.Context(), name)
+		if err != nil {
+			writeOperationError(w, r, err, "Error loading feature flags")
+			return
+		}
+		flags = append(flags, flag)
+	}
+	sort.Slice(flags, func(i, j int) bool { return flags[i].Name < flags[j].Name })
+	writeJSON(w, http.StatusOK, flags)
+}
+
+// Returns a single feature flag
+func getFlag(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+	if _, ok := featureFlags[name]; !ok {
+		writeProblem(w, r, "Feature flag not found", http.StatusNotFound)
+		return
+	}
+	flag, err := featureFlag(r.Context(), name)
+	if err != nil {
+		writeOperationError(w, r, err, "Error loading feature flag")
+		return
+	}
+	writeJSON(w, http.StatusOK, flag)
+}
+
+// Turns a feature on or off until the override is removed
+func updateFlag(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+	if _, ok := featureFlags[name]; !ok {
+		writeProblem(w, r, "Feature flag not found", http.StatusNotFound)
+		return
+	}
+	var update FlagUpdate
+	if !decodeJSON(w, r, &update) {
+		return
+	}
+	if update.Enabled == nil {
+		writeProblem(w, r, "enabled is required", http.StatusBadRequest)
+		return
+	}
+
+	previous, err := featureFlag(r.Context(), name)
+	if err != nil {
+		writeOperationError(w, r, err, "Error loading feature flag")
+		return
+	}
+	if err := flagOverrides.Set(r.Context(), name, *update.Enabled); err != nil {
+		writeOperationError(w, r, err, "Error overriding feature flag")
+		return
+	}
+	flag := previous
+	flag.Enabled, flag.Source = *update.Enabled, flagSourceOverride
+
+	trace.SpanFromContext(r.Context()).SetAttributes(attribute.String("feature_flag.key", name))
+	recordAudit(r.Context(), auditFlagUpdated, "flags/"+name, previous, flag)
+	writeJSON(w, http.StatusOK, flag)
+	slog.InfoContext(r.Context(), "Feature flag overridden", "flag", name, "enabled", *update.Enabled)
+}
+
+// Removes the runtime override of a feature, returning it to the configuration file's state
+func resetFlag(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+	if _, ok := featureFlags[name]; !ok {
+		writeProblem(w, r, "Feature flag not found", http.StatusNotFound)
+		return
+	}
+
+	previous, err := featureFlag(r.Context(), name)
+	if err != nil {
+		writeOperationError(w, r, err, "Error loading feature flag")
+		return
+	}
+	if err := flagOverrides.Delete(r.Context(), name); err != nil {
+		writeOperationError(w, r, err, "Error removing feature flag override")
+		return
+	}
+	flag, err := featureFlag(r.Context(), name)
+	if err != nil {
+		writeOperationError(w, r, err, "Error loading feature flag")
+		return
+	}
+
+	trace.SpanFromContext(r.Context()).SetAttributes(attribute.String("feature_flag.key", name))
+	recordAudit(r.Context(), auditFlagReset, "flags/"+name, previous, flag)
+	writeJSON(w, http.StatusOK, flag)
+	slog.InfoContext(r.Context(), "Feature flag override removed", "flag", name)
+}
@@ -0,0 +1,36 @@
+package httpapi
+
+import (
+	"log/slog"
+	"net/http"
+
+	"otpl/pricecalculator/pricing"
+)
+
+// Returns the formatter for the locale query parameter, nil when there is none.
+// An invalid locale is answered with 400 and reported as false.
+func requestFormatter(w http.ResponseWriter, r *http.Request) (*pricing.Formatter, bool) {
+	locale := r.URL.Query().Get("locale")
+	if locale == "" {
+		return nil, true
+	}
+	formatter, err := pricing.NewFormatter(locale)
+	if err != nil {
+		writeProblem(w, r, err.Error(), http.StatusBadRequest)
+		return nil, false
+	}
+	return formatter, true
+}
+
+// Adds the amounts formatted by formatter to the response, unless formatter is nil
+func formatPrices(r *http.Request, formatter *pricing.Formatter, response *pricing.PriceResponse) {
+	if formatter == nil {
+		return
+	}
+	formatted, err := formatter.FormatPrices(*response)
+	if err != nil {
+		slog.WarnContext(r.Context(), "Error formatting prices", "locale", formatter.Locale, "error", err)
+		return
+	}
+	response.Formatted = formatted
+}
@@ -0,0 +1,35 @@
+package httpapi
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+
+	pricecalculatorv1 "otpl/pricecalculator/gen/pricecalculator/v1"
+)
+
+// Creates the REST gateway of the PriceCalculatorService, transcoding the JSON requests of the
+// routes in proto/pricecalculator/v1/price_calculator_http.yaml to calls of the gRPC implementation.
+// Fields keep their proto names, so the JSON shapes match the messages, and errors are problem documents.
+func newGateway(ctx context.Context) (http.Handler, error) {
+	gateway := runtime.NewServeMux(
+		runtime.WithMarshalerOption(runtime.MIMEWildcard, &runtime.JSONPb{
+			MarshalOptions:   protojson.MarshalOptions{UseProtoNames: true, EmitUnpopulated: true},
+			UnmarshalOptions: protojson.UnmarshalOptions{DiscardUnknown: true},
+		}),
+		runtime.WithErrorHandler(writeGatewayError),
+	)
+	if err := pricecalculatorv1.RegisterPriceCalculatorServiceHandlerServer(ctx, gateway, &grpcServer{}); err != nil {
+		return nil, err
+	}
+	return gateway, nil
+}
+
+// Writes the gRPC status of a failed gateway call as a problem document with the matching HTTP status
+func writeGatewayError(ctx context.Context, _ *runtime.ServeMux, _ runtime.Marshaler, w http.ResponseWriter, r *http.Request, err error) {
+	st := status.Convert(err)
+	writeProblem(w, r, st.Message(), runtime.HTTPStatusFromCode(st.Code()))
+}
@@ -0,0 +1,120 @@
+package httpapi
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"log/slog"
+
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/status"
+
+	pricecalculatorv1 "otpl/pricecalculator/gen/pricecalculator/v1"
+	"otpl/pricecalculator/internal/telemetry"
+	"otpl/pricecalculator/pricing"
+)
+
+// Address of the gRPC server unless GRPC_ADDR or the configuration file sets one
+const defaultGRPCAddr = ":9090"
+
+// grpcServer implements the PriceCalculatorService on top of the shared pricing operations
+type grpcServer struct {
+	pricecalculatorv1.UnimplementedPriceCalculatorServiceServer
+}
+
+// Creates a gRPC server with OpenTelemetry tracing and metrics, serving TLS when tlsConfig is set
+func newGRPCServer(tlsConfig *tls.Config) *grpc.Server {
+	options := []grpc.ServerOption{
+		grpc.StatsHandler(otelgrpc.NewServerHandler()),
+		grpc.UnaryInterceptor(apiKeyInterceptor),
+	}
+	if tlsConfig != nil {
+		options = append(options, grpc.Creds(credentials.NewTLS(tlsConfig)))
+	}
+	server := grpc.NewServer(options...)
+	pricecalculatorv1.RegisterPriceCalculatorServiceServer(server, &grpcServer{})
+	return server
+}
+
+// Calculates the total price based on the base price and tax rate
+func (s *grpcServer) Calculate(ctx context.Context, req *pricecalculatorv1.CalculateRequest) (*pricecalculatorv1.CalculateResponse, error) {
+	request := pricing.PriceRequest{
+		TaxRate:  req.TaxRate,
+		Currency: req.Currency,
+		Quantity: int(req.Quantity),
+	}
+	if req.BasePrice != nil {
+		basePrice, err := pricing.ParseMoney(*req.BasePrice)
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, "invalid base price")
+		}
+		request.BasePrice = &basePrice
+	}
+
+	response, err := calculate(ctx, request)
+	if err != nil {
+		return nil, grpcError(ctx, err, "Error calculating price")
+	}
+
+	discounts := make([]*pricecalculatorv1.AppliedDiscount, len(response.Discounts))
+	for i, d := range response.Discounts {
+		discounts[i] = &pricecalculatorv1.AppliedDiscount{Id: d.ID, Name: d.Name, Amount: d.Amount.String()}
+	}
+	return &pricecalculatorv1.CalculateResponse{
+		TotalPrice: response.TotalPrice.String(),
+		Currency:   response.Currency,
+		Discount:   response.Discount.String(),
+		Discounts:  discounts,
+	}, nil
+}
+
+// Sets the base price from the request
+func (s *grpcServer) SetBasePrice(ctx context.Context, req *pricecalculatorv1.SetBasePriceRequest) (*pricecalculatorv1.SetBasePriceResponse, error) {
+	basePrice, err := pricing.ParseMoney(req.BasePrice)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "invalid base price")
+	}
+	if _, err := updateBasePrice(ctx, basePrice); err != nil {
+		return nil, grpcError(ctx, err, "Error saving base price")
+	}
+	return &pricecalculatorv1.SetBasePriceResponse{Message: "Base price set"}, nil
+}
+
+// Sets the tax rate from the request
+func (s *grpcServer) SetTaxRate(ctx context.Context, req *pricecalculatorv1.SetTaxRateRequest) (*pricecalculatorv1.SetTaxRateResponse, error) {
+	if _, err := updateTaxRate(ctx, req.TaxRate); err != nil {
+		return nil, grpcError(ctx, err, "Error saving tax rate")
+	}
+	return &pricecalculatorv1.SetTaxRateResponse{Message: "Tax rate set"}, nil
+}
+
+// Converts an error from a shared operation to a gRPC status.
+// Validation errors are reported to the client, prices outside the guardrails as a failed
+// precondition, anything else is an internal error.
+func grpcError(ctx context.Context, err error, message string) error {
+	telemetry.RecordError(trace.SpanFromContext(ctx), err)
+	switch ctx.Err() {
+	case context.DeadlineExceeded:
+		slog.WarnContext(ctx, message, "error", err)
+		return status.Error(codes.DeadlineExceeded, "deadline exceeded")
+	case context.Canceled:
+		slog.WarnContext(ctx, message, "error", err)
+		return status.Error(codes.Canceled, "request cancelled")
+	}
+	var validationErr *pricing.ValidationError
+	if errors.As(err, &validationErr) {
+		slog.WarnContext(ctx, message, "error", err)
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	var guardrailErr *pricing.GuardrailError
+	if errors.As(err, &guardrailErr) {
+		slog.WarnContext(ctx, message, "error", err)
+		return status.Error(codes.FailedPrecondition, err.Error())
+	}
+	slog.ErrorContext(ctx, message, "error", err)
+	return status.Error(codes.Internal, "internal server error")
+}
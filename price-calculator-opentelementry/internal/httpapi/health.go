@@ -0,0 +1,61 @@
+package httpapi
+
+import (
+	"net"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// Timeout for the collector connectivity check
+const collectorDialTimeout = 2 * time.Second
+
+// Set once the persisted configuration has been loaded on startup
+var configLoaded atomic.Bool
+
+// HealthResponse structure for the health and readiness endpoints
+type HealthResponse struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks,omitempty"`
+}
+
+// Reports that the process is alive
+func healthz(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, HealthResponse{Status: "ok"})
+}
+
+// Reports whether the service is ready to receive traffic.
+// A missing configuration fails readiness, an unreachable collector only degrades it
+// because calculations still work without telemetry.
+func readyz(w http.ResponseWriter, r *http.Request) {
+	response := HealthResponse{Status: "ready", Checks: map[string]string{}}
+	status := http.StatusOK
+
+	if configLoaded.Load() {
+		response.Checks["config"] = "ok"
+	} else {
+		response.Checks["config"] = "not loaded"
+		response.Status = "not ready"
+		status = http.StatusServiceUnavailable
+	}
+
+	if err := checkCollector(otlpSettings.Endpoint); err != nil {
+		response.Checks["collector"] = err.Error()
+		if status == http.StatusOK {
+			response.Status = "degraded"
+		}
+	} else {
+		response.Checks["collector"] = "ok"
+	}
+
+	writeJSON(w, status, response)
+}
+
+// Checks that the collector accepts TCP connections
+func checkCollector(endpoint string) error {
+	conn, err := net.DialTimeout("tcp", endpoint, collectorDialTimeout)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
@@ -0,0 +1,96 @@
+package httpapi
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+
+	"otpl/pricecalculator/pricing"
+)
+
+// Limits for the calculation history
+const (
+	maxHistory          = 1000 // Oldest records are dropped beyond this
+	defaultHistoryLimit = 50   // Page size when no limit is given
+	maxHistoryLimit     = 500  // Largest page size a client may request
+)
+
+// HistoryRecord structure for a recorded calculation
+type HistoryRecord struct {
+	ID        int                   `json:"id"`
+	Timestamp time.Time             `json:"timestamp"`
+	TraceID   string                `json:"trace_id,omitempty"` // Links the record to its trace
+	Tenant    string                `json:"tenant,omitempty"`   // Tenant the calculation was made for
+	Request   pricing.PriceRequest  `json:"request"`
+	Response  pricing.PriceResponse `json:"response"`
+	Refunded  pricing.Refunded      `json:"refunded"`
+}
+
+// HistoryResponse structure for a page of the calculation history
+type HistoryResponse struct {
+	Records []HistoryRecord `json:"records"`
+	Total   int             `json:"total"` // Number of records matching the filters
+	Limit   int             `json:"limit"`
+	Offset  int             `json:"offset"`
+}
+
+// Records a calculation together with the trace it belongs to
+func recordHistory(ctx context.Context, request pricing.PriceRequest, response pricing.PriceResponse) {
+	if !featureEnabled(ctx, "history") {
+		return
+	}
+	record := HistoryRecord{Timestamp: time.Now().UTC(), Tenant: tenantID(ctx), Request: request, Response: response}
+	if sc := trace.SpanContextFromContext(ctx); sc.HasTraceID() {
+		record.TraceID = sc.TraceID().String()
+	}
+	if err := history.Add(ctx, record); err != nil {
+		slog.WarnContext(ctx, "Failed to record calculation history", "error", err)
+	}
+}
+
+// Lists recorded calculations, newest first.
+// Supports the from and to (RFC 3339) time filters and limit/offset pagination.
+func listHistory(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	var from, to time.Time
+	var err error
+	if value := query.Get("from"); value != "" {
+		if from, err = time.Parse(time.RFC3339, value); err != nil {
+			writeProblem(w, r, "Invalid from time, expected RFC 3339", http.StatusBadRequest)
+			return
+		}
+	}
+	if value := query.Get("to"); value != "" {
+		if to, err = time.Parse(time.RFC3339, value); err != nil {
+			writeProblem(w, r, "Invalid to time, expected RFC 3339", http.StatusBadRequest)
+			return
+		}
+	}
+
+	limit := defaultHistoryLimit
+	if value := query.Get("limit"); value != "" {
+		if limit, err = strconv.Atoi(value); err != nil || limit < 1 || limit > maxHistoryLimit {
+			writeProblem(w, r, "Invalid limit, expected 1 to 500", http.StatusBadRequest)
+			return
+		}
+	}
+	offset := 0
+	if value := query.Get("offset"); value != "" {
+		if offset, err = strconv.Atoi(value); err != nil || offset < 0 {
+			writeProblem(w, r, "Invalid offset", http.StatusBadRequest)
+			return
+		}
+	}
+
+	records, total, err := history.List(r.Context(), HistoryFilter{From: from, To: to, Limit: limit, Offset: offset})
+	if err != nil {
+		writeOperationError(w, r, err, "Error listing history")
+		return
+	}
+	writeJSON(w, http.StatusOK, HistoryResponse{Records: records, Total: total, Limit: limit, Offset: offset})
+}
@@ -0,0 +1,294 @@
+package httpapi
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"otpl/pricecalculator/pricing"
+)
+
+// HistoryFilter structure for selecting a page of the calculation history
+type HistoryFilter struct {
+	From   time.Time // Zero for no lower bound
+	To     time.Time // Zero for no upper bound
+	Limit  int
+	Offset int
+}
+
+// Reports whether a record was made within the filter's time range
+func (f HistoryFilter) matches(record HistoryRecord) bool {
+	return (f.From.IsZero() || !record.Timestamp.Before(f.From)) && (f.To.IsZero() || !record.Timestamp.After(f.To))
+}
+
+// HistoryStore persists the most recent calculations
+type HistoryStore interface {
+	// Add records a calculation under a new ID, dropping the oldest records beyond maxHistory
+	Add(ctx context.Context, record HistoryRecord) error
+	// List returns a page of the matching records, newest first, and the number of matching records
+	List(ctx context.Context, filter HistoryFilter) ([]HistoryRecord, int, error)
+	// Find returns the record of a calculation made for the tenant, empty without one, by its
+	// calculation ID, false if it is not recorded
+	Find(ctx context.Context, tenant, calculationID string) (HistoryRecord, bool, error)
+	// Refund stores what was refunded of the record, unless another refund of it was stored since
+	// it was read, telling by its Refunded.Count, or the record was dropped. False then.
+	Refund(ctx context.Context, record HistoryRecord, refunded pricing.Refunded) (bool, error)
+}
+
+// History store opened on startup
+var history HistoryStore
+
+// memoryHistoryStore keeps the history in memory, oldest first, appended to a JSON lines file when it
+// has one. A refunded record is appended again, replacing the earlier line of its ID on load. The file
+// is rewritten with the records kept once it holds twice as many lines.
+type memoryHistoryStore struct {
+	mu      sync.RWMutex
+	records []HistoryRecord
+	file    *jsonLines
+}
+
+// Creates the in-memory history store, loading the records from file
+func newMemoryHistoryStore(file *jsonLines) (*memoryHistoryStore, error) {
+	s := &memoryHistoryStore{file: file}
+	var lines []HistoryRecord
+	if err := loadJSONLines(file, &lines); err != nil {
+		return nil, err
+	}
+	index := map[int]int{}
+	for _, record := range lines {
+		if i, ok := index[record.ID]; ok {
+			s.records[i] = record
+			continue
+		}
+		index[record.ID] = len(s.records)
+		s.records = append(s.records, record)
+	}
+	if len(s.records) > maxHistory {
+		s.records = s.records[len(s.records)-maxHistory:]
+	}
+	return s, nil
+}
+
+func (s *memoryHistoryStore) Add(ctx context.Context, record HistoryRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	record.ID = 1
+	if len(s.records) > 0 {
+		record.ID = s.records[len(s.records)-1].ID + 1
+	}
+	s.records = append(s.records, record)
+	if len(s.records) > maxHistory {
+		s.records = append([]HistoryRecord(nil), s.records[len(s.records)-maxHistory:]...)
+	}
+	if s.file != nil && s.file.lines >= 2*maxHistory {
+		return s.file.rewrite(&s.records)
+	}
+	return s.file.append(record)
+}
+
+func (s *memoryHistoryStore) List(ctx context.Context, filter HistoryFilter) ([]HistoryRecord, int, error) {
+	s.mu.RLock()
+	matches := make([]HistoryRecord, 0)
+	for i := len(s.records) - 1; i >= 0; i-- {
+		if filter.matches(s.records[i]) {
+			matches = append(matches, s.records[i])
+		}
+	}
+	s.mu.RUnlock()
+
+	if filter.Offset >= len(matches) {
+		return []HistoryRecord{}, len(matches), nil
+	}
+	return matches[filter.Offset:min(filter.Offset+filter.Limit, len(matches))], len(matches), nil
+}
+
+func (s *memoryHistoryStore) Find(ctx context.Context, tenant, calculationID string) (HistoryRecord, bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for i := len(s.records) - 1; i >= 0; i-- {
+		if s.records[i].Response.CalculationID == calculationID && s.records[i].Tenant == tenant {
+			return s.records[i], true, nil
+		}
+	}
+	return HistoryRecord{}, false, nil
+}
+
+func (s *memoryHistoryStore) Refund(ctx context.Context, record HistoryRecord, refunded pricing.Refunded) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := len(s.records) - 1; i >= 0; i-- {
+		if s.records[i].ID != record.ID {
+			continue
+		}
+		if s.records[i].Refunded.Count != record.Refunded.Count {
+			return false, nil
+		}
+		updated := s.records[i]
+		updated.Refunded = refunded
+		if err := s.file.append(updated); err != nil {
+			return false, err
+		}
+		s.records[i] = updated
+		return true, nil
+	}
+	return false, nil
+}
+
+// sqlHistoryStore keeps the history in a SQLite or PostgreSQL table, with the request and
+// result encoded as JSON, the time in Unix nanoseconds and the calculation ID, tenant and
+// refunded totals in columns of their own
+type sqlHistoryStore struct {
+	db *sql.DB
+}
+
+// Creates the history store in db, adding its table if needed
+func newSQLHistoryStore(db *sql.DB) (*sqlHistoryStore, error) {
+	err := createWithGeneratedID(db, "history", `CREATE TABLE IF NOT EXISTS history (
+		id             %s,
+		recorded_at    BIGINT NOT NULL,
+		trace_id       TEXT NOT NULL DEFAULT '',
+		request        TEXT NOT NULL,
+		response       TEXT NOT NULL,
+		calculation_id    TEXT NOT NULL DEFAULT '',
+		tenant            TEXT NOT NULL DEFAULT '',
+		refunded_count    BIGINT NOT NULL DEFAULT 0,
+		refunded_quantity BIGINT NOT NULL DEFAULT 0,
+		refunded_amount   TEXT NOT NULL DEFAULT '0'
+	)`)
+	if err != nil {
+		return nil, err
+	}
+	columns := []struct{ name, definition string }{
+		{"calculation_id", "TEXT NOT NULL DEFAULT ''"},
+		{"tenant", "TEXT NOT NULL DEFAULT ''"},
+		{"refunded_count", "BIGINT NOT NULL DEFAULT 0"},
+		{"refunded_quantity", "BIGINT NOT NULL DEFAULT 0"},
+		{"refunded_amount", "TEXT NOT NULL DEFAULT '0'"},
+	}
+	for _, column := range columns {
+		if err := addColumn(db, "history", column.name, column.definition); err != nil {
+			return nil, err
+		}
+	}
+	return &sqlHistoryStore{db: db}, nil
+}
+
+// Columns selected to read a record with scanHistoryRecord
+const historyColumns = `id, recorded_at, trace_id, tenant, request, response, refunded_count, refunded_quantity, refunded_amount`
+
+// Reads a history record selected with historyColumns
+func scanHistoryRecord(row interface{ Scan(...any) error }) (HistoryRecord, error) {
+	var record HistoryRecord
+	var recordedAt int64
+	var request, response string
+	err := row.Scan(&record.ID, &recordedAt, &record.TraceID, &record.Tenant, &request, &response,
+		&record.Refunded.Count, &record.Refunded.Quantity, &record.Refunded.Amount)
+	if err != nil {
+		return record, err
+	}
+	record.Timestamp = time.Unix(0, recordedAt).UTC()
+	if err := json.Unmarshal([]byte(request), &record.Request); err != nil {
+		return record, fmt.Errorf("invalid stored history request: %v", err)
+	}
+	if err := json.Unmarshal([]byte(response), &record.Response); err != nil {
+		return record, fmt.Errorf("invalid stored history response: %v", err)
+	}
+	return record, nil
+}
+
+func (s *sqlHistoryStore) Add(ctx context.Context, record HistoryRecord) error {
+	request, err := json.Marshal(record.Request)
+	if err != nil {
+		return fmt.Errorf("failed to encode history request: %v", err)
+	}
+	response, err := json.Marshal(record.Response)
+	if err != nil {
+		return fmt.Errorf("failed to encode history response: %v", err)
+	}
+
+	var id int64
+	err = s.db.QueryRowContext(ctx, `INSERT INTO history (recorded_at, trace_id, request, response, calculation_id, tenant)
+		VALUES ($1, $2, $3, $4, $5, $6) RETURNING id`,
+		record.Timestamp.UnixNano(), record.TraceID, string(request), string(response), record.Response.CalculationID, record.Tenant).Scan(&id)
+	if err != nil {
+		return fmt.Errorf("failed to record history: %v", err)
+	}
+	if _, err := s.db.ExecContext(ctx, `DELETE FROM history WHERE id <= $1`, id-maxHistory); err != nil {
+		return fmt.Errorf("failed to trim history: %v", err)
+	}
+	return nil
+}
+
+func (s *sqlHistoryStore) List(ctx context.Context, filter HistoryFilter) ([]HistoryRecord, int, error) {
+	var conditions []string
+	var args []any
+	if !filter.From.IsZero() {
+		args = append(args, filter.From.UnixNano())
+		conditions = append(conditions, fmt.Sprintf("recorded_at >= $%d", len(args)))
+	}
+	if !filter.To.IsZero() {
+		args = append(args, filter.To.UnixNano())
+		conditions = append(conditions, fmt.Sprintf("recorded_at <= $%d", len(args)))
+	}
+	where := ""
+	if len(conditions) > 0 {
+		where = " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	var total int
+	if err := s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM history`+where, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count history: %v", err)
+	}
+	args = append(args, filter.Limit, filter.Offset)
+	rows, err := s.db.QueryContext(ctx, fmt.Sprintf(`SELECT `+historyColumns+` FROM history%s
+		ORDER BY id DESC LIMIT $%d OFFSET $%d`, where, len(args)-1, len(args)), args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list history: %v", err)
+	}
+	defer rows.Close()
+
+	records := []HistoryRecord{}
+	for rows.Next() {
+		record, err := scanHistoryRecord(rows)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to read history record: %v", err)
+		}
+		records = append(records, record)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("failed to list history: %v", err)
+	}
+	return records, total, nil
+}
+
+func (s *sqlHistoryStore) Find(ctx context.Context, tenant, calculationID string) (HistoryRecord, bool, error) {
+	record, err := scanHistoryRecord(s.db.QueryRowContext(ctx, `SELECT `+historyColumns+` FROM history
+		WHERE calculation_id = $1 AND tenant = $2 ORDER BY id DESC LIMIT 1`, calculationID, tenant))
+	if errors.Is(err, sql.ErrNoRows) {
+		return record, false, nil
+	}
+	if err != nil {
+		return record, false, fmt.Errorf("failed to find history record: %v", err)
+	}
+	return record, true, nil
+}
+
+func (s *sqlHistoryStore) Refund(ctx context.Context, record HistoryRecord, refunded pricing.Refunded) (bool, error) {
+	// Store the totals only while no other refund was stored, in a single statement
+	result, err := s.db.ExecContext(ctx, `UPDATE history SET refunded_count = $1, refunded_quantity = $2, refunded_amount = $3
+		WHERE id = $4 AND refunded_count = $5`,
+		refunded.Count, refunded.Quantity, refunded.Amount, record.ID, record.Refunded.Count)
+	if err != nil {
+		return false, fmt.Errorf("failed to record refund: %v", err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to record refund: %v", err)
+	}
+	return n == 1, nil
+}
@@ -0,0 +1,245 @@
+package httpapi
+
+import (
+	"bytes"
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Header carrying the client chosen idempotency key
+const idempotencyHeader = "Idempotency-Key"
+
+// Limits of the stored responses
+const (
+	idempotencyTTL        = 24 * time.Hour   // How long a stored response is replayed for a repeated key
+	idempotencyMaxKeys    = 10000            // Keys kept at most, the oldest are evicted first
+	idempotencyExpiryTick = 10 * time.Minute // How often expired keys are removed
+)
+
+// Stored responses by idempotency key
+var idempotencyKeys = newIdempotencyCache(idempotencyMaxKeys)
+
+// idempotentResponse holds the response to a request made with an idempotency key
+type idempotentResponse struct {
+	scope       string // Key scoped to the API key and tenant
+	fingerprint string // Method, path and body of the original request
+	done        bool   // False while the original request is in flight
+	expires     time.Time
+	status      int
+	header      http.Header // Headers set by the handler
+	body        []byte
+}
+
+// idempotencyCache keeps the responses by scoped key in the order they were first
+// requested, which is also the order they expire in, up to a maximum number of keys
+type idempotencyCache struct {
+	mu      sync.Mutex
+	max     int
+	entries map[string]*list.Element // Elements of order by scoped key
+	order   *list.List               // *idempotentResponse, oldest first
+}
+
+// Creates an empty cache of at most max keys
+func newIdempotencyCache(max int) *idempotencyCache {
+	return &idempotencyCache{max: max, entries: map[string]*list.Element{}, order: list.New()}
+}
+
+// Returns the response stored for a key, or reserves the key for a request
+// in flight and returns false when there is none
+func (c *idempotencyCache) reserve(scope, fingerprint string) (idempotentResponse, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.entries[scope]; ok {
+		stored := e.Value.(*idempotentResponse)
+		if !stored.done || time.Now().Before(stored.expires) {
+			return *stored, true
+		}
+		c.remove(e)
+	}
+	for c.order.Len() >= c.max {
+		c.remove(c.order.Front())
+	}
+	c.entries[scope] = c.order.PushBack(&idempotentResponse{scope: scope, fingerprint: fingerprint})
+	return idempotentResponse{}, false
+}
+
+// Stores the response to the request that reserved its key, if the key is still reserved
+func (c *idempotencyCache) store(response idempotentResponse) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.entries[response.scope]; ok {
+		e.Value = &response
+	}
+}
+
+// Releases a reserved key, so the request can be retried with it
+func (c *idempotencyCache) release(scope string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.entries[scope]; ok {
+		c.remove(e)
+	}
+}
+
+// Removes the stored responses that expired, stopping at the first one still
+// replayed since they expire in order. Requests in flight are skipped.
+func (c *idempotencyCache) expire(now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for e := c.order.Front(); e != nil; {
+		next := e.Next()
+		stored := e.Value.(*idempotentResponse)
+		if stored.done && now.Before(stored.expires) {
+			return
+		}
+		if stored.done {
+			c.remove(e)
+		}
+		e = next
+	}
+}
+
+// Removes an element, with c.mu held
+func (c *idempotencyCache) remove(e *list.Element) {
+	delete(c.entries, e.Value.(*idempotentResponse).scope)
+	c.order.Remove(e)
+}
+
+// Removes the expired idempotency keys periodically until ctx is done
+func runIdempotencyExpiry(ctx context.Context) {
+	ticker := time.NewTicker(idempotencyExpiryTick)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			idempotencyKeys.expire(now)
+		}
+	}
+}
+
+// responseRecorder captures a response while writing it to the client
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	header http.Header // Headers as they were sent
+	body   bytes.Buffer
+}
+
+func (rec *responseRecorder) WriteHeader(status int) {
+	if rec.status == 0 {
+		rec.header = rec.Header().Clone()
+	}
+	rec.status = status
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+func (rec *responseRecorder) Write(b []byte) (int, error) {
+	if rec.status == 0 {
+		rec.status = http.StatusOK
+		rec.header = rec.Header().Clone()
+	}
+	rec.body.Write(b)
+	return rec.ResponseWriter.Write(b)
+}
+
+// Makes POST, PUT and PATCH requests with an Idempotency-Key header safe to retry.
+// The first response for a key is stored and replayed for repeated requests,
+// reusing a key for a different request is rejected with 422 and a request
+// still in flight with 409. Server errors are not stored so they can be retried.
+func idempotent(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(idempotencyHeader)
+		if key == "" || (r.Method != http.MethodPost && r.Method != http.MethodPut && r.Method != http.MethodPatch) {
+			handler(w, r)
+			return
+		}
+		span := trace.SpanFromContext(r.Context())
+
+		// Fingerprint the request so a key cannot be replayed for a different one
+		body, err := io.ReadAll(r.Body)
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			writeProblem(w, r, "Request body too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		if err != nil {
+			writeProblem(w, r, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		sum := sha256.Sum256(append([]byte(r.Method+" "+r.URL.Path+"\n"), body...))
+		fingerprint := hex.EncodeToString(sum[:])
+
+		// Keys are scoped to the client's API key and tenant
+		scope := apiKeyID(r.Header.Get(APIKeyHeader)) + "\n" + tenantID(r.Context()) + "\n" + key
+
+		stored, ok := idempotencyKeys.reserve(scope, fingerprint)
+		switch {
+		case !ok:
+		case stored.fingerprint != fingerprint:
+			writeProblem(w, r, "Idempotency-Key was used for a different request", http.StatusUnprocessableEntity)
+			return
+		case !stored.done:
+			writeProblem(w, r, "A request with this Idempotency-Key is in progress", http.StatusConflict)
+			return
+		default:
+			span.SetAttributes(attribute.Bool("idempotency.replayed", true))
+			slog.InfoContext(r.Context(), "Replaying idempotent response", "status", stored.status)
+			for name, values := range stored.header {
+				w.Header()[name] = values
+			}
+			w.Header().Set("Idempotent-Replayed", "true")
+			w.WriteHeader(stored.status)
+			w.Write(stored.body)
+			return
+		}
+
+		// First request with this key, store its response
+		span.SetAttributes(attribute.Bool("idempotency.replayed", false))
+		// Headers set before the handler ran belong to this request only, such as its request ID
+		before := w.Header().Clone()
+		rec := &responseRecorder{ResponseWriter: w}
+		defer func() {
+			// Release the key of a panicking handler, the panic is answered with 500 further up
+			if p := recover(); p != nil {
+				idempotencyKeys.release(scope)
+				panic(p)
+			}
+		}()
+		handler(rec, r)
+
+		if rec.status >= http.StatusInternalServerError || rec.status == 0 {
+			idempotencyKeys.release(scope)
+			return
+		}
+		header := http.Header{}
+		for name, values := range rec.header {
+			if !slices.Equal(before[name], values) {
+				header[name] = values
+			}
+		}
+		idempotencyKeys.store(idempotentResponse{
+			scope:       scope,
+			fingerprint: fingerprint,
+			done:        true,
+			expires:     time.Now().Add(idempotencyTTL),
+			status:      rec.status,
+			header:      header,
+			body:        rec.body.Bytes(),
+		})
+	}
+}
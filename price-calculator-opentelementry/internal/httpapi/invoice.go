@@ -0,0 +1,231 @@
+package httpapi
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	htmltemplate "html/template"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/gorilla/mux"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"otpl/pricecalculator/internal/config"
+	"otpl/pricecalculator/internal/telemetry"
+	"otpl/pricecalculator/pricing"
+)
+
+// Default invoice templates, overridden by files of the same name in the template directory
+//
+//go:embed templates
+var invoiceTemplates embed.FS
+
+// Supported invoice formats and the templates they are rendered from
+const (
+	invoiceHTML = "html" // invoice.html, an html/template
+	invoicePDF  = "pdf"  // invoice.txt, a text/template laid out as monospaced PDF lines
+)
+
+// Locale of the invoice amounts unless the request names one
+const defaultInvoiceLocale = "en-US"
+
+// InvoiceData structure for the values available to the invoice templates
+type InvoiceData struct {
+	Quote       Quote         // The finalized quote, with its request and response
+	Number      string        // Invoice number, the quote ID
+	IssuedAt    time.Time     // When the quote was finalized
+	Tenant      string        // Tenant of the quote, empty for the default configuration
+	CustomerID  string        // Customer of the request, if any
+	Locale      string        // Locale the amounts are formatted in
+	Currency    string        // Currency code of the amounts
+	Description string        // Line of the base amount, such as "3 x 19.99"
+	BaseAmount  string        // Formatted amounts
+	Lines       []InvoiceLine // Adjustments between the base amount and the total
+	Total       string
+	TraceID     string // Trace of the calculation
+}
+
+// InvoiceLine structure for a formatted line of an invoice
+type InvoiceLine struct {
+	Kind        string // tier, discount, surcharge, tax, guardrail or rounding
+	Description string
+	Amount      string
+}
+
+// Returns the directory whose templates override the embedded ones, from INVOICE_TEMPLATE_DIR or invoice.template_dir
+func invoiceTemplateDir() string {
+	return config.Setting("INVOICE_TEMPLATE_DIR", fileConfig.Load().Invoice.TemplateDir)
+}
+
+// Reads the named template from the template directory, or the embedded one when the
+// directory has none. Also returns where it was read from.
+func readInvoiceTemplate(name string) (string, string, error) {
+	if dir := invoiceTemplateDir(); dir != "" {
+		path := filepath.Join(dir, name)
+		data, err := os.ReadFile(path)
+		if err == nil {
+			return string(data), path, nil
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			return "", path, err
+		}
+	}
+	data, err := invoiceTemplates.ReadFile("templates/" + name)
+	return string(data), "embedded", err
+}
+
+// Builds the template values of a quote with its amounts formatted by formatter
+func newInvoiceData(quote Quote, formatter *pricing.Formatter) (InvoiceData, error) {
+	response := quote.Response
+	currency, err := pricing.LookupCurrency(response.Currency)
+	if err != nil {
+		return InvoiceData{}, err
+	}
+	quantity := max(quote.Request.Quantity, 1)
+	data := InvoiceData{
+		Quote:       quote,
+		Number:      quote.ID,
+		Tenant:      quote.Tenant,
+		CustomerID:  quote.Request.CustomerID,
+		Locale:      formatter.Locale,
+		Currency:    currency.Code,
+		Description: strconv.Itoa(quantity) + " x " + formatter.Format(response.Breakdown.BaseAmount.DivInt(quantity), currency),
+		BaseAmount:  formatter.Format(response.Breakdown.BaseAmount, currency),
+		Lines:       make([]InvoiceLine, 0, len(response.Breakdown.Lines)+1),
+		Total:       formatter.Format(response.TotalPrice, currency),
+		TraceID:     quote.TraceID,
+	}
+	if quote.Request.SKU != "" {
+		data.Description = quote.Request.SKU + ", " + data.Description
+	}
+	if quote.FinalizedAt != nil {
+		data.IssuedAt = *quote.FinalizedAt
+	}
+	for _, line := range response.Breakdown.Lines {
+		description := line.Name
+		if description == "" || description == line.Kind {
+			description = line.Kind
+		} else {
+			description = line.Kind + ": " + line.Name
+		}
+		data.Lines = append(data.Lines, InvoiceLine{Kind: line.Kind, Description: strings.ToUpper(description[:1]) + description[1:],
+			Amount: formatter.Format(line.Amount, currency)})
+	}
+	if !response.Breakdown.RoundingAdjustment.IsZero() {
+		data.Lines = append(data.Lines, InvoiceLine{Kind: "rounding", Description: "Rounding",
+			Amount: formatter.Format(response.Breakdown.RoundingAdjustment, currency)})
+	}
+	return data, nil
+}
+
+// Renders a finalized quote as an HTML invoice, or a PDF one with format=pdf or an
+// Accept header preferring application/pdf. Open quotes are answered with 409.
+func renderQuote(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	id := mux.Vars(r)["id"]
+	span := trace.SpanFromContext(ctx)
+	span.SetAttributes(attribute.String("quote.id", id))
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = invoiceHTML
+		if strings.HasPrefix(r.Header.Get("Accept"), "application/pdf") {
+			format = invoicePDF
+		}
+	}
+	if format != invoiceHTML && format != invoicePDF {
+		writeProblem(w, r, "format must be html or pdf", http.StatusBadRequest)
+		return
+	}
+	formatter, ok := requestFormatter(w, r)
+	if !ok {
+		return
+	}
+	if formatter == nil {
+		formatter, _ = pricing.NewFormatter(defaultInvoiceLocale)
+	}
+
+	quote, ok, err := quotes.Get(ctx, tenantID(ctx), id)
+	if err != nil {
+		writeOperationError(w, r, err, "Error loading quote")
+		return
+	}
+	if !ok {
+		writeProblem(w, r, "Quote not found", http.StatusNotFound)
+		return
+	}
+	if quote.Status != QuoteFinalized {
+		writeProblem(w, r, "Quote must be finalized before it is rendered", http.StatusConflict)
+		return
+	}
+
+	// Render in a child span, so slow or failing templates stand out in the trace
+	_, renderSpan := tracer.Start(ctx, "RenderInvoice", trace.WithAttributes(
+		attribute.String("invoice.format", format),
+		attribute.String("invoice.locale", formatter.Locale),
+	))
+	body, source, err := renderInvoice(quote, formatter, format)
+	renderSpan.SetAttributes(attribute.String("invoice.template", source), attribute.Int("invoice.size", len(body)))
+	if err != nil {
+		telemetry.RecordError(renderSpan, err)
+	}
+	renderSpan.End()
+	if err != nil {
+		slog.ErrorContext(ctx, "Error rendering invoice", "quote_id", id, "template", source, "error", err)
+		writeProblem(w, r, "Error rendering invoice", http.StatusInternalServerError)
+		return
+	}
+
+	if format == invoicePDF {
+		w.Header().Set("Content-Type", "application/pdf")
+		w.Header().Set("Content-Disposition", `inline; filename="`+quote.ID+`.pdf"`)
+	} else {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	}
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(body)
+	slog.InfoContext(ctx, "Invoice rendered", "quote_id", id, "format", format)
+}
+
+// Renders the invoice of a quote in the format, returning where its template was read from
+func renderInvoice(quote Quote, formatter *pricing.Formatter, format string) ([]byte, string, error) {
+	data, err := newInvoiceData(quote, formatter)
+	if err != nil {
+		return nil, "", err
+	}
+	name := "invoice.html"
+	if format == invoicePDF {
+		name = "invoice.txt"
+	}
+	text, source, err := readInvoiceTemplate(name)
+	if err != nil {
+		return nil, source, err
+	}
+
+	var out bytes.Buffer
+	if format == invoiceHTML {
+		tmpl, err := htmltemplate.New(name).Parse(text)
+		if err != nil {
+			return nil, source, err
+		}
+		err = tmpl.Execute(&out, data)
+		return out.Bytes(), source, err
+	}
+	tmpl, err := template.New(name).Parse(text)
+	if err != nil {
+		return nil, source, err
+	}
+	if err := tmpl.Execute(&out, data); err != nil {
+		return nil, source, err
+	}
+	return writePDF(out.String()), source, nil
+}
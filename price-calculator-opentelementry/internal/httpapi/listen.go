@@ -0,0 +1,67 @@
+package httpapi
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"os"
+	"strings"
+
+	"otpl/pricecalculator/internal/config"
+)
+
+// Prefix of a listen address naming a Unix domain socket, such as unix:/run/pricecalc.sock
+const unixAddrPrefix = "unix:"
+
+// ListenOptions structure for overriding the HTTP listen address, such as with the --bind and --port flags
+type ListenOptions struct {
+	Bind string // Host or IP to bind, or unix:<path> for a Unix domain socket
+	Port string
+}
+
+// Returns the HTTP listen address: HTTP_ADDR, the configuration file or :8080, with its host
+// replaced by the bind address and its port by the port when they are set
+func (o ListenOptions) httpAddr() (string, error) {
+	addr := config.Setting("HTTP_ADDR", fileConfig.Load().Server.HTTPAddr)
+	if addr == "" {
+		addr = defaultHTTPAddr
+	}
+	if strings.HasPrefix(o.Bind, unixAddrPrefix) {
+		return o.Bind, nil
+	}
+	if o.Bind == "" && o.Port == "" {
+		return addr, nil
+	}
+	if strings.HasPrefix(addr, unixAddrPrefix) {
+		addr = defaultHTTPAddr
+	}
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return "", fmt.Errorf("invalid HTTP address %q: %v", addr, err)
+	}
+	if o.Bind != "" {
+		host = o.Bind
+	}
+	if o.Port != "" {
+		port = o.Port
+	}
+	return net.JoinHostPort(host, port), nil
+}
+
+// Listens on a TCP address or, for unix:<path>, a Unix domain socket. A socket file left behind
+// by a previous run is removed first; the listener removes its socket file when closed.
+func listen(addr string) (net.Listener, error) {
+	path, ok := strings.CutPrefix(addr, unixAddrPrefix)
+	if !ok {
+		return net.Listen("tcp", addr)
+	}
+	if info, err := os.Stat(path); err == nil && info.Mode().Type() == fs.ModeSocket {
+		if err := os.Remove(path); err != nil {
+			return nil, fmt.Errorf("failed to remove stale socket: %v", err)
+		}
+	} else if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	return net.Listen("unix", path)
+}
@@ -0,0 +1,56 @@
+package httpapi
+
+import (
+	"log/slog"
+	"net/http"
+
+	"otpl/pricecalculator/internal/telemetry"
+	"otpl/pricecalculator/pricing"
+)
+
+// Calculates the selling price of a cost at a target margin or markup
+func calculateSellingPrice(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracer.Start(r.Context(), "CalculateSellingPrice")
+	defer span.End()
+
+	var request pricing.SellingPriceRequest
+	if !decodeJSON(w, r, &request) {
+		return
+	}
+	defaults, currency := tenantDefaults(ctx)
+	if request.Currency == "" {
+		request.Currency = currency
+	}
+	response, err := pricing.SellingPrice(ctx, request, roundingMode(defaults, request.Currency))
+	if err != nil {
+		telemetry.RecordError(span, err)
+		writeOperationError(w, r, err, "Error calculating selling price")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, response)
+	slog.InfoContext(ctx, "Calculated selling price", "price", response.Price.String(), "margin", response.Margin, "currency", response.Currency)
+}
+
+// Derives the margin and markup of a selling price from its cost
+func calculateMargin(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracer.Start(r.Context(), "CalculateMargin")
+	defer span.End()
+
+	var request pricing.MarginRequest
+	if !decodeJSON(w, r, &request) {
+		return
+	}
+	if request.Currency == "" {
+		_, request.Currency = tenantDefaults(ctx)
+	}
+	response, err := pricing.Margin(ctx, request)
+	if err != nil {
+		telemetry.RecordError(span, err)
+		writeOperationError(w, r, err, "Error calculating margin")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, response)
+	slog.InfoContext(ctx, "Calculated margin", "margin", response.Margin, "currency", response.Currency)
+}
@@ -0,0 +1,257 @@
+package httpapi
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel/attribute"
+	otelprometheus "go.opentelemetry.io/otel/exporters/prometheus"
+	"go.opentelemetry.io/otel/metric"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+
+	"otpl/pricecalculator/internal/telemetry"
+)
+
+// Supported metric exporters, selected with METRICS_EXPORTER
+const (
+	metricsExporterOTLP       = "otlp"       // Push to the collector
+	metricsExporterPrometheus = "prometheus" // Serve for scraping at /metrics
+)
+
+// Handler serving the metrics for scraping, nil unless the Prometheus exporter is enabled
+var metricsHandler http.Handler
+
+// Bucket boundaries of the calculation duration in ms, fine grained around the usual 100ms
+// so the exemplar kept for each bucket points at a trace of that speed
+var calculationDurationBuckets = []float64{50, 100, 110, 125, 150, 200, 300, 500, 1000, 2500, 5000, 10000}
+
+// Values of the circuit breaker state gauge
+var circuitStates = map[string]int64{telemetry.CircuitClosed: 0, telemetry.CircuitHalfOpen: 1, telemetry.CircuitOpen: 2}
+
+// Metric instruments for the application
+var requestCounter metric.Int64Counter
+var calculationDuration metric.Float64Histogram
+var totalPriceCounter metric.Float64Counter
+var cacheRequests metric.Int64Counter
+var webhookDeliveries metric.Int64Counter
+var eventsPublished metric.Int64Counter
+var auditFailures metric.Int64Counter
+
+// Creates the metric instruments from the given meter
+func initMetrics(meter metric.Meter) error {
+	var err error
+	requestCounter, err = meter.Int64Counter("price_calculator.requests",
+		metric.WithDescription("Number of requests handled per operation and class of error"),
+		metric.WithUnit("{request}"),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create request counter: %v", err)
+	}
+
+	calculationDuration, err = meter.Float64Histogram("price_calculator.calculation.duration",
+		metric.WithDescription("Time taken to calculate a total price"),
+		metric.WithUnit("ms"),
+		metric.WithExplicitBucketBoundaries(calculationDurationBuckets...),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create calculation duration histogram: %v", err)
+	}
+
+	requestDuration, err = meter.Float64Histogram(requestDurationMetric,
+		metric.WithDescription("Time taken to serve a request by operation, tenant and server error"),
+		metric.WithUnit("ms"),
+		metric.WithExplicitBucketBoundaries(requestDurationBuckets()...),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create request duration histogram: %v", err)
+	}
+
+	totalPriceCounter, err = meter.Float64Counter("price_calculator.total_price",
+		metric.WithDescription("Running total of all prices calculated"),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create total price counter: %v", err)
+	}
+
+	cacheRequests, err = meter.Int64Counter("price_calculator.cache.requests",
+		metric.WithDescription("Number of calculation cache lookups by result: hit, miss or error"),
+		metric.WithUnit("{request}"),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create cache request counter: %v", err)
+	}
+
+	webhookDeliveries, err = meter.Int64Counter("price_calculator.webhook.deliveries",
+		metric.WithDescription("Number of webhook notifications by result: delivered or failed"),
+		metric.WithUnit("{notification}"),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create webhook delivery counter: %v", err)
+	}
+
+	eventsPublished, err = meter.Int64Counter("price_calculator.events.published",
+		metric.WithDescription("Number of PriceCalculated events sent to Kafka by result: published or failed"),
+		metric.WithUnit("{event}"),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create published event counter: %v", err)
+	}
+
+	auditFailures, err = meter.Int64Counter("price_calculator.audit.failures",
+		metric.WithDescription("Number of applied changes whose audit entry could not be recorded, by action"),
+		metric.WithUnit("{entry}"),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create audit failure counter: %v", err)
+	}
+
+	// Report the circuit breaker of the monitored span exporter when it has one
+	_, err = meter.Int64ObservableGauge("price_calculator.telemetry.circuit_breaker.state",
+		metric.WithDescription("State of the span exporter circuit breaker: 0 closed, 1 half open, 2 open"),
+		metric.WithInt64Callback(func(ctx context.Context, o metric.Int64Observer) error {
+			if state, ok := circuitStates[providers.Pipeline.Status().CircuitState]; ok {
+				o.Observe(state)
+			}
+			return nil
+		}),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create circuit breaker gauge: %v", err)
+	}
+	_, err = meter.Int64ObservableCounter("price_calculator.telemetry.dropped_spans",
+		metric.WithDescription("Number of spans lost by the monitored span exporter by reason: queue_full or export_failed"),
+		metric.WithUnit("{span}"),
+		metric.WithInt64Callback(func(ctx context.Context, o metric.Int64Observer) error {
+			status := providers.Pipeline.Status()
+			o.Observe(status.DroppedSpans, metric.WithAttributes(attribute.String("reason", "queue_full")))
+			o.Observe(status.FailedSpans, metric.WithAttributes(attribute.String("reason", "export_failed")))
+			return nil
+		}),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create dropped span counter: %v", err)
+	}
+	_, err = meter.Int64ObservableCounter("price_calculator.telemetry.forced_traces",
+		metric.WithDescription("Number of traces left out by the errors_and_slow sampler and exported because they failed or were slow, by reason: error or slow"),
+		metric.WithUnit("{trace}"),
+		metric.WithInt64Callback(func(ctx context.Context, o metric.Int64Observer) error {
+			errors, slow := providers.ForceSampler.ForcedTraces()
+			o.Observe(errors, metric.WithAttributes(attribute.String("reason", telemetry.ForcedError)))
+			o.Observe(slow, metric.WithAttributes(attribute.String("reason", telemetry.ForcedSlow)))
+			return nil
+		}),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create forced trace counter: %v", err)
+	}
+	_, err = meter.Int64ObservableCounter("price_calculator.telemetry.fallback_spans",
+		metric.WithDescription("Number of spans written to the fallback file while the circuit breaker was open"),
+		metric.WithUnit("{span}"),
+		metric.WithInt64Callback(func(ctx context.Context, o metric.Int64Observer) error {
+			if status := providers.Pipeline.Status(); status.CircuitState != "" {
+				o.Observe(status.FallbackSpans)
+			}
+			return nil
+		}),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create fallback span counter: %v", err)
+	}
+
+	// Report the burn rates of the last SLO evaluation
+	_, err = meter.Float64ObservableGauge("price_calculator.slo.burn_rate",
+		metric.WithDescription("Rate the error budget of an objective is used at over a window, 1 uses it up exactly at the end of the window"),
+		metric.WithFloat64Callback(func(ctx context.Context, o metric.Float64Observer) error {
+			for _, status := range currentSLOReport().Objectives {
+				for _, w := range status.Windows {
+					o.Observe(w.BurnRate, metric.WithAttributes(attribute.String("slo", status.Name), attribute.String("window", w.Window)))
+				}
+			}
+			return nil
+		}),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create SLO burn rate gauge: %v", err)
+	}
+	_, err = meter.Float64ObservableGauge("price_calculator.slo.error_budget_remaining",
+		metric.WithDescription("Fraction of the error budget of an objective left over its longest window"),
+		metric.WithFloat64Callback(func(ctx context.Context, o metric.Float64Observer) error {
+			for _, status := range currentSLOReport().Objectives {
+				o.Observe(status.ErrorBudgetRemaining, metric.WithAttributes(attribute.String("slo", status.Name)))
+			}
+			return nil
+		}),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create SLO error budget gauge: %v", err)
+	}
+	return nil
+}
+
+// Creates the metric reader selected by METRICS_EXPORTER.
+// The Prometheus exporter uses its own registry and sets metricsHandler to serve it.
+func newMetricReader(ctx context.Context, cfg telemetry.OTLPConfig) (sdkmetric.Reader, error) {
+	exporter := os.Getenv("METRICS_EXPORTER")
+	switch exporter {
+	case "", metricsExporterOTLP:
+		metricExporter, err := telemetry.NewMetricExporter(ctx, cfg)
+		if err != nil {
+			return nil, err
+		}
+		return sdkmetric.NewPeriodicReader(metricExporter), nil
+	case metricsExporterPrometheus:
+		registry := prometheus.NewRegistry()
+		reader, err := otelprometheus.New(otelprometheus.WithRegisterer(registry))
+		if err != nil {
+			return nil, err
+		}
+		// Exemplars are only part of the OpenMetrics format, served to scrapers asking for it
+		metricsHandler = promhttp.HandlerFor(registry, promhttp.HandlerOpts{EnableOpenMetrics: true})
+		return reader, nil
+	}
+	return nil, fmt.Errorf("unsupported metrics exporter %q", exporter)
+}
+
+// Wraps the handler of an operation in its request span and the middleware every API route runs through, in order
+func instrumentHandler(handler http.HandlerFunc, operation string) http.Handler {
+	return otelhttp.NewHandler(logAccess(measureRequest(func(w http.ResponseWriter, r *http.Request) {
+		r, errorClassOf := withErrorClass(r)
+		// Counted once the request is answered, after a panic is recovered, so the class of its error is known
+		defer func() {
+			countRequest(r.Context(), operation, errorClassOf())
+		}()
+		defer recoverPanic(w, r)
+		writeTraceHeaders(w, r)
+		recordRequestID(r)
+		recordContentEncoding(w, r)
+		r, cancel := withRequestTimeout(r, operation)
+		defer cancel()
+		r = withRequestBaggage(r)
+		// Inside the request span, so throttled requests are traced too
+		if rateLimited(w, r) {
+			return
+		}
+		r, ok := withTenant(w, r)
+		if !ok {
+			return
+		}
+		if injectFault(w, r, operation) {
+			return
+		}
+		capturePayloads(idempotent(handler), operation)(w, r)
+	}, operation)), operation)
+}
+
+// Counts a handled request of an operation with the class of the error it failed with, if any
+func countRequest(ctx context.Context, operation, errorClass string) {
+	attrs := []attribute.KeyValue{attribute.String("operation", operation)}
+	if errorClass != "" {
+		attrs = append(attrs, attribute.String("error.class", errorClass))
+	}
+	requestCounter.Add(ctx, 1, metric.WithAttributes(attrs...))
+}
@@ -0,0 +1,65 @@
+package httpapi
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Hand maintained OpenAPI 3 specification of the HTTP API, update it together with the routes
+//
+//go:embed openapi.yaml
+var openAPISpec []byte
+
+// Swagger UI page rendering the specification served at /openapi.json
+const swaggerUIPage = `<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <title>Price Calculator API</title>
+  <link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@5/swagger-ui.css">
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="https://unpkg.com/swagger-ui-dist@5/swagger-ui-bundle.js"></script>
+  <script>
+    window.ui = SwaggerUIBundle({ url: "/openapi.json", dom_id: "#swagger-ui" });
+  </script>
+</body>
+</html>
+`
+
+// Converts the embedded YAML specification to JSON
+func openAPIJSON() ([]byte, error) {
+	var spec map[string]any
+	if err := yaml.Unmarshal(openAPISpec, &spec); err != nil {
+		return nil, fmt.Errorf("failed to parse OpenAPI specification: %v", err)
+	}
+	return json.Marshal(spec)
+}
+
+// Serves the OpenAPI specification as JSON
+func serveOpenAPI() (http.HandlerFunc, error) {
+	spec, err := openAPIJSON()
+	if err != nil {
+		return nil, err
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if _, err := w.Write(spec); err != nil {
+			slog.Error("Error writing OpenAPI specification", "error", err)
+		}
+	}, nil
+}
+
+// Serves the Swagger UI for the OpenAPI specification
+func serveDocs(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if _, err := w.Write([]byte(swaggerUIPage)); err != nil {
+		slog.Error("Error writing API docs", "error", err)
+	}
+}
@@ -0,0 +1,104 @@
+package httpapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Size of the captured bodies unless payload_capture sets max_bytes
+const defaultPayloadMaxBytes = 4096
+
+// Replaces the values of redacted fields
+const redactedValue = "[REDACTED]"
+
+// JSON fields always redacted from captured bodies, compared case-insensitively
+var defaultRedactedFields = []string{"api_key", "authorization", "password", "secret", "token"}
+
+// Records the request and response bodies of an operation as span events when payload capture
+// is enabled in the configuration file. JSON bodies have the values of sensitive fields redacted
+// before they are truncated to the size cap.
+func capturePayloads(handler http.HandlerFunc, operation string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		cfg := fileConfig.Load().Payloads
+		span := trace.SpanFromContext(r.Context())
+		if !cfg.Enabled || !span.IsRecording() || (len(cfg.Operations) > 0 && !slices.Contains(cfg.Operations, operation)) {
+			handler(w, r)
+			return
+		}
+		maxBytes := cfg.MaxBytes
+		if maxBytes == 0 {
+			maxBytes = defaultPayloadMaxBytes
+		}
+		redact := append(slices.Clone(defaultRedactedFields), cfg.Redact...)
+
+		// Read the request body, leaving any read error for the handler to report
+		body, _ := io.ReadAll(r.Body)
+		r.Body = io.NopCloser(io.MultiReader(bytes.NewReader(body), r.Body))
+		if len(body) > 0 {
+			span.AddEvent("http.request.body", trace.WithAttributes(payloadAttributes(body, redact, maxBytes)...))
+		}
+
+		rec := &responseRecorder{ResponseWriter: w}
+		handler(rec, r)
+		if rec.body.Len() > 0 {
+			span.AddEvent("http.response.body", trace.WithAttributes(payloadAttributes(rec.body.Bytes(), redact, maxBytes)...))
+		}
+	}
+}
+
+// Returns the attributes of a captured body, redacted and truncated
+func payloadAttributes(body []byte, redact []string, maxBytes int) []attribute.KeyValue {
+	size := len(body)
+	body = redactPayload(body, redact)
+	truncated := len(body) > maxBytes
+	if truncated {
+		body = body[:maxBytes]
+	}
+	return []attribute.KeyValue{
+		attribute.String("http.body", strings.ToValidUTF8(string(body), "")), // Truncation may split a character
+		attribute.Int("http.body.size", size),
+		attribute.Bool("http.body.truncated", truncated),
+	}
+}
+
+// Replaces the values of the redacted fields anywhere in a JSON body.
+// Bodies that are not JSON are returned unchanged.
+func redactPayload(body []byte, fields []string) []byte {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber() // Keep amounts exact
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		return body
+	}
+	redacted, err := json.Marshal(redactValue(value, fields))
+	if err != nil {
+		return body
+	}
+	return redacted
+}
+
+// Redacts the fields of a decoded JSON value and of the values nested in it
+func redactValue(value any, fields []string) any {
+	switch v := value.(type) {
+	case map[string]any:
+		for key, nested := range v {
+			if slices.ContainsFunc(fields, func(field string) bool { return strings.EqualFold(field, key) }) {
+				v[key] = redactedValue
+			} else {
+				v[key] = redactValue(nested, fields)
+			}
+		}
+	case []any:
+		for i, nested := range v {
+			v[i] = redactValue(nested, fields)
+		}
+	}
+	return value
+}
@@ -0,0 +1,123 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"otpl/pricecalculator/pricing"
+)
+
+// Problem structure for RFC 7807 problem details error responses
+type Problem struct {
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Status   int    `json:"status"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"`
+
+	// Limits a calculated price crossed, set on 422 responses to calculations outside the guardrails
+	Violations []pricing.GuardrailViolation `json:"violations,omitempty"`
+}
+
+// Writes an RFC 7807 problem+json response.
+// Errors other than 404 are recorded on the active span with the status, and all client errors
+// but authentication and rate limit failures are recorded as validation errors.
+// A 404 is only classified, as a validation error.
+func writeProblem(w http.ResponseWriter, r *http.Request, detail string, status int) {
+	switch {
+	case status == http.StatusNotFound:
+		setErrorClass(r.Context(), errorClassValidation)
+	case status >= http.StatusBadRequest:
+		recordHTTPError(r.Context(), errors.New(detail), status)
+		if status < http.StatusInternalServerError && status != http.StatusUnauthorized && status != http.StatusForbidden && status != http.StatusTooManyRequests {
+			trace.SpanFromContext(r.Context()).SetAttributes(attribute.String("validation.error", detail))
+		}
+	}
+	encodeProblem(w, r, detail, status)
+}
+
+// Writes the problem+json response body
+func encodeProblem(w http.ResponseWriter, r *http.Request, detail string, status int) {
+	sendProblem(w, r, newProblem(r, detail, status))
+}
+
+// Creates the problem details of a response
+func newProblem(r *http.Request, detail string, status int) Problem {
+	title := http.StatusText(status)
+	if status == statusClientClosedRequest {
+		title = "Client Closed Request"
+	}
+	return Problem{
+		Type:     "about:blank",
+		Title:    title,
+		Status:   status,
+		Detail:   detail,
+		Instance: r.URL.Path,
+	}
+}
+
+// Writes a problem+json response
+func sendProblem(w http.ResponseWriter, r *http.Request, problem Problem) {
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(problem.Status)
+	if err := json.NewEncoder(w).Encode(problem); err != nil {
+		slog.ErrorContext(r.Context(), "Error encoding response", "error", err)
+	}
+}
+
+// Writes a problem response for an error returned by a shared operation.
+// Validation errors are reported to the client, prices outside the guardrails with 422 and
+// the violations, timed out and cancelled requests as such, and anything else is an internal error,
+// classified as a dependency failure when the database does not answer.
+func writeOperationError(w http.ResponseWriter, r *http.Request, err error, message string) {
+	if writeContextError(w, r) {
+		return
+	}
+	var validationErr *pricing.ValidationError
+	if errors.As(err, &validationErr) {
+		slog.WarnContext(r.Context(), message, "error", err)
+		writeProblem(w, r, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var guardrailErr *pricing.GuardrailError
+	if errors.As(err, &guardrailErr) {
+		slog.WarnContext(r.Context(), message, "error", err)
+		recordHTTPError(r.Context(), err, http.StatusUnprocessableEntity)
+		problem := newProblem(r, err.Error(), http.StatusUnprocessableEntity)
+		problem.Violations = guardrailErr.Violations
+		sendProblem(w, r, problem)
+		return
+	}
+	// Record the underlying error rather than the generic detail sent to the client
+	slog.ErrorContext(r.Context(), message, "error", err)
+	recordHTTPError(r.Context(), err, http.StatusInternalServerError)
+	if databaseUnreachable(r.Context()) {
+		setErrorClass(r.Context(), errorClassDependency)
+	}
+	encodeProblem(w, r, "Internal server error", http.StatusInternalServerError)
+}
+
+// Decodes a JSON request body into v, an empty body leaves v unchanged.
+// Writes a problem response and returns false if the body is invalid or too large.
+func decodeJSON(w http.ResponseWriter, r *http.Request, v any) bool {
+	err := json.NewDecoder(r.Body).Decode(v)
+	if err == nil || errors.Is(err, io.EOF) {
+		return true
+	}
+
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		slog.WarnContext(r.Context(), "Request body too large", "limit", maxBytesErr.Limit)
+		writeProblem(w, r, "Request body too large", http.StatusRequestEntityTooLarge)
+		return false
+	}
+	slog.WarnContext(r.Context(), "Invalid request body", "error", err)
+	writeProblem(w, r, "Invalid request body", http.StatusBadRequest)
+	return false
+}
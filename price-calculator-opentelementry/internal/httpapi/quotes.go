@@ -0,0 +1,304 @@
+package httpapi
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"otpl/pricecalculator/pricing"
+)
+
+// Quote statuses, a finalized quote can no longer change
+const (
+	QuoteOpen      = "open"
+	QuoteFinalized = "finalized"
+)
+
+// Quote structure for a persisted calculation with its full breakdown
+type Quote struct {
+	ID          string                `json:"id"` // Derived from the tenant, request and result
+	Tenant      string                `json:"tenant,omitempty"`
+	Status      string                `json:"status"`
+	Request     pricing.PriceRequest  `json:"request"`
+	Response    pricing.PriceResponse `json:"response"`
+	TraceID     string                `json:"trace_id,omitempty"` // Trace of the calculation
+	CreatedAt   time.Time             `json:"created_at"`
+	FinalizedAt *time.Time            `json:"finalized_at,omitempty"`
+}
+
+// QuoteStore persists quotes, scoped by tenant
+type QuoteStore interface {
+	// Create adds a quote, false if there is one with its ID
+	Create(ctx context.Context, quote Quote) (bool, error)
+	// Get returns the tenant's quote with the ID, false if there is none
+	Get(ctx context.Context, tenant, id string) (Quote, bool, error)
+	// Finalize locks an open quote, false if there is no open quote with the ID
+	Finalize(ctx context.Context, tenant, id string, at time.Time) (bool, error)
+}
+
+// Quote store opened on startup
+var quotes QuoteStore
+
+// Derives the ID of a quote from its tenant, request and result, so pricing
+// the same request the same way always yields the same quote. The calculation and
+// trace IDs differ on every calculation and are left out.
+func quoteID(tenant string, request pricing.PriceRequest, response pricing.PriceResponse) (string, error) {
+	response.CalculationID, response.TraceID, response.Explanation = "", "", nil
+	data, err := json.Marshal(struct {
+		Tenant   string
+		Request  pricing.PriceRequest
+		Response pricing.PriceResponse
+	}{tenant, request, response})
+	if err != nil {
+		return "", fmt.Errorf("failed to derive quote id: %v", err)
+	}
+	sum := sha256.Sum256(data)
+	return "q_" + hex.EncodeToString(sum[:16]), nil
+}
+
+// memoryQuoteStore keeps the quotes in memory, saved to a JSON file when it has one
+type memoryQuoteStore struct {
+	mu     sync.Mutex
+	quotes map[string]Quote
+	file   *jsonFile
+}
+
+// Creates the in-memory quote store, loading the quotes from file
+func newMemoryQuoteStore(file *jsonFile) (*memoryQuoteStore, error) {
+	s := &memoryQuoteStore{quotes: map[string]Quote{}, file: file}
+	if err := file.load(&s.quotes); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *memoryQuoteStore) Create(ctx context.Context, q Quote) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.quotes[q.ID]; ok {
+		return false, nil
+	}
+	s.quotes[q.ID] = q
+	return true, s.file.persist(s.quotes)
+}
+
+func (s *memoryQuoteStore) Get(ctx context.Context, tenant, id string) (Quote, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	q, ok := s.quotes[id]
+	if !ok || q.Tenant != tenant {
+		return Quote{}, false, nil
+	}
+	return q, true, nil
+}
+
+func (s *memoryQuoteStore) Finalize(ctx context.Context, tenant, id string, at time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	q, ok := s.quotes[id]
+	if !ok || q.Tenant != tenant || q.Status != QuoteOpen {
+		return false, nil
+	}
+	q.Status, q.FinalizedAt = QuoteFinalized, &at
+	s.quotes[id] = q
+	return true, s.file.persist(s.quotes)
+}
+
+// sqlQuoteStore keeps the quotes in a SQLite or PostgreSQL table, with the request
+// and result encoded as JSON and times as RFC 3339 text
+type sqlQuoteStore struct {
+	db *sql.DB
+}
+
+// Creates the quote store in db, adding its table if needed
+func newSQLQuoteStore(db *sql.DB) (*sqlQuoteStore, error) {
+	_, err := db.Exec(`CREATE TABLE IF NOT EXISTS quotes (
+		id           TEXT PRIMARY KEY,
+		tenant       TEXT NOT NULL DEFAULT '',
+		status       TEXT NOT NULL,
+		request      TEXT NOT NULL,
+		response     TEXT NOT NULL,
+		trace_id     TEXT NOT NULL DEFAULT '',
+		created_at   TEXT NOT NULL,
+		finalized_at TEXT
+	)`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create quotes table: %v", err)
+	}
+	return &sqlQuoteStore{db: db}, nil
+}
+
+func (s *sqlQuoteStore) Create(ctx context.Context, q Quote) (bool, error) {
+	request, err := json.Marshal(q.Request)
+	if err != nil {
+		return false, fmt.Errorf("failed to encode quote request: %v", err)
+	}
+	response, err := json.Marshal(q.Response)
+	if err != nil {
+		return false, fmt.Errorf("failed to encode quote response: %v", err)
+	}
+	result, err := s.db.ExecContext(ctx, `INSERT INTO quotes (id, tenant, status, request, response, trace_id, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7) ON CONFLICT (id) DO NOTHING`,
+		q.ID, q.Tenant, q.Status, string(request), string(response), q.TraceID, q.CreatedAt.Format(time.RFC3339Nano))
+	if err != nil {
+		return false, fmt.Errorf("failed to create quote: %v", err)
+	}
+	return affected(result)
+}
+
+func (s *sqlQuoteStore) Get(ctx context.Context, tenant, id string) (Quote, bool, error) {
+	var q Quote
+	var request, response, createdAt string
+	var finalizedAt sql.NullString
+	err := s.db.QueryRowContext(ctx, `SELECT id, tenant, status, request, response, trace_id, created_at, finalized_at
+		FROM quotes WHERE id = $1 AND tenant = $2`, id, tenant).
+		Scan(&q.ID, &q.Tenant, &q.Status, &request, &response, &q.TraceID, &createdAt, &finalizedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return q, false, nil
+	}
+	if err != nil {
+		return q, false, fmt.Errorf("failed to load quote: %v", err)
+	}
+	if err := json.Unmarshal([]byte(request), &q.Request); err != nil {
+		return q, false, fmt.Errorf("invalid stored quote request: %v", err)
+	}
+	if err := json.Unmarshal([]byte(response), &q.Response); err != nil {
+		return q, false, fmt.Errorf("invalid stored quote response: %v", err)
+	}
+	if q.CreatedAt, err = time.Parse(time.RFC3339Nano, createdAt); err != nil {
+		return q, false, fmt.Errorf("invalid stored quote time: %v", err)
+	}
+	if finalizedAt.Valid {
+		at, err := time.Parse(time.RFC3339Nano, finalizedAt.String)
+		if err != nil {
+			return q, false, fmt.Errorf("invalid stored quote time: %v", err)
+		}
+		q.FinalizedAt = &at
+	}
+	return q, true, nil
+}
+
+func (s *sqlQuoteStore) Finalize(ctx context.Context, tenant, id string, at time.Time) (bool, error) {
+	result, err := s.db.ExecContext(ctx, `UPDATE quotes SET status = $3, finalized_at = $4
+		WHERE id = $1 AND tenant = $2 AND status = $5`,
+		id, tenant, QuoteFinalized, at.Format(time.RFC3339Nano), QuoteOpen)
+	if err != nil {
+		return false, fmt.Errorf("failed to finalize quote: %v", err)
+	}
+	return affected(result)
+}
+
+// Prices a request and stores the result as an open quote.
+// Pricing a request the same way again returns the existing quote with 200 instead of 201.
+// A quote only previews its coupon, and the calculation is recorded in the history and
+// published once the quote is created, so repeating a request has no side effects.
+func createQuote(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	var request pricing.PriceRequest
+	if !decodeJSON(w, r, &request) {
+		return
+	}
+
+	response, err := calculate(withSimulation(ctx), request)
+	if err != nil {
+		writeOperationError(w, r, err, "Error calculating quote")
+		return
+	}
+	quote := Quote{
+		Tenant:    tenantID(ctx),
+		Status:    QuoteOpen,
+		Request:   request,
+		Response:  response,
+		CreatedAt: time.Now().UTC(),
+	}
+	if sc := trace.SpanContextFromContext(ctx); sc.HasTraceID() {
+		quote.TraceID = sc.TraceID().String()
+	}
+	if quote.ID, err = quoteID(quote.Tenant, request, response); err != nil {
+		writeOperationError(w, r, err, "Error creating quote")
+		return
+	}
+	span := trace.SpanFromContext(ctx)
+	span.SetAttributes(attribute.String("quote.id", quote.ID))
+
+	created, err := quotes.Create(ctx, quote)
+	if err != nil {
+		writeOperationError(w, r, err, "Error creating quote")
+		return
+	}
+	w.Header().Set("Location", r.URL.Path+"/"+quote.ID)
+	if !created {
+		existing, ok, err := quotes.Get(ctx, quote.Tenant, quote.ID)
+		if err != nil || !ok {
+			writeOperationError(w, r, fmt.Errorf("quote %s vanished: %v", quote.ID, err), "Error loading quote")
+			return
+		}
+		span.SetAttributes(attribute.Bool("quote.existing", true))
+		writeJSON(w, http.StatusOK, existing)
+		return
+	}
+
+	recordCalculation(ctx, request, response)
+	writeJSON(w, http.StatusCreated, quote)
+	slog.InfoContext(ctx, "Quote created", "quote_id", quote.ID, "total_price", response.TotalPrice.String())
+}
+
+// Returns a single quote of the request's tenant
+func getQuote(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	quote, ok, err := quotes.Get(r.Context(), tenantID(r.Context()), id)
+	if err != nil {
+		writeOperationError(w, r, err, "Error loading quote")
+		return
+	}
+	if !ok {
+		writeProblem(w, r, "Quote not found", http.StatusNotFound)
+		return
+	}
+
+	trace.SpanFromContext(r.Context()).SetAttributes(attribute.String("quote.id", id))
+	writeJSON(w, http.StatusOK, quote)
+}
+
+// Locks an open quote. Finalizing a quote twice is answered with 409.
+func finalizeQuote(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	id := mux.Vars(r)["id"]
+	tenant := tenantID(ctx)
+	trace.SpanFromContext(ctx).SetAttributes(attribute.String("quote.id", id))
+
+	finalized, err := quotes.Finalize(ctx, tenant, id, time.Now().UTC())
+	if err != nil {
+		writeOperationError(w, r, err, "Error finalizing quote")
+		return
+	}
+	quote, ok, err := quotes.Get(ctx, tenant, id)
+	if err != nil {
+		writeOperationError(w, r, err, "Error loading quote")
+		return
+	}
+	if !ok {
+		writeProblem(w, r, "Quote not found", http.StatusNotFound)
+		return
+	}
+	if !finalized {
+		writeProblem(w, r, "Quote is already finalized", http.StatusConflict)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, quote)
+	slog.InfoContext(ctx, "Quote finalized", "quote_id", id)
+}
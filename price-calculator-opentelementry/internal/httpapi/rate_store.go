@@ -0,0 +1,146 @@
+package httpapi
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ExchangeRate structure for a rate refreshed from the exchange-rate provider
+type ExchangeRate struct {
+	Base      string    `json:"base"`
+	Currency  string    `json:"currency"`
+	Rate      float64   `json:"rate"` // Units of the currency per unit of the base currency
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// ExchangeRateStore persists the refreshed exchange rates
+type ExchangeRateStore interface {
+	// List returns all rates by base currency, then currency
+	List(ctx context.Context) ([]ExchangeRate, error)
+	// Save adds the rates, replacing those of the same currency pairs
+	Save(ctx context.Context, rates []ExchangeRate) error
+}
+
+// Exchange rate store opened on startup
+var rateStore ExchangeRateStore
+
+// memoryRateStore keeps the exchange rates in memory, saved to a JSON file when it has one
+type memoryRateStore struct {
+	mu    sync.Mutex
+	rates []ExchangeRate // By base currency, then currency
+	file  *jsonFile
+}
+
+// Creates the in-memory exchange rate store, loading the rates from file
+func newMemoryRateStore(file *jsonFile) (*memoryRateStore, error) {
+	s := &memoryRateStore{rates: []ExchangeRate{}, file: file}
+	if err := file.load(&s.rates); err != nil {
+		return nil, err
+	}
+	sortExchangeRates(s.rates)
+	return s, nil
+}
+
+// Sorts rates by base currency, then currency
+func sortExchangeRates(list []ExchangeRate) {
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Base != list[j].Base {
+			return list[i].Base < list[j].Base
+		}
+		return list[i].Currency < list[j].Currency
+	})
+}
+
+func (s *memoryRateStore) List(ctx context.Context) ([]ExchangeRate, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]ExchangeRate{}, s.rates...), nil
+}
+
+func (s *memoryRateStore) Save(ctx context.Context, rates []ExchangeRate) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, rate := range rates {
+		i := sort.Search(len(s.rates), func(i int) bool {
+			if s.rates[i].Base != rate.Base {
+				return s.rates[i].Base > rate.Base
+			}
+			return s.rates[i].Currency >= rate.Currency
+		})
+		if i < len(s.rates) && s.rates[i].Base == rate.Base && s.rates[i].Currency == rate.Currency {
+			s.rates[i] = rate
+		} else {
+			s.rates = append(s.rates[:i], append([]ExchangeRate{rate}, s.rates[i:]...)...)
+		}
+	}
+	return s.file.persist(s.rates)
+}
+
+// sqlRateStore keeps the exchange rates in a SQLite or PostgreSQL table, with times in Unix nanoseconds
+type sqlRateStore struct {
+	db *sql.DB
+}
+
+// Creates the exchange rate store in db, adding its table if needed
+func newSQLRateStore(db *sql.DB) (*sqlRateStore, error) {
+	_, err := db.Exec(`CREATE TABLE IF NOT EXISTS exchange_rates (
+		base       TEXT NOT NULL,
+		currency   TEXT NOT NULL,
+		rate       DOUBLE PRECISION NOT NULL,
+		updated_at BIGINT NOT NULL,
+		PRIMARY KEY (base, currency)
+	)`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create exchange_rates table: %v", err)
+	}
+	return &sqlRateStore{db: db}, nil
+}
+
+func (s *sqlRateStore) List(ctx context.Context) ([]ExchangeRate, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT base, currency, rate, updated_at FROM exchange_rates ORDER BY base, currency`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list exchange rates: %v", err)
+	}
+	defer rows.Close()
+
+	list := []ExchangeRate{}
+	for rows.Next() {
+		var rate ExchangeRate
+		var updatedAt int64
+		if err := rows.Scan(&rate.Base, &rate.Currency, &rate.Rate, &updatedAt); err != nil {
+			return nil, fmt.Errorf("failed to read exchange rate: %v", err)
+		}
+		rate.UpdatedAt = time.Unix(0, updatedAt).UTC()
+		list = append(list, rate)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list exchange rates: %v", err)
+	}
+	return list, nil
+}
+
+// Saves the rates in one transaction, so a refresh is stored completely or not at all
+func (s *sqlRateStore) Save(ctx context.Context, rates []ExchangeRate) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	for _, rate := range rates {
+		_, err := tx.ExecContext(ctx, `INSERT INTO exchange_rates (base, currency, rate, updated_at) VALUES ($1, $2, $3, $4)
+			ON CONFLICT (base, currency) DO UPDATE SET rate = excluded.rate, updated_at = excluded.updated_at`,
+			rate.Base, rate.Currency, rate.Rate, rate.UpdatedAt.UnixNano())
+		if err != nil {
+			return fmt.Errorf("failed to save exchange rate: %v", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to save exchange rates: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,124 @@
+package httpapi
+
+import (
+	"fmt"
+	"log/slog"
+	"math"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Number of client buckets kept before idle ones are dropped
+const maxRateLimitClients = 10000
+
+// Per client token buckets for the instrumented endpoints
+var limiter = &rateLimiter{buckets: map[string]*tokenBucket{}}
+
+// tokenBucket holds the tokens left for one client
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// rateLimiter keeps a token bucket per client, safe for concurrent use
+type rateLimiter struct {
+	mu      sync.Mutex
+	buckets map[string]*tokenBucket
+}
+
+// Takes a token from the client's bucket, refilled at rate tokens per second up to burst.
+// Returns how long to wait for the next token when the bucket is empty.
+func (l *rateLimiter) Allow(client string, rate float64, burst int, now time.Time) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	bucket, ok := l.buckets[client]
+	if !ok {
+		if len(l.buckets) >= maxRateLimitClients {
+			l.dropIdle(rate, burst, now)
+		}
+		bucket = &tokenBucket{tokens: float64(burst), last: now}
+		l.buckets[client] = bucket
+	}
+
+	bucket.tokens = math.Min(float64(burst), bucket.tokens+now.Sub(bucket.last).Seconds()*rate)
+	bucket.last = now
+	if bucket.tokens < 1 {
+		return false, time.Duration((1 - bucket.tokens) / rate * float64(time.Second))
+	}
+	bucket.tokens--
+	return true, 0
+}
+
+// Drops the buckets that have refilled completely, they behave like new ones
+func (l *rateLimiter) dropIdle(rate float64, burst int, now time.Time) {
+	for client, bucket := range l.buckets {
+		if bucket.tokens+now.Sub(bucket.last).Seconds()*rate >= float64(burst) {
+			delete(l.buckets, client)
+		}
+	}
+}
+
+// Returns the rate limit from RATE_LIMIT and RATE_LIMIT_BURST, or the configuration file.
+// A rate of zero disables rate limiting, the burst defaults to the rate.
+func rateLimit() (float64, int, error) {
+	file := fileConfig.Load().RateLimit
+	rate, burst := file.RequestsPerSecond, file.Burst
+	if value := os.Getenv("RATE_LIMIT"); value != "" {
+		var err error
+		if rate, err = strconv.ParseFloat(value, 64); err != nil || rate < 0 {
+			return 0, 0, fmt.Errorf("invalid RATE_LIMIT %q", value)
+		}
+	}
+	if value := os.Getenv("RATE_LIMIT_BURST"); value != "" {
+		var err error
+		if burst, err = strconv.Atoi(value); err != nil || burst < 0 {
+			return 0, 0, fmt.Errorf("invalid RATE_LIMIT_BURST %q", value)
+		}
+	}
+	if burst == 0 {
+		burst = int(math.Ceil(rate))
+	}
+	return rate, burst, nil
+}
+
+// Identifies the client of a request by its API key, or its IP address without a valid one
+// so clients cannot escape the limit by sending made up keys
+func rateLimitClient(r *http.Request) string {
+	if key := r.Header.Get(APIKeyHeader); key != "" && acceptedAPIKey(apiKeys(), key) {
+		return "key:" + apiKeyID(key)
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "ip:" + host
+}
+
+// Answers 429 with Retry-After once the client has used up its tokens,
+// tagging the span of the rejected request with rate_limited=true
+func rateLimited(w http.ResponseWriter, r *http.Request) bool {
+	rate, burst, err := rateLimit()
+	if err != nil || rate == 0 {
+		return false // Validated on startup
+	}
+
+	client := rateLimitClient(r)
+	allowed, wait := limiter.Allow(client, rate, burst, time.Now())
+	if allowed {
+		return false
+	}
+
+	trace.SpanFromContext(r.Context()).SetAttributes(attribute.Bool("rate_limited", true))
+	slog.WarnContext(r.Context(), "Rate limited", "client", client)
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+	writeProblem(w, r, "Rate limit exceeded", http.StatusTooManyRequests)
+	return true
+}
@@ -0,0 +1,256 @@
+package httpapi
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"maps"
+	"net/http"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"otpl/pricecalculator/internal/cron"
+	"otpl/pricecalculator/internal/telemetry"
+	"otpl/pricecalculator/pricing"
+)
+
+// Base currency of the refreshed rates when the configuration file sets none
+const defaultRateBase = "USD"
+
+// Longest wait of the refresher, so schedule changes on reload apply within a minute
+const rateRefreshPoll = time.Minute
+
+// RateRefresh structure for a run of the scheduled exchange rate refresh
+type RateRefresh struct {
+	StartedAt         time.Time `json:"started_at"`
+	RecordsUpdated    int       `json:"records_updated"`
+	ProviderLatencyMS float64   `json:"provider_latency_ms"`
+	Error             string    `json:"error,omitempty"`
+	TraceID           string    `json:"trace_id,omitempty"`
+}
+
+// RatesResponse structure for the output of GET /rates
+type RatesResponse struct {
+	Base        string         `json:"base"`
+	Refresh     string         `json:"refresh,omitempty"` // Cron schedule, unset without a scheduled refresh
+	LastRefresh *RateRefresh   `json:"last_refresh,omitempty"`
+	NextRefresh *time.Time     `json:"next_refresh,omitempty"`
+	Rates       []ExchangeRate `json:"rates"`
+}
+
+// Refreshed rates by base/currency pair, the last and next refresh runs, guarded by refreshedRatesMu
+var refreshedRates = map[string]ExchangeRate{}
+var lastRateRefresh *RateRefresh
+var nextRateRefresh time.Time
+var refreshedRatesMu sync.Mutex
+
+// Returns the base currency and the currencies of the scheduled refresh
+func rateRefreshCurrencies() (string, []string, error) {
+	cfg := fileConfig.Load().ExchangeRates
+	base := defaultRateBase
+	if cfg.Base != "" {
+		currency, err := pricing.LookupCurrency(cfg.Base)
+		if err != nil {
+			return "", nil, err
+		}
+		base = currency.Code
+	}
+	codes := cfg.Currencies
+	if len(codes) == 0 {
+		codes = slices.Sorted(maps.Keys(staticRates))
+	}
+	currencies := []string{}
+	for _, code := range codes {
+		currency, err := pricing.LookupCurrency(code)
+		if err != nil {
+			return "", nil, err
+		}
+		if currency.Code != base {
+			currencies = append(currencies, currency.Code)
+		}
+	}
+	return base, currencies, nil
+}
+
+// Returns the rate from one currency to another through the base currency of the scheduled refresh,
+// false without a scheduled refresh or before both currencies were refreshed
+func refreshedRate(from, to string) (float64, bool) {
+	if fileConfig.Load().ExchangeRates.Refresh == "" {
+		return 0, false
+	}
+	base, _, err := rateRefreshCurrencies()
+	if err != nil {
+		return 0, false
+	}
+	refreshedRatesMu.Lock()
+	defer refreshedRatesMu.Unlock()
+	rateFor := func(currency string) (float64, bool) {
+		if currency == base {
+			return 1, true
+		}
+		rate, ok := refreshedRates[base+"/"+currency]
+		return rate.Rate, ok
+	}
+	fromRate, fromOK := rateFor(from)
+	toRate, toOK := rateFor(to)
+	if !fromOK || !toOK {
+		return 0, false
+	}
+	return toRate / fromRate, true
+}
+
+// Refreshes the exchange rates on the schedule of the configuration file until ctx is done,
+// starting with the stored rates and refreshing them right away when there are none yet
+func runRateRefresher(ctx context.Context) {
+	stored, err := rateStore.List(ctx)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to load exchange rates", "error", err)
+	}
+	refreshedRatesMu.Lock()
+	for _, rate := range stored {
+		refreshedRates[rate.Base+"/"+rate.Currency] = rate
+	}
+	refreshedRatesMu.Unlock()
+
+	var spec string
+	var schedule cron.Schedule
+	var next time.Time
+	for {
+		if refresh := fileConfig.Load().ExchangeRates.Refresh; refresh != spec {
+			spec, next = refresh, time.Time{}
+			if spec != "" {
+				schedule, _ = cron.Parse(spec) // Validated when the file was loaded
+				next = schedule.Next(time.Now())
+				if len(stored) == 0 {
+					next = time.Now()
+				}
+			}
+			setNextRateRefresh(next)
+		}
+		if !next.IsZero() && !time.Now().Before(next) {
+			refreshExchangeRates(ctx, spec)
+			stored = nil
+			next = schedule.Next(time.Now())
+			setNextRateRefresh(next)
+		}
+
+		wait := rateRefreshPoll
+		if !next.IsZero() {
+			wait = min(wait, time.Until(next))
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+	}
+}
+
+// Records when the next refresh runs, zero without a scheduled refresh
+func setNextRateRefresh(next time.Time) {
+	refreshedRatesMu.Lock()
+	nextRateRefresh = next
+	refreshedRatesMu.Unlock()
+}
+
+// Fetches the rates of the configured currencies in a single provider call and stores them,
+// in a trace of its own recording the provider latency and the number of records updated
+func refreshExchangeRates(ctx context.Context, spec string) {
+	ctx, span := tracer.Start(ctx, "RefreshExchangeRates", trace.WithNewRoot(),
+		trace.WithAttributes(attribute.String("cron.schedule", spec)))
+	defer span.End()
+
+	run := RateRefresh{StartedAt: time.Now().UTC()}
+	if sc := span.SpanContext(); sc.HasTraceID() {
+		run.TraceID = sc.TraceID().String()
+	}
+	defer func() {
+		refreshedRatesMu.Lock()
+		lastRateRefresh = &run
+		refreshedRatesMu.Unlock()
+	}()
+	fail := func(message string, err error) {
+		telemetry.RecordError(span, err)
+		slog.ErrorContext(ctx, message, "error", err)
+		run.Error = err.Error()
+	}
+
+	base, currencies, err := rateRefreshCurrencies()
+	if err != nil {
+		fail("Invalid exchange rate currencies", err)
+		return
+	}
+	span.SetAttributes(attribute.String("exchange.base", base), attribute.StringSlice("exchange.currencies", currencies))
+	provider := os.Getenv("EXCHANGE_RATE_URL")
+	if provider == "" {
+		fail("Failed to refresh exchange rates", errors.New("EXCHANGE_RATE_URL is not set"))
+		return
+	}
+
+	start := time.Now()
+	fetched, err := fetchExchangeRates(ctx, provider, base, currencies)
+	run.ProviderLatencyMS = durationMS(time.Since(start))
+	span.SetAttributes(attribute.Float64("exchange.provider.latency_ms", run.ProviderLatencyMS))
+	if err != nil {
+		recordDependencyError(dependencyExchangeRate, err)
+		fail("Failed to refresh exchange rates", err)
+		return
+	}
+
+	updatedAt := time.Now().UTC()
+	rates := []ExchangeRate{}
+	var missing []string
+	for _, currency := range currencies {
+		rate, ok := fetched[currency]
+		if !ok || rate <= 0 {
+			missing = append(missing, currency)
+			continue
+		}
+		rates = append(rates, ExchangeRate{Base: base, Currency: currency, Rate: rate, UpdatedAt: updatedAt})
+	}
+	if len(missing) > 0 {
+		span.SetAttributes(attribute.StringSlice("exchange.missing_currencies", missing))
+		slog.WarnContext(ctx, "Exchange-rate provider has no rates for some currencies", "base", base, "currencies", missing)
+	}
+	if err := rateStore.Save(ctx, rates); err != nil {
+		fail("Failed to store exchange rates", err)
+		return
+	}
+
+	refreshedRatesMu.Lock()
+	for _, rate := range rates {
+		refreshedRates[rate.Base+"/"+rate.Currency] = rate
+	}
+	refreshedRatesMu.Unlock()
+	run.RecordsUpdated = len(rates)
+	span.SetAttributes(attribute.Int("exchange.records_updated", run.RecordsUpdated))
+	slog.InfoContext(ctx, "Exchange rates refreshed", "base", base, "records_updated", run.RecordsUpdated,
+		"provider_latency_ms", run.ProviderLatencyMS)
+}
+
+// Returns the stored exchange rates with the last and next refresh runs
+func getRates(w http.ResponseWriter, r *http.Request) {
+	rates, err := rateStore.List(r.Context())
+	if err != nil {
+		writeOperationError(w, r, err, "Error listing exchange rates")
+		return
+	}
+	base, _, err := rateRefreshCurrencies()
+	if err != nil {
+		base = defaultRateBase
+	}
+	response := RatesResponse{Base: base, Refresh: fileConfig.Load().ExchangeRates.Refresh, Rates: rates}
+	refreshedRatesMu.Lock()
+	response.LastRefresh = lastRateRefresh
+	if !nextRateRefresh.IsZero() {
+		next := nextRateRefresh.UTC()
+		response.NextRefresh = &next
+	}
+	refreshedRatesMu.Unlock()
+	writeJSON(w, http.StatusOK, response)
+}
@@ -0,0 +1,82 @@
+package httpapi
+
+import (
+	"log/slog"
+	"net/http"
+
+	"go.opentelemetry.io/otel/attribute"
+
+	"otpl/pricecalculator/internal/telemetry"
+	"otpl/pricecalculator/pricing"
+)
+
+// Times a refund is recalculated when other refunds of the same calculation are stored meanwhile
+const maxRefundAttempts = 5
+
+// Calculates a refund or credit note for a calculation of the tenant recorded in the history, by the
+// units returned or a credit amount, reversing tax at the rates of the original calculation. What was
+// refunded is stored with the calculation, so refunds never exceed it.
+func calculateRefund(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	// Start a new span for the refund calculation
+	ctx, span := tracer.Start(ctx, "CalculateRefund")
+	defer span.End()
+
+	var request pricing.RefundRequest
+	if !decodeJSON(w, r, &request) {
+		return
+	}
+	if request.CalculationID == "" {
+		writeProblem(w, r, "calculation_id is required", http.StatusBadRequest)
+		return
+	}
+
+	defaults, _ := tenantDefaults(ctx)
+	var refund pricing.RefundResponse
+	for attempt := 1; ; attempt++ {
+		// Look up the original calculation, linking the refund to its trace
+		record, ok, err := history.Find(ctx, tenantID(ctx), request.CalculationID)
+		if err != nil {
+			telemetry.RecordError(span, err)
+			writeOperationError(w, r, err, "Error loading calculation")
+			return
+		}
+		if !ok {
+			writeProblem(w, r, "Calculation not found in history", http.StatusNotFound)
+			return
+		}
+		span.SetAttributes(attribute.String("refund.original_trace_id", record.TraceID))
+
+		refund, err = pricing.CalculateRefund(ctx, request, record.Request, record.Response, record.Refunded, roundingMode(defaults, record.Response.Currency))
+		if err != nil {
+			telemetry.RecordError(span, err)
+			writeOperationError(w, r, err, "Error calculating refund")
+			return
+		}
+
+		// Store the refund against the original, starting over if another refund got there first
+		stored, err := history.Refund(ctx, record, refund.Refunded)
+		if err != nil {
+			telemetry.RecordError(span, err)
+			writeOperationError(w, r, err, "Error recording refund")
+			return
+		}
+		if stored {
+			break
+		}
+		if attempt == maxRefundAttempts {
+			writeProblem(w, r, "Calculation is being refunded concurrently, try again", http.StatusConflict)
+			return
+		}
+	}
+	refund.CalculationID = randomID()
+	if sc := span.SpanContext(); sc.HasTraceID() {
+		refund.TraceID = sc.TraceID().String()
+	}
+	span.SetAttributes(attribute.String("calculation.id", refund.CalculationID))
+
+	writeJSON(w, http.StatusOK, refund)
+	slog.InfoContext(ctx, "Calculated refund", "total_price", refund.TotalPrice.String(), "currency", refund.Currency,
+		"calculation_id", refund.CalculationID, "original_calculation_id", request.CalculationID)
+}
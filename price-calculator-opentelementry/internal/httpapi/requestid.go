@@ -0,0 +1,49 @@
+package httpapi
+
+import (
+	"net/http"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"otpl/pricecalculator/internal/telemetry"
+)
+
+// Header carrying the ID of a request, taken from the client or generated
+const requestIDHeader = "X-Request-ID"
+
+// Longest client request ID accepted, longer ones are replaced
+const maxRequestIDLength = 128
+
+// Reports whether a client request ID is short printable ASCII, safe to log and echo
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+// Middleware taking the request ID from the X-Request-ID header, or generating one when it is
+// missing or invalid, adding it to the request context and echoing it in the response
+func withRequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(requestIDHeader)
+		if !validRequestID(id) {
+			id = randomID()
+		}
+		w.Header().Set(requestIDHeader, id)
+		next.ServeHTTP(w, r.WithContext(telemetry.WithRequestID(r.Context(), id)))
+	})
+}
+
+// Records the request ID on the request span
+func recordRequestID(r *http.Request) {
+	if id := telemetry.RequestID(r.Context()); id != "" {
+		trace.SpanFromContext(r.Context()).SetAttributes(attribute.String("http.request_id", id))
+	}
+}
@@ -0,0 +1,28 @@
+package httpapi
+
+import (
+	"log/slog"
+	"net/http"
+)
+
+// Returns the current sampler settings
+func getSampling(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, providers.Sampler.Config())
+}
+
+// Changes the sampler settings without a restart.
+// Fields left out of the request keep their current value.
+func updateSampling(w http.ResponseWriter, r *http.Request) {
+	cfg := providers.Sampler.Config()
+	if !decodeJSON(w, r, &cfg) {
+		return
+	}
+	if err := providers.Sampler.Configure(cfg); err != nil {
+		slog.WarnContext(r.Context(), "Invalid sampling configuration", "error", err)
+		writeProblem(w, r, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, cfg)
+	slog.InfoContext(r.Context(), "Sampling updated", "sampler", cfg.Sampler, "ratio", cfg.Ratio)
+}
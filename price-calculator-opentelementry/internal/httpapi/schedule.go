@@ -0,0 +1,229 @@
+package httpapi
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"otpl/pricecalculator/internal/telemetry"
+	"otpl/pricecalculator/pricing"
+)
+
+// How often the scheduler looks for changes that took effect
+const scheduleInterval = time.Second
+
+// ScheduleRequest structure for the input of POST /config/schedule
+type ScheduleRequest struct {
+	BasePrice   *pricing.Money `json:"base_price,omitempty"`
+	TaxRate     *float64       `json:"tax_rate,omitempty"`
+	EffectiveAt time.Time      `json:"effective_at"` // RFC 3339, must be in the future
+}
+
+// Applies the scheduled changes as they take effect until ctx is done,
+// starting with those that took effect while the server was down
+func runScheduler(ctx context.Context) {
+	ticker := time.NewTicker(scheduleInterval)
+	defer ticker.Stop()
+	for {
+		applyDueChanges(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Applies the pending changes that took effect, oldest first
+func applyDueChanges(ctx context.Context) {
+	due, err := schedule.Due(ctx, time.Now())
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to load scheduled changes", "error", err)
+		return
+	}
+	for _, change := range due {
+		applyScheduledChange(ctx, change)
+	}
+}
+
+// Applies a scheduled change in a trace of its own, linked to the request that scheduled it.
+// The change is claimed first, so a change cancelled meanwhile or applied by another
+// instance sharing the database is skipped.
+func applyScheduledChange(ctx context.Context, change ScheduledChange) {
+	options := []trace.SpanStartOption{
+		trace.WithNewRoot(),
+		trace.WithAttributes(
+			attribute.String("schedule.id", change.ID),
+			attribute.String("schedule.effective_at", change.EffectiveAt.Format(time.RFC3339)),
+			attribute.Int64("schedule.delay_ms", time.Since(change.EffectiveAt).Milliseconds()),
+		),
+	}
+	if link, ok := scheduledChangeLink(change); ok {
+		options = append(options, trace.WithLinks(link))
+	}
+	ctx, span := tracer.Start(withAuthor(ctx, change.Author), "ApplyScheduledChange", options...)
+	defer span.End()
+
+	claimed, err := schedule.Transition(ctx, change.ID, SchedulePending, ScheduleApplied, "", time.Now().UTC())
+	if err != nil {
+		telemetry.RecordError(span, err)
+		slog.ErrorContext(ctx, "Failed to claim scheduled change", "schedule_id", change.ID, "error", err)
+		return
+	}
+	if !claimed {
+		return
+	}
+
+	cfg, err := updateConfig(ctx, ConfigUpdate{BasePrice: change.BasePrice, TaxRate: change.TaxRate})
+	if err != nil {
+		telemetry.RecordError(span, err)
+		slog.ErrorContext(ctx, "Failed to apply scheduled change", "schedule_id", change.ID, "error", err)
+		if _, err := schedule.Transition(ctx, change.ID, ScheduleApplied, ScheduleFailed, err.Error(), time.Now().UTC()); err != nil {
+			slog.ErrorContext(ctx, "Failed to record scheduled change failure", "schedule_id", change.ID, "error", err)
+		}
+		return
+	}
+
+	span.AddEvent("schedule.applied", trace.WithAttributes(
+		attribute.String("config.base_price", cfg.BasePrice.String()),
+		attribute.Float64("config.tax_rate", cfg.TaxRate),
+	))
+	change.Status = ScheduleApplied
+	notifyWebhooks(ctx, eventScheduleApplied, change)
+	slog.InfoContext(ctx, "Scheduled change applied", "schedule_id", change.ID, "effective_at", change.EffectiveAt)
+}
+
+// Returns the link to the span of the request that scheduled a change, false if it was not traced
+func scheduledChangeLink(change ScheduledChange) (trace.Link, bool) {
+	traceID, err := trace.TraceIDFromHex(change.TraceID)
+	if err != nil {
+		return trace.Link{}, false
+	}
+	spanID, err := trace.SpanIDFromHex(change.SpanID)
+	if err != nil {
+		return trace.Link{}, false
+	}
+	sc := trace.NewSpanContext(trace.SpanContextConfig{TraceID: traceID, SpanID: spanID, TraceFlags: trace.FlagsSampled, Remote: true})
+	return trace.Link{SpanContext: sc, Attributes: []attribute.KeyValue{attribute.String("link.reason", "scheduled_by")}}, true
+}
+
+// Lists the scheduled changes by effective time, optionally only those with the status query parameter
+func listScheduledChanges(w http.ResponseWriter, r *http.Request) {
+	list, err := schedule.List(r.Context())
+	if err != nil {
+		writeOperationError(w, r, err, "Error listing scheduled changes")
+		return
+	}
+	if status := r.URL.Query().Get("status"); status != "" {
+		filtered := []ScheduledChange{}
+		for _, c := range list {
+			if c.Status == status {
+				filtered = append(filtered, c)
+			}
+		}
+		list = filtered
+	}
+	writeJSON(w, http.StatusOK, list)
+}
+
+// Schedules a change of the default base price, tax rate or both
+func createScheduledChange(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	var request ScheduleRequest
+	if !decodeJSON(w, r, &request) {
+		return
+	}
+	if request.BasePrice == nil && request.TaxRate == nil {
+		writeProblem(w, r, "base_price or tax_rate is required", http.StatusBadRequest)
+		return
+	}
+	if request.BasePrice != nil {
+		if err := pricing.ValidateBasePrice(*request.BasePrice); err != nil {
+			writeProblem(w, r, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	if request.TaxRate != nil {
+		if err := pricing.ValidateTaxRate(*request.TaxRate); err != nil {
+			writeProblem(w, r, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	now := time.Now().UTC()
+	if !request.EffectiveAt.After(now) {
+		writeProblem(w, r, "effective_at must be in the future", http.StatusBadRequest)
+		return
+	}
+
+	span := trace.SpanFromContext(ctx)
+	change := ScheduledChange{
+		ID:          randomID(),
+		BasePrice:   request.BasePrice,
+		TaxRate:     request.TaxRate,
+		EffectiveAt: request.EffectiveAt.UTC(),
+		Status:      SchedulePending,
+		Author:      author(ctx),
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	if sc := span.SpanContext(); sc.IsValid() {
+		change.TraceID, change.SpanID = sc.TraceID().String(), sc.SpanID().String()
+	}
+	if err := schedule.Create(ctx, change); err != nil {
+		writeOperationError(w, r, err, "Error scheduling change")
+		return
+	}
+
+	span.SetAttributes(attribute.String("schedule.id", change.ID), attribute.String("schedule.effective_at", change.EffectiveAt.Format(time.RFC3339)))
+	w.Header().Set("Location", r.URL.Path+"/"+change.ID)
+	writeJSON(w, http.StatusCreated, change)
+	slog.InfoContext(ctx, "Change scheduled", "schedule_id", change.ID, "effective_at", change.EffectiveAt)
+}
+
+// Returns a single scheduled change
+func getScheduledChange(w http.ResponseWriter, r *http.Request) {
+	change, ok, err := schedule.Get(r.Context(), mux.Vars(r)["id"])
+	if err != nil {
+		writeOperationError(w, r, err, "Error loading scheduled change")
+		return
+	}
+	if !ok {
+		writeProblem(w, r, "Scheduled change not found", http.StatusNotFound)
+		return
+	}
+	writeJSON(w, http.StatusOK, change)
+}
+
+// Cancels a pending change. Cancelling a change that is no longer pending is answered with 409.
+func cancelScheduledChange(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	id := mux.Vars(r)["id"]
+	trace.SpanFromContext(ctx).SetAttributes(attribute.String("schedule.id", id))
+
+	cancelled, err := schedule.Transition(ctx, id, SchedulePending, ScheduleCancelled, "", time.Now().UTC())
+	if err != nil {
+		writeOperationError(w, r, err, "Error cancelling scheduled change")
+		return
+	}
+	change, ok, err := schedule.Get(ctx, id)
+	if err != nil {
+		writeOperationError(w, r, err, "Error loading scheduled change")
+		return
+	}
+	if !ok {
+		writeProblem(w, r, "Scheduled change not found", http.StatusNotFound)
+		return
+	}
+	if !cancelled {
+		writeProblem(w, r, "Scheduled change is already "+change.Status, http.StatusConflict)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, change)
+	slog.InfoContext(ctx, "Scheduled change cancelled", "schedule_id", id)
+}
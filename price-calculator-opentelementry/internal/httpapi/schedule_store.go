@@ -0,0 +1,258 @@
+package httpapi
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"otpl/pricecalculator/pricing"
+)
+
+// Statuses of a scheduled change, only pending changes are applied or cancelled
+const (
+	SchedulePending   = "pending"
+	ScheduleApplied   = "applied"
+	ScheduleCancelled = "cancelled"
+	ScheduleFailed    = "failed"
+)
+
+// ScheduledChange structure for a change of the default base price, tax rate or both
+// applied by the scheduler once it takes effect
+type ScheduledChange struct {
+	ID          string         `json:"id"`
+	BasePrice   *pricing.Money `json:"base_price,omitempty"`
+	TaxRate     *float64       `json:"tax_rate,omitempty"`
+	EffectiveAt time.Time      `json:"effective_at"`
+	Status      string         `json:"status"`
+	Error       string         `json:"error,omitempty"` // Why a failed change could not be applied
+	Author      string         `json:"author"`
+	TraceID     string         `json:"trace_id,omitempty"` // Trace of the request scheduling the change
+	SpanID      string         `json:"span_id,omitempty"`
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"` // When the status last changed
+}
+
+// ScheduleStore persists the scheduled changes
+type ScheduleStore interface {
+	// List returns all changes by effective time, then ID
+	List(ctx context.Context) ([]ScheduledChange, error)
+	// Due returns the pending changes effective at or before now, by effective time
+	Due(ctx context.Context, now time.Time) ([]ScheduledChange, error)
+	// Get returns the change with the ID, false if there is none
+	Get(ctx context.Context, id string) (ScheduledChange, bool, error)
+	// Create adds a change
+	Create(ctx context.Context, change ScheduledChange) error
+	// Transition moves a change from one status to another, recording the error of a failed
+	// change. False if there is no change with the ID in the from status, so concurrent
+	// transitions of the same change cannot both succeed.
+	Transition(ctx context.Context, id, from, to, reason string, at time.Time) (bool, error)
+}
+
+// Schedule store opened on startup
+var schedule ScheduleStore
+
+// memoryScheduleStore keeps the scheduled changes in memory, saved to a JSON file when it has one
+type memoryScheduleStore struct {
+	mu      sync.Mutex
+	changes map[string]ScheduledChange
+	file    *jsonFile
+}
+
+// Creates the in-memory schedule store, loading the changes from file
+func newMemoryScheduleStore(file *jsonFile) (*memoryScheduleStore, error) {
+	s := &memoryScheduleStore{changes: map[string]ScheduledChange{}, file: file}
+	if err := file.load(&s.changes); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Sorts changes by effective time, then ID
+func sortScheduledChanges(list []ScheduledChange) {
+	sort.Slice(list, func(i, j int) bool {
+		if !list[i].EffectiveAt.Equal(list[j].EffectiveAt) {
+			return list[i].EffectiveAt.Before(list[j].EffectiveAt)
+		}
+		return list[i].ID < list[j].ID
+	})
+}
+
+func (s *memoryScheduleStore) List(ctx context.Context) ([]ScheduledChange, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	list := make([]ScheduledChange, 0, len(s.changes))
+	for _, c := range s.changes {
+		list = append(list, c)
+	}
+	sortScheduledChanges(list)
+	return list, nil
+}
+
+func (s *memoryScheduleStore) Due(ctx context.Context, now time.Time) ([]ScheduledChange, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var due []ScheduledChange
+	for _, c := range s.changes {
+		if c.Status == SchedulePending && !c.EffectiveAt.After(now) {
+			due = append(due, c)
+		}
+	}
+	sortScheduledChanges(due)
+	return due, nil
+}
+
+func (s *memoryScheduleStore) Get(ctx context.Context, id string) (ScheduledChange, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c, ok := s.changes[id]
+	return c, ok, nil
+}
+
+func (s *memoryScheduleStore) Create(ctx context.Context, change ScheduledChange) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.changes[change.ID] = change
+	return s.file.persist(s.changes)
+}
+
+func (s *memoryScheduleStore) Transition(ctx context.Context, id, from, to, reason string, at time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c, ok := s.changes[id]
+	if !ok || c.Status != from {
+		return false, nil
+	}
+	c.Status, c.Error, c.UpdatedAt = to, reason, at
+	s.changes[id] = c
+	return true, s.file.persist(s.changes)
+}
+
+// sqlScheduleStore keeps the scheduled changes in a SQLite or PostgreSQL table,
+// with times in Unix nanoseconds so pending changes can be selected by effective time
+type sqlScheduleStore struct {
+	db *sql.DB
+}
+
+// Creates the schedule store in db, adding its table if needed
+func newSQLScheduleStore(db *sql.DB) (*sqlScheduleStore, error) {
+	_, err := db.Exec(`CREATE TABLE IF NOT EXISTS scheduled_changes (
+		id           TEXT PRIMARY KEY,
+		base_price   TEXT,
+		tax_rate     DOUBLE PRECISION,
+		effective_at BIGINT NOT NULL,
+		status       TEXT NOT NULL,
+		error        TEXT NOT NULL DEFAULT '',
+		author       TEXT NOT NULL DEFAULT '',
+		trace_id     TEXT NOT NULL DEFAULT '',
+		span_id      TEXT NOT NULL DEFAULT '',
+		created_at   BIGINT NOT NULL,
+		updated_at   BIGINT NOT NULL
+	)`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create scheduled_changes table: %v", err)
+	}
+	return &sqlScheduleStore{db: db}, nil
+}
+
+// Columns of a scheduled change in the order scanScheduledChange reads them
+const scheduledChangeColumns = `id, base_price, tax_rate, effective_at, status, error, author, trace_id, span_id, created_at, updated_at`
+
+// Reads a scheduled change selected with scheduledChangeColumns
+func scanScheduledChange(row interface{ Scan(...any) error }) (ScheduledChange, error) {
+	var c ScheduledChange
+	var basePrice sql.NullString
+	var taxRate sql.NullFloat64
+	var effectiveAt, createdAt, updatedAt int64
+	if err := row.Scan(&c.ID, &basePrice, &taxRate, &effectiveAt, &c.Status, &c.Error, &c.Author,
+		&c.TraceID, &c.SpanID, &createdAt, &updatedAt); err != nil {
+		return c, err
+	}
+	if basePrice.Valid {
+		price, err := pricing.ParseMoney(basePrice.String)
+		if err != nil {
+			return c, fmt.Errorf("invalid stored base price %q", basePrice.String)
+		}
+		c.BasePrice = &price
+	}
+	if taxRate.Valid {
+		c.TaxRate = &taxRate.Float64
+	}
+	c.EffectiveAt = time.Unix(0, effectiveAt).UTC()
+	c.CreatedAt = time.Unix(0, createdAt).UTC()
+	c.UpdatedAt = time.Unix(0, updatedAt).UTC()
+	return c, nil
+}
+
+// Reads the scheduled changes selected by a query
+func (s *sqlScheduleStore) query(ctx context.Context, query string, args ...any) ([]ScheduledChange, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list scheduled changes: %v", err)
+	}
+	defer rows.Close()
+
+	list := []ScheduledChange{}
+	for rows.Next() {
+		c, err := scanScheduledChange(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read scheduled change: %v", err)
+		}
+		list = append(list, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list scheduled changes: %v", err)
+	}
+	return list, nil
+}
+
+func (s *sqlScheduleStore) List(ctx context.Context) ([]ScheduledChange, error) {
+	return s.query(ctx, `SELECT `+scheduledChangeColumns+` FROM scheduled_changes ORDER BY effective_at, id`)
+}
+
+func (s *sqlScheduleStore) Due(ctx context.Context, now time.Time) ([]ScheduledChange, error) {
+	return s.query(ctx, `SELECT `+scheduledChangeColumns+` FROM scheduled_changes
+		WHERE status = $1 AND effective_at <= $2 ORDER BY effective_at, id`, SchedulePending, now.UnixNano())
+}
+
+func (s *sqlScheduleStore) Get(ctx context.Context, id string) (ScheduledChange, bool, error) {
+	c, err := scanScheduledChange(s.db.QueryRowContext(ctx, `SELECT `+scheduledChangeColumns+` FROM scheduled_changes WHERE id = $1`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return c, false, nil
+	}
+	if err != nil {
+		return c, false, fmt.Errorf("failed to load scheduled change: %v", err)
+	}
+	return c, true, nil
+}
+
+func (s *sqlScheduleStore) Create(ctx context.Context, c ScheduledChange) error {
+	var basePrice sql.NullString
+	if c.BasePrice != nil {
+		basePrice = sql.NullString{String: c.BasePrice.String(), Valid: true}
+	}
+	var taxRate sql.NullFloat64
+	if c.TaxRate != nil {
+		taxRate = sql.NullFloat64{Float64: *c.TaxRate, Valid: true}
+	}
+	_, err := s.db.ExecContext(ctx, `INSERT INTO scheduled_changes (`+scheduledChangeColumns+`)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)`,
+		c.ID, basePrice, taxRate, c.EffectiveAt.UnixNano(), c.Status, c.Error, c.Author,
+		c.TraceID, c.SpanID, c.CreatedAt.UnixNano(), c.UpdatedAt.UnixNano())
+	if err != nil {
+		return fmt.Errorf("failed to create scheduled change: %v", err)
+	}
+	return nil
+}
+
+func (s *sqlScheduleStore) Transition(ctx context.Context, id, from, to, reason string, at time.Time) (bool, error) {
+	result, err := s.db.ExecContext(ctx, `UPDATE scheduled_changes SET status = $3, error = $4, updated_at = $5
+		WHERE id = $1 AND status = $2`, id, from, to, reason, at.UnixNano())
+	if err != nil {
+		return false, fmt.Errorf("failed to update scheduled change: %v", err)
+	}
+	return affected(result)
+}
@@ -0,0 +1,513 @@
+// Package httpapi implements the price calculator service: the HTTP and gRPC APIs, the
+// stores behind them and the Kafka worker, started with Serve and RunWorker.
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/gorilla/mux"
+	"go.opentelemetry.io/otel/trace"
+
+	"otpl/pricecalculator/internal/config"
+	"otpl/pricecalculator/internal/telemetry"
+	"otpl/pricecalculator/pricing"
+)
+
+// Default base price and tax rate used when a request omits them
+var prices = newPriceStore(pricing.Config{})
+var tracer trace.Tracer
+
+// Storage for the base price and tax rate, opened on startup with the other stores
+var storage Storage
+
+// Maximum time to wait for in-flight requests to complete on shutdown
+const shutdownTimeout = 10 * time.Second
+
+// Address of the HTTP server unless HTTP_ADDR or the configuration file sets one
+const defaultHTTPAddr = ":8080"
+
+// Loads the configuration, initializes the telemetry and opens the stores shared by the server
+// and the worker, exiting on failure. Returns a function closing them, flushing the telemetry last.
+func startService(ctx context.Context) func() {
+	var closers []func()
+	shutdown := func() {
+		for i := len(closers) - 1; i >= 0; i-- {
+			closers[i]()
+		}
+	}
+
+	// Load the optional configuration file, environment variables take precedence over it
+	if err := loadConfigFile(); err != nil {
+		slog.Error("Failed to load config file", "error", err)
+		os.Exit(1)
+	}
+
+	// Initialize structured logging
+	if err := telemetry.InitLogging(config.Setting("LOG_LEVEL", fileConfig.Load().LogLevel)); err != nil {
+		slog.Error("Failed to initialize logging", "error", err)
+		os.Exit(1)
+	}
+	slog.Info("Price Calculator Project")
+
+	// Initialize OpenTelemetry
+	cleanup, err := initOpenTelemetry(ctx)
+	if err != nil {
+		slog.Error("Failed to initialize OpenTelemetry", "error", err)
+		os.Exit(1)
+	}
+	closers = append(closers, cleanup) // Ensure resources are cleaned up on exit
+
+	// Check the rounding mode for calculated amounts
+	if _, err := pricing.ParseRoundingMode(os.Getenv("ROUNDING_MODE")); err != nil {
+		slog.Error("Invalid rounding mode", "error", err)
+		os.Exit(1)
+	}
+	// Check the CORS settings with the origins of CORS_ALLOWED_ORIGINS
+	if err := corsConfig().Validate(); err != nil {
+		slog.Error("Invalid CORS settings", "error", err)
+		os.Exit(1)
+	}
+
+	// Open the stores and restore the persisted base price and tax rate
+	closeStores, err := openStores()
+	if err != nil {
+		slog.Error("Failed to open storage", "error", err)
+		os.Exit(1)
+	}
+	closers = append(closers, closeStores)
+	cfg, err := storage.Load(ctx)
+	if err != nil {
+		slog.Error("Failed to load configuration", "error", err)
+		os.Exit(1)
+	}
+	// Nothing stored yet, start from the defaults in the configuration file
+	if cfg.BasePrice.IsZero() && cfg.TaxRate == 0 {
+		filePricing := fileConfig.Load().Pricing
+		if basePrice, _ := filePricing.BasePriceMoney(); basePrice != nil {
+			cfg.BasePrice = *basePrice
+		}
+		if filePricing.TaxRate != nil {
+			cfg.TaxRate = *filePricing.TaxRate
+		}
+		if err := storage.Save(ctx, cfg); err != nil {
+			slog.Error("Failed to save configuration", "error", err)
+			os.Exit(1)
+		}
+	}
+	prices.Set(cfg)
+	if err := recordStartupVersion(withAuthor(ctx, "system")); err != nil {
+		slog.Error("Failed to record configuration version", "error", err)
+		os.Exit(1)
+	}
+	syncTaxRateHistory(withAuthor(ctx, "system"), cfg.TaxRate)
+	configLoaded.Store(true)
+	slog.Info("Loaded configuration", "base_price", cfg.BasePrice.String(), "tax_rate", cfg.TaxRate)
+
+	// Connect to the optional calculation cache
+	resultCache, err = openResultCache()
+	if err != nil {
+		slog.Error("Failed to open calculation cache", "error", err)
+		os.Exit(1)
+	}
+	if resultCache != nil {
+		closers = append(closers, func() { resultCache.Close() })
+	}
+
+	// Publish the calculations to the optional Kafka topic, flushed on shutdown
+	if eventWriter = openEventWriter(); eventWriter != nil {
+		closers = append(closers, func() { eventWriter.Close() })
+	}
+	return shutdown
+}
+
+// Serve runs the HTTP and gRPC servers until interrupted or terminated, exiting on failure
+func Serve(listenOpts ListenOptions) {
+	ctx := context.Background()
+	shutdown := startService(ctx)
+	defer shutdown()
+
+	var err error
+	envFault, err = loadChaosFromEnv()
+	if err != nil {
+		slog.Error("Invalid chaos settings", "error", err)
+		os.Exit(1)
+	}
+	if envFault != nil || len(fileConfig.Load().Chaos) > 0 {
+		slog.Warn("Chaos fault injection is enabled")
+	}
+	if err := loadSimulatedDelay(); err != nil {
+		slog.Error("Invalid simulated delay", "error", err)
+		os.Exit(1)
+	}
+
+	if _, err := requestTimeout(""); err != nil {
+		slog.Error("Invalid request timeout", "error", err)
+		os.Exit(1)
+	}
+	if _, err := maxBodyBytes(); err != nil {
+		slog.Error("Invalid request body limit", "error", err)
+		os.Exit(1)
+	}
+	if _, _, err := rateLimit(); err != nil {
+		slog.Error("Invalid rate limit", "error", err)
+		os.Exit(1)
+	}
+	if len(apiKeys()) == 0 {
+		slog.Warn("No API keys configured, the set and admin endpoints are not authenticated")
+	}
+
+	router, err := newRouter(ctx)
+	if err != nil {
+		slog.Error("Failed to create router", "error", err)
+		os.Exit(1)
+	}
+
+	// Stop on an interrupt or a termination signal
+	signalCtx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Load the server certificates, reloaded until shutdown
+	tlsConfig, err := newServerTLSConfig(signalCtx)
+	if err != nil {
+		slog.Error("Failed to configure TLS", "error", err)
+		os.Exit(1)
+	}
+
+	// Start the HTTP server in the background
+	httpAddr, err := listenOpts.httpAddr()
+	if err != nil {
+		slog.Error("Invalid HTTP address", "error", err)
+		os.Exit(1)
+	}
+	httpListener, err := listen(httpAddr)
+	if err != nil {
+		slog.Error("Failed to listen", "addr", httpAddr, "error", err)
+		os.Exit(1)
+	}
+	server := &http.Server{Handler: handleCORS(router), TLSConfig: tlsConfig}
+	serverErr := make(chan error, 2)
+	go func() {
+		addr := httpListener.Addr()
+		slog.Info("Server is running", "network", addr.Network(), "addr", addr.String(), "tls", tlsConfig != nil)
+		if tlsConfig != nil {
+			serverErr <- server.ServeTLS(httpListener, "", "")
+			return
+		}
+		serverErr <- server.Serve(httpListener)
+	}()
+
+	// Start the gRPC server in the background
+	grpcAddr := config.Setting("GRPC_ADDR", fileConfig.Load().Server.GRPCAddr)
+	if grpcAddr == "" {
+		grpcAddr = defaultGRPCAddr
+	}
+	grpcServer := newGRPCServer(tlsConfig)
+	listener, err := net.Listen("tcp", grpcAddr)
+	if err != nil {
+		slog.Error("Failed to listen", "addr", grpcAddr, "error", err)
+		os.Exit(1)
+	}
+	go func() {
+		slog.Info("gRPC server is running", "addr", grpcAddr)
+		serverErr <- grpcServer.Serve(listener)
+	}()
+
+	// Apply the scheduled price changes as they take effect until shutdown
+	go runScheduler(signalCtx)
+
+	// Evaluate the service level objectives until shutdown
+	go runSLOEvaluator(signalCtx)
+
+	// Refresh the exchange rates on the configured schedule until shutdown
+	go runRateRefresher(signalCtx)
+
+	// Remove the expired idempotency keys until shutdown
+	go runIdempotencyExpiry(signalCtx)
+
+	// Apply changes to the configuration file until shutdown
+	if err := watchConfigFile(signalCtx); err != nil {
+		slog.Error("Failed to watch config file", "error", err)
+	}
+	// Wait for an interrupt, a termination signal or a server failure
+	select {
+	case err := <-serverErr:
+		slog.Error("Server error", "error", err)
+	case <-signalCtx.Done():
+		slog.Info("Shutting down server")
+	}
+
+	// Drain in-flight requests before the deferred cleanup flushes the telemetry
+	shutdownCtx, cancel := context.WithTimeout(ctx, shutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		slog.Error("Error shutting down server", "error", err)
+	}
+
+	// Drain in-flight RPCs, closing the remaining ones once the timeout expires
+	stopped := make(chan struct{})
+	go func() {
+		grpcServer.GracefulStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-shutdownCtx.Done():
+		grpcServer.Stop()
+	}
+}
+
+// Creates the router of the HTTP API, every endpoint traced and measured
+func newRouter(ctx context.Context) (*mux.Router, error) {
+	// Initialize Gorilla Mux router
+	router := mux.NewRouter()
+	router.Use(withRequestID, limitRequestBody)
+
+	// Health endpoints are not traced to keep probes out of the trace backend
+	router.HandleFunc("/healthz", healthz).Methods("GET")
+	router.HandleFunc("/readyz", readyz).Methods("GET")
+
+	// Health of the soft dependencies, traced so failing probes can be followed
+	router.Handle("/status", instrumentHandler(getStatus, "GetStatus")).Methods("GET")
+
+	// Service level objectives and their burn rates
+	router.Handle("/slo", instrumentHandler(getSLOs, "GetSLOs")).Methods("GET")
+
+	// Metrics for scraping when the Prometheus exporter is enabled
+	if metricsHandler != nil {
+		router.Handle("/metrics", metricsHandler).Methods("GET")
+	}
+
+	// API specification and its Swagger UI
+	openAPIHandler, err := serveOpenAPI()
+	if err != nil {
+		return nil, fmt.Errorf("invalid OpenAPI specification: %v", err)
+	}
+	router.HandleFunc("/openapi.json", openAPIHandler).Methods("GET")
+	router.HandleFunc("/docs", serveDocs).Methods("GET")
+
+	// Demo web UI, disabled with the ui feature
+	router.HandleFunc("/", requireFeature("ui", serveUI)).Methods("GET")
+	router.HandleFunc("/ui/settings.json", requireFeature("ui", getUISettings)).Methods("GET")
+	router.PathPrefix("/ui/").HandlerFunc(requireFeature("ui", serveUIAssets)).Methods("GET")
+
+	// Define the API endpoints with OpenTelemetry tracing and metrics
+	router.Handle("/tenants", instrumentHandler(listTenants, "ListTenants")).Methods("GET")
+	router.Handle("/tenants", instrumentHandler(requireAPIKey(createTenant), "CreateTenant")).Methods("POST")
+	router.Handle("/tenants/{id}", instrumentHandler(getTenant, "GetTenant")).Methods("GET")
+	router.Handle("/tenants/{id}", instrumentHandler(requireAPIKey(updateTenant), "UpdateTenant")).Methods("PUT")
+	router.Handle("/tenants/{id}", instrumentHandler(requireAPIKey(deleteTenant), "DeleteTenant")).Methods("DELETE")
+	router.Handle("/customers", instrumentHandler(listCustomers, "ListCustomers")).Methods("GET")
+	router.Handle("/customers", instrumentHandler(requireAPIKey(createCustomer), "CreateCustomer")).Methods("POST")
+	router.Handle("/customers/{id}", instrumentHandler(getCustomer, "GetCustomer")).Methods("GET")
+	router.Handle("/customers/{id}", instrumentHandler(requireAPIKey(updateCustomer), "UpdateCustomer")).Methods("PUT")
+	router.Handle("/customers/{id}", instrumentHandler(requireAPIKey(deleteCustomer), "DeleteCustomer")).Methods("DELETE")
+
+	// Pricing endpoints, served for the default configuration and per tenant under /tenants/{tenant}
+	for _, api := range []*mux.Router{router, router.PathPrefix("/tenants/{tenant}").Subrouter()} {
+		api.Handle("/calculate", instrumentHandler(negotiateVersion(calculatePrice, calculatePriceV2), "CalculatePrice")).Methods("POST")
+		api.Handle("/calculate/cart", instrumentHandler(requireFeature("cart", negotiateVersion(calculateCart, calculateCartV2)), "CalculateCart")).Methods("POST")
+		api.Handle("/v2/calculate", instrumentHandler(calculatePriceV2, "CalculatePriceV2")).Methods("POST")
+		api.Handle("/v2/calculate/cart", instrumentHandler(requireFeature("cart", calculateCartV2), "CalculateCartV2")).Methods("POST")
+		api.Handle("/calculate/subscription", instrumentHandler(requireFeature("subscription", calculateSubscription), "CalculateSubscription")).Methods("POST")
+		api.Handle("/calculate/batch", instrumentHandler(requireFeature("batch", calculateBatch), "CalculateBatch")).Methods("POST")
+		api.Handle("/calculate/selling-price", instrumentHandler(calculateSellingPrice, "CalculateSellingPrice")).Methods("POST")
+		api.Handle("/calculate/margin", instrumentHandler(calculateMargin, "CalculateMargin")).Methods("POST")
+		api.Handle("/calculate/unit", instrumentHandler(calculateUnitPrice, "CalculateUnitPrice")).Methods("POST")
+		api.Handle("/calculate/refund", instrumentHandler(calculateRefund, "CalculateRefund")).Methods("POST")
+		api.Handle("/simulate", instrumentHandler(simulatePrices, "SimulatePrices")).Methods("POST")
+		api.Handle("/quotes", instrumentHandler(createQuote, "CreateQuote")).Methods("POST")
+		api.Handle("/quotes/{id}", instrumentHandler(getQuote, "GetQuote")).Methods("GET")
+		api.Handle("/quotes/{id}/finalize", instrumentHandler(finalizeQuote, "FinalizeQuote")).Methods("POST")
+		api.Handle("/quotes/{id}/render", instrumentHandler(renderQuote, "RenderQuote")).Methods("POST")
+		api.Handle("/discounts", instrumentHandler(listDiscounts, "ListDiscounts")).Methods("GET")
+		api.Handle("/discounts", instrumentHandler(requireAPIKey(createDiscount), "CreateDiscount")).Methods("POST")
+		api.Handle("/discounts/{id}", instrumentHandler(getDiscount, "GetDiscount")).Methods("GET")
+		api.Handle("/discounts/{id}", instrumentHandler(requireAPIKey(updateDiscount), "UpdateDiscount")).Methods("PUT")
+		api.Handle("/discounts/{id}", instrumentHandler(requireAPIKey(deleteDiscount), "DeleteDiscount")).Methods("DELETE")
+	}
+
+	router.Handle("/config/versions", instrumentHandler(listConfigVersions, "ListConfigVersions")).Methods("GET")
+	router.Handle("/audit", instrumentHandler(requireAPIKey(listAudit), "ListAudit")).Methods("GET")
+	router.Handle("/config/versions/{n}", instrumentHandler(getConfigVersion, "GetConfigVersion")).Methods("GET")
+	router.Handle("/config/rollback/{n}", instrumentHandler(requireAPIKey(rollbackConfig), "RollbackConfig")).Methods("POST")
+	router.Handle("/v1/config", instrumentHandler(getConfigV1, "GetConfigV1")).Methods("GET")
+	router.Handle("/v1/config", instrumentHandler(requireAPIKey(putConfigV1), "PutConfigV1")).Methods("PUT")
+	router.Handle("/v1/config", instrumentHandler(requireAPIKey(patchConfigV1), "PatchConfigV1")).Methods("PATCH")
+	router.Handle("/config/bulk", instrumentHandler(requireAPIKey(bulkUpdateConfig), "BulkUpdateConfig")).Methods("POST")
+	router.Handle("/config/schedule", instrumentHandler(listScheduledChanges, "ListScheduledChanges")).Methods("GET")
+	router.Handle("/config/schedule", instrumentHandler(requireAPIKey(createScheduledChange), "CreateScheduledChange")).Methods("POST")
+	router.Handle("/config/schedule/{id}", instrumentHandler(getScheduledChange, "GetScheduledChange")).Methods("GET")
+	router.Handle("/config/schedule/{id}/cancel", instrumentHandler(requireAPIKey(cancelScheduledChange), "CancelScheduledChange")).Methods("POST")
+	router.Handle("/config/tax-rates", instrumentHandler(listTaxRateHistory, "ListTaxRateHistory")).Methods("GET")
+	router.Handle("/config/tax-rates", instrumentHandler(requireAPIKey(createTaxRatePeriod), "CreateTaxRatePeriod")).Methods("POST")
+	router.Handle("/convert", instrumentHandler(convertCurrency, "ConvertCurrency")).Methods("GET")
+	router.Handle("/rates", instrumentHandler(getRates, "GetExchangeRates")).Methods("GET")
+	router.Handle("/history", instrumentHandler(requireFeature("history", listHistory), "ListHistory")).Methods("GET")
+	router.Handle("/coupons", instrumentHandler(listCoupons, "ListCoupons")).Methods("GET")
+	router.Handle("/coupons", instrumentHandler(requireAPIKey(createCoupon), "CreateCoupon")).Methods("POST")
+	router.Handle("/coupons/{id}", instrumentHandler(getCoupon, "GetCoupon")).Methods("GET")
+	router.Handle("/coupons/{id}", instrumentHandler(requireAPIKey(updateCoupon), "UpdateCoupon")).Methods("PUT")
+	router.Handle("/coupons/{id}", instrumentHandler(requireAPIKey(deleteCoupon), "DeleteCoupon")).Methods("DELETE")
+	router.Handle("/tax/rules", instrumentHandler(listTaxRules, "ListTaxRules")).Methods("GET")
+	router.Handle("/tax/rules", instrumentHandler(requireAPIKey(createTaxRule), "CreateTaxRule")).Methods("POST")
+	router.Handle("/tax/rules/{id}", instrumentHandler(getTaxRule, "GetTaxRule")).Methods("GET")
+	router.Handle("/tax/rules/{id}", instrumentHandler(requireAPIKey(updateTaxRule), "UpdateTaxRule")).Methods("PUT")
+	router.Handle("/tax/rules/{id}", instrumentHandler(requireAPIKey(deleteTaxRule), "DeleteTaxRule")).Methods("DELETE")
+	router.Handle("/surcharges", instrumentHandler(listSurcharges, "ListSurcharges")).Methods("GET")
+	router.Handle("/surcharges", instrumentHandler(requireAPIKey(createSurcharge), "CreateSurcharge")).Methods("POST")
+	router.Handle("/surcharges/{id}", instrumentHandler(getSurcharge, "GetSurcharge")).Methods("GET")
+	router.Handle("/surcharges/{id}", instrumentHandler(requireAPIKey(updateSurcharge), "UpdateSurcharge")).Methods("PUT")
+	router.Handle("/surcharges/{id}", instrumentHandler(requireAPIKey(deleteSurcharge), "DeleteSurcharge")).Methods("DELETE")
+	router.Handle("/products", instrumentHandler(listProducts, "ListProducts")).Methods("GET")
+	router.Handle("/products", instrumentHandler(requireAPIKey(createProduct), "CreateProduct")).Methods("POST")
+	router.Handle("/products/{sku}", instrumentHandler(getProduct, "GetProduct")).Methods("GET")
+	router.Handle("/products/{sku}", instrumentHandler(requireAPIKey(updateProduct), "UpdateProduct")).Methods("PUT")
+	router.Handle("/products/{sku}", instrumentHandler(requireAPIKey(deleteProduct), "DeleteProduct")).Methods("DELETE")
+	router.Handle("/webhooks", instrumentHandler(requireAPIKey(listWebhooks), "ListWebhooks")).Methods("GET")
+	router.Handle("/webhooks", instrumentHandler(requireAPIKey(createWebhook), "CreateWebhook")).Methods("POST")
+	router.Handle("/webhooks/{id}", instrumentHandler(requireAPIKey(getWebhook), "GetWebhook")).Methods("GET")
+	router.Handle("/webhooks/{id}", instrumentHandler(requireAPIKey(deleteWebhook), "DeleteWebhook")).Methods("DELETE")
+	router.Handle("/admin/flags", instrumentHandler(requireAPIKey(listFlags), "ListFlags")).Methods("GET")
+	router.Handle("/admin/flags/{name}", instrumentHandler(requireAPIKey(getFlag), "GetFlag")).Methods("GET")
+	router.Handle("/admin/flags/{name}", instrumentHandler(requireAPIKey(updateFlag), "UpdateFlag")).Methods("PUT")
+	router.Handle("/admin/flags/{name}", instrumentHandler(requireAPIKey(resetFlag), "ResetFlag")).Methods("DELETE")
+	router.Handle("/admin/sampling", instrumentHandler(requireAPIKey(getSampling), "GetSampling")).Methods("GET")
+	router.Handle("/admin/sampling", instrumentHandler(requireAPIKey(updateSampling), "UpdateSampling")).Methods("PUT")
+	router.Handle("/admin/telemetry", instrumentHandler(requireAPIKey(getTelemetry), "GetTelemetry")).Methods("GET")
+	router.Handle("/admin/telemetry/flush", instrumentHandler(requireAPIKey(flushTelemetry), "FlushTelemetry")).Methods("POST")
+	router.Handle("/admin/telemetry/stdout", instrumentHandler(requireAPIKey(updateStdoutExporter), "UpdateStdoutExporter")).Methods("PUT")
+	router.Handle("/admin/telemetry/exporter", instrumentHandler(requireAPIKey(updateTraceExporter), "UpdateTraceExporter")).Methods("PUT")
+
+	// Legacy configuration routes, replaced by /v1/config and disabled with the legacy_routes feature
+	router.Handle("/setBasePrice/{value}", instrumentHandler(deprecatedRoute(requireAPIKey(setBasePrice)), "SetBasePrice")).Methods("POST")
+	router.Handle("/setTaxRate/{value}", instrumentHandler(deprecatedRoute(requireAPIKey(setTaxRate)), "SetTaxRate")).Methods("POST")
+	router.Handle("/config", instrumentHandler(deprecatedRoute(getConfig), "GetConfig")).Methods("GET")
+
+	// REST gateway of the gRPC API, generated from the same proto definitions
+	gateway, err := newGateway(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create gRPC gateway: %v", err)
+	}
+	router.Handle("/v1/calculate", instrumentHandler(gateway.ServeHTTP, "GatewayCalculate")).Methods("POST")
+	router.Handle("/v1/base-price", instrumentHandler(requireAPIKey(gateway.ServeHTTP), "GatewaySetBasePrice")).Methods("PUT")
+	router.Handle("/v1/tax-rate", instrumentHandler(requireAPIKey(gateway.ServeHTTP), "GatewaySetTaxRate")).Methods("PUT")
+	return router, nil
+}
+
+// Calculates the total price based on the base price and tax rate
+func calculatePrice(w http.ResponseWriter, r *http.Request) {
+	// Decode the request body, an empty body uses the stored defaults
+	var request pricing.PriceRequest
+	if !decodeJSON(w, r, &request) {
+		return
+	}
+	formatter, ok := requestFormatter(w, r)
+	if !ok {
+		return
+	}
+	ctx, explanation, ok := requestExplanation(w, r)
+	if !ok {
+		return
+	}
+
+	response, err := calculate(ctx, request)
+	if err != nil {
+		writeOperationError(w, r, err, "Error calculating price")
+		return
+	}
+	formatPrices(r, formatter, &response)
+	response.Explanation = explanation
+
+	// Prepare the response
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		slog.ErrorContext(r.Context(), "Error encoding response", "error", err)
+		writeProblem(w, r, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+}
+
+// Sets the base price from the request
+func setBasePrice(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	value := vars["value"]
+
+	parsed, err := pricing.ParseMoney(value) // Parse the base price from the URL
+	if err != nil {
+		slog.WarnContext(r.Context(), "Invalid base price", "error", err)
+		writeProblem(w, r, "Invalid base price", http.StatusBadRequest)
+		return
+	}
+
+	// Persist the new value before applying it
+	if _, err := updateBasePrice(r.Context(), parsed); err != nil {
+		writeOperationError(w, r, err, "Error saving base price")
+		return
+	}
+
+	// Respond with a success message
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(map[string]string{"message": "Base price set"}); err != nil {
+		slog.ErrorContext(r.Context(), "Error encoding response", "error", err)
+		writeProblem(w, r, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+}
+
+// Sets the tax rate from the request
+func setTaxRate(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	value := vars["value"]
+
+	parsed, err := strconv.ParseFloat(value, 64) // Parse the tax rate from the URL
+	if err != nil {
+		slog.WarnContext(r.Context(), "Invalid tax rate", "error", err)
+		writeProblem(w, r, "Invalid tax rate", http.StatusBadRequest)
+		return
+	}
+
+	// Persist the new value before applying it
+	if _, err := updateTaxRate(r.Context(), parsed); err != nil {
+		writeOperationError(w, r, err, "Error saving tax rate")
+		return
+	}
+
+	// Respond with a success message
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(map[string]string{"message": "Tax rate set"}); err != nil {
+		slog.ErrorContext(r.Context(), "Error encoding response", "error", err)
+		writeProblem(w, r, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+}
+
+// Returns the persisted base price and tax rate
+func getConfig(w http.ResponseWriter, r *http.Request) {
+	cfg, err := storage.Load(r.Context())
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Error loading configuration", "error", err)
+		writeProblem(w, r, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, cfg)
+}
+
+// Writes a JSON response with the given status code
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		slog.Error("Error encoding response", "error", err)
+	}
+}
@@ -0,0 +1,755 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric/noop"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+
+	"otpl/pricecalculator/internal/config"
+	"otpl/pricecalculator/internal/telemetry"
+	"otpl/pricecalculator/pricing"
+)
+
+// Loads the configuration file, creates the metric instruments and discards the logs once for the handler tests
+var setupOnce sync.Once
+var setupErr error
+
+// testServer is the HTTP API served with empty in-memory stores, recording the spans it ends
+type testServer struct {
+	*httptest.Server
+	spans *tracetest.SpanRecorder
+}
+
+// Starts the HTTP API for a test, with the default base price of 100 and tax rate of 20
+func newTestServer(t testing.TB) *testServer {
+	t.Helper()
+	setupOnce.Do(func() {
+		slog.SetDefault(slog.New(slog.NewTextHandler(io.Discard, nil)))
+		if setupErr = loadConfigFile(); setupErr != nil {
+			return
+		}
+		setupErr = initMetrics(noop.NewMeterProvider().Meter("price-calculator"))
+	})
+	if setupErr != nil {
+		t.Fatalf("setup: %v", setupErr)
+	}
+	t.Setenv("API_KEYS", "")
+
+	storage = &memoryStorage{}
+	if err := openMemoryStores(""); err != nil {
+		t.Fatalf("openMemoryStores: %v", err)
+	}
+	prices.Set(pricing.Config{BasePrice: pricing.MoneyFromFloat(100), TaxRate: 20})
+
+	// Record the ended spans, and export them through a pipeline for the admin endpoints
+	sampler, err := telemetry.NewDynamicSampler(telemetry.SamplingConfig{Sampler: telemetry.SamplerAlwaysOn})
+	if err != nil {
+		t.Fatalf("NewDynamicSampler: %v", err)
+	}
+	pipeline, err := telemetry.NewSpanPipeline("memory", tracetest.NewInMemoryExporter(), config.BatchSpanProcessor{})
+	if err != nil {
+		t.Fatalf("NewSpanPipeline: %v", err)
+	}
+	spans := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithSampler(sampler),
+		sdktrace.WithSpanProcessor(spans),
+		sdktrace.WithSpanProcessor(pipeline),
+	)
+	otel.SetTracerProvider(provider)
+	tracer = provider.Tracer("price-calculator")
+	providers = &telemetry.Telemetry{TracerProvider: provider, Sampler: sampler, Pipeline: pipeline}
+
+	router, err := newRouter(context.Background())
+	if err != nil {
+		t.Fatalf("newRouter: %v", err)
+	}
+	server := httptest.NewServer(handleCORS(router))
+	t.Cleanup(func() {
+		server.Close()
+		provider.Shutdown(context.Background())
+	})
+	return &testServer{Server: server, spans: spans}
+}
+
+// Sends a request with a JSON body and the given headers, returning the response and its body
+func (s *testServer) do(t testing.TB, method, path, body string, header ...string) (*http.Response, []byte) {
+	t.Helper()
+	req, err := http.NewRequest(method, s.URL+path, strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("NewRequest: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for i := 0; i+1 < len(header); i += 2 {
+		req.Header.Set(header[i], header[i+1])
+	}
+	resp, err := s.Client().Do(req)
+	if err != nil {
+		t.Fatalf("%s %s: %v", method, path, err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("reading %s %s: %v", method, path, err)
+	}
+	return resp, data
+}
+
+// Returns the ended spans of the kind named name, waiting for n of them since the server
+// ends its span after the response is sent. Server spans are named after the operation.
+func (s *testServer) ended(t testing.TB, kind trace.SpanKind, name string, n int) []sdktrace.ReadOnlySpan {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		var matches []sdktrace.ReadOnlySpan
+		for _, span := range s.spans.Ended() {
+			if span.SpanKind() == kind && span.Name() == name {
+				matches = append(matches, span)
+			}
+		}
+		if len(matches) >= n {
+			return matches
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("got %d ended %s %s spans, want %d", len(matches), kind, name, n)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// Returns the value of the span attribute key, false if the span does not have it
+func spanAttribute(span sdktrace.ReadOnlySpan, key string) (attribute.Value, bool) {
+	for _, kv := range span.Attributes() {
+		if string(kv.Key) == key {
+			return kv.Value, true
+		}
+	}
+	return attribute.Value{}, false
+}
+
+// Fails the test unless the span has the attribute key set to want
+func assertAttribute(t testing.TB, span sdktrace.ReadOnlySpan, key string, want any) {
+	t.Helper()
+	got, ok := spanAttribute(span, key)
+	if !ok {
+		t.Errorf("%s span has no %s attribute", span.Name(), key)
+		return
+	}
+	if got.AsInterface() != want {
+		t.Errorf("%s span %s = %v, want %v", span.Name(), key, got.AsInterface(), want)
+	}
+}
+
+// Decodes a JSON response body into v
+func decodeBody(t testing.TB, data []byte, v any) {
+	t.Helper()
+	if err := json.Unmarshal(data, v); err != nil {
+		t.Fatalf("decoding %s: %v", data, err)
+	}
+}
+
+// routeTest is a request to one route of the router and the response and server span it should produce.
+// The cases of a table run in order against one server, so later cases can use what earlier ones created.
+type routeTest struct {
+	method    string
+	path      string // {name} is replaced with the ID captured under name
+	body      string // {name} is replaced as in the path
+	status    int
+	operation string // Name of the server span, empty for the untraced routes
+	want      string // Part of the response body
+	capture   string // Captures the id, sku or calculation_id field of the response under this name
+}
+
+// Runs the cases of a route table, asserting the status, the body and the server span of each
+func runRouteTests(t *testing.T, tests []routeTest) {
+	s := newTestServer(t)
+	ids := map[string]string{}
+	spans := map[string]int{}
+	for _, tt := range tests {
+		path, body := tt.path, tt.body
+		for name, id := range ids {
+			path = strings.ReplaceAll(path, "{"+name+"}", id)
+			body = strings.ReplaceAll(body, "{"+name+"}", id)
+		}
+		if !t.Run(tt.method+" "+path, func(t *testing.T) {
+			resp, data := s.do(t, tt.method, path, body)
+			if resp.StatusCode != tt.status {
+				t.Fatalf("status = %d, want %d: %s", resp.StatusCode, tt.status, data)
+			}
+			if !strings.Contains(string(data), tt.want) {
+				t.Errorf("body = %s, want it to contain %s", data, tt.want)
+			}
+			if tt.capture != "" {
+				var created struct {
+					ID            string `json:"id"`
+					SKU           string `json:"sku"`
+					CalculationID string `json:"calculation_id"`
+				}
+				decodeBody(t, data, &created)
+				ids[tt.capture] = created.ID + created.SKU + created.CalculationID
+			}
+			if tt.operation == "" {
+				return
+			}
+
+			spans[tt.operation]++
+			server := s.ended(t, trace.SpanKindServer, tt.operation, spans[tt.operation])[spans[tt.operation]-1]
+			assertAttribute(t, server, "http.status_code", int64(tt.status))
+			if failed := server.Status().Code == codes.Error; failed && tt.status < 400 || !failed && tt.status >= 500 {
+				t.Errorf("server span status = %v for a %d response", server.Status(), tt.status)
+			}
+			if resp.Header.Get("traceparent") == "" && resp.Header.Get("X-Trace-Id") == "" {
+				t.Error("response does not carry its trace")
+			}
+		}) {
+			return
+		}
+	}
+}
+
+func TestRoutes(t *testing.T) {
+	runRouteTests(t, []routeTest{
+		// Untraced routes
+		{method: "GET", path: "/healthz", status: 200},
+		{method: "GET", path: "/readyz", status: 503, want: `"not ready"`}, // No collector to connect to
+		{method: "GET", path: "/openapi.json", status: 200, want: `"openapi"`},
+		{method: "GET", path: "/docs", status: 200},
+		{method: "GET", path: "/", status: 200},
+		{method: "GET", path: "/ui/settings.json", status: 200},
+		{method: "GET", path: "/ui/app.js", status: 200},
+
+		// Dependencies and objectives
+		{method: "GET", path: "/status", status: 200, operation: "GetStatus", want: `"status"`},
+		{method: "GET", path: "/slo", status: 200, operation: "GetSLOs", want: `"objectives"`},
+
+		// Pricing
+		{method: "POST", path: "/calculate", body: `{"quantity": 2}`, status: 200, operation: "CalculatePrice", want: `"total_price":240`},
+		{method: "POST", path: "/calculate", body: `{"quantity": -1}`, status: 400, operation: "CalculatePrice"},
+		{method: "POST", path: "/calculate", body: `{"quantity": 3}`, status: 200, operation: "CalculatePrice", capture: "calculation"},
+		{method: "POST", path: "/calculate/refund", body: `{"calculation_id": "{calculation}", "quantity": -1}`, status: 200, operation: "CalculateRefund", want: `"total_price":-120`},
+		{method: "POST", path: "/calculate/refund", body: `{"calculation_id": "{calculation}", "quantity": -3}`, status: 400, operation: "CalculateRefund"},
+		{method: "POST", path: "/calculate/refund", body: `{"calculation_id": "unknown", "quantity": -1}`, status: 404, operation: "CalculateRefund"},
+		{method: "POST", path: "/v2/calculate", body: `{"quantity": 2}`, status: 200, operation: "CalculatePriceV2", want: `"240`},
+		{method: "POST", path: "/v2/calculate", body: `{"quantity": -1}`, status: 400, operation: "CalculatePriceV2"},
+		{method: "POST", path: "/calculate/selling-price", body: `{"cost": 60, "margin": 40}`, status: 200, operation: "CalculateSellingPrice", want: `"price":100`},
+		{method: "POST", path: "/calculate/selling-price", body: `{"cost": 60}`, status: 400, operation: "CalculateSellingPrice"},
+		{method: "POST", path: "/calculate/margin", body: `{"cost": 60, "price": 100}`, status: 200, operation: "CalculateMargin", want: `"margin":40`},
+		{method: "POST", path: "/calculate/margin", body: `{"cost": 60, "price": -1}`, status: 400, operation: "CalculateMargin"},
+		{method: "POST", path: "/calculate/unit", body: `{"unit_price": 10, "quantity": 3, "tax_rate": 0}`, status: 200, operation: "CalculateUnitPrice", want: `"total_price":30`},
+		{method: "POST", path: "/calculate/unit", body: `{"unit_price": -10}`, status: 400, operation: "CalculateUnitPrice"},
+		{method: "POST", path: "/calculate/cart", body: `{"items": [{"name": "Book", "unit_price": 10, "quantity": 2}]}`, status: 200, operation: "CalculateCart", want: `"Book"`},
+		{method: "POST", path: "/calculate/cart", body: `{"items": []}`, status: 400, operation: "CalculateCart"},
+		{method: "POST", path: "/v2/calculate/cart", body: `{"lines": [{"name": "Book", "unit_price": {"value": "10"}, "quantity": 2}]}`, status: 200, operation: "CalculateCartV2", want: `"Book"`},
+		{method: "POST", path: "/v2/calculate/cart", body: `{"lines": []}`, status: 400, operation: "CalculateCartV2"},
+		{method: "POST", path: "/calculate/subscription", body: `{"price": 30, "interval": "monthly", "anchor_date": "2026-01-01", "change_date": "2026-01-16", "new_price": 60}`, status: 200, operation: "CalculateSubscription"},
+		{method: "POST", path: "/calculate/batch", body: `[{}, {"quantity": 2}]`, status: 200, operation: "CalculateBatch", want: `"total_price":240`},
+		{method: "POST", path: "/simulate", body: `{"base": {}, "scenarios": [{"name": "cheaper", "base_price": 50}]}`, status: 200, operation: "SimulatePrices", want: `"cheaper"`},
+		{method: "POST", path: "/quotes", body: `{"quantity": 3}`, status: 201, operation: "CreateQuote", want: `"status":"open"`, capture: "quote"},
+		{method: "GET", path: "/quotes/{quote}", status: 200, operation: "GetQuote", want: `"total_price":360`},
+		{method: "POST", path: "/quotes/{quote}/render", status: 409, operation: "RenderQuote"},
+		{method: "POST", path: "/quotes/{quote}/finalize", status: 200, operation: "FinalizeQuote", want: `"status":"finalized"`},
+		{method: "POST", path: "/quotes/{quote}/finalize", status: 409, operation: "FinalizeQuote"},
+		{method: "POST", path: "/quotes/{quote}/render", status: 200, operation: "RenderQuote", want: `<html`},
+		{method: "POST", path: "/quotes/{quote}/render?format=doc", status: 400, operation: "RenderQuote"},
+		{method: "GET", path: "/quotes/unknown", status: 404, operation: "GetQuote"},
+		{method: "GET", path: "/history", status: 200, operation: "ListHistory", want: `"total_price":240`},
+		{method: "GET", path: "/convert?from=USD&to=USD&amount=10", status: 200, operation: "ConvertCurrency", want: `"converted":10`},
+		{method: "GET", path: "/convert?from=USD&to=XXX&amount=10", status: 400, operation: "ConvertCurrency"},
+		{method: "GET", path: "/rates", status: 200, operation: "GetExchangeRates", want: `"rates"`},
+
+		// Discounts
+		{method: "POST", path: "/discounts", body: `{"name": "Sale", "type": "percentage", "value": 10}`, status: 201, operation: "CreateDiscount", capture: "discount"},
+		{method: "GET", path: "/discounts", status: 200, operation: "ListDiscounts", want: `"Sale"`},
+		{method: "GET", path: "/discounts/{discount}", status: 200, operation: "GetDiscount", want: `"Sale"`},
+		{method: "PUT", path: "/discounts/{discount}", body: `{"name": "Big sale", "type": "percentage", "value": 20}`, status: 200, operation: "UpdateDiscount", want: `"Big sale"`},
+		{method: "DELETE", path: "/discounts/{discount}", status: 204, operation: "DeleteDiscount"},
+		{method: "GET", path: "/discounts/{discount}", status: 404, operation: "GetDiscount"},
+
+		// Coupons
+		{method: "POST", path: "/coupons", body: `{"code": "SAVE10", "type": "percentage", "value": 10}`, status: 201, operation: "CreateCoupon", capture: "coupon"},
+		{method: "GET", path: "/coupons", status: 200, operation: "ListCoupons", want: `"SAVE10"`},
+		{method: "GET", path: "/coupons/{coupon}", status: 200, operation: "GetCoupon", want: `"SAVE10"`},
+		{method: "PUT", path: "/coupons/{coupon}", body: `{"code": "SAVE20", "type": "percentage", "value": 20}`, status: 200, operation: "UpdateCoupon", want: `"SAVE20"`},
+		{method: "DELETE", path: "/coupons/{coupon}", status: 204, operation: "DeleteCoupon"},
+
+		// Tax rules
+		{method: "POST", path: "/tax/rules", body: `{"country": "DE", "rate": 19}`, status: 201, operation: "CreateTaxRule", capture: "taxrule"},
+		{method: "GET", path: "/tax/rules", status: 200, operation: "ListTaxRules", want: `"DE"`},
+		{method: "GET", path: "/tax/rules/{taxrule}", status: 200, operation: "GetTaxRule", want: `"DE"`},
+		{method: "PUT", path: "/tax/rules/{taxrule}", body: `{"country": "DE", "rate": 7}`, status: 200, operation: "UpdateTaxRule", want: `"rate":7`},
+		{method: "DELETE", path: "/tax/rules/{taxrule}", status: 204, operation: "DeleteTaxRule"},
+
+		// Surcharges
+		{method: "POST", path: "/surcharges", body: `{"name": "Handling", "type": "flat", "amount": 2}`, status: 201, operation: "CreateSurcharge", capture: "surcharge"},
+		{method: "GET", path: "/surcharges", status: 200, operation: "ListSurcharges", want: `"Handling"`},
+		{method: "GET", path: "/surcharges/{surcharge}", status: 200, operation: "GetSurcharge", want: `"Handling"`},
+		{method: "PUT", path: "/surcharges/{surcharge}", body: `{"name": "Packing", "type": "flat", "amount": 3}`, status: 200, operation: "UpdateSurcharge", want: `"Packing"`},
+		{method: "DELETE", path: "/surcharges/{surcharge}", status: 204, operation: "DeleteSurcharge"},
+
+		// Products
+		{method: "POST", path: "/products", body: `{"sku": "BOOK-1", "name": "Book", "unit_price": 12.5}`, status: 201, operation: "CreateProduct", capture: "product"},
+		{method: "GET", path: "/products", status: 200, operation: "ListProducts", want: `"BOOK-1"`},
+		{method: "GET", path: "/products/{product}", status: 200, operation: "GetProduct", want: `"Book"`},
+		{method: "PUT", path: "/products/{product}", body: `{"sku": "BOOK-1", "name": "Hardcover", "unit_price": 20}`, status: 200, operation: "UpdateProduct", want: `"Hardcover"`},
+		{method: "DELETE", path: "/products/{product}", status: 204, operation: "DeleteProduct"},
+
+		// Customers
+		{method: "POST", path: "/customers", body: `{"id": "acme", "name": "Acme"}`, status: 201, operation: "CreateCustomer"},
+		{method: "GET", path: "/customers", status: 200, operation: "ListCustomers", want: `"acme"`},
+		{method: "GET", path: "/customers/acme", status: 200, operation: "GetCustomer", want: `"Acme"`},
+		{method: "PUT", path: "/customers/acme", body: `{"id": "acme", "name": "Acme Inc", "tax_exempt": true}`, status: 200, operation: "UpdateCustomer", want: `"Acme Inc"`},
+		{method: "DELETE", path: "/customers/acme", status: 204, operation: "DeleteCustomer"},
+
+		// Tenants, whose pricing endpoints use their own defaults
+		{method: "POST", path: "/tenants", body: `{"id": "shop", "base_price": 10, "tax_rate": 10}`, status: 201, operation: "CreateTenant"},
+		{method: "GET", path: "/tenants", status: 200, operation: "ListTenants", want: `"shop"`},
+		{method: "GET", path: "/tenants/shop", status: 200, operation: "GetTenant", want: `"shop"`},
+		{method: "POST", path: "/tenants/shop/calculate", status: 200, operation: "CalculatePrice", want: `"total_price":11`},
+		{method: "PUT", path: "/tenants/shop", body: `{"id": "shop", "base_price": 20, "tax_rate": 10}`, status: 200, operation: "UpdateTenant"},
+		{method: "POST", path: "/tenants/shop/calculate", status: 200, operation: "CalculatePrice", want: `"total_price":22`},
+		{method: "POST", path: "/tenants/shop/discounts", body: `{"name": "Shop sale", "type": "percentage", "value": 10}`, status: 201, operation: "CreateDiscount"},
+		{method: "GET", path: "/tenants/shop/discounts", status: 200, operation: "ListDiscounts", want: `"Shop sale"`},
+		{method: "GET", path: "/discounts", status: 200, operation: "ListDiscounts", want: `[]`},
+		{method: "DELETE", path: "/tenants/shop", status: 204, operation: "DeleteTenant"},
+		{method: "POST", path: "/tenants/shop/calculate", status: 404, operation: "CalculatePrice"},
+
+		// Configuration
+		{method: "GET", path: "/v1/config", status: 200, operation: "GetConfigV1", want: `"base_price":100`},
+		{method: "PUT", path: "/v1/config", body: `{"base_price": 80, "tax_rate": 10}`, status: 200, operation: "PutConfigV1", want: `"base_price":80`},
+		{method: "PUT", path: "/v1/config", body: `{"base_price": 80}`, status: 400, operation: "PutConfigV1"},
+		{method: "PATCH", path: "/v1/config", body: `{"tax_rate": 5}`, status: 200, operation: "PatchConfigV1", want: `"tax_rate":5`},
+		{method: "PATCH", path: "/v1/config", body: `{}`, status: 400, operation: "PatchConfigV1"},
+		{method: "POST", path: "/config/bulk", body: `{"base_price": 90, "tax_rate": 20}`, status: 200, operation: "BulkUpdateConfig", want: `"base_price":90`},
+		{method: "GET", path: "/config/versions", status: 200, operation: "ListConfigVersions", want: `"base_price":90`},
+		{method: "GET", path: "/config/versions/1", status: 200, operation: "GetConfigVersion", want: `"version":1`},
+		{method: "POST", path: "/config/rollback/1", status: 200, operation: "RollbackConfig", want: `"base_price":100`},
+		{method: "GET", path: "/config/versions/99", status: 404, operation: "GetConfigVersion"},
+		{method: "POST", path: "/config/schedule", body: `{"tax_rate": 25, "effective_at": "2099-01-01T00:00:00Z"}`, status: 201, operation: "CreateScheduledChange", capture: "change"},
+		{method: "GET", path: "/config/schedule", status: 200, operation: "ListScheduledChanges", want: `"pending"`},
+		{method: "GET", path: "/config/schedule/{change}", status: 200, operation: "GetScheduledChange", want: `"tax_rate":25`},
+		{method: "POST", path: "/config/schedule/{change}/cancel", status: 200, operation: "CancelScheduledChange", want: `"cancelled"`},
+		{method: "POST", path: "/setBasePrice/70", status: 200, operation: "SetBasePrice"},
+		{method: "POST", path: "/setTaxRate/15", status: 200, operation: "SetTaxRate"},
+		{method: "GET", path: "/config", status: 200, operation: "GetConfig", want: `"base_price":70`},
+		{method: "POST", path: "/config/tax-rates", body: `{"tax_rate": 19, "effective_from": "2020-01-01T00:00:00Z"}`, status: 201, operation: "CreateTaxRatePeriod", want: `"tax_rate":19`},
+		{method: "POST", path: "/config/tax-rates", body: `{"tax_rate": 19, "effective_from": "2099-01-01T00:00:00Z"}`, status: 400, operation: "CreateTaxRatePeriod"},
+		{method: "GET", path: "/config/tax-rates", status: 200, operation: "ListTaxRateHistory", want: `"2020-01-01T00:00:00Z"`},
+
+		// Gateway of the gRPC API
+		{method: "POST", path: "/v1/calculate", body: `{"quantity": 2}`, status: 200, operation: "GatewayCalculate", want: `"total_price"`},
+		{method: "POST", path: "/v1/calculate", body: `{"quantity": -1}`, status: 400, operation: "GatewayCalculate"},
+		{method: "PUT", path: "/v1/base-price", body: `{"base_price": "100"}`, status: 200, operation: "GatewaySetBasePrice"},
+		{method: "PUT", path: "/v1/tax-rate", body: `{"tax_rate": -1}`, status: 400, operation: "GatewaySetTaxRate"},
+		{method: "PUT", path: "/v1/tax-rate", body: `{"tax_rate": 20}`, status: 200, operation: "GatewaySetTaxRate"},
+
+		// Audit log of the changes above
+		{method: "GET", path: "/audit?action=" + auditCouponCreated, status: 200, operation: "ListAudit", want: `"SAVE10"`},
+		{method: "GET", path: "/audit?limit=0", status: 400, operation: "ListAudit"},
+
+		// Webhooks
+		{method: "POST", path: "/webhooks", body: `{"url": "http://127.0.0.1:1/hook", "secret": "s3cret", "events": ["prices.updated"]}`, status: 201, operation: "CreateWebhook", capture: "webhook"},
+		{method: "GET", path: "/webhooks", status: 200, operation: "ListWebhooks", want: `"http://127.0.0.1:1/hook"`},
+		{method: "GET", path: "/webhooks/{webhook}", status: 200, operation: "GetWebhook", want: `"prices.updated"`},
+		{method: "DELETE", path: "/webhooks/{webhook}", status: 204, operation: "DeleteWebhook"},
+		{method: "GET", path: "/webhooks/{webhook}", status: 404, operation: "GetWebhook"},
+
+		// Administration
+		{method: "GET", path: "/admin/flags", status: 200, operation: "ListFlags", want: `"cart"`},
+		{method: "PUT", path: "/admin/flags/cart", body: `{"enabled": false}`, status: 200, operation: "UpdateFlag", want: `"enabled":false`},
+		{method: "GET", path: "/admin/flags/cart", status: 200, operation: "GetFlag", want: `"enabled":false`},
+		{method: "POST", path: "/calculate/cart", body: `{"items": [{"name": "Book", "unit_price": 10, "quantity": 1}]}`, status: 404, operation: "CalculateCart"},
+		{method: "DELETE", path: "/admin/flags/cart", status: 200, operation: "ResetFlag", want: `"enabled":true`},
+		{method: "GET", path: "/admin/flags/unknown", status: 404, operation: "GetFlag"},
+		{method: "GET", path: "/admin/sampling", status: 200, operation: "GetSampling", want: `"always_on"`},
+		{method: "PUT", path: "/admin/sampling", body: `{"sampler": "traceidratio", "ratio": 2}`, status: 400, operation: "UpdateSampling"},
+		{method: "PUT", path: "/admin/sampling", body: `{"sampler": "always_on"}`, status: 200, operation: "UpdateSampling", want: `"always_on"`},
+		{method: "GET", path: "/admin/telemetry", status: 200, operation: "GetTelemetry", want: `"queue_depth"`},
+		{method: "POST", path: "/admin/telemetry/flush", status: 200, operation: "FlushTelemetry", want: `"exported_spans"`},
+		{method: "PUT", path: "/admin/telemetry/stdout", body: `{}`, status: 400, operation: "UpdateStdoutExporter"},
+		{method: "PUT", path: "/admin/telemetry/exporter", body: `{"exporter": "zipkin"}`, status: 400, operation: "UpdateTraceExporter"},
+		{method: "PUT", path: "/admin/telemetry/exporter", body: `{"exporter": "none"}`, status: 200, operation: "UpdateTraceExporter", want: `"none"`},
+	})
+}
+
+func TestCalculatePrice(t *testing.T) {
+	s := newTestServer(t)
+	resp, data := s.do(t, http.MethodPost, "/calculate", `{"quantity": 2}`)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", resp.StatusCode, data)
+	}
+	var response pricing.PriceResponse
+	decodeBody(t, data, &response)
+	if !response.TotalPrice.Equal(pricing.MoneyFromFloat(240)) {
+		t.Errorf("total_price = %v, want 240 for 2 units of 100 with 20%% tax", response.TotalPrice)
+	}
+	if response.CalculationID == "" {
+		t.Error("response has no calculation_id")
+	}
+
+	server := s.ended(t, trace.SpanKindServer, "CalculatePrice", 1)[0]
+	calculation := s.ended(t, trace.SpanKindInternal, "CalculateTotalPrice", 1)[0]
+	if calculation.Parent().SpanID() != server.SpanContext().SpanID() {
+		t.Error("CalculateTotalPrice span is not a child of the CalculatePrice server span")
+	}
+	if response.TraceID != calculation.SpanContext().TraceID().String() {
+		t.Errorf("trace_id = %s, want the calculation's trace %s", response.TraceID, calculation.SpanContext().TraceID())
+	}
+	assertAttribute(t, calculation, "calculation.id", response.CalculationID)
+	assertAttribute(t, server, "http.status_code", int64(http.StatusOK))
+	if server.Status().Code == codes.Error {
+		t.Errorf("server span status = %v, want no error", server.Status())
+	}
+}
+
+func TestCalculatePriceValidationErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		body   string
+		detail string
+	}{
+		{"malformed body", `{"quantity":`, "Invalid request body"},
+		{"wrong type", `{"quantity": "two"}`, "Invalid request body"},
+		{"negative weight", `{"weight": -1}`, "weight must not be negative"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(t)
+			resp, data := s.do(t, http.MethodPost, "/calculate", tt.body)
+			if resp.StatusCode != http.StatusBadRequest {
+				t.Fatalf("status = %d, want 400: %s", resp.StatusCode, data)
+			}
+			if ct := resp.Header.Get("Content-Type"); ct != "application/problem+json" {
+				t.Errorf("Content-Type = %q, want application/problem+json", ct)
+			}
+			var problem Problem
+			decodeBody(t, data, &problem)
+			if problem.Status != http.StatusBadRequest || !strings.Contains(problem.Detail, tt.detail) {
+				t.Errorf("problem = %+v, want status 400 and detail %q", problem, tt.detail)
+			}
+
+			server := s.ended(t, trace.SpanKindServer, "CalculatePrice", 1)[0]
+			assertAttribute(t, server, "error.class", errorClassValidation)
+			assertAttribute(t, server, "validation.error", problem.Detail)
+			if server.Status().Code != codes.Error {
+				t.Errorf("server span status = %v, want error", server.Status())
+			}
+		})
+	}
+}
+
+func TestCalculateBatch(t *testing.T) {
+	s := newTestServer(t)
+	resp, data := s.do(t, http.MethodPost, "/calculate/batch", `[{"quantity": 1}, {"weight": -1}, {"base_price": 10, "tax_rate": 0}]`)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", resp.StatusCode, data)
+	}
+	var results []BatchResult
+	decodeBody(t, data, &results)
+	if len(results) != 3 {
+		t.Fatalf("got %d results, want 3", len(results))
+	}
+	if results[0].Result == nil || !results[0].Result.TotalPrice.Equal(pricing.MoneyFromFloat(120)) {
+		t.Errorf("result 0 = %+v, want a total of 120", results[0])
+	}
+	if results[1].Result != nil || !strings.Contains(results[1].Error, "weight must not be negative") {
+		t.Errorf("result 1 = %+v, want the weight error", results[1])
+	}
+	if results[2].Result == nil || !results[2].Result.TotalPrice.Equal(pricing.MoneyFromFloat(10)) {
+		t.Errorf("result 2 = %+v, want a total of 10", results[2])
+	}
+
+	batch := s.ended(t, trace.SpanKindInternal, "CalculateBatch", 1)[0]
+	assertAttribute(t, batch, "batch.size", int64(3))
+	calculations := s.ended(t, trace.SpanKindInternal, "CalculateTotalPrice", 3)
+	failed := 0
+	for _, calculation := range calculations {
+		if calculation.Parent().SpanID() != batch.SpanContext().SpanID() {
+			t.Error("CalculateTotalPrice span is not a child of the CalculateBatch span")
+		}
+		if calculation.Status().Code == codes.Error {
+			failed++
+		}
+	}
+	if failed != 1 {
+		t.Errorf("%d failed calculation spans, want 1", failed)
+	}
+}
+
+func TestCalculateBatchLimits(t *testing.T) {
+	tooMany := "[" + strings.Repeat(`{},`, maxBatchSize) + "{}]"
+	for name, body := range map[string]string{"empty": `[]`, "too many": tooMany, "not a list": `{}`} {
+		t.Run(name, func(t *testing.T) {
+			s := newTestServer(t)
+			resp, data := s.do(t, http.MethodPost, "/calculate/batch", body)
+			if resp.StatusCode != http.StatusBadRequest {
+				t.Fatalf("status = %d, want 400: %s", resp.StatusCode, data)
+			}
+			assertAttribute(t, s.ended(t, trace.SpanKindServer, "CalculateBatch", 1)[0], "error.class", errorClassValidation)
+		})
+	}
+}
+
+func TestQuotes(t *testing.T) {
+	s := newTestServer(t)
+	body := `{"base_price": 50, "quantity": 3}`
+	resp, data := s.do(t, http.MethodPost, "/quotes", body)
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("status = %d, want 201: %s", resp.StatusCode, data)
+	}
+	var quote Quote
+	decodeBody(t, data, &quote)
+	if quote.ID == "" || quote.Status != QuoteOpen {
+		t.Fatalf("quote = %+v, want an open quote with an ID", quote)
+	}
+	if want := "/quotes/" + quote.ID; resp.Header.Get("Location") != want {
+		t.Errorf("Location = %q, want %q", resp.Header.Get("Location"), want)
+	}
+	if !quote.Response.TotalPrice.Equal(pricing.MoneyFromFloat(180)) {
+		t.Errorf("total_price = %v, want 180", quote.Response.TotalPrice)
+	}
+	created := s.ended(t, trace.SpanKindServer, "CreateQuote", 1)[0]
+	assertAttribute(t, created, "quote.id", quote.ID)
+	if quote.TraceID != created.SpanContext().TraceID().String() {
+		t.Errorf("trace_id = %s, want the trace of the request %s", quote.TraceID, created.SpanContext().TraceID())
+	}
+
+	// Pricing the same request again finds the quote
+	resp, data = s.do(t, http.MethodPost, "/quotes", body)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("repeated status = %d, want 200: %s", resp.StatusCode, data)
+	}
+	var existing Quote
+	decodeBody(t, data, &existing)
+	if existing.ID != quote.ID {
+		t.Errorf("repeated quote ID = %s, want %s", existing.ID, quote.ID)
+	}
+	assertAttribute(t, s.ended(t, trace.SpanKindServer, "CreateQuote", 2)[1], "quote.existing", true)
+
+	resp, data = s.do(t, http.MethodGet, "/quotes/"+quote.ID, "")
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("get status = %d, want 200: %s", resp.StatusCode, data)
+	}
+	assertAttribute(t, s.ended(t, trace.SpanKindServer, "GetQuote", 1)[0], "quote.id", quote.ID)
+
+	resp, _ = s.do(t, http.MethodGet, "/quotes/unknown", "")
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("unknown quote status = %d, want 404", resp.StatusCode)
+	}
+
+	// Quoting with a coupon, even twice, neither redeems it nor records more than the created quote
+	if resp, data = s.do(t, http.MethodPost, "/coupons", `{"code": "ONCE", "type": "percentage", "value": 10, "max_redemptions": 1}`); resp.StatusCode != http.StatusCreated {
+		t.Fatalf("coupon status = %d, want 201: %s", resp.StatusCode, data)
+	}
+	for i, status := range []int{http.StatusCreated, http.StatusOK} {
+		if resp, data = s.do(t, http.MethodPost, "/quotes", `{"coupon_code": "once"}`); resp.StatusCode != status {
+			t.Fatalf("coupon quote %d status = %d, want %d: %s", i+1, resp.StatusCode, status, data)
+		}
+	}
+	coupon, _, err := coupons.GetByCode(context.Background(), "ONCE")
+	if err != nil || coupon.Redemptions != 0 {
+		t.Errorf("coupon = %+v, %v, want no redemptions", coupon, err)
+	}
+	_, total, err := history.List(context.Background(), HistoryFilter{Limit: 10})
+	if err != nil || total != 2 {
+		t.Errorf("history holds %d records, %v, want the 2 created quotes", total, err)
+	}
+}
+
+func TestAPIKeyRequired(t *testing.T) {
+	s := newTestServer(t)
+	t.Setenv("API_KEYS", "secret,other")
+	coupon := `{"code": "save10", "type": "percentage", "value": 10}`
+
+	tests := []struct {
+		name   string
+		key    string
+		status int
+	}{
+		{"missing key", "", http.StatusUnauthorized},
+		{"unknown key", "wrong", http.StatusForbidden},
+		{"accepted key", "secret", http.StatusCreated},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var header []string
+			if tt.key != "" {
+				header = []string{APIKeyHeader, tt.key}
+			}
+			resp, data := s.do(t, http.MethodPost, "/coupons", coupon, header...)
+			if resp.StatusCode != tt.status {
+				t.Fatalf("status = %d, want %d: %s", resp.StatusCode, tt.status, data)
+			}
+
+			span := s.ended(t, trace.SpanKindServer, "CreateCoupon", i+1)[i]
+			if tt.key == "" {
+				if _, ok := spanAttribute(span, "auth.key_id"); ok {
+					t.Error("span has an auth.key_id without a key")
+				}
+			} else {
+				assertAttribute(t, span, "auth.key_id", apiKeyID(tt.key))
+			}
+			if tt.status >= 400 {
+				if _, ok := spanAttribute(span, "validation.error"); ok {
+					t.Error("authentication failure recorded as a validation error")
+				}
+			}
+		})
+	}
+
+	// Reading needs no key
+	resp, data := s.do(t, http.MethodGet, "/coupons", "")
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("list status = %d, want 200: %s", resp.StatusCode, data)
+	}
+
+	// The accepted change is attributed to the key in the audit log
+	entries, _, err := auditLog.List(context.Background(), AuditFilter{Action: auditCouponCreated, Limit: 10})
+	if err != nil {
+		t.Fatalf("listing audit log: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Actor != apiKeyID("secret") {
+		t.Errorf("audit entries = %+v, want one coupon.created by %s", entries, apiKeyID("secret"))
+	}
+}
+
+func TestPutConfigReplacesConfig(t *testing.T) {
+	s := newTestServer(t)
+	steps := []struct {
+		method, body, currency string
+		rounding               pricing.RoundingMode
+	}{
+		{http.MethodPatch, `{"currency": "EUR", "rounding": "up"}`, "EUR", pricing.RoundUp},
+		{http.MethodPatch, `{"tax_rate": 10}`, "EUR", pricing.RoundUp}, // PATCH keeps what it omits
+		{http.MethodPut, `{"base_price": 50, "tax_rate": 10}`, "", ""}, // PUT resets it
+		{http.MethodPut, `{"base_price": 50, "tax_rate": 10, "currency": "GBP"}`, "GBP", ""},
+	}
+	for _, step := range steps {
+		resp, data := s.do(t, step.method, "/v1/config", step.body)
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("%s %s status = %d, want 200: %s", step.method, step.body, resp.StatusCode, data)
+		}
+		var cfg pricing.Config
+		decodeBody(t, data, &cfg)
+		if cfg.Currency != step.currency || cfg.Rounding != step.rounding {
+			t.Errorf("%s %s: currency %q and rounding %q, want %q and %q", step.method, step.body, cfg.Currency, cfg.Rounding, step.currency, step.rounding)
+		}
+	}
+}
+
+func TestOpenAPIFlagNames(t *testing.T) {
+	data, err := openAPIJSON()
+	if err != nil {
+		t.Fatalf("openAPIJSON: %v", err)
+	}
+	var spec struct {
+		Paths map[string]struct {
+			Parameters []struct {
+				Name   string `json:"name"`
+				Schema struct {
+					Enum []string `json:"enum"`
+				} `json:"schema"`
+			} `json:"parameters"`
+		} `json:"paths"`
+	}
+	decodeBody(t, data, &spec)
+	params := spec.Paths["/admin/flags/{name}"].Parameters
+	if len(params) != 1 {
+		t.Fatalf("/admin/flags/{name} has %d parameters, want 1", len(params))
+	}
+	names := slices.Sorted(maps.Keys(featureFlags))
+	if !slices.Equal(params[0].Schema.Enum, names) {
+		t.Errorf("flag name enum = %v, want the known flags %v", params[0].Schema.Enum, names)
+	}
+}
+
+func TestCORSCredentials(t *testing.T) {
+	s := newTestServer(t)
+	t.Setenv("CORS_ALLOWED_ORIGINS", "")
+	previous := fileConfig.Load()
+	t.Cleanup(func() { fileConfig.Store(previous) })
+	cfg := *previous
+	cfg.CORS = config.CORS{AllowedOrigins: []string{"https://shop.example"}, AllowCredentials: true}
+	fileConfig.Store(&cfg)
+
+	resp, data := s.do(t, http.MethodGet, "/v1/config", "", "Origin", "https://shop.example")
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", resp.StatusCode, data)
+	}
+	if got := resp.Header.Get("Access-Control-Allow-Origin"); got != "https://shop.example" {
+		t.Errorf("Access-Control-Allow-Origin = %q, want the request origin", got)
+	}
+	if got := resp.Header.Get("Access-Control-Allow-Credentials"); got != "true" {
+		t.Errorf("Access-Control-Allow-Credentials = %q, want true", got)
+	}
+
+	// Any origin from the environment cannot be combined with the file's credentials
+	t.Setenv("CORS_ALLOWED_ORIGINS", "*")
+	if err := corsConfig().Validate(); err == nil {
+		t.Error("credentials allowed for the * origin of CORS_ALLOWED_ORIGINS")
+	}
+}
+
+// failingAuditStore fails every append, as an unreachable audit database would
+type failingAuditStore struct{}
+
+func (failingAuditStore) Append(ctx context.Context, entry AuditEntry) (int64, error) {
+	return 0, errors.New("audit log unavailable")
+}
+
+func (failingAuditStore) List(ctx context.Context, filter AuditFilter) ([]AuditEntry, int, error) {
+	return nil, 0, errors.New("audit log unavailable")
+}
+
+func TestAuditFailureKeepsChange(t *testing.T) {
+	s := newTestServer(t)
+	auditLog = failingAuditStore{}
+
+	resp, data := s.do(t, http.MethodPost, "/tax/rules", `{"country": "DE", "rate": 19}`)
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("status = %d, want 201: %s", resp.StatusCode, data)
+	}
+	span := s.ended(t, trace.SpanKindServer, "CreateTaxRule", 1)[0]
+	failed := false
+	for _, event := range span.Events() {
+		failed = failed || event.Name == "audit.failed"
+	}
+	if !failed {
+		t.Error("span has no audit.failed event")
+	}
+
+	// The change is applied and reported as such
+	resp, data = s.do(t, http.MethodGet, "/tax/rules", "")
+	if resp.StatusCode != http.StatusOK || !strings.Contains(string(data), `"DE"`) {
+		t.Errorf("tax rules = %d %s, want the created rule", resp.StatusCode, data)
+	}
+}
+
+// Measures a calculation through the whole handler stack, from the request to the encoded response
+func BenchmarkCalculatePrice(b *testing.B) {
+	s := newTestServer(b)
+	b.ReportAllocs()
+	for range b.N {
+		resp, data := s.do(b, http.MethodPost, "/calculate", `{"quantity": 3, "taxes": [{"name": "GST", "rate": 5}, {"name": "QST", "rate": 9.975, "compound": true}]}`)
+		if resp.StatusCode != http.StatusOK {
+			b.Fatalf("status = %d, want 200: %s", resp.StatusCode, data)
+		}
+	}
+}
@@ -0,0 +1,307 @@
+package httpapi
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"slices"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+
+	"otpl/pricecalculator/internal/telemetry"
+	"otpl/pricecalculator/pricing"
+)
+
+// Operations shared by the HTTP and gRPC APIs
+
+// Steps every calculation runs through
+var pricingPipeline = pricing.DefaultPipeline()
+
+// Adds a custom calculation step before the named step, or at the end when no step has that name.
+// Forks register their steps from an init function, before any calculation runs, e.g.
+//
+//	func init() {
+//		registerPricingStep(pricing.StepRounding, pricing.Surcharge{Label: "handling", Amount: pricing.MoneyFromFloat(1.5)})
+//	}
+func registerPricingStep(before string, step pricing.PricingStep) {
+	pricingPipeline = pricingPipeline.Insert(before, step)
+}
+
+// Loads the discount rules of the request's tenant and the tax and surcharge rules, recording
+// the active configuration version on the span for the default configuration
+func storedRules(ctx context.Context) (pricing.Rules, error) {
+	var rules pricing.Rules
+	var err error
+	if rules.Discounts, err = discounts.List(ctx, tenantID(ctx)); err != nil {
+		return rules, err
+	}
+	if rules.TaxRules, err = taxRules.List(ctx); err != nil {
+		return rules, err
+	}
+	if rules.Surcharges, err = surcharges.List(ctx); err != nil {
+		return rules, err
+	}
+	if tenantID(ctx) == "" {
+		version, _, err := configVersions.Latest(ctx)
+		if err != nil {
+			return rules, err
+		}
+		trace.SpanFromContext(ctx).SetAttributes(attribute.Int("config.version", version.Version))
+	}
+	return rules, nil
+}
+
+// Calculates a price with the stored defaults and discount rules, recording the calculation metrics
+func calculate(ctx context.Context, request pricing.PriceRequest) (pricing.PriceResponse, error) {
+	// Start a new span for the price calculation
+	ctx, span := tracer.Start(ctx, "CalculateTotalPrice")
+	defer span.End() // Ensure the span is ended when the function exits
+
+	start := time.Now()
+	// Price with the defaults, discount rules and currency of the request's tenant
+	defaults, currency := tenantDefaults(ctx)
+	if request.Currency == "" {
+		request.Currency = currency
+	}
+	// Recompute a past calculation with the default tax rate in effect at the time
+	if request.AsOf != nil {
+		period, err := taxRateAt(ctx, *request.AsOf)
+		if err != nil {
+			telemetry.RecordError(span, err)
+			return pricing.PriceResponse{}, err
+		}
+		defaults.TaxRate = period.TaxRate
+	}
+	// Products with volume price breaks of their own replace the global ones
+	tiers := fileConfig.Load().Pricing.PriceTiers()
+	var unitCost *pricing.Money
+	if request.SKU != "" {
+		product, err := priceFromCatalog(ctx, &request)
+		if err != nil {
+			telemetry.RecordError(span, err)
+			return pricing.PriceResponse{}, err
+		}
+		if len(product.Tiers) > 0 {
+			tiers = product.Tiers
+		}
+		unitCost = product.UnitCost
+	}
+	if err := applyCustomerOverrides(ctx, &request); err != nil {
+		telemetry.RecordError(span, err)
+		return pricing.PriceResponse{}, err
+	}
+	if err := verifyTaxExemption(ctx, request); err != nil {
+		telemetry.RecordError(span, err)
+		return pricing.PriceResponse{}, err
+	}
+	linkConfigOrigins(ctx, request)
+	rules, err := storedRules(ctx)
+	if err != nil {
+		telemetry.RecordError(span, err)
+		return pricing.PriceResponse{}, err
+	}
+	rules.Tiers, rules.UnitCost = tiers, unitCost
+	rules.Guardrails, _ = fileConfig.Load().Pricing.PriceGuardrails() // Validated when the file was loaded
+	rules.Stacking = fileConfig.Load().Pricing.StackingPolicy()
+	if request.CouponCode != "" {
+		redeem := redeemCoupon
+		if simulating(ctx) {
+			redeem = previewCoupon
+		}
+		coupon, err := redeem(ctx, request.CouponCode)
+		if err != nil {
+			telemetry.RecordError(span, err)
+			return pricing.PriceResponse{}, err
+		}
+		rules.Coupon = &coupon
+	}
+
+	// Serve identical calculations from the cache, coupon redemptions and explained calculations are always calculated
+	mode := roundingMode(defaults, request.Currency)
+	var cacheKey string
+	if resultCache != nil && rules.Coupon == nil && pricing.ExplanationFrom(ctx) == nil {
+		cacheKey = calculationCacheKey(ctx, request, defaults, rules, mode)
+	}
+	var response pricing.PriceResponse
+	cached := false
+	if cacheKey != "" {
+		response, cached = resultCache.Get(ctx, cacheKey)
+	}
+	if !cached {
+		var err error
+		calculator := pricing.NewCalculator(pricing.CalculatorOptions{Pipeline: pricingPipeline})
+		response, err = calculator.Calculate(ctx, pricing.Request{PriceRequest: request, Defaults: defaults, Rules: rules, Rounding: mode})
+		if err != nil {
+			if rules.Coupon != nil && !simulating(ctx) {
+				releaseCoupon(ctx, rules.Coupon.ID)
+			}
+			telemetry.RecordError(span, err)
+			return response, err
+		}
+		if cacheKey != "" {
+			resultCache.Set(ctx, cacheKey, response)
+		}
+		// A coupon left out by the stacking policy is not used up
+		if rules.Coupon != nil && !simulating(ctx) && slices.ContainsFunc(response.SkippedDiscounts, func(d pricing.SkippedDiscount) bool {
+			return d.ID == rules.Coupon.Discount().ID
+		}) {
+			releaseCoupon(ctx, rules.Coupon.ID)
+		}
+	}
+
+	// Identify the calculation, cached results included, so the response can be traced
+	response.CalculationID = randomID()
+	response.TraceID = ""
+	if sc := span.SpanContext(); sc.HasTraceID() {
+		response.TraceID = sc.TraceID().String()
+	}
+	span.SetAttributes(attribute.String("calculation.id", response.CalculationID))
+
+	// Record the calculation metrics, simulations are neither counted nor recorded
+	calculationDuration.Record(ctx, float64(time.Since(start).Microseconds())/1000)
+	if response.Tier != nil {
+		span.SetAttributes(attribute.Int("pricing.tier.min_quantity", response.Tier.MinQuantity))
+	}
+	if span.IsRecording() && featureEnabled(ctx, "price_attributes") {
+		span.SetAttributes(priceAttributes(request, defaults, response)...)
+	}
+	if simulating(ctx) {
+		slog.DebugContext(ctx, "Simulated total price", "total_price", response.TotalPrice.String(), "currency", response.Currency)
+		return response, nil
+	}
+	recordCalculation(ctx, request, response)
+	return response, nil
+}
+
+// Counts a calculation, records it in the history and publishes it, for calculations that
+// are kept rather than simulated
+func recordCalculation(ctx context.Context, request pricing.PriceRequest, response pricing.PriceResponse) {
+	totalPriceCounter.Add(ctx, response.TotalPrice.Float64(), metric.WithAttributes(attribute.String("currency", response.Currency)))
+	recordHistory(ctx, request, response)
+	publishCalculation(ctx, request, response)
+	slog.InfoContext(ctx, "Calculated total price", "total_price", response.TotalPrice.String(), "currency", response.Currency,
+		"calculation_id", response.CalculationID)
+}
+
+// Returns the inputs and totals of a calculation as typed span attributes, so traces can be
+// queried by price range. The tax rate is only recorded when a single tax applied.
+func priceAttributes(request pricing.PriceRequest, defaults pricing.Config, response pricing.PriceResponse) []attribute.KeyValue {
+	basePrice := defaults.BasePrice
+	if request.BasePrice != nil {
+		basePrice = *request.BasePrice
+	}
+	quantity := request.Quantity
+	if quantity == 0 {
+		quantity = 1
+	}
+	attrs := []attribute.KeyValue{
+		attribute.Float64("pricing.base_price", basePrice.Float64()),
+		attribute.Int("pricing.quantity", quantity),
+		attribute.Float64("pricing.discount_total", response.Discount.Float64()),
+		attribute.Float64("pricing.surcharge_total", response.Surcharge.Float64()),
+		attribute.Float64("pricing.tax_total", response.Tax.Float64()),
+		attribute.Float64("pricing.total_price", response.TotalPrice.Float64()),
+		attribute.String("currency", response.Currency),
+	}
+	if len(response.Taxes) == 1 {
+		attrs = append(attrs, attribute.Float64("pricing.tax_rate", response.Taxes[0].Rate))
+	}
+	return attrs
+}
+
+// Returns a random 128-bit ID in hex, such as a calculation or request ID
+func randomID() string {
+	var id [16]byte
+	_, _ = rand.Read(id[:]) // Never fails, see crypto/rand.Read
+	return hex.EncodeToString(id[:])
+}
+
+// Links the calculation span to the spans of the changes that set the default base price
+// and tax rate the request is priced with, so a price can be traced to the change behind it.
+// Tenants have defaults of their own and are not linked.
+func linkConfigOrigins(ctx context.Context, request pricing.PriceRequest) {
+	if tenantID(ctx) != "" {
+		return
+	}
+	basePrice, taxRate := prices.Origins()
+	span := trace.SpanFromContext(ctx)
+	if request.BasePrice == nil && basePrice.IsValid() {
+		span.AddLink(trace.Link{SpanContext: basePrice, Attributes: []attribute.KeyValue{attribute.String("config.field", "base_price")}})
+	}
+	if request.TaxRate == nil && len(request.Taxes) == 0 && request.Jurisdiction == nil && taxRate.IsValid() {
+		span.AddLink(trace.Link{SpanContext: taxRate, Attributes: []attribute.KeyValue{attribute.String("config.field", "tax_rate")}})
+	}
+}
+
+// Persists and applies a new default base price
+func updateBasePrice(ctx context.Context, basePrice pricing.Money) (pricing.Config, error) {
+	return updateConfig(ctx, ConfigUpdate{BasePrice: &basePrice})
+}
+
+// Persists and applies a new default tax rate
+func updateTaxRate(ctx context.Context, taxRate float64) (pricing.Config, error) {
+	return updateConfig(ctx, ConfigUpdate{TaxRate: &taxRate})
+}
+
+// Persists and applies new defaults in one update, nil values are left unchanged.
+// Every value is validated first, so an invalid one leaves the configuration untouched,
+// and calculations see either all or none of the new values.
+func updateConfig(ctx context.Context, update ConfigUpdate) (pricing.Config, error) {
+	if update.BasePrice != nil {
+		if err := pricing.ValidateBasePrice(*update.BasePrice); err != nil {
+			return prices.Get(), err
+		}
+	}
+	if update.TaxRate != nil {
+		if err := pricing.ValidateTaxRate(*update.TaxRate); err != nil {
+			return prices.Get(), err
+		}
+	}
+	if update.Currency != nil && *update.Currency != "" {
+		currency, err := pricing.LookupCurrency(*update.Currency)
+		if err != nil {
+			return prices.Get(), err
+		}
+		update.Currency = &currency.Code
+	}
+	if update.Rounding != nil && *update.Rounding != "" {
+		mode, err := pricing.ParseRoundingMode(string(*update.Rounding))
+		if err != nil {
+			return prices.Get(), &pricing.ValidationError{Message: err.Error()}
+		}
+		update.Rounding = &mode
+	}
+	var previous pricing.Config
+	cfg, err := prices.Update(ctx, func(cfg *pricing.Config) error {
+		previous = *cfg
+		if update.BasePrice != nil {
+			cfg.BasePrice = *update.BasePrice
+		}
+		if update.TaxRate != nil {
+			cfg.TaxRate = *update.TaxRate
+		}
+		if update.Currency != nil {
+			cfg.Currency = *update.Currency
+		}
+		if update.Rounding != nil {
+			cfg.Rounding = *update.Rounding
+		}
+		return storage.Save(ctx, *cfg)
+	})
+	if err != nil {
+		return cfg, err
+	}
+	slog.InfoContext(ctx, "Prices set", "base_price", cfg.BasePrice.String(), "tax_rate", cfg.TaxRate,
+		"currency", cfg.Currency, "rounding", cfg.Rounding)
+	if cfg.TaxRate != previous.TaxRate {
+		recordTaxRate(ctx, cfg.TaxRate, time.Now())
+	}
+	recordConfigVersion(ctx, cfg, eventPricesUpdated)
+	recordAudit(ctx, eventPricesUpdated, "prices", previous, cfg)
+	notifyWebhooks(ctx, eventPricesUpdated, cfg)
+	return cfg, nil
+}
@@ -0,0 +1,190 @@
+package httpapi
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+
+	"go.opentelemetry.io/otel/attribute"
+
+	"otpl/pricecalculator/internal/telemetry"
+	"otpl/pricecalculator/pricing"
+)
+
+// Limits for simulations
+const (
+	maxScenarios      = 20 // Maximum number of scenarios in a simulation
+	simulationWorkers = 4  // Number of scenarios calculated concurrently
+)
+
+// Context key marking the calculations of a simulation
+type simulationKey struct{}
+
+// Returns a context whose calculations are simulated: coupons are checked but not redeemed,
+// and nothing is counted, recorded in the history or published
+func withSimulation(ctx context.Context) context.Context {
+	return context.WithValue(ctx, simulationKey{}, true)
+}
+
+// Reports whether the calculations made with ctx are simulated
+func simulating(ctx context.Context) bool {
+	simulated, _ := ctx.Value(simulationKey{}).(bool)
+	return simulated
+}
+
+// Scenario structure for a what-if change to the base request of a simulation,
+// omitted fields keep the base request's values
+type Scenario struct {
+	Name       string         `json:"name"`
+	BasePrice  *pricing.Money `json:"base_price,omitempty"`
+	TaxRate    *float64       `json:"tax_rate,omitempty"`
+	Quantity   int            `json:"quantity,omitempty"`
+	CouponCode *string        `json:"coupon_code,omitempty"` // An empty code removes the base request's coupon
+	Currency   string         `json:"currency,omitempty"`    // Converts the base request's base_price at the current exchange rate
+}
+
+// SimulationRequest structure for the input of /simulate
+type SimulationRequest struct {
+	Base      pricing.PriceRequest `json:"base"`
+	Scenarios []Scenario           `json:"scenarios"`
+}
+
+// ScenarioResult structure for the outcome of one scenario in a simulation
+type ScenarioResult struct {
+	Name       string                 `json:"name"`
+	Result     *pricing.PriceResponse `json:"result,omitempty"`
+	Difference *pricing.Money         `json:"difference,omitempty"` // Total price minus the base total, when both are in the same currency
+	Error      string                 `json:"error,omitempty"`
+}
+
+// SimulationResponse structure for the output of /simulate
+type SimulationResponse struct {
+	Base      pricing.PriceResponse `json:"base"`
+	Scenarios []ScenarioResult      `json:"scenarios"`
+}
+
+// Prices a base request and what-if scenarios derived from it, calculating the scenarios
+// concurrently in child spans of their own. Nothing is redeemed, recorded or published.
+func simulatePrices(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	// Start a parent span for the simulation, each scenario gets its own span
+	ctx, span := tracer.Start(ctx, "SimulatePrices")
+	defer span.End()
+
+	var request SimulationRequest
+	if !decodeJSON(w, r, &request) {
+		return
+	}
+	span.SetAttributes(attribute.Int("simulation.scenarios", len(request.Scenarios)))
+	if len(request.Scenarios) == 0 {
+		writeProblem(w, r, "simulation must contain at least one scenario", http.StatusBadRequest)
+		return
+	}
+	if len(request.Scenarios) > maxScenarios {
+		writeProblem(w, r, fmt.Sprintf("simulation must not contain more than %d scenarios", maxScenarios), http.StatusBadRequest)
+		return
+	}
+	for i, scenario := range request.Scenarios {
+		if scenario.Name == "" {
+			writeProblem(w, r, fmt.Sprintf("scenario %d has no name", i), http.StatusBadRequest)
+			return
+		}
+	}
+
+	ctx = withSimulation(ctx)
+	base, err := calculate(ctx, request.Base)
+	if err != nil {
+		telemetry.RecordError(span, err)
+		writeOperationError(w, r, err, "Error calculating simulation base")
+		return
+	}
+
+	// Feed the scenario indexes to the workers, each result is written to its own slot
+	results := make([]ScenarioResult, len(request.Scenarios))
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for range min(simulationWorkers, len(request.Scenarios)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				results[i] = simulateScenario(ctx, i, request.Base, request.Scenarios[i], base)
+			}
+		}()
+	}
+	for i := range request.Scenarios {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+	if writeContextError(w, r) {
+		return
+	}
+
+	writeJSON(w, http.StatusOK, SimulationResponse{Base: base, Scenarios: results})
+	slog.InfoContext(ctx, "Simulated prices", "scenarios", len(request.Scenarios), "base_total", base.TotalPrice.String())
+}
+
+// Prices one scenario of a simulation in its own span
+func simulateScenario(ctx context.Context, index int, request pricing.PriceRequest, scenario Scenario, base pricing.PriceResponse) ScenarioResult {
+	ctx, span := tracer.Start(ctx, "SimulateScenario")
+	defer span.End()
+	span.SetAttributes(attribute.String("scenario.name", scenario.Name), attribute.Int("scenario.index", index))
+
+	result := ScenarioResult{Name: scenario.Name}
+	request, err := applyScenario(ctx, request, scenario, base.Currency)
+	if err == nil {
+		var response pricing.PriceResponse
+		if response, err = calculate(ctx, request); err == nil {
+			result.Result = &response
+			if response.Currency == base.Currency {
+				difference := response.TotalPrice.Sub(base.TotalPrice)
+				result.Difference = &difference
+			}
+			span.SetAttributes(attribute.String("scenario.total_price", response.TotalPrice.String()))
+			return result
+		}
+	}
+	telemetry.RecordError(span, err)
+	slog.WarnContext(ctx, "Error calculating scenario", "scenario", scenario.Name, "error", err)
+	result.Error = err.Error()
+	return result
+}
+
+// Returns the base request with the scenario's changes applied, converting an explicit
+// base price from the base currency when the scenario switches currency
+func applyScenario(ctx context.Context, request pricing.PriceRequest, scenario Scenario, baseCurrency string) (pricing.PriceRequest, error) {
+	if scenario.BasePrice != nil {
+		request.BasePrice = scenario.BasePrice
+	}
+	if scenario.TaxRate != nil {
+		request.TaxRate = scenario.TaxRate
+		request.Taxes = nil
+		request.Jurisdiction = nil
+	}
+	if scenario.Quantity != 0 {
+		request.Quantity = scenario.Quantity
+	}
+	if scenario.CouponCode != nil {
+		request.CouponCode = *scenario.CouponCode
+	}
+	if scenario.Currency != "" {
+		currency, err := pricing.LookupCurrency(scenario.Currency)
+		if err != nil {
+			return request, err
+		}
+		if request.BasePrice != nil && scenario.BasePrice == nil && currency.Code != baseCurrency {
+			rate, _, err := exchangeRate(ctx, baseCurrency, currency.Code)
+			if err != nil {
+				return request, &pricing.ValidationError{Message: err.Error()}
+			}
+			converted := request.BasePrice.MulRate(rate)
+			request.BasePrice = &converted
+		}
+		request.Currency = currency.Code
+	}
+	return request, nil
+}
@@ -0,0 +1,287 @@
+package httpapi
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+
+	"otpl/pricecalculator/internal/config"
+)
+
+// Name of the request duration histogram the objectives are evaluated from
+const requestDurationMetric = "price_calculator.request.duration"
+
+// How often the request duration histogram is read to update the burn rates
+const sloEvaluationInterval = 10 * time.Second
+
+// Burn rate windows used when the configuration file sets none
+var defaultSLOWindows = []time.Duration{5 * time.Minute, time.Hour, 6 * time.Hour}
+
+// Bucket boundaries of the request duration in ms, the default OpenTelemetry ones,
+// completed with the latency thresholds of the objectives by requestDurationBuckets
+var requestDurationBaseBuckets = []float64{0, 5, 10, 25, 50, 75, 100, 250, 500, 750, 1000, 2500, 5000, 7500, 10000}
+
+// Reader of the request duration histogram, collected in process to evaluate the objectives
+var sloReader = sdkmetric.NewManualReader()
+
+// Duration of every instrumented request by operation, tenant and server error
+var requestDuration metric.Float64Histogram
+
+// SLOWindow structure for the requests of an objective over a burn rate window
+type SLOWindow struct {
+	Window      string  `json:"window"`
+	Requests    int64   `json:"requests"`
+	BadRequests int64   `json:"bad_requests"`
+	BurnRate    float64 `json:"burn_rate"` // Rate the error budget is used at, 1 uses it up exactly at the end of the window
+}
+
+// SLOStatus structure for an objective and its burn rates in the output of GET /slo
+type SLOStatus struct {
+	Name                 string      `json:"name"`
+	Type                 string      `json:"type"`
+	Operation            string      `json:"operation,omitempty"`
+	Tenant               string      `json:"tenant,omitempty"`
+	ThresholdMS          float64     `json:"threshold_ms,omitempty"`
+	Target               float64     `json:"target"`
+	ErrorBudgetRemaining float64     `json:"error_budget_remaining"` // Over the longest window, negative once overspent
+	Windows              []SLOWindow `json:"windows"`
+}
+
+// SLOReport structure for the output of GET /slo
+type SLOReport struct {
+	EvaluatedAt *time.Time  `json:"evaluated_at,omitempty"` // Unset until the first evaluation
+	Objectives  []SLOStatus `json:"objectives"`
+}
+
+// Cumulative requests and bad requests of an objective
+type sloCount struct {
+	total, bad int64
+}
+
+// Counts of every objective at a point in time
+type sloSnapshot struct {
+	at     time.Time
+	counts map[string]sloCount
+}
+
+// Snapshots covering the longest window and the report computed from them, guarded by sloMu
+var sloSnapshots []sloSnapshot
+var sloReport = SLOReport{Objectives: []SLOStatus{}}
+var sloMu sync.Mutex
+
+// Returns the request duration buckets with the latency thresholds of the objectives as boundaries,
+// so the requests within a threshold are counted exactly
+func requestDurationBuckets() []float64 {
+	buckets := slices.Clone(requestDurationBaseBuckets)
+	for _, o := range fileConfig.Load().SLO.Objectives {
+		if o.Type == config.ObjectiveLatency {
+			buckets = append(buckets, durationMS(o.Threshold))
+		}
+	}
+	slices.Sort(buckets)
+	return slices.Compact(buckets)
+}
+
+// Returns the burn rate windows of the configuration file
+func sloWindows() []time.Duration {
+	if windows := fileConfig.Load().SLO.Windows; len(windows) > 0 {
+		return windows
+	}
+	return defaultSLOWindows
+}
+
+// Wraps a handler to record the duration of the request, with the tenant when it is a known one
+// and whether it failed with a server error
+func measureRequest(handler http.HandlerFunc, operation string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &accessLogWriter{ResponseWriter: w}
+		handler(rec, r)
+		attrs := []attribute.KeyValue{
+			attribute.String("operation", operation),
+			attribute.Bool("error", rec.status >= http.StatusInternalServerError),
+		}
+		if tenant := knownTenantID(r); tenant != "" {
+			attrs = append(attrs, attribute.String("tenant", tenant))
+		}
+		requestDuration.Record(r.Context(), durationMS(time.Since(start)), metric.WithAttributes(attrs...))
+	}
+}
+
+// Returns the ID of the tenant named by the path or the X-Tenant-ID header when it exists,
+// so unknown IDs cannot grow the attribute sets of the histogram
+func knownTenantID(r *http.Request) string {
+	id, inPath := mux.Vars(r)["tenant"]
+	if !inPath {
+		id = r.Header.Get("X-Tenant-ID")
+	}
+	if id == "" {
+		return ""
+	}
+	if _, ok, err := tenants.Get(r.Context(), id); err != nil || !ok {
+		return ""
+	}
+	return id
+}
+
+// Returns a duration in fractional ms
+func durationMS(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
+
+// Evaluates the objectives until ctx is done
+func runSLOEvaluator(ctx context.Context) {
+	ticker := time.NewTicker(sloEvaluationInterval)
+	defer ticker.Stop()
+	for {
+		evaluateSLOs(ctx, time.Now())
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Reads the request duration histogram, keeps the counts of the objectives
+// and computes their burn rates over each window
+func evaluateSLOs(ctx context.Context, now time.Time) {
+	objectives := fileConfig.Load().SLO.Objectives
+	windows := sloWindows()
+
+	var rm metricdata.ResourceMetrics
+	if err := sloReader.Collect(ctx, &rm); err != nil {
+		slog.ErrorContext(ctx, "Failed to collect request durations", "error", err)
+		return
+	}
+	var points []metricdata.HistogramDataPoint[float64]
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			if histogram, ok := m.Data.(metricdata.Histogram[float64]); ok && m.Name == requestDurationMetric {
+				points = append(points, histogram.DataPoints...)
+			}
+		}
+	}
+	snapshot := sloSnapshot{at: now, counts: make(map[string]sloCount, len(objectives))}
+	for _, o := range objectives {
+		snapshot.counts[o.Name] = countRequests(o, points)
+	}
+
+	sloMu.Lock()
+	defer sloMu.Unlock()
+	sloSnapshots = append(sloSnapshots, snapshot)
+	// Keep the last snapshot taken before the longest window started, as its base
+	longest := slices.Max(windows)
+	for len(sloSnapshots) > 1 && !sloSnapshots[1].at.After(now.Add(-longest)) {
+		sloSnapshots = sloSnapshots[1:]
+	}
+
+	report := SLOReport{EvaluatedAt: &now, Objectives: make([]SLOStatus, 0, len(objectives))}
+	for _, o := range objectives {
+		status := SLOStatus{Name: o.Name, Type: o.Type, Operation: o.Operation, Tenant: o.Tenant, Target: o.Target, Windows: []SLOWindow{}}
+		if o.Type == config.ObjectiveLatency {
+			status.ThresholdMS = durationMS(o.Threshold)
+		}
+		for _, window := range windows {
+			w := burnRate(o, window, now)
+			status.Windows = append(status.Windows, w)
+			if window == longest {
+				status.ErrorBudgetRemaining = 1 - w.BurnRate
+			}
+		}
+		report.Objectives = append(report.Objectives, status)
+	}
+	sloReport = report
+}
+
+// Returns the requests and bad requests of an objective in the histogram data points.
+// A latency threshold that is no bucket boundary, added since the histogram was created,
+// is rounded down to one, counting the requests of the bucket it falls in as bad.
+func countRequests(o config.Objective, points []metricdata.HistogramDataPoint[float64]) sloCount {
+	var count sloCount
+	threshold := durationMS(o.Threshold)
+	for _, p := range points {
+		if o.Operation != "" && !hasAttribute(p.Attributes, "operation", o.Operation) {
+			continue
+		}
+		if o.Tenant != "" && !hasAttribute(p.Attributes, "tenant", o.Tenant) {
+			continue
+		}
+		count.total += int64(p.Count)
+		switch o.Type {
+		case config.ObjectiveLatency:
+			good := uint64(0)
+			for i, bound := range p.Bounds {
+				if bound > threshold {
+					break
+				}
+				good += p.BucketCounts[i]
+			}
+			count.bad += int64(p.Count - good)
+		case config.ObjectiveErrors:
+			if failed, ok := p.Attributes.Value("error"); ok && failed.AsBool() {
+				count.bad += int64(p.Count)
+			}
+		}
+	}
+	return count
+}
+
+// Reports whether a string attribute has the given value
+func hasAttribute(set attribute.Set, key, value string) bool {
+	v, ok := set.Value(attribute.Key(key))
+	return ok && v.AsString() == value
+}
+
+// Returns the requests of an objective over a window and the rate its error budget was used at,
+// from the last snapshot taken before the window started, or the oldest one while the service
+// has not been up for the whole window. Requires sloMu.
+func burnRate(o config.Objective, window time.Duration, now time.Time) SLOWindow {
+	latest := sloSnapshots[len(sloSnapshots)-1].counts[o.Name]
+	base := sloSnapshots[0].counts[o.Name]
+	for _, s := range sloSnapshots {
+		if s.at.After(now.Add(-window)) {
+			break
+		}
+		base = s.counts[o.Name]
+	}
+	w := SLOWindow{Window: formatWindow(window), Requests: latest.total - base.total, BadRequests: latest.bad - base.bad}
+	if w.Requests > 0 {
+		w.BurnRate = float64(w.BadRequests) / float64(w.Requests) / (1 - o.Target)
+	}
+	return w
+}
+
+// Returns a window without its zero minutes and seconds, such as 5m or 1h
+func formatWindow(d time.Duration) string {
+	s := d.String()
+	if strings.HasSuffix(s, "m0s") {
+		s = strings.TrimSuffix(s, "0s")
+	}
+	if strings.HasSuffix(s, "h0m") {
+		s = strings.TrimSuffix(s, "0m")
+	}
+	return s
+}
+
+// Returns the last evaluation of the objectives
+func currentSLOReport() SLOReport {
+	sloMu.Lock()
+	defer sloMu.Unlock()
+	return sloReport
+}
+
+// Returns the objectives with their requests and burn rates over each window
+func getSLOs(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, currentSLOReport())
+}
@@ -0,0 +1,45 @@
+package httpapi
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.18.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"otpl/pricecalculator/internal/telemetry"
+)
+
+// Records err on the active span of a request together with the HTTP status it is answered with
+// and the class of the error
+func recordHTTPError(ctx context.Context, err error, status int) {
+	span := trace.SpanFromContext(ctx)
+	span.SetAttributes(semconv.HTTPStatusCode(status))
+	telemetry.RecordError(span, err)
+	setErrorClass(ctx, errorClass(status))
+}
+
+// Recovers from a panic in a handler, recording it with its stack trace on the active span
+// and answering with 500. http.ErrAbortHandler is passed on so net/http aborts the response.
+func recoverPanic(w http.ResponseWriter, r *http.Request) {
+	recovered := recover()
+	if recovered == nil {
+		return
+	}
+	if recovered == http.ErrAbortHandler {
+		panic(recovered)
+	}
+
+	err := fmt.Errorf("panic: %v", recovered)
+	stack := string(debug.Stack())
+	span := trace.SpanFromContext(r.Context())
+	span.SetAttributes(semconv.HTTPStatusCode(http.StatusInternalServerError))
+	telemetry.RecordError(span, err, attribute.Bool("exception.escaped", true), attribute.String("exception.stacktrace", stack))
+	setErrorClass(r.Context(), errorClassInternal)
+	slog.ErrorContext(r.Context(), "Recovered from panic in handler", "error", err, "stack", stack)
+	encodeProblem(w, r, "Internal server error", http.StatusInternalServerError)
+}
@@ -0,0 +1,150 @@
+package httpapi
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"otpl/pricecalculator/internal/telemetry"
+)
+
+// Timeout of a single dependency probe
+const dependencyProbeTimeout = 2 * time.Second
+
+// States of a dependency
+const (
+	dependencyOK       = "ok"
+	dependencyDown     = "down"
+	dependencyDisabled = "disabled" // Not configured, nothing to probe
+)
+
+// Soft dependencies of the service, the service keeps calculating prices without them
+const (
+	dependencyCollector    = "collector"
+	dependencyDatabase     = "database"
+	dependencyCache        = "cache"
+	dependencyExchangeRate = "exchange_rate_provider"
+)
+
+// Database of the stores, nil unless STORAGE_DRIVER keeps the state in a database
+var storeDB *sql.DB
+
+// Last errors of the dependencies by name, from probes and from regular use, guarded by dependencyErrorsMu
+var dependencyErrors = map[string]dependencyError{}
+var dependencyErrorsMu sync.Mutex
+
+// dependencyError holds the last error of a dependency and when it happened
+type dependencyError struct {
+	message string
+	at      time.Time
+}
+
+// DependencyStatus structure for the health of one dependency
+type DependencyStatus struct {
+	Name        string     `json:"name"`
+	Status      string     `json:"status"`               // ok, down or disabled
+	LatencyMS   float64    `json:"latency_ms"`           // Duration of the probe
+	LastError   string     `json:"last_error,omitempty"` // Last error seen, even when the dependency has recovered
+	LastErrorAt *time.Time `json:"last_error_at,omitempty"`
+}
+
+// StatusResponse structure for the output of /status
+type StatusResponse struct {
+	Status       string             `json:"status"` // ok, or degraded when a dependency is down
+	Dependencies []DependencyStatus `json:"dependencies"`
+}
+
+// Remembers the last error of a dependency for /status
+func recordDependencyError(name string, err error) {
+	dependencyErrorsMu.Lock()
+	dependencyErrors[name] = dependencyError{message: err.Error(), at: time.Now().UTC()}
+	dependencyErrorsMu.Unlock()
+}
+
+// Returns the probes of the configured dependencies by name
+func dependencyProbes() map[string]func(ctx context.Context) error {
+	probes := map[string]func(ctx context.Context) error{
+		dependencyCollector: func(ctx context.Context) error { return checkCollector(otlpSettings.Endpoint) },
+	}
+	if db := storeDB; db != nil {
+		probes[dependencyDatabase] = db.PingContext
+	}
+	if cache := resultCache; cache != nil {
+		probes[dependencyCache] = func(ctx context.Context) error { return cache.client.Ping(ctx).Err() }
+	}
+	if provider := os.Getenv("EXCHANGE_RATE_URL"); provider != "" {
+		probes[dependencyExchangeRate] = func(ctx context.Context) error {
+			_, err := fetchExchangeRate(ctx, provider, "USD", "EUR")
+			return err
+		}
+	}
+	return probes
+}
+
+// Reports the health of each soft dependency with its probe latency and last error.
+// The probes run concurrently, each in its own span, and a failing dependency only
+// degrades the status, so the endpoint always answers 200.
+func getStatus(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracer.Start(r.Context(), "GetStatus")
+	defer span.End()
+
+	names := []string{dependencyCollector, dependencyDatabase, dependencyCache, dependencyExchangeRate}
+	probes := dependencyProbes()
+	response := StatusResponse{Status: dependencyOK, Dependencies: make([]DependencyStatus, len(names))}
+	var wg sync.WaitGroup
+	for i, name := range names {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			response.Dependencies[i] = probeDependency(ctx, name, probes[name])
+		}()
+	}
+	wg.Wait()
+
+	for _, dependency := range response.Dependencies {
+		if dependency.Status == dependencyDown {
+			response.Status = "degraded"
+		}
+	}
+	span.SetAttributes(attribute.String("status", response.Status))
+	writeJSON(w, http.StatusOK, response)
+}
+
+// Probes one dependency in a span recording its status and latency
+func probeDependency(ctx context.Context, name string, probe func(ctx context.Context) error) DependencyStatus {
+	ctx, span := tracer.Start(ctx, "ProbeDependency", trace.WithAttributes(attribute.String("dependency.name", name)))
+	defer span.End()
+
+	result := DependencyStatus{Name: name, Status: dependencyDisabled}
+	if probe != nil {
+		ctx, cancel := context.WithTimeout(ctx, dependencyProbeTimeout)
+		defer cancel()
+		start := time.Now()
+		err := probe(ctx)
+		result.LatencyMS = float64(time.Since(start).Microseconds()) / 1000
+		result.Status = dependencyOK
+		if err != nil {
+			if errors.Is(err, context.DeadlineExceeded) {
+				err = errors.New("probe timed out")
+			}
+			telemetry.RecordError(span, err)
+			recordDependencyError(name, err)
+			result.Status = dependencyDown
+		}
+	}
+
+	dependencyErrorsMu.Lock()
+	if last, ok := dependencyErrors[name]; ok {
+		result.LastError, result.LastErrorAt = last.message, &last.at
+	}
+	dependencyErrorsMu.Unlock()
+	span.SetAttributes(attribute.String("dependency.status", result.Status), attribute.Float64("dependency.latency_ms", result.LatencyMS))
+	return result
+}
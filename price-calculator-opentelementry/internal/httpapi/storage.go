@@ -0,0 +1,435 @@
+package httpapi
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"otpl/pricecalculator/internal/config"
+	"otpl/pricecalculator/pricing"
+)
+
+// Supported storage drivers
+const (
+	storageMemory   = "memory"   // Nothing survives a restart
+	storageFile     = "file"     // JSON files next to STORAGE_PATH
+	storageDatabase = "database" // The database of CATALOG_DRIVER and CATALOG_DSN
+	storageSQLite   = "sqlite"   // Configuration in the SQLite file at STORAGE_PATH, the rest in the database
+)
+
+// Storage persists the pricing configuration across restarts
+type Storage interface {
+	// Load returns the stored configuration, or the zero Config if nothing has been stored yet
+	Load(ctx context.Context) (pricing.Config, error)
+	// Save replaces the stored configuration
+	Save(ctx context.Context, cfg pricing.Config) error
+}
+
+// Opens the configuration store and the stores of the rest of the state with the driver selected by
+// STORAGE_DRIVER or the storage section of the configuration file. Returns a function closing them.
+func openStores() (func(), error) {
+	file := fileConfig.Load().Storage
+	driver := config.Setting("STORAGE_DRIVER", file.Driver)
+	path := config.Setting("STORAGE_PATH", file.Path)
+	switch driver {
+	case storageMemory:
+		storage = &memoryStorage{}
+		return func() {}, openMemoryStores("")
+	case "", storageFile:
+		if path == "" {
+			path = "pricecalculator.json"
+		}
+		storage = newFileStorage(path)
+		return func() {}, openMemoryStores(filepath.Dir(path))
+	case storageDatabase, storageSQLite:
+		return openDatabaseStores(driver, path)
+	default:
+		return nil, fmt.Errorf("unknown storage driver %q", driver)
+	}
+}
+
+// Opens the catalog, quote, coupon, history, schedule, tax rate, exchange rate, audit, discount, tenant,
+// customer, tax rule, surcharge, webhook, configuration version and feature flag stores keeping their
+// state in memory, each saved to its own JSON file in dir unless dir is empty
+func openMemoryStores(dir string) error {
+	file := func(name string) *jsonFile {
+		if dir == "" {
+			return nil
+		}
+		return &jsonFile{path: filepath.Join(dir, name)}
+	}
+	lines := func(name, legacy string) *jsonLines {
+		if dir == "" {
+			return nil
+		}
+		f := &jsonLines{path: filepath.Join(dir, name)}
+		if legacy != "" {
+			f.legacy = filepath.Join(dir, legacy)
+		}
+		return f
+	}
+	var err error
+	if catalog, err = newMemoryCatalog(file("products.json")); err != nil {
+		return err
+	}
+	if quotes, err = newMemoryQuoteStore(file("quotes.json")); err != nil {
+		return err
+	}
+	if coupons, err = newMemoryCouponStore(file("coupons.json")); err != nil {
+		return err
+	}
+	if history, err = newMemoryHistoryStore(lines("history.jsonl", "history.json")); err != nil {
+		return err
+	}
+	if schedule, err = newMemoryScheduleStore(file("schedule.json")); err != nil {
+		return err
+	}
+	if taxRateHistory, err = newMemoryTaxRateStore(file("tax_rates.json")); err != nil {
+		return err
+	}
+	if rateStore, err = newMemoryRateStore(file("exchange_rates.json")); err != nil {
+		return err
+	}
+	if auditLog, err = newMemoryAuditStore(lines("audit.jsonl", "audit.json")); err != nil {
+		return err
+	}
+	if discounts, err = newMemoryDiscountStore(file("discounts.json")); err != nil {
+		return err
+	}
+	if tenants, err = newMemoryTenantStore(file("tenants.json")); err != nil {
+		return err
+	}
+	if customers, err = newMemoryCustomerStore(file("customers.json")); err != nil {
+		return err
+	}
+	if taxRules, err = newMemoryTaxRuleStore(file("tax_rules.json")); err != nil {
+		return err
+	}
+	if surcharges, err = newMemorySurchargeStore(file("surcharges.json")); err != nil {
+		return err
+	}
+	if webhooks, err = newMemoryWebhookStore(file("webhooks.json")); err != nil {
+		return err
+	}
+	if configVersions, err = newMemoryConfigVersionStore(lines("config_versions.jsonl", "")); err != nil {
+		return err
+	}
+	flagOverrides, err = newMemoryFlagStore(file("flags.json"))
+	return err
+}
+
+// Opens the stores keeping their state in the database. The sqlite driver keeps
+// the configuration in its own SQLite file at path, as it did before the database stores.
+func openDatabaseStores(driver, path string) (func(), error) {
+	db, err := openDatabase()
+	if err != nil {
+		return nil, err
+	}
+	configDB := db
+	closeAll := func() {
+		if configDB != db {
+			configDB.Close()
+		}
+		db.Close()
+	}
+	fail := func(err error) (func(), error) {
+		closeAll()
+		return nil, err
+	}
+
+	if driver == storageSQLite {
+		if path == "" {
+			path = "pricecalculator.db"
+		}
+		if configDB, err = sql.Open("sqlite", path); err != nil {
+			configDB = db
+			return fail(fmt.Errorf("failed to open %s: %v", path, err))
+		}
+	}
+	if storage, err = newSQLStorage(configDB); err != nil {
+		return fail(err)
+	}
+	if catalog, err = newSQLCatalog(db); err != nil {
+		return fail(err)
+	}
+	if quotes, err = newSQLQuoteStore(db); err != nil {
+		return fail(err)
+	}
+	if coupons, err = newSQLCouponStore(db); err != nil {
+		return fail(err)
+	}
+	if history, err = newSQLHistoryStore(db); err != nil {
+		return fail(err)
+	}
+	if schedule, err = newSQLScheduleStore(db); err != nil {
+		return fail(err)
+	}
+	if taxRateHistory, err = newSQLTaxRateStore(db); err != nil {
+		return fail(err)
+	}
+	if rateStore, err = newSQLRateStore(db); err != nil {
+		return fail(err)
+	}
+	if auditLog, err = newSQLAuditStore(db); err != nil {
+		return fail(err)
+	}
+	if discounts, err = newSQLDiscountStore(db); err != nil {
+		return fail(err)
+	}
+	if tenants, err = newSQLTenantStore(db); err != nil {
+		return fail(err)
+	}
+	if customers, err = newSQLCustomerStore(db); err != nil {
+		return fail(err)
+	}
+	if taxRules, err = newSQLTaxRuleStore(db); err != nil {
+		return fail(err)
+	}
+	if surcharges, err = newSQLSurchargeStore(db); err != nil {
+		return fail(err)
+	}
+	if webhooks, err = newSQLWebhookStore(db); err != nil {
+		return fail(err)
+	}
+	if configVersions, err = newSQLConfigVersionStore(db); err != nil {
+		return fail(err)
+	}
+	if flagOverrides, err = newSQLFlagStore(db); err != nil {
+		return fail(err)
+	}
+	storeDB = db
+	return closeAll, nil
+}
+
+// jsonFile is a file holding the state of a store as JSON, replaced as a whole on every change
+type jsonFile struct {
+	path string
+}
+
+// Decodes the file into v, leaving v unchanged if the file does not exist yet
+// or a memory store has no file
+func (f *jsonFile) load(v any) error {
+	if f == nil {
+		return nil
+	}
+	data, err := os.ReadFile(f.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %v", f.path, err)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("failed to parse %s: %v", f.path, err)
+	}
+	return nil
+}
+
+// Replaces the file with v encoded as JSON
+func (f *jsonFile) save(v any) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode %s: %v", filepath.Base(f.path), err)
+	}
+
+	// Write to a temporary file and rename it so a crash never leaves a partial file
+	tmp, err := os.CreateTemp(filepath.Dir(f.path), filepath.Base(f.path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %v", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write %s: %v", tmp.Name(), err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %v", tmp.Name(), err)
+	}
+	if err := os.Rename(tmp.Name(), f.path); err != nil {
+		return fmt.Errorf("failed to replace %s: %v", f.path, err)
+	}
+	return nil
+}
+
+// Saves v to the file of a memory store, nothing to do without one
+func (f *jsonFile) persist(v any) error {
+	if f == nil {
+		return nil
+	}
+	return f.save(v)
+}
+
+// jsonLines is a file holding the records of an append-only store, one JSON document per line,
+// so adding a record writes that record only
+type jsonLines struct {
+	path   string
+	legacy string // JSON file of the records from before they were kept as lines, moved over on load
+	lines  int    // Lines in the file
+}
+
+// Decodes the records of the file into records, leaving them unchanged if the file does not exist yet
+// or a memory store has no file. A last line cut short by a crash while appending is skipped.
+func loadJSONLines[T any](f *jsonLines, records *[]T) error {
+	if f == nil {
+		return nil
+	}
+	data, err := os.ReadFile(f.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return f.loadLegacy(records)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %v", f.path, err)
+	}
+	lines := bytes.Split(data, []byte("\n"))
+	for i, line := range lines {
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		var record T
+		if err := json.Unmarshal(line, &record); err != nil {
+			if i == len(lines)-1 {
+				// Without its newline, drop the line so the next record starts on a line of its own
+				return f.rewrite(records)
+			}
+			return fmt.Errorf("failed to parse %s line %d: %v", f.path, i+1, err)
+		}
+		*records = append(*records, record)
+		f.lines++
+	}
+	return nil
+}
+
+// Moves the records of the legacy JSON file, if there is one, into the file
+func (f *jsonLines) loadLegacy(records any) error {
+	if f.legacy == "" {
+		return nil
+	}
+	legacy := &jsonFile{path: f.legacy}
+	if _, err := os.Stat(legacy.path); errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err := legacy.load(records); err != nil {
+		return err
+	}
+	if err := f.rewrite(records); err != nil {
+		return err
+	}
+	if err := os.Remove(legacy.path); err != nil {
+		return fmt.Errorf("failed to remove %s: %v", legacy.path, err)
+	}
+	return nil
+}
+
+// Appends a record to the file of a memory store, nothing to do without one
+func (f *jsonLines) append(record any) error {
+	if f == nil {
+		return nil
+	}
+	data, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s record: %v", filepath.Base(f.path), err)
+	}
+	file, err := os.OpenFile(f.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %v", f.path, err)
+	}
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to append to %s: %v", f.path, err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to append to %s: %v", f.path, err)
+	}
+	f.lines++
+	return nil
+}
+
+// Replaces the file with one line per record of records, a pointer to a slice,
+// dropping the lines of records no longer kept
+func (f *jsonLines) rewrite(records any) error {
+	if f == nil {
+		return nil
+	}
+	var encoded []json.RawMessage
+	data, err := json.Marshal(records)
+	if err == nil {
+		err = json.Unmarshal(data, &encoded)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to encode %s: %v", filepath.Base(f.path), err)
+	}
+	var buf bytes.Buffer
+	for _, record := range encoded {
+		buf.Write(record)
+		buf.WriteByte('\n')
+	}
+
+	// Write to a temporary file and rename it so a crash never leaves a partial file
+	tmp, err := os.CreateTemp(filepath.Dir(f.path), filepath.Base(f.path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %v", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(buf.Bytes()); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write %s: %v", tmp.Name(), err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %v", tmp.Name(), err)
+	}
+	if err := os.Rename(tmp.Name(), f.path); err != nil {
+		return fmt.Errorf("failed to replace %s: %v", f.path, err)
+	}
+	f.lines = len(encoded)
+	return nil
+}
+
+// memoryStorage keeps the configuration in memory only, nothing survives a restart
+type memoryStorage struct {
+	mu  sync.Mutex
+	cfg pricing.Config
+}
+
+func (s *memoryStorage) Load(ctx context.Context) (pricing.Config, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.cfg, nil
+}
+
+func (s *memoryStorage) Save(ctx context.Context, cfg pricing.Config) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cfg = cfg
+	return nil
+}
+
+// fileStorage keeps the configuration in a JSON file
+type fileStorage struct {
+	mu   sync.Mutex
+	file jsonFile
+}
+
+func newFileStorage(path string) *fileStorage {
+	return &fileStorage{file: jsonFile{path: path}}
+}
+
+func (s *fileStorage) Load(ctx context.Context) (pricing.Config, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var cfg pricing.Config
+	err := s.file.load(&cfg)
+	return cfg, err
+}
+
+func (s *fileStorage) Save(ctx context.Context, cfg pricing.Config) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.file.save(cfg)
+}
@@ -0,0 +1,163 @@
+package httpapi
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	_ "modernc.org/sqlite" // Registers the pure Go "sqlite" driver
+
+	"otpl/pricecalculator/pricing"
+)
+
+// sqlStorage keeps the configuration in a single-row SQLite or PostgreSQL table
+type sqlStorage struct {
+	db *sql.DB
+}
+
+// Creates the configuration storage in db, adding its table if needed
+func newSQLStorage(db *sql.DB) (*sqlStorage, error) {
+	_, err := db.Exec(`CREATE TABLE IF NOT EXISTS config (
+		id         INTEGER PRIMARY KEY CHECK (id = 1),
+		base_price TEXT NOT NULL,
+		tax_rate   DOUBLE PRECISION NOT NULL
+	)`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create config table: %v", err)
+	}
+	for _, column := range []string{"currency", "rounding"} {
+		if err := addColumn(db, "config", column, "TEXT NOT NULL DEFAULT ''"); err != nil {
+			return nil, err
+		}
+	}
+	return &sqlStorage{db: db}, nil
+}
+
+// Adds a column to a table created before the column existed
+func addColumn(db *sql.DB, table, column, definition string) error {
+	if _, err := db.Exec(fmt.Sprintf(`SELECT %s FROM %s WHERE 1 = 0`, column, table)); err == nil {
+		return nil
+	}
+	if _, err := db.Exec(fmt.Sprintf(`ALTER TABLE %s ADD COLUMN %s %s`, table, column, definition)); err != nil {
+		return fmt.Errorf("failed to add %s column to %s table: %v", column, table, err)
+	}
+	return nil
+}
+
+// Reports whether db is a SQLite database rather than PostgreSQL
+func isSQLite(db *sql.DB) bool {
+	var version string
+	return db.QueryRow(`SELECT sqlite_version()`).Scan(&version) == nil
+}
+
+// Returns the definition of an id primary key generated by the database on insert:
+// AUTOINCREMENT on SQLite, so IDs of deleted rows are never reused, and an identity on PostgreSQL
+func generatedID(db *sql.DB) string {
+	if isSQLite(db) {
+		return "INTEGER PRIMARY KEY AUTOINCREMENT"
+	}
+	return "BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY"
+}
+
+// Creates a table whose id is generated by the database, create holding %s for the definition
+// of the id column. A table created before its IDs were generated is migrated: PostgreSQL turns
+// the column into an identity continuing after the largest ID, and SQLite copies the rows to
+// a new table since it cannot alter a column.
+func createWithGeneratedID(db *sql.DB, table, create string) error {
+	create = fmt.Sprintf(create, generatedID(db))
+	if _, err := db.Exec(create); err != nil {
+		return fmt.Errorf("failed to create %s table: %v", table, err)
+	}
+	if !isSQLite(db) {
+		var identity string
+		err := db.QueryRow(`SELECT is_identity FROM information_schema.columns
+			WHERE table_schema = current_schema() AND table_name = $1 AND column_name = 'id'`, table).Scan(&identity)
+		if err != nil {
+			return fmt.Errorf("failed to inspect %s table: %v", table, err)
+		}
+		if identity == "YES" {
+			return nil
+		}
+		tx, err := db.Begin()
+		if err != nil {
+			return fmt.Errorf("failed to begin transaction: %v", err)
+		}
+		defer tx.Rollback()
+		if _, err := tx.Exec(fmt.Sprintf(`ALTER TABLE %s ALTER COLUMN id ADD GENERATED BY DEFAULT AS IDENTITY`, table)); err != nil {
+			return fmt.Errorf("failed to generate %s ids: %v", table, err)
+		}
+		if _, err := tx.Exec(fmt.Sprintf(`SELECT setval(pg_get_serial_sequence('%[1]s', 'id'), COALESCE(MAX(id), 0) + 1, false) FROM %[1]s`, table)); err != nil {
+			return fmt.Errorf("failed to generate %s ids: %v", table, err)
+		}
+		return tx.Commit()
+	}
+
+	var definition string
+	if err := db.QueryRow(`SELECT sql FROM sqlite_master WHERE type = 'table' AND name = $1`, table).Scan(&definition); err != nil {
+		return fmt.Errorf("failed to inspect %s table: %v", table, err)
+	}
+	if strings.Contains(strings.ToUpper(definition), "AUTOINCREMENT") {
+		return nil
+	}
+	rows, err := db.Query(fmt.Sprintf(`SELECT name FROM pragma_table_info('%s')`, table))
+	if err != nil {
+		return fmt.Errorf("failed to inspect %s table: %v", table, err)
+	}
+	var columns []string
+	for rows.Next() {
+		var column string
+		if err := rows.Scan(&column); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to inspect %s table: %v", table, err)
+		}
+		columns = append(columns, column)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to inspect %s table: %v", table, err)
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+	list := strings.Join(columns, ", ")
+	for _, statement := range []string{
+		fmt.Sprintf(`ALTER TABLE %[1]s RENAME TO %[1]s_old`, table),
+		create,
+		fmt.Sprintf(`INSERT INTO %[1]s (%[2]s) SELECT %[2]s FROM %[1]s_old`, table, list),
+		fmt.Sprintf(`DROP TABLE %s_old`, table),
+	} {
+		if _, err := tx.Exec(statement); err != nil {
+			return fmt.Errorf("failed to migrate %s table: %v", table, err)
+		}
+	}
+	return tx.Commit()
+}
+
+func (s *sqlStorage) Load(ctx context.Context) (pricing.Config, error) {
+	var cfg pricing.Config
+	err := s.db.QueryRowContext(ctx, `SELECT base_price, tax_rate, currency, rounding FROM config WHERE id = 1`).
+		Scan(&cfg.BasePrice, &cfg.TaxRate, &cfg.Currency, &cfg.Rounding)
+	if errors.Is(err, sql.ErrNoRows) {
+		return cfg, nil
+	}
+	if err != nil {
+		return cfg, fmt.Errorf("failed to load config: %v", err)
+	}
+	return cfg, nil
+}
+
+func (s *sqlStorage) Save(ctx context.Context, cfg pricing.Config) error {
+	_, err := s.db.ExecContext(ctx, `INSERT INTO config (id, base_price, tax_rate, currency, rounding) VALUES (1, $1, $2, $3, $4)
+		ON CONFLICT (id) DO UPDATE SET base_price = excluded.base_price, tax_rate = excluded.tax_rate,
+			currency = excluded.currency, rounding = excluded.rounding`,
+		cfg.BasePrice, cfg.TaxRate, cfg.Currency, string(cfg.Rounding))
+	if err != nil {
+		return fmt.Errorf("failed to save config: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package httpapi
+
+import (
+	"context"
+	"sync"
+
+	"go.opentelemetry.io/otel/trace"
+
+	"otpl/pricecalculator/pricing"
+)
+
+// PriceStore holds the default base price and tax rate, safe for concurrent use
+type PriceStore struct {
+	mu  sync.RWMutex
+	cfg pricing.Config
+
+	// Serializes updates, held while they persist so readers only wait for the values to be swapped
+	updateMu sync.Mutex
+
+	// Spans of the changes that set the current base price and tax rate, invalid for the startup values
+	basePriceOrigin trace.SpanContext
+	taxRateOrigin   trace.SpanContext
+}
+
+// Creates a store with the given initial values
+func newPriceStore(cfg pricing.Config) *PriceStore {
+	return &PriceStore{cfg: cfg}
+}
+
+// Get returns a consistent snapshot of the base price and tax rate
+func (s *PriceStore) Get() pricing.Config {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.cfg
+}
+
+// Set replaces the base price and tax rate, waiting for an update in progress
+func (s *PriceStore) Set(cfg pricing.Config) {
+	s.updateMu.Lock()
+	defer s.updateMu.Unlock()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cfg = cfg
+}
+
+// Update applies fn to a copy of the current values and keeps the result only if fn succeeds.
+// Updates are serialized, so fn can persist the new values without racing other writers, while
+// Get keeps returning the current values until fn returns.
+// The span of ctx is remembered as the origin of the values the update changed.
+func (s *PriceStore) Update(ctx context.Context, fn func(cfg *pricing.Config) error) (pricing.Config, error) {
+	s.updateMu.Lock()
+	defer s.updateMu.Unlock()
+	cfg := s.Get()
+	if err := fn(&cfg); err != nil {
+		return s.Get(), err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	origin := trace.SpanContextFromContext(ctx)
+	if !cfg.BasePrice.Equal(s.cfg.BasePrice) {
+		s.basePriceOrigin = origin
+	}
+	if cfg.TaxRate != s.cfg.TaxRate {
+		s.taxRateOrigin = origin
+	}
+	s.cfg = cfg
+	return cfg, nil
+}
+
+// Origins returns the spans of the changes that set the current base price and tax rate
+func (s *PriceStore) Origins() (basePrice, taxRate trace.SpanContext) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.basePriceOrigin, s.taxRateOrigin
+}
@@ -0,0 +1,174 @@
+package httpapi
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+
+	"otpl/pricecalculator/pricing"
+)
+
+func TestPriceStoreConcurrentUpdates(t *testing.T) {
+	s := newPriceStore(pricing.Config{})
+	const writers, updates = 8, 50
+
+	// Readers check that the base price and tax rate, always changed together, are never seen apart
+	done := make(chan struct{})
+	var readers sync.WaitGroup
+	for range 4 {
+		readers.Add(1)
+		go func() {
+			defer readers.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				cfg := s.Get()
+				if !cfg.BasePrice.Equal(pricing.MoneyFromFloat(cfg.TaxRate)) {
+					t.Errorf("torn read: base price %v, tax rate %v", cfg.BasePrice, cfg.TaxRate)
+					return
+				}
+				s.Origins()
+			}
+		}()
+	}
+
+	var wg sync.WaitGroup
+	for range writers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range updates {
+				_, err := s.Update(context.Background(), func(cfg *pricing.Config) error {
+					cfg.TaxRate++
+					cfg.BasePrice = pricing.MoneyFromFloat(cfg.TaxRate)
+					return nil
+				})
+				if err != nil {
+					t.Errorf("Update: %v", err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(done)
+	readers.Wait()
+
+	if got := s.Get().TaxRate; got != writers*updates {
+		t.Errorf("tax rate = %v after %d increments, lost updates", got, writers*updates)
+	}
+}
+
+func TestPriceStoreGetDuringUpdate(t *testing.T) {
+	s := newPriceStore(pricing.Config{TaxRate: 10})
+	saving := make(chan struct{})
+	saved := make(chan struct{})
+	go s.Update(context.Background(), func(cfg *pricing.Config) error {
+		cfg.TaxRate = 20
+		close(saving)
+		<-saved // A slow save of the new values
+		return nil
+	})
+	<-saving
+
+	got := make(chan float64)
+	go func() { got <- s.Get().TaxRate }()
+	select {
+	case rate := <-got:
+		if rate != 10 {
+			t.Errorf("tax rate = %v while saving, want the current 10", rate)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Get blocked while an update was saving")
+	}
+
+	close(saved)
+	deadline := time.Now().Add(time.Second)
+	for s.Get().TaxRate != 20 {
+		if time.Now().After(deadline) {
+			t.Fatal("update not applied after saving")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestPriceStoreSetWaitsForUpdate(t *testing.T) {
+	s := newPriceStore(pricing.Config{TaxRate: 10})
+	saving := make(chan struct{})
+	saved := make(chan struct{})
+	updated := make(chan struct{})
+	go func() {
+		defer close(updated)
+		s.Update(context.Background(), func(cfg *pricing.Config) error {
+			cfg.TaxRate = 20
+			close(saving)
+			<-saved
+			return nil
+		})
+	}()
+	<-saving
+
+	set := make(chan struct{})
+	go func() {
+		s.Set(pricing.Config{TaxRate: 30})
+		close(set)
+	}()
+	select {
+	case <-set:
+		t.Fatal("Set did not wait for the update in progress")
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(saved)
+	<-updated
+	<-set
+	if got := s.Get().TaxRate; got != 30 {
+		t.Errorf("tax rate = %v, want 30 set after the update", got)
+	}
+}
+
+func TestPriceStoreFailedUpdate(t *testing.T) {
+	s := newPriceStore(pricing.Config{BasePrice: pricing.MoneyFromFloat(100), TaxRate: 10})
+	saveErr := errors.New("disk full")
+	cfg, err := s.Update(context.Background(), func(cfg *pricing.Config) error {
+		cfg.TaxRate = 20
+		return saveErr
+	})
+	if err != saveErr {
+		t.Fatalf("err = %v, want %v", err, saveErr)
+	}
+	if cfg.TaxRate != 10 || s.Get().TaxRate != 10 {
+		t.Errorf("tax rate = %v, stored %v after a failed update, want 10", cfg.TaxRate, s.Get().TaxRate)
+	}
+	if _, taxRate := s.Origins(); taxRate.IsValid() {
+		t.Error("failed update set the tax rate origin")
+	}
+}
+
+func TestPriceStoreOrigins(t *testing.T) {
+	s := newPriceStore(pricing.Config{BasePrice: pricing.MoneyFromFloat(100), TaxRate: 10})
+	origin := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: trace.TraceID{1},
+		SpanID:  trace.SpanID{1},
+	})
+	ctx := trace.ContextWithSpanContext(context.Background(), origin)
+	_, err := s.Update(ctx, func(cfg *pricing.Config) error {
+		cfg.TaxRate = 20
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	basePrice, taxRate := s.Origins()
+	if basePrice.IsValid() {
+		t.Error("base price origin set though the base price did not change")
+	}
+	if !taxRate.Equal(origin) {
+		t.Errorf("tax rate origin = %v, want %v", taxRate.SpanID(), origin.SpanID())
+	}
+}
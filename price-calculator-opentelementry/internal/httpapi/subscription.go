@@ -0,0 +1,49 @@
+package httpapi
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+
+	"otpl/pricecalculator/internal/telemetry"
+	"otpl/pricecalculator/pricing"
+)
+
+// Calculates the next invoice of a subscription, prorating a mid-cycle plan change
+func calculateSubscription(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	// Start a new span for the subscription calculation
+	ctx, span := tracer.Start(ctx, "CalculateSubscription")
+	defer span.End()
+
+	var request pricing.SubscriptionRequest
+	if !decodeJSON(w, r, &request) {
+		return
+	}
+
+	// Price with the default tax rate and currency of the request's tenant, changing the plan today unless given
+	defaults, currency := tenantDefaults(ctx)
+	if request.Currency == "" {
+		request.Currency = currency
+	}
+	if request.ChangeDate == "" {
+		request.ChangeDate = time.Now().UTC().Format(time.DateOnly)
+	}
+	response, err := pricing.CalculateSubscription(ctx, request, defaults, roundingMode(defaults, request.Currency))
+	if err != nil {
+		telemetry.RecordError(span, err)
+		writeOperationError(w, r, err, "Error calculating subscription")
+		return
+	}
+
+	// Record the invoice alongside the single price calculations
+	totalPriceCounter.Add(ctx, response.NextInvoice.Float64(), metric.WithAttributes(attribute.String("currency", response.Currency)))
+
+	writeJSON(w, http.StatusOK, response)
+	slog.InfoContext(ctx, "Calculated subscription invoice", "next_invoice", response.NextInvoice.String(),
+		"next_invoice_date", response.NextInvoiceDate, "currency", response.Currency)
+}
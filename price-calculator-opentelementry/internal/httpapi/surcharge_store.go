@@ -0,0 +1,207 @@
+package httpapi
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+
+	"otpl/pricecalculator/pricing"
+)
+
+// SurchargeStore persists the surcharge rules added to every calculation
+type SurchargeStore interface {
+	// List returns all surcharge rules in ID order
+	List(ctx context.Context) ([]pricing.SurchargeRule, error)
+	// Get returns the surcharge rule with the ID, false if there is none
+	Get(ctx context.Context, id string) (pricing.SurchargeRule, bool, error)
+	// Create adds a surcharge rule under a new ID
+	Create(ctx context.Context, rule pricing.SurchargeRule) (pricing.SurchargeRule, error)
+	// Update replaces a surcharge rule, false if there is none with its ID
+	Update(ctx context.Context, rule pricing.SurchargeRule) (bool, error)
+	// Delete removes a surcharge rule, false if there is none with the ID
+	Delete(ctx context.Context, id string) (bool, error)
+}
+
+// Surcharge store opened on startup
+var surcharges SurchargeStore
+
+// memorySurchargeStore keeps the surcharge rules in memory, saved to a JSON file when it has one
+type memorySurchargeStore struct {
+	mu     sync.Mutex
+	rules  map[string]pricing.SurchargeRule
+	nextID int
+	file   *jsonFile
+}
+
+// Creates the in-memory surcharge store, loading the rules from file
+func newMemorySurchargeStore(file *jsonFile) (*memorySurchargeStore, error) {
+	s := &memorySurchargeStore{rules: map[string]pricing.SurchargeRule{}, file: file}
+	if err := file.load(&s.rules); err != nil {
+		return nil, err
+	}
+	for id := range s.rules {
+		s.nextID = max(s.nextID, surchargeRuleID(id))
+	}
+	return s, nil
+}
+
+func (s *memorySurchargeStore) List(ctx context.Context) ([]pricing.SurchargeRule, error) {
+	s.mu.Lock()
+	list := make([]pricing.SurchargeRule, 0, len(s.rules))
+	for _, rule := range s.rules {
+		list = append(list, rule)
+	}
+	s.mu.Unlock()
+	sort.Slice(list, func(i, j int) bool { return surchargeRuleID(list[i].ID) < surchargeRuleID(list[j].ID) })
+	return list, nil
+}
+
+func (s *memorySurchargeStore) Get(ctx context.Context, id string) (pricing.SurchargeRule, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	rule, ok := s.rules[id]
+	return rule, ok, nil
+}
+
+func (s *memorySurchargeStore) Create(ctx context.Context, rule pricing.SurchargeRule) (pricing.SurchargeRule, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.nextID++
+	rule.ID = strconv.Itoa(s.nextID)
+	s.rules[rule.ID] = rule
+	return rule, s.file.persist(s.rules)
+}
+
+func (s *memorySurchargeStore) Update(ctx context.Context, rule pricing.SurchargeRule) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.rules[rule.ID]; !ok {
+		return false, nil
+	}
+	s.rules[rule.ID] = rule
+	return true, s.file.persist(s.rules)
+}
+
+func (s *memorySurchargeStore) Delete(ctx context.Context, id string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.rules[id]; !ok {
+		return false, nil
+	}
+	delete(s.rules, id)
+	return true, s.file.persist(s.rules)
+}
+
+// sqlSurchargeStore keeps the surcharge rules in a SQLite or PostgreSQL table
+type sqlSurchargeStore struct {
+	db *sql.DB
+}
+
+// Creates the surcharge store in db, adding its table if needed
+func newSQLSurchargeStore(db *sql.DB) (*sqlSurchargeStore, error) {
+	_, err := db.Exec(`CREATE TABLE IF NOT EXISTS surcharges (
+		id        BIGINT PRIMARY KEY,
+		name      TEXT NOT NULL,
+		type      TEXT NOT NULL,
+		amount    TEXT NOT NULL DEFAULT '0',
+		rate      DOUBLE PRECISION NOT NULL DEFAULT 0,
+		after_tax BOOLEAN NOT NULL DEFAULT FALSE
+	)`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create surcharges table: %v", err)
+	}
+	return &sqlSurchargeStore{db: db}, nil
+}
+
+// Columns of a surcharge rule in the order scanSurcharge reads them
+const surchargeColumns = `id, name, type, amount, rate, after_tax`
+
+// Reads a surcharge rule selected with surchargeColumns
+func scanSurcharge(row interface{ Scan(...any) error }) (pricing.SurchargeRule, error) {
+	var rule pricing.SurchargeRule
+	var id int64
+	if err := row.Scan(&id, &rule.Name, &rule.Type, &rule.Amount, &rule.Rate, &rule.AfterTax); err != nil {
+		return rule, err
+	}
+	rule.ID = strconv.FormatInt(id, 10)
+	return rule, nil
+}
+
+func (s *sqlSurchargeStore) List(ctx context.Context) ([]pricing.SurchargeRule, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT `+surchargeColumns+` FROM surcharges ORDER BY id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list surcharges: %v", err)
+	}
+	defer rows.Close()
+
+	list := []pricing.SurchargeRule{}
+	for rows.Next() {
+		rule, err := scanSurcharge(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read surcharge: %v", err)
+		}
+		list = append(list, rule)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list surcharges: %v", err)
+	}
+	return list, nil
+}
+
+func (s *sqlSurchargeStore) Get(ctx context.Context, id string) (pricing.SurchargeRule, bool, error) {
+	n, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return pricing.SurchargeRule{}, false, nil
+	}
+	rule, err := scanSurcharge(s.db.QueryRowContext(ctx, `SELECT `+surchargeColumns+` FROM surcharges WHERE id = $1`, n))
+	if errors.Is(err, sql.ErrNoRows) {
+		return rule, false, nil
+	}
+	if err != nil {
+		return rule, false, fmt.Errorf("failed to load surcharge: %v", err)
+	}
+	return rule, true, nil
+}
+
+func (s *sqlSurchargeStore) Create(ctx context.Context, rule pricing.SurchargeRule) (pricing.SurchargeRule, error) {
+	var id int64
+	err := s.db.QueryRowContext(ctx, `INSERT INTO surcharges (`+surchargeColumns+`)
+		SELECT COALESCE(MAX(id), 0) + 1, CAST($1 AS TEXT), CAST($2 AS TEXT), CAST($3 AS TEXT),
+			CAST($4 AS DOUBLE PRECISION), CAST($5 AS BOOLEAN) FROM surcharges
+		RETURNING id`,
+		rule.Name, rule.Type, rule.Amount, rule.Rate, rule.AfterTax).Scan(&id)
+	if err != nil {
+		return rule, fmt.Errorf("failed to create surcharge: %v", err)
+	}
+	rule.ID = strconv.FormatInt(id, 10)
+	return rule, nil
+}
+
+func (s *sqlSurchargeStore) Update(ctx context.Context, rule pricing.SurchargeRule) (bool, error) {
+	id, err := strconv.ParseInt(rule.ID, 10, 64)
+	if err != nil {
+		return false, nil
+	}
+	result, err := s.db.ExecContext(ctx, `UPDATE surcharges SET name = $2, type = $3, amount = $4, rate = $5, after_tax = $6 WHERE id = $1`,
+		id, rule.Name, rule.Type, rule.Amount, rule.Rate, rule.AfterTax)
+	if err != nil {
+		return false, fmt.Errorf("failed to update surcharge: %v", err)
+	}
+	return affected(result)
+}
+
+func (s *sqlSurchargeStore) Delete(ctx context.Context, id string) (bool, error) {
+	n, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return false, nil
+	}
+	result, err := s.db.ExecContext(ctx, `DELETE FROM surcharges WHERE id = $1`, n)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete surcharge: %v", err)
+	}
+	return affected(result)
+}
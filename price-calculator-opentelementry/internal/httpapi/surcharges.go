@@ -0,0 +1,131 @@
+package httpapi
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"otpl/pricecalculator/pricing"
+)
+
+// Parses the numeric part of a surcharge rule ID for ordering
+func surchargeRuleID(id string) int {
+	n, _ := strconv.Atoi(id)
+	return n
+}
+
+// Lists all surcharge rules
+func listSurcharges(w http.ResponseWriter, r *http.Request) {
+	rules, err := surcharges.List(r.Context())
+	if err != nil {
+		writeOperationError(w, r, err, "Error listing surcharge rules")
+		return
+	}
+	writeJSON(w, http.StatusOK, rules)
+}
+
+// Creates a surcharge rule
+func createSurcharge(w http.ResponseWriter, r *http.Request) {
+	var rule pricing.SurchargeRule
+	if !decodeJSON(w, r, &rule) {
+		return
+	}
+	if err := rule.Validate(); err != nil {
+		slog.WarnContext(r.Context(), "Invalid surcharge rule", "error", err)
+		writeProblem(w, r, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	rule, err := surcharges.Create(r.Context(), rule)
+	if err != nil {
+		writeOperationError(w, r, err, "Error creating surcharge rule")
+		return
+	}
+
+	trace.SpanFromContext(r.Context()).SetAttributes(attribute.String("surcharge.id", rule.ID))
+	recordAudit(r.Context(), auditSurchargeCreated, "surcharges/"+rule.ID, nil, rule)
+	writeJSON(w, http.StatusCreated, rule)
+	slog.InfoContext(r.Context(), "Surcharge rule created", "surcharge_id", rule.ID)
+}
+
+// Returns a single surcharge rule
+func getSurcharge(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	rule, ok, err := surcharges.Get(r.Context(), id)
+	if err != nil {
+		writeOperationError(w, r, err, "Error loading surcharge rule")
+		return
+	}
+	if !ok {
+		writeProblem(w, r, "Surcharge rule not found", http.StatusNotFound)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, rule)
+}
+
+// Replaces a surcharge rule
+func updateSurcharge(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	var rule pricing.SurchargeRule
+	if !decodeJSON(w, r, &rule) {
+		return
+	}
+	if err := rule.Validate(); err != nil {
+		slog.WarnContext(r.Context(), "Invalid surcharge rule", "error", err)
+		writeProblem(w, r, err.Error(), http.StatusBadRequest)
+		return
+	}
+	rule.ID = id
+
+	previous, _, err := surcharges.Get(r.Context(), id)
+	if err != nil {
+		writeOperationError(w, r, err, "Error loading surcharge rule")
+		return
+	}
+	ok, err := surcharges.Update(r.Context(), rule)
+	if err != nil {
+		writeOperationError(w, r, err, "Error updating surcharge rule")
+		return
+	}
+	if !ok {
+		writeProblem(w, r, "Surcharge rule not found", http.StatusNotFound)
+		return
+	}
+
+	trace.SpanFromContext(r.Context()).SetAttributes(attribute.String("surcharge.id", id))
+	recordAudit(r.Context(), auditSurchargeUpdated, "surcharges/"+id, previous, rule)
+	writeJSON(w, http.StatusOK, rule)
+	slog.InfoContext(r.Context(), "Surcharge rule updated", "surcharge_id", id)
+}
+
+// Deletes a surcharge rule
+func deleteSurcharge(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	previous, _, err := surcharges.Get(r.Context(), id)
+	if err != nil {
+		writeOperationError(w, r, err, "Error loading surcharge rule")
+		return
+	}
+	ok, err := surcharges.Delete(r.Context(), id)
+	if err != nil {
+		writeOperationError(w, r, err, "Error deleting surcharge rule")
+		return
+	}
+	if !ok {
+		writeProblem(w, r, "Surcharge rule not found", http.StatusNotFound)
+		return
+	}
+
+	trace.SpanFromContext(r.Context()).SetAttributes(attribute.String("surcharge.id", id))
+	recordAudit(r.Context(), auditSurchargeDeleted, "surcharges/"+id, previous, nil)
+	w.WriteHeader(http.StatusNoContent)
+	slog.InfoContext(r.Context(), "Surcharge rule deleted", "surcharge_id", id)
+}
@@ -0,0 +1,153 @@
+package httpapi
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// TaxRatePeriod structure for a default tax rate and the time it took effect,
+// valid until the next period takes effect
+type TaxRatePeriod struct {
+	TaxRate       float64   `json:"tax_rate"`
+	EffectiveFrom time.Time `json:"effective_from"`
+	Author        string    `json:"author"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// TaxRateStore persists the history of the default tax rate
+type TaxRateStore interface {
+	// List returns all periods by effective time
+	List(ctx context.Context) ([]TaxRatePeriod, error)
+	// At returns the period in effect at t, false if none had taken effect yet
+	At(ctx context.Context, t time.Time) (TaxRatePeriod, bool, error)
+	// Add adds a period, replacing the one taking effect at the same time
+	Add(ctx context.Context, period TaxRatePeriod) error
+}
+
+// Tax rate history store opened on startup
+var taxRateHistory TaxRateStore
+
+// memoryTaxRateStore keeps the tax rate history in memory, saved to a JSON file when it has one
+type memoryTaxRateStore struct {
+	mu      sync.Mutex
+	periods []TaxRatePeriod // By effective time
+	file    *jsonFile
+}
+
+// Creates the in-memory tax rate history store, loading the periods from file
+func newMemoryTaxRateStore(file *jsonFile) (*memoryTaxRateStore, error) {
+	s := &memoryTaxRateStore{periods: []TaxRatePeriod{}, file: file}
+	if err := file.load(&s.periods); err != nil {
+		return nil, err
+	}
+	sort.Slice(s.periods, func(i, j int) bool { return s.periods[i].EffectiveFrom.Before(s.periods[j].EffectiveFrom) })
+	return s, nil
+}
+
+func (s *memoryTaxRateStore) List(ctx context.Context) ([]TaxRatePeriod, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]TaxRatePeriod{}, s.periods...), nil
+}
+
+func (s *memoryTaxRateStore) At(ctx context.Context, t time.Time) (TaxRatePeriod, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := sort.Search(len(s.periods), func(i int) bool { return s.periods[i].EffectiveFrom.After(t) })
+	if i == 0 {
+		return TaxRatePeriod{}, false, nil
+	}
+	return s.periods[i-1], true, nil
+}
+
+func (s *memoryTaxRateStore) Add(ctx context.Context, period TaxRatePeriod) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := sort.Search(len(s.periods), func(i int) bool { return !s.periods[i].EffectiveFrom.Before(period.EffectiveFrom) })
+	if i < len(s.periods) && s.periods[i].EffectiveFrom.Equal(period.EffectiveFrom) {
+		s.periods[i] = period
+	} else {
+		s.periods = append(s.periods[:i], append([]TaxRatePeriod{period}, s.periods[i:]...)...)
+	}
+	return s.file.persist(s.periods)
+}
+
+// sqlTaxRateStore keeps the tax rate history in a SQLite or PostgreSQL table,
+// with times in Unix nanoseconds so the period in effect can be selected by time
+type sqlTaxRateStore struct {
+	db *sql.DB
+}
+
+// Creates the tax rate history store in db, adding its table if needed
+func newSQLTaxRateStore(db *sql.DB) (*sqlTaxRateStore, error) {
+	_, err := db.Exec(`CREATE TABLE IF NOT EXISTS tax_rate_history (
+		effective_from BIGINT PRIMARY KEY,
+		tax_rate       DOUBLE PRECISION NOT NULL,
+		author         TEXT NOT NULL DEFAULT '',
+		created_at     BIGINT NOT NULL
+	)`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create tax_rate_history table: %v", err)
+	}
+	return &sqlTaxRateStore{db: db}, nil
+}
+
+// Reads a tax rate period selected as effective_from, tax_rate, author, created_at
+func scanTaxRatePeriod(row interface{ Scan(...any) error }) (TaxRatePeriod, error) {
+	var p TaxRatePeriod
+	var effectiveFrom, createdAt int64
+	if err := row.Scan(&effectiveFrom, &p.TaxRate, &p.Author, &createdAt); err != nil {
+		return p, err
+	}
+	p.EffectiveFrom = time.Unix(0, effectiveFrom).UTC()
+	p.CreatedAt = time.Unix(0, createdAt).UTC()
+	return p, nil
+}
+
+func (s *sqlTaxRateStore) List(ctx context.Context) ([]TaxRatePeriod, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT effective_from, tax_rate, author, created_at FROM tax_rate_history ORDER BY effective_from`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tax rate history: %v", err)
+	}
+	defer rows.Close()
+
+	list := []TaxRatePeriod{}
+	for rows.Next() {
+		p, err := scanTaxRatePeriod(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read tax rate period: %v", err)
+		}
+		list = append(list, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list tax rate history: %v", err)
+	}
+	return list, nil
+}
+
+func (s *sqlTaxRateStore) At(ctx context.Context, t time.Time) (TaxRatePeriod, bool, error) {
+	p, err := scanTaxRatePeriod(s.db.QueryRowContext(ctx, `SELECT effective_from, tax_rate, author, created_at FROM tax_rate_history
+		WHERE effective_from <= $1 ORDER BY effective_from DESC LIMIT 1`, t.UnixNano()))
+	if errors.Is(err, sql.ErrNoRows) {
+		return p, false, nil
+	}
+	if err != nil {
+		return p, false, fmt.Errorf("failed to load tax rate period: %v", err)
+	}
+	return p, true, nil
+}
+
+func (s *sqlTaxRateStore) Add(ctx context.Context, p TaxRatePeriod) error {
+	_, err := s.db.ExecContext(ctx, `INSERT INTO tax_rate_history (effective_from, tax_rate, author, created_at) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (effective_from) DO UPDATE SET tax_rate = excluded.tax_rate, author = excluded.author, created_at = excluded.created_at`,
+		p.EffectiveFrom.UnixNano(), p.TaxRate, p.Author, p.CreatedAt.UnixNano())
+	if err != nil {
+		return fmt.Errorf("failed to add tax rate period: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package httpapi
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"otpl/pricecalculator/pricing"
+)
+
+// TaxRatePeriodRequest structure for recording a past default tax rate
+type TaxRatePeriodRequest struct {
+	TaxRate       *float64  `json:"tax_rate"`
+	EffectiveFrom time.Time `json:"effective_from"`
+}
+
+// Records a default tax rate taking effect at the given time
+func recordTaxRate(ctx context.Context, taxRate float64, effectiveFrom time.Time) {
+	period := TaxRatePeriod{TaxRate: taxRate, EffectiveFrom: effectiveFrom.UTC(), Author: author(ctx), CreatedAt: time.Now().UTC()}
+	if err := taxRateHistory.Add(ctx, period); err != nil {
+		slog.WarnContext(ctx, "Failed to record tax rate history", "error", err)
+	}
+}
+
+// Records the default tax rate loaded on startup unless it is already the one in effect,
+// such as after a change made while the service was stopped
+func syncTaxRateHistory(ctx context.Context, taxRate float64) {
+	now := time.Now()
+	period, ok, err := taxRateHistory.At(ctx, now)
+	if err != nil {
+		slog.WarnContext(ctx, "Failed to load tax rate history", "error", err)
+		return
+	}
+	if !ok || period.TaxRate != taxRate {
+		recordTaxRate(ctx, taxRate, now)
+	}
+}
+
+// Returns the default tax rate in effect at t for a calculation dated as_of.
+// Tenants have no tax rate history, and nothing can be priced before the history starts.
+func taxRateAt(ctx context.Context, t time.Time) (TaxRatePeriod, error) {
+	if tenantID(ctx) != "" {
+		return TaxRatePeriod{}, &pricing.ValidationError{Message: "as_of is only supported with the default configuration"}
+	}
+	if t.After(time.Now()) {
+		return TaxRatePeriod{}, &pricing.ValidationError{Message: "as_of must not be in the future"}
+	}
+	period, ok, err := taxRateHistory.At(ctx, t)
+	if err != nil {
+		return period, err
+	}
+	if !ok {
+		return period, &pricing.ValidationError{Message: "no tax rate was in effect at " + t.UTC().Format(time.RFC3339)}
+	}
+	trace.SpanFromContext(ctx).SetAttributes(
+		attribute.String("pricing.as_of", t.UTC().Format(time.RFC3339)),
+		attribute.String("tax.rate_effective_from", period.EffectiveFrom.Format(time.RFC3339)),
+	)
+	return period, nil
+}
+
+// Lists the default tax rates by the time they took effect
+func listTaxRateHistory(w http.ResponseWriter, r *http.Request) {
+	list, err := taxRateHistory.List(r.Context())
+	if err != nil {
+		writeOperationError(w, r, err, "Error listing tax rate history")
+		return
+	}
+	writeJSON(w, http.StatusOK, list)
+}
+
+// Records a default tax rate in effect from a past time, such as one in force before
+// the service kept the history. Future rates are scheduled at /config/schedule instead.
+func createTaxRatePeriod(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	var request TaxRatePeriodRequest
+	if !decodeJSON(w, r, &request) {
+		return
+	}
+	if request.TaxRate == nil {
+		writeProblem(w, r, "tax_rate is required", http.StatusBadRequest)
+		return
+	}
+	if err := pricing.ValidateTaxRate(*request.TaxRate); err != nil {
+		writeProblem(w, r, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if request.EffectiveFrom.IsZero() {
+		writeProblem(w, r, "effective_from is required", http.StatusBadRequest)
+		return
+	}
+	if request.EffectiveFrom.After(time.Now()) {
+		writeProblem(w, r, "effective_from must not be in the future, schedule future changes at /config/schedule", http.StatusBadRequest)
+		return
+	}
+
+	period := TaxRatePeriod{TaxRate: *request.TaxRate, EffectiveFrom: request.EffectiveFrom.UTC(), Author: author(ctx), CreatedAt: time.Now().UTC()}
+	if err := taxRateHistory.Add(ctx, period); err != nil {
+		writeOperationError(w, r, err, "Error recording tax rate")
+		return
+	}
+	trace.SpanFromContext(ctx).SetAttributes(attribute.String("tax.rate_effective_from", period.EffectiveFrom.Format(time.RFC3339)))
+	writeJSON(w, http.StatusCreated, period)
+	slog.InfoContext(ctx, "Tax rate recorded", "tax_rate", period.TaxRate, "effective_from", period.EffectiveFrom)
+}
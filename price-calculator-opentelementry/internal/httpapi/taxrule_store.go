@@ -0,0 +1,211 @@
+package httpapi
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+
+	"otpl/pricecalculator/pricing"
+)
+
+// TaxRuleStore persists the tax rules of the jurisdictions
+type TaxRuleStore interface {
+	// List returns all tax rules in ID order
+	List(ctx context.Context) ([]pricing.TaxRule, error)
+	// Get returns the tax rule with the ID, false if there is none
+	Get(ctx context.Context, id string) (pricing.TaxRule, bool, error)
+	// Create adds a tax rule under a new ID
+	Create(ctx context.Context, rule pricing.TaxRule) (pricing.TaxRule, error)
+	// Update replaces a tax rule, false if there is none with its ID
+	Update(ctx context.Context, rule pricing.TaxRule) (bool, error)
+	// Delete removes a tax rule, false if there is none with the ID
+	Delete(ctx context.Context, id string) (bool, error)
+}
+
+// Tax rule store opened on startup
+var taxRules TaxRuleStore
+
+// memoryTaxRuleStore keeps the tax rules in memory, saved to a JSON file when it has one
+type memoryTaxRuleStore struct {
+	mu     sync.Mutex
+	rules  map[string]pricing.TaxRule
+	nextID int
+	file   *jsonFile
+}
+
+// Creates the in-memory tax rule store, loading the rules from file
+func newMemoryTaxRuleStore(file *jsonFile) (*memoryTaxRuleStore, error) {
+	s := &memoryTaxRuleStore{rules: map[string]pricing.TaxRule{}, file: file}
+	if err := file.load(&s.rules); err != nil {
+		return nil, err
+	}
+	for id := range s.rules {
+		s.nextID = max(s.nextID, taxRuleID(id))
+	}
+	return s, nil
+}
+
+func (s *memoryTaxRuleStore) List(ctx context.Context) ([]pricing.TaxRule, error) {
+	s.mu.Lock()
+	list := make([]pricing.TaxRule, 0, len(s.rules))
+	for _, rule := range s.rules {
+		list = append(list, rule)
+	}
+	s.mu.Unlock()
+	sort.Slice(list, func(i, j int) bool { return taxRuleID(list[i].ID) < taxRuleID(list[j].ID) })
+	return list, nil
+}
+
+func (s *memoryTaxRuleStore) Get(ctx context.Context, id string) (pricing.TaxRule, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	rule, ok := s.rules[id]
+	return rule, ok, nil
+}
+
+func (s *memoryTaxRuleStore) Create(ctx context.Context, rule pricing.TaxRule) (pricing.TaxRule, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.nextID++
+	rule.ID = strconv.Itoa(s.nextID)
+	s.rules[rule.ID] = rule
+	return rule, s.file.persist(s.rules)
+}
+
+func (s *memoryTaxRuleStore) Update(ctx context.Context, rule pricing.TaxRule) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.rules[rule.ID]; !ok {
+		return false, nil
+	}
+	s.rules[rule.ID] = rule
+	return true, s.file.persist(s.rules)
+}
+
+func (s *memoryTaxRuleStore) Delete(ctx context.Context, id string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.rules[id]; !ok {
+		return false, nil
+	}
+	delete(s.rules, id)
+	return true, s.file.persist(s.rules)
+}
+
+// sqlTaxRuleStore keeps the tax rules in a SQLite or PostgreSQL table, each rule
+// encoded as JSON under its numeric ID
+type sqlTaxRuleStore struct {
+	db *sql.DB
+}
+
+// Creates the tax rule store in db, adding its table if needed
+func newSQLTaxRuleStore(db *sql.DB) (*sqlTaxRuleStore, error) {
+	_, err := db.Exec(`CREATE TABLE IF NOT EXISTS tax_rules (
+		id   BIGINT PRIMARY KEY,
+		rule TEXT NOT NULL
+	)`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create tax_rules table: %v", err)
+	}
+	return &sqlTaxRuleStore{db: db}, nil
+}
+
+// Reads a tax rule selected as id, rule
+func scanTaxRule(row interface{ Scan(...any) error }) (pricing.TaxRule, error) {
+	var rule pricing.TaxRule
+	var id int64
+	var data string
+	if err := row.Scan(&id, &data); err != nil {
+		return rule, err
+	}
+	if err := json.Unmarshal([]byte(data), &rule); err != nil {
+		return rule, fmt.Errorf("invalid stored tax rule: %v", err)
+	}
+	rule.ID = strconv.FormatInt(id, 10)
+	return rule, nil
+}
+
+func (s *sqlTaxRuleStore) List(ctx context.Context) ([]pricing.TaxRule, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT id, rule FROM tax_rules ORDER BY id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tax rules: %v", err)
+	}
+	defer rows.Close()
+
+	list := []pricing.TaxRule{}
+	for rows.Next() {
+		rule, err := scanTaxRule(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read tax rule: %v", err)
+		}
+		list = append(list, rule)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list tax rules: %v", err)
+	}
+	return list, nil
+}
+
+func (s *sqlTaxRuleStore) Get(ctx context.Context, id string) (pricing.TaxRule, bool, error) {
+	n, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return pricing.TaxRule{}, false, nil
+	}
+	rule, err := scanTaxRule(s.db.QueryRowContext(ctx, `SELECT id, rule FROM tax_rules WHERE id = $1`, n))
+	if errors.Is(err, sql.ErrNoRows) {
+		return rule, false, nil
+	}
+	if err != nil {
+		return rule, false, fmt.Errorf("failed to load tax rule: %v", err)
+	}
+	return rule, true, nil
+}
+
+func (s *sqlTaxRuleStore) Create(ctx context.Context, rule pricing.TaxRule) (pricing.TaxRule, error) {
+	rule.ID = ""
+	data, err := json.Marshal(rule)
+	if err != nil {
+		return rule, fmt.Errorf("failed to encode tax rule: %v", err)
+	}
+	var id int64
+	err = s.db.QueryRowContext(ctx, `INSERT INTO tax_rules (id, rule)
+		SELECT COALESCE(MAX(id), 0) + 1, CAST($1 AS TEXT) FROM tax_rules RETURNING id`, string(data)).Scan(&id)
+	if err != nil {
+		return rule, fmt.Errorf("failed to create tax rule: %v", err)
+	}
+	rule.ID = strconv.FormatInt(id, 10)
+	return rule, nil
+}
+
+func (s *sqlTaxRuleStore) Update(ctx context.Context, rule pricing.TaxRule) (bool, error) {
+	id, err := strconv.ParseInt(rule.ID, 10, 64)
+	if err != nil {
+		return false, nil
+	}
+	data, err := json.Marshal(rule)
+	if err != nil {
+		return false, fmt.Errorf("failed to encode tax rule: %v", err)
+	}
+	result, err := s.db.ExecContext(ctx, `UPDATE tax_rules SET rule = $2 WHERE id = $1`, id, string(data))
+	if err != nil {
+		return false, fmt.Errorf("failed to update tax rule: %v", err)
+	}
+	return affected(result)
+}
+
+func (s *sqlTaxRuleStore) Delete(ctx context.Context, id string) (bool, error) {
+	n, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return false, nil
+	}
+	result, err := s.db.ExecContext(ctx, `DELETE FROM tax_rules WHERE id = $1`, n)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete tax rule: %v", err)
+	}
+	return affected(result)
+}
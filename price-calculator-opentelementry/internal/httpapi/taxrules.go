@@ -0,0 +1,131 @@
+package httpapi
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"otpl/pricecalculator/pricing"
+)
+
+// Parses the numeric part of a tax rule ID for ordering
+func taxRuleID(id string) int {
+	n, _ := strconv.Atoi(id)
+	return n
+}
+
+// Lists all tax rules
+func listTaxRules(w http.ResponseWriter, r *http.Request) {
+	rules, err := taxRules.List(r.Context())
+	if err != nil {
+		writeOperationError(w, r, err, "Error listing tax rules")
+		return
+	}
+	writeJSON(w, http.StatusOK, rules)
+}
+
+// Creates a tax rule
+func createTaxRule(w http.ResponseWriter, r *http.Request) {
+	var rule pricing.TaxRule
+	if !decodeJSON(w, r, &rule) {
+		return
+	}
+	if err := rule.Validate(); err != nil {
+		slog.WarnContext(r.Context(), "Invalid tax rule", "error", err)
+		writeProblem(w, r, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	rule, err := taxRules.Create(r.Context(), rule)
+	if err != nil {
+		writeOperationError(w, r, err, "Error creating tax rule")
+		return
+	}
+
+	trace.SpanFromContext(r.Context()).SetAttributes(attribute.String("tax.rule_id", rule.ID))
+	recordAudit(r.Context(), auditTaxRuleCreated, "tax/rules/"+rule.ID, nil, rule)
+	writeJSON(w, http.StatusCreated, rule)
+	slog.InfoContext(r.Context(), "Tax rule created", "tax_rule_id", rule.ID)
+}
+
+// Returns a single tax rule
+func getTaxRule(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	rule, ok, err := taxRules.Get(r.Context(), id)
+	if err != nil {
+		writeOperationError(w, r, err, "Error loading tax rule")
+		return
+	}
+	if !ok {
+		writeProblem(w, r, "Tax rule not found", http.StatusNotFound)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, rule)
+}
+
+// Replaces a tax rule
+func updateTaxRule(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	var rule pricing.TaxRule
+	if !decodeJSON(w, r, &rule) {
+		return
+	}
+	if err := rule.Validate(); err != nil {
+		slog.WarnContext(r.Context(), "Invalid tax rule", "error", err)
+		writeProblem(w, r, err.Error(), http.StatusBadRequest)
+		return
+	}
+	rule.ID = id
+
+	previous, _, err := taxRules.Get(r.Context(), id)
+	if err != nil {
+		writeOperationError(w, r, err, "Error loading tax rule")
+		return
+	}
+	ok, err := taxRules.Update(r.Context(), rule)
+	if err != nil {
+		writeOperationError(w, r, err, "Error updating tax rule")
+		return
+	}
+	if !ok {
+		writeProblem(w, r, "Tax rule not found", http.StatusNotFound)
+		return
+	}
+
+	trace.SpanFromContext(r.Context()).SetAttributes(attribute.String("tax.rule_id", id))
+	recordAudit(r.Context(), auditTaxRuleUpdated, "tax/rules/"+id, previous, rule)
+	writeJSON(w, http.StatusOK, rule)
+	slog.InfoContext(r.Context(), "Tax rule updated", "tax_rule_id", id)
+}
+
+// Deletes a tax rule
+func deleteTaxRule(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	previous, _, err := taxRules.Get(r.Context(), id)
+	if err != nil {
+		writeOperationError(w, r, err, "Error loading tax rule")
+		return
+	}
+	ok, err := taxRules.Delete(r.Context(), id)
+	if err != nil {
+		writeOperationError(w, r, err, "Error deleting tax rule")
+		return
+	}
+	if !ok {
+		writeProblem(w, r, "Tax rule not found", http.StatusNotFound)
+		return
+	}
+
+	trace.SpanFromContext(r.Context()).SetAttributes(attribute.String("tax.rule_id", id))
+	recordAudit(r.Context(), auditTaxRuleDeleted, "tax/rules/"+id, previous, nil)
+	w.WriteHeader(http.StatusNoContent)
+	slog.InfoContext(r.Context(), "Tax rule deleted", "tax_rule_id", id)
+}
@@ -0,0 +1,77 @@
+package httpapi
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+
+	"otpl/pricecalculator/internal/telemetry"
+)
+
+// Providers of the application, inspected and changed through /admin/telemetry and /admin/sampling
+var providers *telemetry.Telemetry
+
+// OTLP settings in use, kept for the readiness check
+var otlpSettings telemetry.OTLPConfig
+
+// How often the spans lost since the previous check are logged
+const lostSpansLogInterval = time.Minute
+
+// Initializes OpenTelemetry
+func initOpenTelemetry(ctx context.Context) (func(), error) {
+	// Load the collector settings from the environment
+	otlpCfg, err := telemetry.LoadOTLPConfig(fileConfig.Load().OTLP)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load OTLP configuration: %v", err)
+	}
+	otlpSettings = otlpCfg
+
+	// Load the sampler settings from the environment
+	samplingCfg, err := telemetry.LoadSamplingConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load sampling configuration: %v", err)
+	}
+
+	// Create the metric reader, pushing to the collector or serving /metrics
+	metricReader, err := newMetricReader(ctx, otlpCfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create metric exporter: %v", err)
+	}
+
+	// Create the providers, batching the spans for the first configured exporter
+	// in the pipeline counted by /admin/telemetry
+	providers, err = telemetry.Setup(ctx, telemetry.Options{
+		ServiceName:    "price-calculator",
+		OTLP:           otlpCfg,
+		Sampling:       samplingCfg,
+		TraceExporters: fileConfig.Load().TraceExporters,
+		SpanProcessors: []sdktrace.SpanProcessor{baggageSpanProcessor{}}, // Copies customer and tenant baggage onto spans
+		MetricReader:   metricReader,
+		MetricReaders:  []sdkmetric.Reader{sloReader}, // Evaluates the SLOs in process
+	})
+	if err != nil {
+		return nil, err
+	}
+	slog.InfoContext(ctx, "Exporting telemetry", "endpoint", otlpCfg.Endpoint, "protocol", otlpCfg.Protocol, "sampler", samplingCfg.Sampler,
+		"trace_exporters", max(len(fileConfig.Load().TraceExporters), 1), "retry", otlpCfg.Retry.Enabled)
+	tracer = providers.TracerProvider.Tracer("price-calculator") // Create a tracer for the application
+
+	// Create the application instruments
+	if err := initMetrics(providers.MeterProvider.Meter("price-calculator")); err != nil {
+		return nil, fmt.Errorf("failed to create metric instruments: %v", err)
+	}
+
+	// Log the spans dropped or failed by the monitored pipeline
+	logCtx, stopLogging := context.WithCancel(context.WithoutCancel(ctx))
+	go providers.Pipeline.LogLostSpans(logCtx, lostSpansLogInterval)
+
+	// Return a cleanup function to shutdown the tracer, meter and logger providers
+	return func() {
+		stopLogging()
+		providers.Shutdown(ctx)
+	}, nil
+}
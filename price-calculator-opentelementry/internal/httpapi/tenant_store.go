@@ -0,0 +1,180 @@
+package httpapi
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// TenantStore persists the tenants
+type TenantStore interface {
+	// List returns all tenants in ID order
+	List(ctx context.Context) ([]Tenant, error)
+	// Get returns the tenant with the ID, false if there is none
+	Get(ctx context.Context, id string) (Tenant, bool, error)
+	// Create adds a tenant, false if there is one with its ID already
+	Create(ctx context.Context, tenant Tenant) (bool, error)
+	// Update replaces the settings of a tenant, false if there is none with its ID
+	Update(ctx context.Context, tenant Tenant) (bool, error)
+	// Delete removes a tenant, false if there is none with the ID
+	Delete(ctx context.Context, id string) (bool, error)
+}
+
+// Tenant store opened on startup
+var tenants TenantStore
+
+// memoryTenantStore keeps the tenants in memory, saved to a JSON file when it has one
+type memoryTenantStore struct {
+	mu      sync.Mutex
+	tenants map[string]Tenant
+	file    *jsonFile
+}
+
+// Creates the in-memory tenant store, loading the tenants from file
+func newMemoryTenantStore(file *jsonFile) (*memoryTenantStore, error) {
+	s := &memoryTenantStore{tenants: map[string]Tenant{}, file: file}
+	if err := file.load(&s.tenants); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *memoryTenantStore) List(ctx context.Context) ([]Tenant, error) {
+	s.mu.Lock()
+	list := make([]Tenant, 0, len(s.tenants))
+	for _, t := range s.tenants {
+		list = append(list, t)
+	}
+	s.mu.Unlock()
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+	return list, nil
+}
+
+func (s *memoryTenantStore) Get(ctx context.Context, id string) (Tenant, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t, ok := s.tenants[id]
+	return t, ok, nil
+}
+
+func (s *memoryTenantStore) Create(ctx context.Context, tenant Tenant) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, exists := s.tenants[tenant.ID]; exists {
+		return false, nil
+	}
+	s.tenants[tenant.ID] = tenant
+	return true, s.file.persist(s.tenants)
+}
+
+func (s *memoryTenantStore) Update(ctx context.Context, tenant Tenant) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.tenants[tenant.ID]; !ok {
+		return false, nil
+	}
+	s.tenants[tenant.ID] = tenant
+	return true, s.file.persist(s.tenants)
+}
+
+func (s *memoryTenantStore) Delete(ctx context.Context, id string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.tenants[id]; !ok {
+		return false, nil
+	}
+	delete(s.tenants, id)
+	return true, s.file.persist(s.tenants)
+}
+
+// sqlTenantStore keeps the tenants in a SQLite or PostgreSQL table
+type sqlTenantStore struct {
+	db *sql.DB
+}
+
+// Creates the tenant store in db, adding its table if needed
+func newSQLTenantStore(db *sql.DB) (*sqlTenantStore, error) {
+	_, err := db.Exec(`CREATE TABLE IF NOT EXISTS tenants (
+		id         TEXT PRIMARY KEY,
+		name       TEXT NOT NULL DEFAULT '',
+		currency   TEXT NOT NULL DEFAULT '',
+		base_price TEXT NOT NULL,
+		tax_rate   DOUBLE PRECISION NOT NULL
+	)`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create tenants table: %v", err)
+	}
+	return &sqlTenantStore{db: db}, nil
+}
+
+// Columns of a tenant in the order scanTenant reads them
+const tenantColumns = `id, name, currency, base_price, tax_rate`
+
+// Reads a tenant selected with tenantColumns
+func scanTenant(row interface{ Scan(...any) error }) (Tenant, error) {
+	var t Tenant
+	err := row.Scan(&t.ID, &t.Name, &t.Currency, &t.BasePrice, &t.TaxRate)
+	return t, err
+}
+
+func (s *sqlTenantStore) List(ctx context.Context) ([]Tenant, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT `+tenantColumns+` FROM tenants ORDER BY id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tenants: %v", err)
+	}
+	defer rows.Close()
+
+	list := []Tenant{}
+	for rows.Next() {
+		t, err := scanTenant(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read tenant: %v", err)
+		}
+		list = append(list, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list tenants: %v", err)
+	}
+	return list, nil
+}
+
+func (s *sqlTenantStore) Get(ctx context.Context, id string) (Tenant, bool, error) {
+	t, err := scanTenant(s.db.QueryRowContext(ctx, `SELECT `+tenantColumns+` FROM tenants WHERE id = $1`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return t, false, nil
+	}
+	if err != nil {
+		return t, false, fmt.Errorf("failed to load tenant: %v", err)
+	}
+	return t, true, nil
+}
+
+func (s *sqlTenantStore) Create(ctx context.Context, tenant Tenant) (bool, error) {
+	result, err := s.db.ExecContext(ctx, `INSERT INTO tenants (`+tenantColumns+`) VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (id) DO NOTHING`,
+		tenant.ID, tenant.Name, tenant.Currency, tenant.BasePrice, tenant.TaxRate)
+	if err != nil {
+		return false, fmt.Errorf("failed to create tenant: %v", err)
+	}
+	return affected(result)
+}
+
+func (s *sqlTenantStore) Update(ctx context.Context, tenant Tenant) (bool, error) {
+	result, err := s.db.ExecContext(ctx, `UPDATE tenants SET name = $2, currency = $3, base_price = $4, tax_rate = $5 WHERE id = $1`,
+		tenant.ID, tenant.Name, tenant.Currency, tenant.BasePrice, tenant.TaxRate)
+	if err != nil {
+		return false, fmt.Errorf("failed to update tenant: %v", err)
+	}
+	return affected(result)
+}
+
+func (s *sqlTenantStore) Delete(ctx context.Context, id string) (bool, error) {
+	result, err := s.db.ExecContext(ctx, `DELETE FROM tenants WHERE id = $1`, id)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete tenant: %v", err)
+	}
+	return affected(result)
+}
@@ -0,0 +1,224 @@
+package httpapi
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"regexp"
+
+	"github.com/gorilla/mux"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/trace"
+
+	"otpl/pricecalculator/pricing"
+)
+
+// Valid tenant IDs, usable in paths and headers
+var tenantIDPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,62}$`)
+
+// Tenant structure for a tenant with its own pricing configuration
+type Tenant struct {
+	ID        string        `json:"id"`
+	Name      string        `json:"name,omitempty"`
+	Currency  string        `json:"currency,omitempty"` // Used when a request does not specify one
+	BasePrice pricing.Money `json:"base_price"`
+	TaxRate   float64       `json:"tax_rate"`
+}
+
+// Validate checks the tenant settings
+func (t Tenant) Validate() error {
+	if !tenantIDPattern.MatchString(t.ID) {
+		return fmt.Errorf("tenant id must be lowercase letters, digits and dashes")
+	}
+	if t.Currency != "" {
+		if _, err := pricing.LookupCurrency(t.Currency); err != nil {
+			return err
+		}
+	}
+	if err := pricing.ValidateBasePrice(t.BasePrice); err != nil {
+		return err
+	}
+	return pricing.ValidateTaxRate(t.TaxRate)
+}
+
+// Context key of the tenant a request is handled for
+type tenantKey struct{}
+
+// Resolves the tenant of a request from the /tenants/{tenant} path prefix, or the X-Tenant-ID header.
+// An unknown tenant in the path is answered with 404, an unknown tenant in the header
+// only labels the telemetry and the request uses the default configuration.
+func withTenant(w http.ResponseWriter, r *http.Request) (*http.Request, bool) {
+	id, inPath := mux.Vars(r)["tenant"]
+	if !inPath {
+		id = r.Header.Get("X-Tenant-ID")
+	}
+	if id == "" {
+		return r, true
+	}
+
+	tenant, ok, err := tenants.Get(r.Context(), id)
+	if err != nil {
+		writeOperationError(w, r, err, "Error loading tenant")
+		return r, false
+	}
+	if !ok {
+		if inPath {
+			writeProblem(w, r, "Tenant not found", http.StatusNotFound)
+			return r, false
+		}
+		return r, true
+	}
+
+	// Carry the tenant in the baggage too, so the spans of the request are tagged with it
+	ctx := r.Context()
+	trace.SpanFromContext(ctx).SetAttributes(attribute.String("tenant.id", tenant.ID))
+	if member, err := baggage.NewMemberRaw("tenant_id", tenant.ID); err == nil {
+		if bag, err := baggage.FromContext(ctx).SetMember(member); err == nil {
+			ctx = baggage.ContextWithBaggage(ctx, bag)
+		}
+	}
+	return r.WithContext(context.WithValue(ctx, tenantKey{}, tenant)), true
+}
+
+// Returns the tenant a request is handled for, false for the default configuration
+func tenantFromContext(ctx context.Context) (Tenant, bool) {
+	tenant, ok := ctx.Value(tenantKey{}).(Tenant)
+	return tenant, ok
+}
+
+// Returns the ID of the tenant a request is handled for, empty for the default configuration
+func tenantID(ctx context.Context) string {
+	tenant, _ := tenantFromContext(ctx)
+	return tenant.ID
+}
+
+// Returns the default base price and tax rate, and currency, of the request's tenant
+func tenantDefaults(ctx context.Context) (pricing.Config, string) {
+	if tenant, ok := tenantFromContext(ctx); ok {
+		return pricing.Config{BasePrice: tenant.BasePrice, TaxRate: tenant.TaxRate, Rounding: prices.Get().Rounding}, tenant.Currency
+	}
+	cfg := prices.Get()
+	return cfg, cfg.Currency
+}
+
+// Lists all tenants in ID order
+func listTenants(w http.ResponseWriter, r *http.Request) {
+	list, err := tenants.List(r.Context())
+	if err != nil {
+		writeOperationError(w, r, err, "Error listing tenants")
+		return
+	}
+	writeJSON(w, http.StatusOK, list)
+}
+
+// Creates a tenant
+func createTenant(w http.ResponseWriter, r *http.Request) {
+	var tenant Tenant
+	if !decodeJSON(w, r, &tenant) {
+		return
+	}
+	if err := tenant.Validate(); err != nil {
+		slog.WarnContext(r.Context(), "Invalid tenant", "error", err)
+		writeProblem(w, r, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	created, err := tenants.Create(r.Context(), tenant)
+	if err != nil {
+		writeOperationError(w, r, err, "Error creating tenant")
+		return
+	}
+	if !created {
+		writeProblem(w, r, "Tenant already exists", http.StatusConflict)
+		return
+	}
+
+	trace.SpanFromContext(r.Context()).SetAttributes(attribute.String("tenant.id", tenant.ID))
+	recordAudit(r.Context(), auditTenantCreated, "tenants/"+tenant.ID, nil, tenant)
+	writeJSON(w, http.StatusCreated, tenant)
+	slog.InfoContext(r.Context(), "Tenant created", "tenant_id", tenant.ID)
+}
+
+// Returns a single tenant
+func getTenant(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	tenant, ok, err := tenants.Get(r.Context(), id)
+	if err != nil {
+		writeOperationError(w, r, err, "Error loading tenant")
+		return
+	}
+	if !ok {
+		writeProblem(w, r, "Tenant not found", http.StatusNotFound)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, tenant)
+}
+
+// Replaces the settings of a tenant
+func updateTenant(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	var tenant Tenant
+	if !decodeJSON(w, r, &tenant) {
+		return
+	}
+	tenant.ID = id
+	if err := tenant.Validate(); err != nil {
+		slog.WarnContext(r.Context(), "Invalid tenant", "error", err)
+		writeProblem(w, r, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	previous, _, err := tenants.Get(r.Context(), id)
+	if err != nil {
+		writeOperationError(w, r, err, "Error loading tenant")
+		return
+	}
+	ok, err := tenants.Update(r.Context(), tenant)
+	if err != nil {
+		writeOperationError(w, r, err, "Error updating tenant")
+		return
+	}
+	if !ok {
+		writeProblem(w, r, "Tenant not found", http.StatusNotFound)
+		return
+	}
+
+	trace.SpanFromContext(r.Context()).SetAttributes(attribute.String("tenant.id", id))
+	recordAudit(r.Context(), auditTenantUpdated, "tenants/"+id, previous, tenant)
+	writeJSON(w, http.StatusOK, tenant)
+	slog.InfoContext(r.Context(), "Tenant updated", "tenant_id", id)
+}
+
+// Deletes a tenant together with its discount rules
+func deleteTenant(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	previous, _, err := tenants.Get(r.Context(), id)
+	if err != nil {
+		writeOperationError(w, r, err, "Error loading tenant")
+		return
+	}
+	ok, err := tenants.Delete(r.Context(), id)
+	if err != nil {
+		writeOperationError(w, r, err, "Error deleting tenant")
+		return
+	}
+	if !ok {
+		writeProblem(w, r, "Tenant not found", http.StatusNotFound)
+		return
+	}
+	if err := discounts.DeleteTenant(r.Context(), id); err != nil {
+		writeOperationError(w, r, err, "Error deleting tenant discounts")
+		return
+	}
+
+	trace.SpanFromContext(r.Context()).SetAttributes(attribute.String("tenant.id", id))
+	recordAudit(r.Context(), auditTenantDeleted, "tenants/"+id, previous, nil)
+	w.WriteHeader(http.StatusNoContent)
+	slog.InfoContext(r.Context(), "Tenant deleted", "tenant_id", id)
+}
@@ -0,0 +1,69 @@
+package httpapi
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Time a request may take unless REQUEST_TIMEOUT or the configuration file sets one
+const defaultRequestTimeout = 30 * time.Second
+
+// Status of a request the client went away from before it was answered, as used by nginx
+const statusClientClosedRequest = 499
+
+// Returns the timeout of an operation: its entry under timeouts.endpoints in the configuration file,
+// or REQUEST_TIMEOUT, or timeouts.request in the configuration file, or the default
+func requestTimeout(operation string) (time.Duration, error) {
+	timeouts := fileConfig.Load().Timeouts
+	if timeout, ok := timeouts.Endpoints[operation]; ok {
+		return timeout, nil
+	}
+	if value := os.Getenv("REQUEST_TIMEOUT"); value != "" {
+		timeout, err := time.ParseDuration(value)
+		if err != nil || timeout <= 0 {
+			return 0, fmt.Errorf("invalid REQUEST_TIMEOUT %q", value)
+		}
+		return timeout, nil
+	}
+	if timeouts.Request > 0 {
+		return timeouts.Request, nil
+	}
+	return defaultRequestTimeout, nil
+}
+
+// Returns the request with a context that ends after the operation's timeout
+func withRequestTimeout(r *http.Request, operation string) (*http.Request, context.CancelFunc) {
+	timeout, err := requestTimeout(operation)
+	if err != nil {
+		timeout = defaultRequestTimeout // Validated on startup
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), timeout)
+	return r.WithContext(ctx), cancel
+}
+
+// Answers a request whose context ended before it was handled: 503 when its timeout expired,
+// 499 when the client went away. The span is marked as cancelled.
+// Returns false if the context has not ended.
+func writeContextError(w http.ResponseWriter, r *http.Request) bool {
+	err := r.Context().Err()
+	status, detail := http.StatusServiceUnavailable, "Request timed out"
+	switch err {
+	case nil:
+		return false
+	case context.Canceled:
+		status, detail = statusClientClosedRequest, "Client closed request"
+	}
+
+	trace.SpanFromContext(r.Context()).SetAttributes(attribute.Bool("request.cancelled", true))
+	slog.WarnContext(r.Context(), detail, "error", err)
+	recordHTTPError(r.Context(), err, status)
+	encodeProblem(w, r, detail, status)
+	return true
+}
@@ -0,0 +1,169 @@
+package httpapi
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/crypto/acme/autocert"
+
+	"otpl/pricecalculator/internal/config"
+	"otpl/pricecalculator/internal/telemetry"
+)
+
+// Time to wait for a burst of certificate file events to settle, the certificate
+// and key are usually replaced one after the other
+const certReloadDelay = 500 * time.Millisecond
+
+// Directory of the certificates obtained from Let's Encrypt unless autocert sets cache_dir
+const defaultAutocertCacheDir = "autocert"
+
+// Returns the TLS configuration of the HTTP and gRPC servers, nil when TLS is disabled.
+// Certificates read from files are reloaded when the files change and on SIGHUP until ctx is cancelled.
+func newServerTLSConfig(ctx context.Context) (*tls.Config, error) {
+	cfg := fileConfig.Load().TLS
+	certFile := config.Setting("TLS_CERT_FILE", cfg.CertFile)
+	keyFile := config.Setting("TLS_KEY_FILE", cfg.KeyFile)
+	domains := cfg.Autocert.Domains
+	if value := os.Getenv("TLS_AUTOCERT_DOMAINS"); value != "" {
+		domains = nil
+		for _, domain := range strings.Split(value, ",") {
+			if domain = strings.TrimSpace(domain); domain != "" {
+				domains = append(domains, domain)
+			}
+		}
+	}
+
+	if (certFile == "") != (keyFile == "") {
+		return nil, fmt.Errorf("TLS_CERT_FILE and TLS_KEY_FILE must be set together")
+	}
+	if certFile != "" && len(domains) > 0 {
+		return nil, fmt.Errorf("TLS certificate files and autocert domains are mutually exclusive")
+	}
+	if len(domains) > 0 {
+		cacheDir := cfg.Autocert.CacheDir
+		if cacheDir == "" {
+			cacheDir = defaultAutocertCacheDir
+		}
+		manager := &autocert.Manager{
+			Prompt:     autocert.AcceptTOS,
+			HostPolicy: autocert.HostWhitelist(domains...),
+			Cache:      autocert.DirCache(cacheDir),
+			Email:      cfg.Autocert.Email,
+		}
+		slog.Info("Obtaining TLS certificates from Let's Encrypt", "domains", domains)
+		return manager.TLSConfig(), nil
+	}
+	if certFile == "" {
+		return nil, nil
+	}
+
+	reloader := &certReloader{certFile: certFile, keyFile: keyFile}
+	if err := reloader.load(); err != nil {
+		return nil, err
+	}
+	if err := reloader.watch(ctx); err != nil {
+		return nil, err
+	}
+	return &tls.Config{MinVersion: tls.VersionTLS12, GetCertificate: reloader.getCertificate}, nil
+}
+
+// certReloader serves the certificate of a certificate and key file, swapped when they are reloaded
+type certReloader struct {
+	certFile string
+	keyFile  string
+	cert     atomic.Pointer[tls.Certificate]
+}
+
+// Returns the current certificate for a TLS handshake
+func (c *certReloader) getCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	return c.cert.Load(), nil
+}
+
+// Reads the certificate and key, keeping the current certificate if they are invalid
+func (c *certReloader) load() error {
+	cert, err := tls.LoadX509KeyPair(c.certFile, c.keyFile)
+	if err != nil {
+		return fmt.Errorf("failed to load TLS certificate: %v", err)
+	}
+	c.cert.Store(&cert)
+	return nil
+}
+
+// Reloads the certificate, traced as its own root span
+func (c *certReloader) reload(ctx context.Context, trigger string) {
+	ctx, span := tracer.Start(ctx, "ReloadCertificate", trace.WithNewRoot(),
+		trace.WithAttributes(attribute.String("tls.reload.trigger", trigger)))
+	defer span.End()
+
+	if err := c.load(); err != nil {
+		telemetry.RecordError(span, err)
+		slog.ErrorContext(ctx, "Failed to reload TLS certificate, keeping the current one", "error", err)
+		return
+	}
+	leaf := c.cert.Load().Leaf
+	span.SetAttributes(attribute.String("tls.certificate.not_after", leaf.NotAfter.Format(time.RFC3339)))
+	slog.InfoContext(ctx, "Reloaded TLS certificate", "trigger", trigger, "subject", leaf.Subject.String(), "not_after", leaf.NotAfter)
+}
+
+// Reloads the certificate when its files change or the process receives SIGHUP, until ctx is cancelled
+func (c *certReloader) watch(ctx context.Context) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to create certificate watcher: %v", err)
+	}
+	paths := map[string]bool{filepath.Clean(c.certFile): true, filepath.Clean(c.keyFile): true}
+	for path := range paths {
+		if err := watcher.Add(filepath.Dir(path)); err != nil {
+			watcher.Close()
+			return fmt.Errorf("failed to watch certificate: %v", err)
+		}
+	}
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+
+	go func() {
+		defer watcher.Close()
+		defer signal.Stop(hangup)
+		var timer *time.Timer
+		for {
+			select {
+			case <-ctx.Done():
+				if timer != nil {
+					timer.Stop()
+				}
+				return
+			case <-hangup:
+				c.reload(ctx, "signal")
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if !paths[filepath.Clean(event.Name)] || event.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Rename) == 0 {
+					continue
+				}
+				if timer != nil {
+					timer.Stop()
+				}
+				timer = time.AfterFunc(certReloadDelay, func() { c.reload(ctx, "file") })
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				slog.WarnContext(ctx, "Certificate watcher error", "error", err)
+			}
+		}
+	}()
+	return nil
+}
@@ -0,0 +1,31 @@
+package httpapi
+
+import (
+	"net/http"
+	"strings"
+
+	"go.opentelemetry.io/otel/trace"
+
+	"otpl/pricecalculator/internal/config"
+)
+
+// Response headers identifying the trace of a request
+const (
+	traceResponseHeader = "traceresponse" // W3C Trace Context Level 2 version-traceid-spanid-flags of the server span
+	traceIDHeader       = "X-Trace-ID"    // Trace ID to paste into a tracing backend's search
+	traceURLHeader      = "X-Trace-URL"   // Link to the trace when TRACE_UI_URL or ui.trace_url is set
+)
+
+// Writes the trace of the request span into the response headers, before the handler writes the status
+func writeTraceHeaders(w http.ResponseWriter, r *http.Request) {
+	sc := trace.SpanContextFromContext(r.Context())
+	if !sc.IsValid() {
+		return
+	}
+	header := w.Header()
+	header.Set(traceResponseHeader, "00-"+sc.TraceID().String()+"-"+sc.SpanID().String()+"-"+sc.TraceFlags().String())
+	header.Set(traceIDHeader, sc.TraceID().String())
+	if url := config.Setting("TRACE_UI_URL", fileConfig.Load().UI.TraceURL); url != "" {
+		header.Set(traceURLHeader, strings.ReplaceAll(url, "{trace_id}", sc.TraceID().String()))
+	}
+}
@@ -0,0 +1,80 @@
+package httpapi
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"otpl/pricecalculator/internal/config"
+	"otpl/pricecalculator/internal/telemetry"
+)
+
+// TraceExporterUpdate structure for switching the exporter of the span pipeline
+type TraceExporterUpdate struct {
+	Exporter string `json:"exporter"` // otlp, stdout or none
+}
+
+// StdoutExporterUpdate structure for toggling the stdout exporter
+type StdoutExporterUpdate struct {
+	Enabled *bool `json:"enabled"`
+}
+
+// Reports the state of the span export pipeline
+func getTelemetry(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, providers.Pipeline.Status())
+}
+
+// Exports all queued spans now, waiting for the export to finish.
+// A failing exporter is reported with 502 and its error.
+func flushTelemetry(w http.ResponseWriter, r *http.Request) {
+	if err := providers.TracerProvider.ForceFlush(r.Context()); err != nil {
+		slog.ErrorContext(r.Context(), "Error flushing spans", "error", err)
+		writeProblem(w, r, fmt.Sprintf("failed to flush spans: %v", err), http.StatusBadGateway)
+		return
+	}
+	status := providers.Pipeline.Status()
+	writeJSON(w, http.StatusOK, status)
+	slog.InfoContext(r.Context(), "Flushed spans", "exported_spans", status.ExportedSpans)
+}
+
+// Enables or disables writing every span to stdout for debugging
+func updateStdoutExporter(w http.ResponseWriter, r *http.Request) {
+	var update StdoutExporterUpdate
+	if !decodeJSON(w, r, &update) {
+		return
+	}
+	if update.Enabled == nil {
+		writeProblem(w, r, "enabled is required", http.StatusBadRequest)
+		return
+	}
+	if err := providers.Pipeline.SetStdoutExporter(providers.TracerProvider, *update.Enabled); err != nil {
+		writeOperationError(w, r, err, "Error toggling stdout exporter")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, providers.Pipeline.Status())
+	slog.InfoContext(r.Context(), "Stdout exporter toggled", "enabled", *update.Enabled)
+}
+
+// Switches the exporter of the span pipeline between otlp, stdout and none, for example to debug
+// locally without a collector. The spans already queued are exported to the previous exporter.
+func updateTraceExporter(w http.ResponseWriter, r *http.Request) {
+	var update TraceExporterUpdate
+	if !decodeJSON(w, r, &update) {
+		return
+	}
+	switch update.Exporter {
+	case config.ExporterOTLP, config.ExporterStdout, telemetry.ExporterNone:
+	default:
+		writeProblem(w, r, "exporter must be otlp, stdout or none", http.StatusBadRequest)
+		return
+	}
+	previous := providers.Pipeline.Status().Exporter
+	if err := providers.SetTraceExporter(r.Context(), update.Exporter); err != nil {
+		writeOperationError(w, r, err, "Error switching trace exporter")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, providers.Pipeline.Status())
+	slog.InfoContext(r.Context(), "Trace exporter switched", "previous", previous, "exporter", update.Exporter)
+}
@@ -0,0 +1,40 @@
+package httpapi
+
+import (
+	"embed"
+	"io/fs"
+	"net/http"
+
+	"otpl/pricecalculator/internal/config"
+)
+
+// Demo web UI served at /, with its assets under /ui/
+//
+//go:embed ui
+var uiFiles embed.FS
+
+// UISettings structure for the settings the demo UI reads on load
+type UISettings struct {
+	TraceURL string `json:"trace_url,omitempty"` // Link to a trace in the tracing backend, {trace_id} is replaced
+}
+
+// Returns the files of the demo UI
+func uiAssets() fs.FS {
+	assets, _ := fs.Sub(uiFiles, "ui") // The directory is embedded, so it always exists
+	return assets
+}
+
+// Serves the page of the demo UI
+func serveUI(w http.ResponseWriter, r *http.Request) {
+	http.ServeFileFS(w, r, uiAssets(), "index.html")
+}
+
+// Serves the scripts and stylesheets of the demo UI
+func serveUIAssets(w http.ResponseWriter, r *http.Request) {
+	http.StripPrefix("/ui/", http.FileServerFS(uiAssets())).ServeHTTP(w, r)
+}
+
+// Returns the settings of the demo UI, the trace link comes from TRACE_UI_URL or ui.trace_url
+func getUISettings(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, UISettings{TraceURL: config.Setting("TRACE_UI_URL", fileConfig.Load().UI.TraceURL)})
+}
@@ -0,0 +1,87 @@
+// Demo UI for the price calculator. Every request starts its own trace by sending
+// a traceparent header, so the trace ID shown with the result is known up front.
+
+let settings = {};
+
+// Returns n random bytes as hex
+function randomHex(n) {
+  const bytes = crypto.getRandomValues(new Uint8Array(n));
+  return Array.from(bytes, (b) => b.toString(16).padStart(2, "0")).join("");
+}
+
+// Sends a JSON request in a new sampled trace, returning the trace ID with the response
+async function call(method, path, body, apiKey) {
+  const traceId = randomHex(16);
+  const headers = {
+    "Content-Type": "application/json",
+    traceparent: `00-${traceId}-${randomHex(8)}-01`,
+  };
+  if (apiKey) {
+    headers["X-API-Key"] = apiKey;
+  }
+  const response = await fetch(path, { method, headers, body: body && JSON.stringify(body) });
+  const data = await response.json().catch(() => null);
+  return { ok: response.ok, status: response.status, data, traceId };
+}
+
+// Collects the filled in fields of a form, numbers as numbers
+function formValues(form) {
+  const values = {};
+  for (const input of form.querySelectorAll("input")) {
+    if (input.value === "" || input.name === "api_key") {
+      continue;
+    }
+    values[input.name] = input.type === "number" ? Number(input.value) : input.value;
+  }
+  return values;
+}
+
+// Shows the outcome of a request with a link to its trace
+function show(result) {
+  document.getElementById("result").hidden = false;
+  const output = document.getElementById("output");
+  output.textContent = JSON.stringify(result.data, null, 2);
+  output.className = result.ok ? "" : "error";
+
+  const trace = document.getElementById("trace");
+  trace.textContent = "Trace ";
+  if (settings.trace_url) {
+    const link = document.createElement("a");
+    link.href = settings.trace_url.replace("{trace_id}", result.traceId);
+    link.target = "_blank";
+    link.rel = "noopener";
+    link.textContent = result.traceId;
+    trace.append(link);
+  } else {
+    const code = document.createElement("code");
+    code.textContent = result.traceId;
+    trace.append(code);
+  }
+}
+
+// Fills the defaults form with the current base price and tax rate
+async function loadDefaults() {
+  const result = await call("GET", "/v1/config");
+  if (result.ok) {
+    const form = document.getElementById("defaults");
+    form.base_price.value = result.data.base_price;
+    form.tax_rate.value = result.data.tax_rate;
+  }
+}
+
+document.getElementById("defaults").addEventListener("submit", async (event) => {
+  event.preventDefault();
+  const form = event.target;
+  show(await call("PATCH", "/v1/config", formValues(form), form.api_key.value));
+});
+
+document.getElementById("calculate").addEventListener("submit", async (event) => {
+  event.preventDefault();
+  show(await call("POST", "/calculate", formValues(event.target)));
+});
+
+fetch("/ui/settings.json")
+  .then((response) => response.json())
+  .then((data) => { settings = data; })
+  .catch(() => {});
+loadDefaults();
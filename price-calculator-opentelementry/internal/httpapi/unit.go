@@ -0,0 +1,40 @@
+package httpapi
+
+import (
+	"log/slog"
+	"net/http"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+
+	"otpl/pricecalculator/internal/telemetry"
+	"otpl/pricecalculator/pricing"
+)
+
+// Calculates the total and effective unit price of a quantity of units with per-unit discounts
+func calculateUnitPrice(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracer.Start(r.Context(), "CalculateUnitPrice")
+	defer span.End()
+
+	var request pricing.UnitPriceRequest
+	if !decodeJSON(w, r, &request) {
+		return
+	}
+	defaults, currency := tenantDefaults(ctx)
+	if request.Currency == "" {
+		request.Currency = currency
+	}
+	response, err := pricing.CalculateUnitPrice(ctx, request, defaults, roundingMode(defaults, request.Currency))
+	if err != nil {
+		telemetry.RecordError(span, err)
+		writeOperationError(w, r, err, "Error calculating unit price")
+		return
+	}
+
+	// Record the total alongside the single price calculations
+	totalPriceCounter.Add(ctx, response.TotalPrice.Float64(), metric.WithAttributes(attribute.String("currency", response.Currency)))
+
+	writeJSON(w, http.StatusOK, response)
+	slog.InfoContext(ctx, "Calculated unit price", "total_price", response.TotalPrice.String(),
+		"effective_unit_price", response.EffectiveUnitPrice.String(), "quantity", response.Quantity, "currency", response.Currency)
+}
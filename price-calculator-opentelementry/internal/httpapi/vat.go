@@ -0,0 +1,101 @@
+package httpapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel/attribute"
+
+	"otpl/pricecalculator/internal/telemetry"
+	"otpl/pricecalculator/pricing"
+)
+
+// Timeout of a VIES call
+const viesTimeout = 5 * time.Second
+
+// Verifications of a VAT ID, recorded as tax.exemption.verification
+const (
+	vatVerifiedFormat = "format" // Only the format was checked
+	vatVerifiedVIES   = "vies"   // Confirmed by VIES
+)
+
+// HTTP client for VIES, instrumented so each call is a client span
+var viesClient = &http.Client{
+	Transport: otelhttp.NewTransport(http.DefaultTransport),
+	Timeout:   viesTimeout,
+}
+
+// Checks the VAT ID of a request's tax exemption. EU numbers are confirmed with the VIES REST API
+// at VIES_URL when it is set; the format is checked by the pricing pipeline either way. An
+// unavailable VIES is logged and the exemption accepted on its format alone.
+func verifyTaxExemption(ctx context.Context, request pricing.PriceRequest) error {
+	if request.TaxExemption == nil {
+		return nil
+	}
+	exemption, err := pricing.NormalizeTaxExemption(*request.TaxExemption)
+	if err != nil {
+		return err
+	}
+	provider := os.Getenv("VIES_URL")
+	if provider == "" || !exemption.ViesCheckable() {
+		return nil
+	}
+
+	ctx, span := tracer.Start(ctx, "VerifyVATID")
+	defer span.End()
+	span.SetAttributes(attribute.String("tax.exemption.country", exemption.Country()))
+
+	valid, err := checkVIES(ctx, provider, exemption)
+	if err != nil {
+		telemetry.RecordError(span, err)
+		slog.WarnContext(ctx, "VIES is unavailable, accepting the VAT ID on its format", "error", err)
+		span.SetAttributes(attribute.String("tax.exemption.verification", vatVerifiedFormat))
+		return nil
+	}
+	span.SetAttributes(attribute.Bool("tax.exemption.valid", valid), attribute.String("tax.exemption.verification", vatVerifiedVIES))
+	if !valid {
+		err := &pricing.ValidationError{Message: fmt.Sprintf("VAT ID %q is not registered in VIES", exemption.VATID)}
+		telemetry.RecordError(span, err)
+		return err
+	}
+	return nil
+}
+
+// Asks VIES whether a VAT number is registered,
+// POST {provider}/check-vat-number with {"countryCode": "DE", "vatNumber": "123456789"} answering {"valid": true}
+func checkVIES(ctx context.Context, provider string, exemption pricing.TaxExemption) (bool, error) {
+	body, err := json.Marshal(map[string]string{"countryCode": exemption.Country(), "vatNumber": exemption.Number()})
+	if err != nil {
+		return false, err
+	}
+	endpoint := strings.TrimSuffix(provider, "/") + "/check-vat-number"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := viesClient.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return false, fmt.Errorf("VIES returned %s", resp.Status)
+	}
+
+	var result struct {
+		Valid bool `json:"valid"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return false, fmt.Errorf("invalid VIES response: %v", err)
+	}
+	return result.Valid, nil
+}
@@ -0,0 +1,200 @@
+package httpapi
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ConfigVersionStore persists the most recent versions of the default pricing configuration
+type ConfigVersionStore interface {
+	// Add records a version under the next number, dropping the oldest versions beyond maxConfigVersions
+	Add(ctx context.Context, version ConfigVersion) (ConfigVersion, error)
+	// Latest returns the active version, false before the first one is recorded
+	Latest(ctx context.Context) (ConfigVersion, bool, error)
+	// Get returns the version with the number, false if it is not recorded
+	Get(ctx context.Context, n int) (ConfigVersion, bool, error)
+	// List returns the recorded versions, newest first
+	List(ctx context.Context) ([]ConfigVersion, error)
+}
+
+// Configuration version store opened on startup
+var configVersions ConfigVersionStore
+
+// memoryConfigVersionStore keeps the versions in memory, oldest first, appended to a JSON lines file
+// when it has one. The file is rewritten with the versions kept once it holds twice as many lines.
+type memoryConfigVersionStore struct {
+	mu       sync.RWMutex
+	versions []ConfigVersion
+	file     *jsonLines
+}
+
+// Creates the in-memory configuration version store, loading the versions from file
+func newMemoryConfigVersionStore(file *jsonLines) (*memoryConfigVersionStore, error) {
+	s := &memoryConfigVersionStore{file: file}
+	if err := loadJSONLines(file, &s.versions); err != nil {
+		return nil, err
+	}
+	if len(s.versions) > maxConfigVersions {
+		s.versions = s.versions[len(s.versions)-maxConfigVersions:]
+	}
+	return s, nil
+}
+
+func (s *memoryConfigVersionStore) Add(ctx context.Context, version ConfigVersion) (ConfigVersion, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	version.Version = 1
+	if len(s.versions) > 0 {
+		version.Version = s.versions[len(s.versions)-1].Version + 1
+	}
+	s.versions = append(s.versions, version)
+	if len(s.versions) > maxConfigVersions {
+		s.versions = append([]ConfigVersion(nil), s.versions[len(s.versions)-maxConfigVersions:]...)
+	}
+	if s.file != nil && s.file.lines >= 2*maxConfigVersions {
+		return version, s.file.rewrite(&s.versions)
+	}
+	return version, s.file.append(version)
+}
+
+func (s *memoryConfigVersionStore) Latest(ctx context.Context) (ConfigVersion, bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if len(s.versions) == 0 {
+		return ConfigVersion{}, false, nil
+	}
+	return s.versions[len(s.versions)-1], true, nil
+}
+
+func (s *memoryConfigVersionStore) Get(ctx context.Context, n int) (ConfigVersion, bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, v := range s.versions {
+		if v.Version == n {
+			return v, true, nil
+		}
+	}
+	return ConfigVersion{}, false, nil
+}
+
+func (s *memoryConfigVersionStore) List(ctx context.Context) ([]ConfigVersion, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	list := make([]ConfigVersion, 0, len(s.versions))
+	for i := len(s.versions) - 1; i >= 0; i-- {
+		list = append(list, s.versions[i])
+	}
+	return list, nil
+}
+
+// sqlConfigVersionStore keeps the versions in a SQLite or PostgreSQL table, with the time
+// in Unix nanoseconds and the discount rules encoded as JSON
+type sqlConfigVersionStore struct {
+	db *sql.DB
+}
+
+// Creates the configuration version store in db, adding its table if needed
+func newSQLConfigVersionStore(db *sql.DB) (*sqlConfigVersionStore, error) {
+	_, err := db.Exec(`CREATE TABLE IF NOT EXISTS config_versions (
+		version     BIGINT PRIMARY KEY,
+		recorded_at BIGINT NOT NULL,
+		author      TEXT NOT NULL,
+		change      TEXT NOT NULL,
+		base_price  TEXT NOT NULL,
+		tax_rate    DOUBLE PRECISION NOT NULL,
+		currency    TEXT NOT NULL DEFAULT '',
+		rounding    TEXT NOT NULL DEFAULT '',
+		discounts   TEXT NOT NULL
+	)`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create config_versions table: %v", err)
+	}
+	return &sqlConfigVersionStore{db: db}, nil
+}
+
+// Columns of a version in the order scanConfigVersion reads them
+const configVersionColumns = `version, recorded_at, author, change, base_price, tax_rate, currency, rounding, discounts`
+
+// Reads a configuration version selected with configVersionColumns
+func scanConfigVersion(row interface{ Scan(...any) error }) (ConfigVersion, error) {
+	var v ConfigVersion
+	var recordedAt int64
+	var discounts string
+	err := row.Scan(&v.Version, &recordedAt, &v.Author, &v.Change, &v.BasePrice, &v.TaxRate, &v.Currency, &v.Rounding, &discounts)
+	if err != nil {
+		return v, err
+	}
+	v.Timestamp = time.Unix(0, recordedAt).UTC()
+	if err := json.Unmarshal([]byte(discounts), &v.Discounts); err != nil {
+		return v, fmt.Errorf("invalid stored discounts: %v", err)
+	}
+	return v, nil
+}
+
+func (s *sqlConfigVersionStore) Add(ctx context.Context, version ConfigVersion) (ConfigVersion, error) {
+	discounts, err := json.Marshal(version.Discounts)
+	if err != nil {
+		return version, fmt.Errorf("failed to encode version discounts: %v", err)
+	}
+	err = s.db.QueryRowContext(ctx, `INSERT INTO config_versions (`+configVersionColumns+`)
+		SELECT COALESCE(MAX(version), 0) + 1, CAST($1 AS BIGINT), CAST($2 AS TEXT), CAST($3 AS TEXT), CAST($4 AS TEXT),
+			CAST($5 AS DOUBLE PRECISION), CAST($6 AS TEXT), CAST($7 AS TEXT), CAST($8 AS TEXT) FROM config_versions
+		RETURNING version`,
+		version.Timestamp.UnixNano(), version.Author, version.Change, version.BasePrice, version.TaxRate,
+		version.Currency, string(version.Rounding), string(discounts)).Scan(&version.Version)
+	if err != nil {
+		return version, fmt.Errorf("failed to record configuration version: %v", err)
+	}
+	if _, err := s.db.ExecContext(ctx, `DELETE FROM config_versions WHERE version <= $1`, version.Version-maxConfigVersions); err != nil {
+		return version, fmt.Errorf("failed to trim configuration versions: %v", err)
+	}
+	return version, nil
+}
+
+func (s *sqlConfigVersionStore) Latest(ctx context.Context) (ConfigVersion, bool, error) {
+	v, err := scanConfigVersion(s.db.QueryRowContext(ctx, `SELECT `+configVersionColumns+` FROM config_versions ORDER BY version DESC LIMIT 1`))
+	if errors.Is(err, sql.ErrNoRows) {
+		return v, false, nil
+	}
+	if err != nil {
+		return v, false, fmt.Errorf("failed to load configuration version: %v", err)
+	}
+	return v, true, nil
+}
+
+func (s *sqlConfigVersionStore) Get(ctx context.Context, n int) (ConfigVersion, bool, error) {
+	v, err := scanConfigVersion(s.db.QueryRowContext(ctx, `SELECT `+configVersionColumns+` FROM config_versions WHERE version = $1`, n))
+	if errors.Is(err, sql.ErrNoRows) {
+		return v, false, nil
+	}
+	if err != nil {
+		return v, false, fmt.Errorf("failed to load configuration version: %v", err)
+	}
+	return v, true, nil
+}
+
+func (s *sqlConfigVersionStore) List(ctx context.Context) ([]ConfigVersion, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT `+configVersionColumns+` FROM config_versions ORDER BY version DESC`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list configuration versions: %v", err)
+	}
+	defer rows.Close()
+
+	list := []ConfigVersion{}
+	for rows.Next() {
+		v, err := scanConfigVersion(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read configuration version: %v", err)
+		}
+		list = append(list, v)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list configuration versions: %v", err)
+	}
+	return list, nil
+}
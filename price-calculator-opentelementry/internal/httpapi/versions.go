@@ -0,0 +1,182 @@
+package httpapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"otpl/pricecalculator/pricing"
+)
+
+// Oldest configuration versions are dropped beyond this
+const maxConfigVersions = 1000
+
+// ConfigVersion structure for a snapshot of the default pricing configuration
+type ConfigVersion struct {
+	Version   int                  `json:"version"`
+	Timestamp time.Time            `json:"timestamp"`
+	Author    string               `json:"author"` // API key ID, "config-file", "system" or "anonymous"
+	Change    string               `json:"change"` // What created the version, e.g. prices.updated
+	BasePrice pricing.Money        `json:"base_price"`
+	TaxRate   float64              `json:"tax_rate"`
+	Currency  string               `json:"currency,omitempty"`
+	Rounding  pricing.RoundingMode `json:"rounding,omitempty"`
+	Discounts []pricing.Discount   `json:"discounts"`
+}
+
+// Records the default prices cfg, as applied by the change, and the current discount rules as a new
+// configuration version. Runs once the change is applied, so a failure is logged and recorded on the
+// span, and returned with the unrecorded snapshot, numbered 0, for callers that need the version.
+func recordConfigVersion(ctx context.Context, cfg pricing.Config, change string) (ConfigVersion, error) {
+	version := ConfigVersion{
+		Timestamp: time.Now().UTC(),
+		Author:    author(ctx),
+		Change:    change,
+		BasePrice: cfg.BasePrice,
+		TaxRate:   cfg.TaxRate,
+		Currency:  cfg.Currency,
+		Rounding:  cfg.Rounding,
+	}
+	span := trace.SpanFromContext(ctx)
+	rules, err := discounts.List(ctx, "")
+	if err == nil {
+		version.Discounts = rules
+		var recorded ConfigVersion
+		if recorded, err = configVersions.Add(ctx, version); err == nil {
+			version = recorded
+		}
+	}
+	if err != nil {
+		span.AddEvent("config.version_failed", trace.WithAttributes(
+			attribute.String("config.change", change),
+			attribute.String("error.message", err.Error()),
+		))
+		slog.ErrorContext(ctx, "Failed to record configuration version", "change", change, "error", err)
+		return version, err
+	}
+
+	span.AddEvent("config.versioned", trace.WithAttributes(
+		attribute.Int("config.version", version.Version),
+		attribute.String("config.change", change),
+	))
+	return version, nil
+}
+
+// Records the configuration loaded on startup as a new version, unless the stored active version has
+// the same prices and discount rules already, so restarts do not add versions for an unchanged configuration
+func recordStartupVersion(ctx context.Context) error {
+	latest, ok, err := configVersions.Latest(ctx)
+	if err != nil {
+		return err
+	}
+	cfg := prices.Get()
+	if ok {
+		rules, err := discounts.List(ctx, "")
+		if err != nil {
+			return err
+		}
+		current, _ := json.Marshal(rules)
+		recorded, _ := json.Marshal(latest.Discounts)
+		if latest.BasePrice.Equal(cfg.BasePrice) && latest.TaxRate == cfg.TaxRate && latest.Currency == cfg.Currency &&
+			latest.Rounding == cfg.Rounding && bytes.Equal(current, recorded) {
+			return nil
+		}
+	}
+	_, err = recordConfigVersion(ctx, cfg, "startup")
+	return err
+}
+
+// Parses the version number of a request path, answering 400 when it is invalid
+func versionNumber(w http.ResponseWriter, r *http.Request) (int, bool) {
+	n, err := strconv.Atoi(mux.Vars(r)["n"])
+	if err != nil || n < 1 {
+		writeProblem(w, r, "Invalid version number", http.StatusBadRequest)
+		return 0, false
+	}
+	return n, true
+}
+
+// Lists the configuration versions, newest first
+func listConfigVersions(w http.ResponseWriter, r *http.Request) {
+	list, err := configVersions.List(r.Context())
+	if err != nil {
+		writeOperationError(w, r, err, "Error listing configuration versions")
+		return
+	}
+	writeJSON(w, http.StatusOK, list)
+}
+
+// Returns a single configuration version
+func getConfigVersion(w http.ResponseWriter, r *http.Request) {
+	n, ok := versionNumber(w, r)
+	if !ok {
+		return
+	}
+	version, ok, err := configVersions.Get(r.Context(), n)
+	if err != nil {
+		writeOperationError(w, r, err, "Error loading configuration version")
+		return
+	}
+	if !ok {
+		writeProblem(w, r, "Configuration version not found", http.StatusNotFound)
+		return
+	}
+	writeJSON(w, http.StatusOK, version)
+}
+
+// Restores the prices and discount rules of a configuration version, recorded as a new version
+func rollbackConfig(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	n, ok := versionNumber(w, r)
+	if !ok {
+		return
+	}
+	target, ok, err := configVersions.Get(ctx, n)
+	if err != nil {
+		writeOperationError(w, r, err, "Error loading configuration version")
+		return
+	}
+	if !ok {
+		writeProblem(w, r, "Configuration version not found", http.StatusNotFound)
+		return
+	}
+	trace.SpanFromContext(ctx).SetAttributes(attribute.Int("config.rollback_to", n))
+	previous, _, err := configVersions.Latest(ctx)
+	if err != nil {
+		writeOperationError(w, r, err, "Error loading configuration version")
+		return
+	}
+
+	var previousTaxRate float64
+	cfg, err := prices.Update(ctx, func(cfg *pricing.Config) error {
+		previousTaxRate = cfg.TaxRate
+		cfg.BasePrice, cfg.TaxRate, cfg.Currency, cfg.Rounding = target.BasePrice, target.TaxRate, target.Currency, target.Rounding
+		return storage.Save(ctx, *cfg)
+	})
+	if err != nil {
+		writeOperationError(w, r, err, "Error rolling back configuration")
+		return
+	}
+	if err := discounts.Replace(ctx, "", target.Discounts); err != nil {
+		writeOperationError(w, r, err, "Error rolling back discounts")
+		return
+	}
+	if cfg.TaxRate != previousTaxRate {
+		recordTaxRate(ctx, cfg.TaxRate, time.Now())
+	}
+	version, _ := recordConfigVersion(ctx, cfg, fmt.Sprintf("rollback to %d", n))
+	recordAudit(ctx, auditConfigRolledBack, "config", previous, version)
+	notifyWebhooks(ctx, eventPricesUpdated, cfg)
+
+	writeJSON(w, http.StatusOK, version)
+	slog.InfoContext(ctx, "Configuration rolled back", "to_version", n, "version", version.Version)
+}
@@ -0,0 +1,190 @@
+package httpapi
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+// WebhookStore persists the registered webhooks with their secrets
+type WebhookStore interface {
+	// List returns all webhooks in ID order
+	List(ctx context.Context) ([]Webhook, error)
+	// Get returns the webhook with the ID, false if there is none
+	Get(ctx context.Context, id string) (Webhook, bool, error)
+	// Create registers a webhook under a new ID
+	Create(ctx context.Context, webhook Webhook) (Webhook, error)
+	// Delete removes a webhook, false if there is none with the ID
+	Delete(ctx context.Context, id string) (bool, error)
+}
+
+// Webhook store opened on startup
+var webhooks WebhookStore
+
+// memoryWebhookStore keeps the webhooks in memory, saved to a JSON file when it has one
+type memoryWebhookStore struct {
+	mu       sync.Mutex
+	webhooks map[string]Webhook
+	nextID   int
+	file     *jsonFile
+}
+
+// Creates the in-memory webhook store, loading the webhooks from file
+func newMemoryWebhookStore(file *jsonFile) (*memoryWebhookStore, error) {
+	s := &memoryWebhookStore{webhooks: map[string]Webhook{}, file: file}
+	if err := file.load(&s.webhooks); err != nil {
+		return nil, err
+	}
+	for id := range s.webhooks {
+		s.nextID = max(s.nextID, discountID(id))
+	}
+	return s, nil
+}
+
+func (s *memoryWebhookStore) List(ctx context.Context) ([]Webhook, error) {
+	s.mu.Lock()
+	list := make([]Webhook, 0, len(s.webhooks))
+	for _, h := range s.webhooks {
+		list = append(list, h)
+	}
+	s.mu.Unlock()
+	sort.Slice(list, func(i, j int) bool { return discountID(list[i].ID) < discountID(list[j].ID) })
+	return list, nil
+}
+
+func (s *memoryWebhookStore) Get(ctx context.Context, id string) (Webhook, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	h, ok := s.webhooks[id]
+	return h, ok, nil
+}
+
+func (s *memoryWebhookStore) Create(ctx context.Context, webhook Webhook) (Webhook, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.nextID++
+	webhook.ID = strconv.Itoa(s.nextID)
+	s.webhooks[webhook.ID] = webhook
+	return webhook, s.file.persist(s.webhooks)
+}
+
+func (s *memoryWebhookStore) Delete(ctx context.Context, id string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.webhooks[id]; !ok {
+		return false, nil
+	}
+	delete(s.webhooks, id)
+	return true, s.file.persist(s.webhooks)
+}
+
+// sqlWebhookStore keeps the webhooks in a SQLite or PostgreSQL table, with the
+// subscribed events encoded as JSON
+type sqlWebhookStore struct {
+	db *sql.DB
+}
+
+// Creates the webhook store in db, adding its table if needed
+func newSQLWebhookStore(db *sql.DB) (*sqlWebhookStore, error) {
+	_, err := db.Exec(`CREATE TABLE IF NOT EXISTS webhooks (
+		id     BIGINT PRIMARY KEY,
+		url    TEXT NOT NULL,
+		secret TEXT NOT NULL,
+		events TEXT NOT NULL DEFAULT '[]'
+	)`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create webhooks table: %v", err)
+	}
+	return &sqlWebhookStore{db: db}, nil
+}
+
+// Columns of a webhook in the order scanWebhook reads them
+const webhookColumns = `id, url, secret, events`
+
+// Reads a webhook selected with webhookColumns
+func scanWebhook(row interface{ Scan(...any) error }) (Webhook, error) {
+	var h Webhook
+	var id int64
+	var events string
+	if err := row.Scan(&id, &h.URL, &h.Secret, &events); err != nil {
+		return h, err
+	}
+	h.ID = strconv.FormatInt(id, 10)
+	if err := json.Unmarshal([]byte(events), &h.Events); err != nil {
+		return h, fmt.Errorf("invalid stored webhook events: %v", err)
+	}
+	if len(h.Events) == 0 {
+		h.Events = nil
+	}
+	return h, nil
+}
+
+func (s *sqlWebhookStore) List(ctx context.Context) ([]Webhook, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT `+webhookColumns+` FROM webhooks ORDER BY id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list webhooks: %v", err)
+	}
+	defer rows.Close()
+
+	list := []Webhook{}
+	for rows.Next() {
+		h, err := scanWebhook(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read webhook: %v", err)
+		}
+		list = append(list, h)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list webhooks: %v", err)
+	}
+	return list, nil
+}
+
+func (s *sqlWebhookStore) Get(ctx context.Context, id string) (Webhook, bool, error) {
+	n, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return Webhook{}, false, nil
+	}
+	h, err := scanWebhook(s.db.QueryRowContext(ctx, `SELECT `+webhookColumns+` FROM webhooks WHERE id = $1`, n))
+	if errors.Is(err, sql.ErrNoRows) {
+		return h, false, nil
+	}
+	if err != nil {
+		return h, false, fmt.Errorf("failed to load webhook: %v", err)
+	}
+	return h, true, nil
+}
+
+func (s *sqlWebhookStore) Create(ctx context.Context, webhook Webhook) (Webhook, error) {
+	events, err := json.Marshal(webhook.Events)
+	if err != nil {
+		return webhook, fmt.Errorf("failed to encode webhook events: %v", err)
+	}
+	var id int64
+	err = s.db.QueryRowContext(ctx, `INSERT INTO webhooks (`+webhookColumns+`)
+		SELECT COALESCE(MAX(id), 0) + 1, CAST($1 AS TEXT), CAST($2 AS TEXT), CAST($3 AS TEXT) FROM webhooks
+		RETURNING id`,
+		webhook.URL, webhook.Secret, string(events)).Scan(&id)
+	if err != nil {
+		return webhook, fmt.Errorf("failed to create webhook: %v", err)
+	}
+	webhook.ID = strconv.FormatInt(id, 10)
+	return webhook, nil
+}
+
+func (s *sqlWebhookStore) Delete(ctx context.Context, id string) (bool, error) {
+	n, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return false, nil
+	}
+	result, err := s.db.ExecContext(ctx, `DELETE FROM webhooks WHERE id = $1`, n)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete webhook: %v", err)
+	}
+	return affected(result)
+}
@@ -0,0 +1,261 @@
+package httpapi
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/gorilla/mux"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+
+	"otpl/pricecalculator/internal/telemetry"
+)
+
+// Settings for webhook deliveries
+const (
+	webhookAttempts = 5               // Deliveries are given up after this many failed attempts
+	webhookBackoff  = time.Second     // Wait before the first retry, doubled for every further retry
+	webhookTimeout  = 5 * time.Second // Timeout of a single attempt
+)
+
+// Header carrying the HMAC-SHA256 signature of a notification body
+const webhookSignatureHeader = "X-Webhook-Signature"
+
+// Webhook events
+const (
+	eventPricesUpdated   = "prices.updated"
+	eventDiscountCreated = "discount.created"
+	eventDiscountUpdated = "discount.updated"
+	eventDiscountDeleted = "discount.deleted"
+	eventScheduleApplied = "schedule.applied"
+)
+
+// Webhook structure for a URL notified of configuration changes
+type Webhook struct {
+	ID     string   `json:"id"`
+	URL    string   `json:"url"`
+	Secret string   `json:"secret,omitempty"` // Signs the notifications, never returned
+	Events []string `json:"events,omitempty"` // Events to deliver, all when empty
+}
+
+// Validate checks that the webhook has an absolute HTTP URL, a secret and known events
+func (h Webhook) Validate() error {
+	u, err := url.Parse(h.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("url must be an absolute http or https URL")
+	}
+	if h.Secret == "" {
+		return fmt.Errorf("secret is required")
+	}
+	for _, event := range h.Events {
+		switch event {
+		case eventPricesUpdated, eventDiscountCreated, eventDiscountUpdated, eventDiscountDeleted, eventScheduleApplied:
+		default:
+			return fmt.Errorf("unknown event %q", event)
+		}
+	}
+	return nil
+}
+
+// Reports whether the webhook is notified of an event
+func (h Webhook) subscribed(event string) bool {
+	if len(h.Events) == 0 {
+		return true
+	}
+	for _, e := range h.Events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookNotification structure for the JSON body delivered to a webhook
+type WebhookNotification struct {
+	Event     string    `json:"event"`
+	Timestamp time.Time `json:"timestamp"`
+	Tenant    string    `json:"tenant,omitempty"`
+	Data      any       `json:"data"`
+}
+
+// HTTP client for webhook deliveries, instrumented so each attempt is a client span
+var webhookClient = &http.Client{
+	Transport: otelhttp.NewTransport(http.DefaultTransport),
+	Timeout:   webhookTimeout,
+}
+
+// Notifies the subscribed webhooks of a configuration change in the background.
+// Each delivery is traced in its own trace, linked to the span of the change.
+func notifyWebhooks(ctx context.Context, event string, data any) {
+	registered, err := webhooks.List(ctx)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error loading webhooks", "event", event, "error", err)
+		return
+	}
+	var targets []Webhook
+	for _, h := range registered {
+		if h.subscribed(event) {
+			targets = append(targets, h)
+		}
+	}
+	if len(targets) == 0 {
+		return
+	}
+
+	body, err := json.Marshal(WebhookNotification{Event: event, Timestamp: time.Now().UTC(), Tenant: tenantID(ctx), Data: data})
+	if err != nil {
+		slog.ErrorContext(ctx, "Error encoding webhook notification", "event", event, "error", err)
+		return
+	}
+	link := trace.LinkFromContext(ctx)
+	ctx = context.WithoutCancel(ctx)
+	for _, h := range targets {
+		go deliverWebhook(ctx, link, h, event, body)
+	}
+}
+
+// Delivers a notification to a webhook, retrying failed attempts with exponential backoff
+func deliverWebhook(ctx context.Context, link trace.Link, h Webhook, event string, body []byte) {
+	ctx, span := tracer.Start(ctx, "DeliverWebhook",
+		trace.WithNewRoot(),
+		trace.WithLinks(link),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("webhook.id", h.ID),
+			attribute.String("webhook.event", event),
+		),
+	)
+	defer span.End()
+
+	mac := hmac.New(sha256.New, []byte(h.Secret))
+	mac.Write(body)
+	signature := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+
+	backoff := webhookBackoff
+	for attempt := 1; ; attempt++ {
+		err := postWebhook(ctx, h.URL, signature, body)
+		if err == nil {
+			span.SetAttributes(attribute.Int("webhook.attempts", attempt))
+			webhookDeliveries.Add(ctx, 1, metric.WithAttributes(attribute.String("result", "delivered")))
+			slog.InfoContext(ctx, "Webhook delivered", "webhook_id", h.ID, "event", event, "attempts", attempt)
+			return
+		}
+		span.AddEvent("webhook.attempt_failed", trace.WithAttributes(
+			attribute.Int("webhook.attempt", attempt),
+			attribute.String("error", err.Error()),
+		))
+		if attempt == webhookAttempts {
+			span.SetAttributes(attribute.Int("webhook.attempts", attempt))
+			telemetry.RecordError(span, err)
+			webhookDeliveries.Add(ctx, 1, metric.WithAttributes(attribute.String("result", "failed")))
+			slog.ErrorContext(ctx, "Webhook delivery failed", "webhook_id", h.ID, "event", event, "attempts", attempt, "error", err)
+			return
+		}
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+// Makes a single delivery attempt, a 2xx answer counts as delivered
+func postWebhook(ctx context.Context, target, signature string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create webhook request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(webhookSignatureHeader, signature)
+	resp, err := webhookClient.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook answered with status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// Lists all webhooks in ID order, without their secrets
+func listWebhooks(w http.ResponseWriter, r *http.Request) {
+	list, err := webhooks.List(r.Context())
+	if err != nil {
+		writeOperationError(w, r, err, "Error listing webhooks")
+		return
+	}
+	for i := range list {
+		list[i].Secret = ""
+	}
+	writeJSON(w, http.StatusOK, list)
+}
+
+// Registers a webhook
+func createWebhook(w http.ResponseWriter, r *http.Request) {
+	var webhook Webhook
+	if !decodeJSON(w, r, &webhook) {
+		return
+	}
+	if err := webhook.Validate(); err != nil {
+		slog.WarnContext(r.Context(), "Invalid webhook", "error", err)
+		writeProblem(w, r, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	webhook, err := webhooks.Create(r.Context(), webhook)
+	if err != nil {
+		writeOperationError(w, r, err, "Error creating webhook")
+		return
+	}
+
+	trace.SpanFromContext(r.Context()).SetAttributes(attribute.String("webhook.id", webhook.ID))
+	webhook.Secret = ""
+	writeJSON(w, http.StatusCreated, webhook)
+	slog.InfoContext(r.Context(), "Webhook created", "webhook_id", webhook.ID)
+}
+
+// Returns a single webhook, without its secret
+func getWebhook(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	webhook, ok, err := webhooks.Get(r.Context(), id)
+	if err != nil {
+		writeOperationError(w, r, err, "Error loading webhook")
+		return
+	}
+	if !ok {
+		writeProblem(w, r, "Webhook not found", http.StatusNotFound)
+		return
+	}
+
+	webhook.Secret = ""
+	writeJSON(w, http.StatusOK, webhook)
+}
+
+// Removes a webhook, deliveries already in progress are completed
+func deleteWebhook(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	ok, err := webhooks.Delete(r.Context(), id)
+	if err != nil {
+		writeOperationError(w, r, err, "Error deleting webhook")
+		return
+	}
+	if !ok {
+		writeProblem(w, r, "Webhook not found", http.StatusNotFound)
+		return
+	}
+
+	trace.SpanFromContext(r.Context()).SetAttributes(attribute.String("webhook.id", id))
+	w.WriteHeader(http.StatusNoContent)
+	slog.InfoContext(r.Context(), "Webhook deleted", "webhook_id", id)
+}
@@ -0,0 +1,159 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/segmentio/kafka-go"
+	"go.opentelemetry.io/otel"
+	semconv "go.opentelemetry.io/otel/semconv/v1.18.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"otpl/pricecalculator/internal/telemetry"
+	"otpl/pricecalculator/pricing"
+)
+
+// Message headers of the queued calculations
+const (
+	replyToHeader       = "reply-to"       // Topic of the reply, overriding the worker's reply topic
+	correlationIDHeader = "correlation-id" // Copied from a request to its reply
+)
+
+// CalculationReply structure for the reply to a queued calculation request
+type CalculationReply struct {
+	Response *pricing.PriceResponse `json:"response,omitempty"`
+	Error    string                 `json:"error,omitempty"` // Why the request could not be priced
+	TraceID  string                 `json:"trace_id,omitempty"`
+}
+
+// WorkerOptions structure for the Kafka topics of a worker
+type WorkerOptions struct {
+	Brokers    []string
+	Topic      string // Topic the requests are consumed from
+	Group      string // Consumer group the workers share the requests in
+	ReplyTopic string // Topic the replies are published to unless a request names another
+}
+
+// RunWorker starts the service and prices the requests consumed from a Kafka topic like
+// POST /calculate until interrupted or terminated, publishing a reply with the result or
+// error of each. The trace context in the request headers is continued, and the message key
+// and correlation-id header are copied to the reply.
+func RunWorker(ctx context.Context, opts WorkerOptions) error {
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	shutdown := startService(ctx)
+	defer shutdown()
+	return runWorker(ctx, opts.Brokers, opts.Topic, opts.Group, opts.ReplyTopic)
+}
+
+// Prices the requests of the topic until ctx is cancelled. A request is committed once its reply
+// is published, so the requests of a worker that fails to publish are redelivered.
+func runWorker(ctx context.Context, brokers []string, topic, group, replyTopic string) error {
+	reader := kafka.NewReader(kafka.ReaderConfig{Brokers: brokers, Topic: topic, GroupID: group})
+	defer reader.Close()
+	writer := &kafka.Writer{Addr: kafka.TCP(brokers...), Balancer: &kafka.Hash{}, AllowAutoTopicCreation: true}
+	defer writer.Close()
+
+	slog.InfoContext(ctx, "Worker is running", "topic", topic, "group", group, "reply_topic", replyTopic)
+	for {
+		message, err := reader.FetchMessage(ctx)
+		if ctx.Err() != nil {
+			slog.InfoContext(ctx, "Shutting down worker")
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to consume requests: %v", err)
+		}
+		if err := processRequest(ctx, writer, group, replyTopic, message); err != nil {
+			return err
+		}
+		if err := reader.CommitMessages(ctx, message); err != nil && ctx.Err() == nil {
+			return fmt.Errorf("failed to commit request: %v", err)
+		}
+	}
+}
+
+// Prices a consumed request and publishes its reply, continuing the trace of the request
+func processRequest(ctx context.Context, writer *kafka.Writer, group, replyTopic string, message kafka.Message) error {
+	carrier := kafkaHeaderCarrier{&message.Headers}
+	ctx = otel.GetTextMapPropagator().Extract(ctx, carrier)
+	ctx, span := tracer.Start(ctx, message.Topic+" process",
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(
+			semconv.MessagingSystem("kafka"),
+			semconv.MessagingOperationProcess,
+			semconv.MessagingSourceName(message.Topic),
+			semconv.MessagingKafkaConsumerGroup(group),
+			semconv.MessagingKafkaSourcePartition(message.Partition),
+			semconv.MessagingKafkaMessageOffset(int(message.Offset)),
+		),
+	)
+	defer span.End()
+
+	reply := CalculationReply{TraceID: span.SpanContext().TraceID().String()}
+	var request pricing.PriceRequest
+	if err := json.Unmarshal(message.Value, &request); err != nil {
+		reply.Error = fmt.Sprintf("invalid request: %v", err)
+		telemetry.RecordError(span, err)
+		setErrorClass(ctx, errorClassValidation)
+		countRequest(ctx, "ConsumeCalculation", errorClassValidation)
+		slog.WarnContext(ctx, "Invalid queued request", "offset", message.Offset, "error", err)
+	} else if response, err := calculate(ctx, request); err != nil {
+		reply.Error = err.Error()
+		class := operationErrorClass(ctx, err)
+		setErrorClass(ctx, class)
+		countRequest(ctx, "ConsumeCalculation", class)
+		slog.WarnContext(ctx, "Error calculating queued request", "offset", message.Offset, "error", err)
+	} else {
+		countRequest(ctx, "ConsumeCalculation", "")
+		reply.Response = &response
+	}
+
+	if topic := carrier.Get(replyToHeader); topic != "" {
+		replyTopic = topic
+	}
+	if err := publishReply(ctx, writer, replyTopic, message.Key, carrier.Get(correlationIDHeader), reply); err != nil {
+		telemetry.RecordError(span, err)
+		return err
+	}
+	return nil
+}
+
+// Publishes the reply to a request, traced as a producer span whose context travels in the headers
+func publishReply(ctx context.Context, writer *kafka.Writer, topic string, key []byte, correlationID string, reply CalculationReply) error {
+	ctx, span := tracer.Start(ctx, topic+" publish",
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(
+			semconv.MessagingSystem("kafka"),
+			semconv.MessagingOperationPublish,
+			semconv.MessagingDestinationName(topic),
+		),
+	)
+	defer span.End()
+
+	value, err := json.Marshal(reply)
+	if err != nil {
+		telemetry.RecordError(span, err)
+		return fmt.Errorf("failed to encode reply: %v", err)
+	}
+	message := kafka.Message{
+		Topic:   topic,
+		Key:     key,
+		Value:   value,
+		Headers: []kafka.Header{{Key: "content-type", Value: []byte("application/json")}},
+	}
+	if correlationID != "" {
+		message.Headers = append(message.Headers, kafka.Header{Key: correlationIDHeader, Value: []byte(correlationID)})
+	}
+	otel.GetTextMapPropagator().Inject(ctx, kafkaHeaderCarrier{&message.Headers})
+	if err := writer.WriteMessages(ctx, message); err != nil {
+		telemetry.RecordError(span, err)
+		return fmt.Errorf("failed to publish reply: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+package telemetry
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+
+	"otpl/pricecalculator/internal/config"
+)
+
+// Resolves the batch settings of an exporter: the OTEL_BSP_* variables, then the configuration
+// file, then the SDK defaults. The variables hold counts and milliseconds, as in the SDK.
+func batchSettings(cfg config.BatchSpanProcessor) (config.BatchSpanProcessor, error) {
+	settings := config.BatchSpanProcessor{
+		MaxQueueSize:       sdktrace.DefaultMaxQueueSize,
+		MaxExportBatchSize: sdktrace.DefaultMaxExportBatchSize,
+		BatchTimeout:       sdktrace.DefaultScheduleDelay * time.Millisecond,
+		ExportTimeout:      sdktrace.DefaultExportTimeout * time.Millisecond,
+	}
+	for _, setting := range []struct {
+		env   string
+		file  int
+		value *int
+	}{
+		{"OTEL_BSP_MAX_QUEUE_SIZE", cfg.MaxQueueSize, &settings.MaxQueueSize},
+		{"OTEL_BSP_MAX_EXPORT_BATCH_SIZE", cfg.MaxExportBatchSize, &settings.MaxExportBatchSize},
+	} {
+		if setting.file > 0 {
+			*setting.value = setting.file
+		}
+		if value := os.Getenv(setting.env); value != "" {
+			n, err := strconv.Atoi(value)
+			if err != nil || n <= 0 {
+				return settings, fmt.Errorf("invalid %s %q", setting.env, value)
+			}
+			*setting.value = n
+		}
+	}
+	for _, setting := range []struct {
+		env   string
+		file  time.Duration
+		value *time.Duration
+	}{
+		{"OTEL_BSP_SCHEDULE_DELAY", cfg.BatchTimeout, &settings.BatchTimeout},
+		{"OTEL_BSP_EXPORT_TIMEOUT", cfg.ExportTimeout, &settings.ExportTimeout},
+	} {
+		if setting.file > 0 {
+			*setting.value = setting.file
+		}
+		if value := os.Getenv(setting.env); value != "" {
+			ms, err := strconv.Atoi(value)
+			if err != nil || ms <= 0 {
+				return settings, fmt.Errorf("invalid %s %q, expected milliseconds", setting.env, value)
+			}
+			*setting.value = time.Duration(ms) * time.Millisecond
+		}
+	}
+	if settings.MaxExportBatchSize > settings.MaxQueueSize {
+		return settings, fmt.Errorf("max export batch size %d exceeds max queue size %d", settings.MaxExportBatchSize, settings.MaxQueueSize)
+	}
+	return settings, nil
+}
+
+// Returns the batch span processor options applying the settings
+func batchOptions(settings config.BatchSpanProcessor) []sdktrace.BatchSpanProcessorOption {
+	return []sdktrace.BatchSpanProcessorOption{
+		sdktrace.WithMaxQueueSize(settings.MaxQueueSize),
+		sdktrace.WithMaxExportBatchSize(settings.MaxExportBatchSize),
+		sdktrace.WithBatchTimeout(settings.BatchTimeout),
+		sdktrace.WithExportTimeout(settings.ExportTimeout),
+	}
+}
@@ -0,0 +1,132 @@
+package telemetry
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// States of a circuit breaker
+const (
+	CircuitClosed   = "closed"    // Exporting to the collector
+	CircuitOpen     = "open"      // Collector unreachable, spans go to the fallback or are dropped
+	CircuitHalfOpen = "half_open" // Trying the collector again after the open timeout
+)
+
+// Defaults of the circuit breaker settings
+const (
+	defaultFailureThreshold = 3
+	defaultOpenTimeout      = 30 * time.Second
+)
+
+// CircuitBreaker wraps a span exporter, stopping calls to it after consecutive failed exports
+// so spans are not held up by the exporter's retries while its collector is down. While the
+// circuit is open the spans go to the fallback exporter, if any, and one export is tried again
+// after the open timeout, closing the circuit when it succeeds.
+type CircuitBreaker struct {
+	exporter    sdktrace.SpanExporter
+	fallback    sdktrace.SpanExporter // Receives the spans while open, nil to drop them
+	threshold   int
+	openTimeout time.Duration
+
+	mu       sync.Mutex
+	state    string
+	failures int // Consecutive failed exports
+	openedAt time.Time
+
+	fallbackSpans atomic.Int64 // Spans written to the fallback
+}
+
+// NewCircuitBreaker wraps exporter with a circuit breaker, writing the spans to fallback while open
+func NewCircuitBreaker(exporter, fallback sdktrace.SpanExporter, threshold int, openTimeout time.Duration) *CircuitBreaker {
+	if threshold <= 0 {
+		threshold = defaultFailureThreshold
+	}
+	if openTimeout <= 0 {
+		openTimeout = defaultOpenTimeout
+	}
+	return &CircuitBreaker{exporter: exporter, fallback: fallback, threshold: threshold, openTimeout: openTimeout, state: CircuitClosed}
+}
+
+// State returns the current state of the circuit
+func (b *CircuitBreaker) State() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state
+}
+
+// FallbackSpans returns the number of spans written to the fallback exporter
+func (b *CircuitBreaker) FallbackSpans() int64 {
+	return b.fallbackSpans.Load()
+}
+
+// ExportSpans exports the spans unless the circuit is open, then falls back
+func (b *CircuitBreaker) ExportSpans(ctx context.Context, spans []sdktrace.ReadOnlySpan) error {
+	b.mu.Lock()
+	if b.state == CircuitOpen && time.Since(b.openedAt) >= b.openTimeout {
+		b.setState(ctx, CircuitHalfOpen, nil)
+	}
+	open := b.state == CircuitOpen
+	b.mu.Unlock()
+	if open {
+		return b.exportFallback(ctx, spans, fmt.Errorf("circuit breaker is open"))
+	}
+
+	err := b.exporter.ExportSpans(ctx, spans)
+	b.mu.Lock()
+	if err == nil {
+		b.failures = 0
+		if b.state != CircuitClosed {
+			b.setState(ctx, CircuitClosed, nil)
+		}
+		b.mu.Unlock()
+		return nil
+	}
+	b.failures++
+	if b.state == CircuitHalfOpen || b.failures >= b.threshold {
+		b.openedAt = time.Now()
+		if b.state != CircuitOpen {
+			b.setState(ctx, CircuitOpen, err)
+		}
+	}
+	b.mu.Unlock()
+	return b.exportFallback(ctx, spans, err)
+}
+
+// Writes the spans the collector did not receive to the fallback, returning err without one
+func (b *CircuitBreaker) exportFallback(ctx context.Context, spans []sdktrace.ReadOnlySpan, err error) error {
+	if b.fallback == nil {
+		return err
+	}
+	if fallbackErr := b.fallback.ExportSpans(ctx, spans); fallbackErr != nil {
+		return fmt.Errorf("%v, fallback failed: %v", err, fallbackErr)
+	}
+	b.fallbackSpans.Add(int64(len(spans)))
+	return nil
+}
+
+// Changes the state and logs the change, b.mu must be held
+func (b *CircuitBreaker) setState(ctx context.Context, state string, err error) {
+	attrs := []any{"from", b.state, "to", state, "failures", b.failures}
+	if err != nil {
+		attrs = append(attrs, "error", err)
+	}
+	slog.WarnContext(ctx, "Span exporter circuit breaker changed state", attrs...)
+	b.state = state
+}
+
+// Shutdown shuts down the exporter and the fallback
+func (b *CircuitBreaker) Shutdown(ctx context.Context) error {
+	err := b.exporter.Shutdown(ctx)
+	if b.fallback != nil {
+		if fallbackErr := b.fallback.Shutdown(ctx); err == nil {
+			err = fallbackErr
+		}
+	}
+	return err
+}
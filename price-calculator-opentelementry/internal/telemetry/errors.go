@@ -0,0 +1,13 @@
+package telemetry
+
+import (
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// RecordError records err as an exception event on span and marks the span as failed
+func RecordError(span trace.Span, err error, attrs ...attribute.KeyValue) {
+	span.RecordError(err, trace.WithAttributes(attrs...))
+	span.SetStatus(codes.Error, err.Error())
+}
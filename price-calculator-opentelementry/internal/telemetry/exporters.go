@@ -0,0 +1,135 @@
+package telemetry
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+
+	"otpl/pricecalculator/internal/config"
+)
+
+// Exporter type switching the export of the spans off at runtime
+const ExporterNone = "none"
+
+// SetTraceExporter switches the exporter of the monitored pipeline to a new exporter of the given
+// type, otlp, stdout or none, at runtime. The configured settings of an exporter of that type, such as
+// the circuit breaker of the otlp exporter, are reused. The other configured exporters keep exporting.
+func (t *Telemetry) SetTraceExporter(ctx context.Context, exporterType string) error {
+	if exporterType == ExporterNone {
+		return t.Pipeline.SetExporter(ctx, ExporterNone, nil)
+	}
+	if exporterType != config.ExporterOTLP && exporterType != config.ExporterStdout {
+		return fmt.Errorf("unsupported trace exporter %q, expected %s, %s or %s", exporterType, config.ExporterOTLP, config.ExporterStdout, ExporterNone)
+	}
+	cfg := config.TraceExporter{Type: exporterType}
+	for _, configured := range t.traceExporters {
+		if configured.Type == exporterType || configured.Type == "" && exporterType == config.ExporterOTLP {
+			cfg = configured
+			break
+		}
+	}
+	exporter, err := NewSpanExporter(ctx, cfg, t.otlp)
+	if err != nil {
+		return err
+	}
+	return t.Pipeline.SetExporter(ctx, exporterType, exporter)
+}
+
+// Creates the span processors of the exporters, the OTLP exporter when there are none.
+// The first exporter is batched by the returned monitored pipeline, the others by their own batch processor.
+func newSpanProcessors(ctx context.Context, exporters []config.TraceExporter, otlpCfg OTLPConfig) ([]sdktrace.SpanProcessor, *SpanPipeline, error) {
+	if len(exporters) == 0 {
+		exporters = []config.TraceExporter{{Type: config.ExporterOTLP}}
+	}
+	var processors []sdktrace.SpanProcessor
+	var pipeline *SpanPipeline
+	for i, cfg := range exporters {
+		exporter, err := NewSpanExporter(ctx, cfg, otlpCfg)
+		if err != nil {
+			for _, p := range processors {
+				_ = p.Shutdown(ctx)
+			}
+			return nil, nil, err
+		}
+		if i > 0 {
+			settings, err := batchSettings(cfg.Batch)
+			if err != nil {
+				_ = exporter.Shutdown(ctx)
+				for _, p := range processors {
+					_ = p.Shutdown(ctx)
+				}
+				return nil, nil, err
+			}
+			processors = append(processors, sdktrace.NewBatchSpanProcessor(exporter, batchOptions(settings)...))
+			continue
+		}
+		exporterType := cfg.Type
+		if exporterType == "" {
+			exporterType = config.ExporterOTLP
+		}
+		if pipeline, err = NewSpanPipeline(exporterType, exporter, cfg.Batch); err != nil {
+			_ = exporter.Shutdown(ctx)
+			return nil, nil, fmt.Errorf("failed to create span processor: %v", err)
+		}
+		processors = append(processors, pipeline)
+	}
+	return processors, pipeline, nil
+}
+
+// NewSpanExporter creates a span exporter of the trace_exporters section of the configuration file
+func NewSpanExporter(ctx context.Context, cfg config.TraceExporter, otlpCfg OTLPConfig) (sdktrace.SpanExporter, error) {
+	var options []stdouttrace.Option
+	if cfg.PrettyPrint {
+		options = append(options, stdouttrace.WithPrettyPrint())
+	}
+	switch cfg.Type {
+	case config.ExporterStdout:
+		exporter, err := stdouttrace.New(options...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create stdout exporter: %v", err)
+		}
+		return exporter, nil
+	case config.ExporterFile:
+		file, err := os.OpenFile(cfg.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open span file: %v", err)
+		}
+		exporter, err := stdouttrace.New(append(options, stdouttrace.WithWriter(file))...)
+		if err != nil {
+			file.Close()
+			return nil, fmt.Errorf("failed to create file exporter: %v", err)
+		}
+		return fileExporter{exporter, file}, nil
+	default:
+		exporter, err := NewTraceExporter(ctx, otlpCfg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create OTLP exporter: %v", err)
+		}
+		breaker := cfg.CircuitBreaker
+		var fallback sdktrace.SpanExporter
+		if breaker.FallbackPath != "" {
+			if fallback, err = NewSpanExporter(ctx, config.TraceExporter{Type: config.ExporterFile, Path: breaker.FallbackPath}, otlpCfg); err != nil {
+				_ = exporter.Shutdown(ctx)
+				return nil, err
+			}
+		}
+		return NewCircuitBreaker(exporter, fallback, breaker.FailureThreshold, breaker.OpenTimeout), nil
+	}
+}
+
+// fileExporter writes spans to a file, closed when the exporter shuts down
+type fileExporter struct {
+	sdktrace.SpanExporter
+	file *os.File
+}
+
+func (e fileExporter) Shutdown(ctx context.Context) error {
+	err := e.SpanExporter.Shutdown(ctx)
+	if closeErr := e.file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
@@ -0,0 +1,114 @@
+package telemetry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// LogLevel is the minimum level of the records logged, changeable at runtime
+var LogLevel slog.LevelVar
+
+// Handler writing JSON records to stdout
+var stdoutHandler slog.Handler
+
+// Context key of the ID of the request a context belongs to
+type requestIDKey struct{}
+
+// InitLogging configures slog as the default logger, writing JSON to stdout at the given level,
+// info when empty. Every record carries the trace and span IDs, and the request ID, of its context.
+func InitLogging(level string) error {
+	if level != "" {
+		if err := LogLevel.UnmarshalText([]byte(strings.ToUpper(level))); err != nil {
+			return fmt.Errorf("invalid LOG_LEVEL %q: %v", level, err)
+		}
+	}
+
+	stdoutHandler = traceHandler{slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: &LogLevel})}
+	slog.SetDefault(slog.New(stdoutHandler))
+	return nil
+}
+
+// ExportLogs sends every log record to the given handler as well as stdout
+func ExportLogs(handler slog.Handler) {
+	slog.SetDefault(slog.New(fanoutHandler{level: &LogLevel, handlers: []slog.Handler{stdoutHandler, handler}}))
+}
+
+// WithRequestID returns a context whose log records carry the request ID
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestID returns the ID of the request handled in ctx, empty outside a request
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// fanoutHandler sends every record at or above its level to all of its handlers
+type fanoutHandler struct {
+	level    slog.Leveler
+	handlers []slog.Handler
+}
+
+func (h fanoutHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return level >= h.level.Level()
+}
+
+func (h fanoutHandler) Handle(ctx context.Context, record slog.Record) error {
+	var errs []error
+	for _, handler := range h.handlers {
+		if err := handler.Handle(ctx, record.Clone()); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (h fanoutHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	handlers := make([]slog.Handler, len(h.handlers))
+	for i, handler := range h.handlers {
+		handlers[i] = handler.WithAttrs(attrs)
+	}
+	return fanoutHandler{level: h.level, handlers: handlers}
+}
+
+func (h fanoutHandler) WithGroup(name string) slog.Handler {
+	handlers := make([]slog.Handler, len(h.handlers))
+	for i, handler := range h.handlers {
+		handlers[i] = handler.WithGroup(name)
+	}
+	return fanoutHandler{level: h.level, handlers: handlers}
+}
+
+// traceHandler adds the trace and span IDs of the span in the context, and the request ID,
+// to every record so log lines can be joined with their traces and support requests
+type traceHandler struct {
+	slog.Handler
+}
+
+func (h traceHandler) Handle(ctx context.Context, record slog.Record) error {
+	if spanContext := trace.SpanContextFromContext(ctx); spanContext.IsValid() {
+		record.AddAttrs(
+			slog.String("trace_id", spanContext.TraceID().String()),
+			slog.String("span_id", spanContext.SpanID().String()),
+		)
+	}
+	if id := RequestID(ctx); id != "" {
+		record.AddAttrs(slog.String("request_id", id))
+	}
+	return h.Handler.Handle(ctx, record)
+}
+
+func (h traceHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return traceHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h traceHandler) WithGroup(name string) slog.Handler {
+	return traceHandler{h.Handler.WithGroup(name)}
+}
@@ -0,0 +1,222 @@
+package telemetry
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/exporters/otlp/otlplog/otlploggrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlplog/otlploghttp"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetricgrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"google.golang.org/grpc/credentials"
+
+	"otpl/pricecalculator/internal/config"
+)
+
+// Supported OTLP protocols
+const (
+	ProtocolHTTP = "http/protobuf"
+	ProtocolGRPC = "grpc"
+)
+
+// OTLPConfig holds the collector connection settings for the OTLP exporters
+type OTLPConfig struct {
+	Endpoint string      // host:port of the collector
+	URLPath  string      // optional path prefix for the HTTP protocol
+	Protocol string      // http/protobuf or grpc
+	Insecure bool        // disable TLS
+	TLS      *tls.Config // TLS settings when Insecure is false
+	Retry    RetryConfig // Retry of the exports the collector failed
+}
+
+// RetryConfig holds the exponential backoff of the exports retried by the OTLP exporters
+type RetryConfig struct {
+	Enabled         bool
+	InitialInterval time.Duration // Wait before the first retry, doubled for every further retry
+	MaxInterval     time.Duration // Longest wait between retries
+	MaxElapsedTime  time.Duration // Time after which a failed export is given up
+}
+
+// Defaults of the retry settings, those of the OTLP exporters
+var defaultRetry = RetryConfig{Enabled: true, InitialInterval: 5 * time.Second, MaxInterval: 30 * time.Second, MaxElapsedTime: time.Minute}
+
+// Applies the retry settings of the configuration file over the defaults
+func loadRetryConfig(file config.OTLPRetry) RetryConfig {
+	retry := defaultRetry
+	if file.Enabled != nil {
+		retry.Enabled = *file.Enabled
+	}
+	if file.InitialInterval > 0 {
+		retry.InitialInterval = file.InitialInterval
+	}
+	if file.MaxInterval > 0 {
+		retry.MaxInterval = file.MaxInterval
+	}
+	if file.MaxElapsedTime > 0 {
+		retry.MaxElapsedTime = file.MaxElapsedTime
+	}
+	return retry
+}
+
+// LoadOTLPConfig loads the OTLP settings from the standard OTEL_EXPORTER_OTLP_* environment
+// variables, falling back to the otlp section of the configuration file
+func LoadOTLPConfig(file config.OTLP) (OTLPConfig, error) {
+	cfg := OTLPConfig{
+		Protocol: config.Setting("OTEL_EXPORTER_OTLP_PROTOCOL", file.Protocol),
+		Insecure: true, // The collector runs locally without TLS by default
+		Retry:    loadRetryConfig(file.Retry),
+	}
+	if cfg.Protocol == "" {
+		cfg.Protocol = ProtocolHTTP
+	}
+	if cfg.Protocol != ProtocolHTTP && cfg.Protocol != ProtocolGRPC {
+		return cfg, fmt.Errorf("unsupported OTLP protocol %q", cfg.Protocol)
+	}
+
+	// Default to the local collector on the standard port for the protocol
+	cfg.Endpoint = "localhost:4318"
+	if cfg.Protocol == ProtocolGRPC {
+		cfg.Endpoint = "localhost:4317"
+	}
+
+	// The endpoint may be a plain host:port or a URL whose scheme selects TLS
+	if endpoint := config.Setting("OTEL_EXPORTER_OTLP_ENDPOINT", file.Endpoint); endpoint != "" {
+		if strings.Contains(endpoint, "://") {
+			u, err := url.Parse(endpoint)
+			if err != nil {
+				return cfg, fmt.Errorf("invalid OTLP endpoint: %v", err)
+			}
+			cfg.Endpoint = u.Host
+			cfg.URLPath = strings.TrimSuffix(u.Path, "/")
+			cfg.Insecure = u.Scheme != "https"
+		} else {
+			cfg.Endpoint = endpoint
+		}
+	}
+
+	caFile := os.Getenv("OTEL_EXPORTER_OTLP_CERTIFICATE")
+	certFile := os.Getenv("OTEL_EXPORTER_OTLP_CLIENT_CERTIFICATE")
+	keyFile := os.Getenv("OTEL_EXPORTER_OTLP_CLIENT_KEY")
+	if caFile != "" || certFile != "" {
+		cfg.Insecure = false
+	}
+	if file.Insecure != nil {
+		cfg.Insecure = *file.Insecure
+	}
+	if value := os.Getenv("OTEL_EXPORTER_OTLP_INSECURE"); value != "" {
+		insecure, err := strconv.ParseBool(value)
+		if err != nil {
+			return cfg, fmt.Errorf("invalid OTEL_EXPORTER_OTLP_INSECURE: %v", err)
+		}
+		cfg.Insecure = insecure
+	}
+	if cfg.Insecure {
+		return cfg, nil
+	}
+
+	// Build the TLS configuration from the optional CA and client certificate
+	cfg.TLS = &tls.Config{MinVersion: tls.VersionTLS12}
+	if caFile != "" {
+		pem, err := os.ReadFile(caFile)
+		if err != nil {
+			return cfg, fmt.Errorf("failed to read OTLP certificate: %v", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return cfg, fmt.Errorf("no certificates found in %s", caFile)
+		}
+		cfg.TLS.RootCAs = pool
+	}
+	if certFile != "" || keyFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return cfg, fmt.Errorf("failed to load OTLP client certificate: %v", err)
+		}
+		cfg.TLS.Certificates = []tls.Certificate{cert}
+	}
+	return cfg, nil
+}
+
+// NewTraceExporter creates the OTLP trace exporter for the configured protocol
+func NewTraceExporter(ctx context.Context, cfg OTLPConfig) (sdktrace.SpanExporter, error) {
+	if cfg.Protocol == ProtocolGRPC {
+		opts := []otlptracegrpc.Option{otlptracegrpc.WithEndpoint(cfg.Endpoint), otlptracegrpc.WithRetry(otlptracegrpc.RetryConfig(cfg.Retry))}
+		if cfg.Insecure {
+			opts = append(opts, otlptracegrpc.WithInsecure())
+		} else {
+			opts = append(opts, otlptracegrpc.WithTLSCredentials(credentials.NewTLS(cfg.TLS)))
+		}
+		return otlptracegrpc.New(ctx, opts...)
+	}
+
+	opts := []otlptracehttp.Option{otlptracehttp.WithEndpoint(cfg.Endpoint), otlptracehttp.WithRetry(otlptracehttp.RetryConfig(cfg.Retry))}
+	if cfg.URLPath != "" {
+		opts = append(opts, otlptracehttp.WithURLPath(cfg.URLPath+"/v1/traces"))
+	}
+	if cfg.Insecure {
+		opts = append(opts, otlptracehttp.WithInsecure())
+	} else {
+		opts = append(opts, otlptracehttp.WithTLSClientConfig(cfg.TLS))
+	}
+	return otlptracehttp.New(ctx, opts...)
+}
+
+// NewMetricExporter creates the OTLP metric exporter for the configured protocol
+func NewMetricExporter(ctx context.Context, cfg OTLPConfig) (sdkmetric.Exporter, error) {
+	if cfg.Protocol == ProtocolGRPC {
+		opts := []otlpmetricgrpc.Option{otlpmetricgrpc.WithEndpoint(cfg.Endpoint), otlpmetricgrpc.WithRetry(otlpmetricgrpc.RetryConfig(cfg.Retry))}
+		if cfg.Insecure {
+			opts = append(opts, otlpmetricgrpc.WithInsecure())
+		} else {
+			opts = append(opts, otlpmetricgrpc.WithTLSCredentials(credentials.NewTLS(cfg.TLS)))
+		}
+		return otlpmetricgrpc.New(ctx, opts...)
+	}
+
+	opts := []otlpmetrichttp.Option{otlpmetrichttp.WithEndpoint(cfg.Endpoint), otlpmetrichttp.WithRetry(otlpmetrichttp.RetryConfig(cfg.Retry))}
+	if cfg.URLPath != "" {
+		opts = append(opts, otlpmetrichttp.WithURLPath(cfg.URLPath+"/v1/metrics"))
+	}
+	if cfg.Insecure {
+		opts = append(opts, otlpmetrichttp.WithInsecure())
+	} else {
+		opts = append(opts, otlpmetrichttp.WithTLSClientConfig(cfg.TLS))
+	}
+	return otlpmetrichttp.New(ctx, opts...)
+}
+
+// NewLogExporter creates the OTLP log exporter for the configured protocol
+func NewLogExporter(ctx context.Context, cfg OTLPConfig) (sdklog.Exporter, error) {
+	if cfg.Protocol == ProtocolGRPC {
+		opts := []otlploggrpc.Option{otlploggrpc.WithEndpoint(cfg.Endpoint), otlploggrpc.WithRetry(otlploggrpc.RetryConfig(cfg.Retry))}
+		if cfg.Insecure {
+			opts = append(opts, otlploggrpc.WithInsecure())
+		} else {
+			opts = append(opts, otlploggrpc.WithTLSCredentials(credentials.NewTLS(cfg.TLS)))
+		}
+		return otlploggrpc.New(ctx, opts...)
+	}
+
+	opts := []otlploghttp.Option{otlploghttp.WithEndpoint(cfg.Endpoint), otlploghttp.WithRetry(otlploghttp.RetryConfig(cfg.Retry))}
+	if cfg.URLPath != "" {
+		opts = append(opts, otlploghttp.WithURLPath(cfg.URLPath+"/v1/logs"))
+	}
+	if cfg.Insecure {
+		opts = append(opts, otlploghttp.WithInsecure())
+	} else {
+		opts = append(opts, otlploghttp.WithTLSClientConfig(cfg.TLS))
+	}
+	return otlploghttp.New(ctx, opts...)
+}
@@ -0,0 +1,255 @@
+package telemetry
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+
+	"otpl/pricecalculator/internal/config"
+)
+
+// PipelineStatus structure for the state of the span export pipeline
+type PipelineStatus struct {
+	QueueDepth     int64  `json:"queue_depth"` // Spans ended but not yet exported
+	MaxQueueSize   int64  `json:"max_queue_size"`
+	ExportedSpans  int64  `json:"exported_spans"`
+	FailedSpans    int64  `json:"failed_spans"`  // Spans the exporter failed to send
+	DroppedSpans   int64  `json:"dropped_spans"` // Spans discarded because the queue was full
+	StdoutExporter bool   `json:"stdout_exporter"`
+	Exporter       string `json:"exporter"` // Type of the exporter the pipeline exports to: otlp, stdout, file or none
+
+	// Circuit breaker of an OTLP exporter
+	CircuitState  string `json:"circuit_state,omitempty"`  // closed, open or half_open
+	FallbackSpans int64  `json:"fallback_spans,omitempty"` // Spans written to the fallback file while open
+}
+
+// SpanPipeline is a batch span processor that counts the spans passing through it.
+// The batch processor blocks instead of dropping, and spans are dropped here once
+// the queue is full, so the queue depth and dropped spans are known exactly.
+// Its exporter can be swapped at runtime, replacing the batch processor.
+type SpanPipeline struct {
+	settings     config.BatchSpanProcessor
+	maxQueueSize int64
+	pending      atomic.Int64 // Spans handed to the batch processor and not yet exported
+	exported     atomic.Int64
+	failed       atomic.Int64
+	dropped      atomic.Int64
+
+	mu        sync.RWMutex
+	processor *batch          // Batch processor of the exporter, nil when spans are not exported
+	exporter  string          // Type of the exporter
+	breaker   *CircuitBreaker // Circuit breaker of the exporter, nil unless it has one
+
+	stdoutMu sync.Mutex
+	stdout   sdktrace.SpanProcessor // Debug exporter, nil when disabled
+}
+
+// NewSpanPipeline creates the batch span processor for exporter, of the given type, with the batch
+// settings, overridden by the OTEL_BSP_* variables
+func NewSpanPipeline(exporterType string, exporter sdktrace.SpanExporter, batch config.BatchSpanProcessor) (*SpanPipeline, error) {
+	settings, err := batchSettings(batch)
+	if err != nil {
+		return nil, err
+	}
+	p := &SpanPipeline{settings: settings, maxQueueSize: int64(settings.MaxQueueSize)}
+	p.processor, p.breaker = p.newProcessor(exporter)
+	p.exporter = exporterType
+	return p, nil
+}
+
+// batch is the batch processor of an exporter with the spans queued on it, so the spans it
+// discards on shutdown can be taken off the pipeline's queue depth
+type batch struct {
+	sdktrace.SpanProcessor
+	pending atomic.Int64 // Spans queued on this processor and not yet exported
+}
+
+// Takes up to n exported or discarded spans off the processor's and the pipeline's queue depth.
+// Spans exported after a timed out shutdown were already taken off.
+func (b *batch) settle(p *SpanPipeline, n int64) {
+	for {
+		pending := b.pending.Load()
+		settled := min(pending, n)
+		if b.pending.CompareAndSwap(pending, pending-settled) {
+			p.pending.Add(-settled)
+			return
+		}
+	}
+}
+
+// Creates the batch processor of an exporter, nil for no exporter, and returns the exporter's circuit breaker
+func (p *SpanPipeline) newProcessor(exporter sdktrace.SpanExporter) (*batch, *CircuitBreaker) {
+	if exporter == nil {
+		return nil, nil
+	}
+	breaker, _ := exporter.(*CircuitBreaker)
+	b := &batch{}
+	b.SpanProcessor = sdktrace.NewBatchSpanProcessor(countingExporter{exporter, p, b},
+		append(batchOptions(p.settings), sdktrace.WithBlocking())...,
+	)
+	return b, breaker
+}
+
+// Shuts down a batch processor. The spans it discarded instead of exporting, when ctx
+// ended first, are taken off the queue depth and counted as dropped.
+func (p *SpanPipeline) shutdownProcessor(ctx context.Context, b *batch) error {
+	err := b.Shutdown(ctx)
+	discarded := b.pending.Load()
+	b.settle(p, discarded)
+	p.dropped.Add(discarded)
+	return err
+}
+
+func (p *SpanPipeline) OnStart(parent context.Context, s sdktrace.ReadWriteSpan) {}
+
+// OnEnd queues a sampled span for export, dropping it when the queue is full.
+// Spans are discarded uncounted while the pipeline has no exporter.
+func (p *SpanPipeline) OnEnd(s sdktrace.ReadOnlySpan) {
+	if !s.SpanContext().IsSampled() {
+		return
+	}
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.processor == nil {
+		return
+	}
+	if p.pending.Add(1) > p.maxQueueSize {
+		p.pending.Add(-1)
+		p.dropped.Add(1)
+		return
+	}
+	p.processor.pending.Add(1)
+	p.processor.OnEnd(s)
+}
+
+// ForceFlush exports the queued spans now
+func (p *SpanPipeline) ForceFlush(ctx context.Context) error {
+	p.mu.RLock()
+	processor := p.processor
+	p.mu.RUnlock()
+	if processor == nil {
+		return nil
+	}
+	return processor.ForceFlush(ctx)
+}
+
+// Shutdown exports the queued spans and stops the exporter
+func (p *SpanPipeline) Shutdown(ctx context.Context) error {
+	p.mu.Lock()
+	processor := p.processor
+	p.processor, p.breaker = nil, nil
+	p.mu.Unlock()
+	if processor == nil {
+		return nil
+	}
+	return p.shutdownProcessor(ctx, processor)
+}
+
+// SetExporter replaces the exporter of the pipeline with exporter, of the given type, or with none
+// when exporter is nil. The spans queued for the previous exporter are exported to it before it is
+// shut down, while new spans already go to the new exporter.
+func (p *SpanPipeline) SetExporter(ctx context.Context, exporterType string, exporter sdktrace.SpanExporter) error {
+	processor, breaker := p.newProcessor(exporter)
+	p.mu.Lock()
+	previous := p.processor
+	p.processor, p.breaker, p.exporter = processor, breaker, exporterType
+	p.mu.Unlock()
+	if previous == nil {
+		return nil
+	}
+	if err := p.shutdownProcessor(ctx, previous); err != nil {
+		return fmt.Errorf("failed to shut down previous exporter: %v", err)
+	}
+	return nil
+}
+
+// Status returns the current state of the pipeline
+func (p *SpanPipeline) Status() PipelineStatus {
+	p.stdoutMu.Lock()
+	stdout := p.stdout != nil
+	p.stdoutMu.Unlock()
+	p.mu.RLock()
+	exporter, breaker := p.exporter, p.breaker
+	p.mu.RUnlock()
+	status := PipelineStatus{
+		QueueDepth:     p.pending.Load(),
+		MaxQueueSize:   p.maxQueueSize,
+		ExportedSpans:  p.exported.Load(),
+		FailedSpans:    p.failed.Load(),
+		DroppedSpans:   p.dropped.Load(),
+		StdoutExporter: stdout,
+		Exporter:       exporter,
+	}
+	if breaker != nil {
+		status.CircuitState = breaker.State()
+		status.FallbackSpans = breaker.FallbackSpans()
+	}
+	return status
+}
+
+// LogLostSpans logs the spans dropped or failed since the previous line every interval until ctx
+// is done, so a collector outage does not lose spans silently. Nothing is logged while none are lost.
+func (p *SpanPipeline) LogLostSpans(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	var dropped, failed int64
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		status := p.Status()
+		if status.DroppedSpans == dropped && status.FailedSpans == failed {
+			continue
+		}
+		slog.WarnContext(ctx, "Spans lost", "dropped_spans", status.DroppedSpans-dropped, "failed_spans", status.FailedSpans-failed,
+			"interval", interval.String(), "total_dropped_spans", status.DroppedSpans, "total_failed_spans", status.FailedSpans)
+		dropped, failed = status.DroppedSpans, status.FailedSpans
+	}
+}
+
+// SetStdoutExporter adds or removes a processor writing every span to stdout on tp
+func (p *SpanPipeline) SetStdoutExporter(tp *sdktrace.TracerProvider, enabled bool) error {
+	p.stdoutMu.Lock()
+	defer p.stdoutMu.Unlock()
+	if enabled == (p.stdout != nil) {
+		return nil
+	}
+	if !enabled {
+		tp.UnregisterSpanProcessor(p.stdout)
+		p.stdout = nil
+		return nil
+	}
+	exporter, err := stdouttrace.New(stdouttrace.WithPrettyPrint())
+	if err != nil {
+		return fmt.Errorf("failed to create stdout exporter: %v", err)
+	}
+	p.stdout = sdktrace.NewSimpleSpanProcessor(exporter)
+	tp.RegisterSpanProcessor(p.stdout)
+	return nil
+}
+
+// countingExporter counts the spans its exporter sent or failed to send
+type countingExporter struct {
+	sdktrace.SpanExporter
+	pipeline *SpanPipeline
+	batch    *batch // Processor exporting to it
+}
+
+func (e countingExporter) ExportSpans(ctx context.Context, spans []sdktrace.ReadOnlySpan) error {
+	err := e.SpanExporter.ExportSpans(ctx, spans)
+	e.batch.settle(e.pipeline, int64(len(spans)))
+	if err != nil {
+		e.pipeline.failed.Add(int64(len(spans)))
+		return err
+	}
+	e.pipeline.exported.Add(int64(len(spans)))
+	return nil
+}
@@ -0,0 +1,42 @@
+package telemetry
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"go.opentelemetry.io/contrib/propagators/b3"
+	"go.opentelemetry.io/contrib/propagators/jaeger"
+	"go.opentelemetry.io/otel/propagation"
+)
+
+// NewPropagator creates the composite propagator named by OTEL_PROPAGATORS, a comma separated list of
+// tracecontext, baggage, b3 (single header), b3multi and jaeger, defaulting to tracecontext,baggage.
+// Every listed format is extracted from incoming requests and injected into outgoing ones,
+// so traces stitch with upstream services whatever format they use.
+func NewPropagator() (propagation.TextMapPropagator, error) {
+	value := os.Getenv("OTEL_PROPAGATORS")
+	if value == "" {
+		value = "tracecontext,baggage"
+	}
+
+	var propagators []propagation.TextMapPropagator
+	for _, name := range strings.Split(value, ",") {
+		switch name = strings.TrimSpace(name); name {
+		case "tracecontext":
+			propagators = append(propagators, propagation.TraceContext{})
+		case "baggage":
+			propagators = append(propagators, propagation.Baggage{})
+		case "b3":
+			propagators = append(propagators, b3.New(b3.WithInjectEncoding(b3.B3SingleHeader)))
+		case "b3multi":
+			propagators = append(propagators, b3.New(b3.WithInjectEncoding(b3.B3MultipleHeader)))
+		case "jaeger":
+			propagators = append(propagators, jaeger.Jaeger{})
+		case "none":
+		default:
+			return nil, fmt.Errorf("unsupported propagator %q", name)
+		}
+	}
+	return propagation.NewCompositeTextMapPropagator(propagators...), nil
+}
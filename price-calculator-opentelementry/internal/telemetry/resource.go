@@ -0,0 +1,68 @@
+package telemetry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"runtime/debug"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/resource"
+	semconv "go.opentelemetry.io/otel/semconv/v1.18.0"
+)
+
+// Version of the service, set at build time with
+// -ldflags "-X otpl/pricecalculator/internal/telemetry.version=1.2.3"
+var version string
+
+// ServiceVersion returns the version set at build time, else the module version or the
+// VCS revision recorded in the build info, else "unknown"
+func ServiceVersion() string {
+	if version != "" {
+		return version
+	}
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "unknown"
+	}
+	if v := info.Main.Version; v != "" && v != "(devel)" {
+		return v
+	}
+	for _, setting := range info.Settings {
+		if setting.Key == "vcs.revision" && len(setting.Value) >= 12 {
+			return setting.Value[:12]
+		}
+	}
+	return "unknown"
+}
+
+// NewResource describes the service in its telemetry: the service name and version, the
+// deployment environment of DEPLOYMENT_ENVIRONMENT, the SDK, and the host and container detected
+// at startup. OTEL_SERVICE_NAME and OTEL_RESOURCE_ATTRIBUTES take precedence over all of them.
+func NewResource(ctx context.Context, serviceName string) (*resource.Resource, error) {
+	attrs := []attribute.KeyValue{
+		semconv.ServiceName(serviceName),
+		semconv.ServiceVersion(ServiceVersion()),
+	}
+	if environment := os.Getenv("DEPLOYMENT_ENVIRONMENT"); environment != "" {
+		attrs = append(attrs, semconv.DeploymentEnvironment(environment))
+	}
+	res, err := resource.New(ctx,
+		resource.WithAttributes(attrs...),
+		resource.WithTelemetrySDK(),
+		resource.WithHost(),
+		resource.WithContainer(),
+		resource.WithFromEnv(), // Last, so the variables override the detected attributes
+	)
+	// A detector failing, such as outside a container, leaves its attributes out
+	if errors.Is(err, resource.ErrPartialResource) {
+		slog.WarnContext(ctx, "Some resource attributes could not be detected", "error", err)
+		return res, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create resource: %v", err)
+	}
+	return res, nil
+}
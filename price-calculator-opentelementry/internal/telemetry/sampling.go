@@ -0,0 +1,146 @@
+package telemetry
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// Supported samplers, named as in the OTEL_TRACES_SAMPLER specification
+const (
+	SamplerAlwaysOn                = "always_on"
+	SamplerAlwaysOff               = "always_off"
+	SamplerTraceIDRatio            = "traceidratio"
+	SamplerParentBasedAlwaysOn     = "parentbased_always_on"
+	SamplerParentBasedAlwaysOff    = "parentbased_always_off"
+	SamplerParentBasedTraceIDRatio = "parentbased_traceidratio"
+
+	// Samples the ratio of root spans, and every trace with an error or a slow local root
+	SamplerErrorsAndSlow = "errors_and_slow"
+)
+
+// Default slow threshold of the errors_and_slow sampler
+const defaultSlowThresholdMS = 1000
+
+// SamplingConfig structure for the sampler settings
+type SamplingConfig struct {
+	Sampler string  `json:"sampler"`
+	Ratio   float64 `json:"ratio"` // Only used by the ratio based samplers and errors_and_slow
+
+	// Traces whose local root takes at least this long are kept by errors_and_slow
+	SlowThresholdMS float64 `json:"slow_threshold_ms"`
+}
+
+// DynamicSampler delegates to a sampler that can be replaced without restarting
+type DynamicSampler struct {
+	mu       sync.RWMutex
+	cfg      SamplingConfig
+	delegate sdktrace.Sampler
+}
+
+// NewDynamicSampler creates a sampler with the given settings, replaced later with Configure
+func NewDynamicSampler(cfg SamplingConfig) (*DynamicSampler, error) {
+	s := &DynamicSampler{}
+	if err := s.Configure(cfg); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// ShouldSample decides whether a span is sampled using the current sampler
+func (s *DynamicSampler) ShouldSample(p sdktrace.SamplingParameters) sdktrace.SamplingResult {
+	s.mu.RLock()
+	delegate := s.delegate
+	s.mu.RUnlock()
+	return delegate.ShouldSample(p)
+}
+
+// Description describes the current sampler
+func (s *DynamicSampler) Description() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.delegate.Description()
+}
+
+// Config returns the current sampler settings
+func (s *DynamicSampler) Config() SamplingConfig {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.cfg
+}
+
+// SlowThreshold returns the duration from which the errors_and_slow sampler keeps a trace
+func (s *DynamicSampler) SlowThreshold() time.Duration {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return time.Duration(s.cfg.SlowThresholdMS * float64(time.Millisecond))
+}
+
+// Configure replaces the sampler with one built from the given settings
+func (s *DynamicSampler) Configure(cfg SamplingConfig) error {
+	delegate, err := NewSampler(cfg)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	s.cfg = cfg
+	s.delegate = delegate
+	s.mu.Unlock()
+	return nil
+}
+
+// NewSampler creates the sampler for the given settings
+func NewSampler(cfg SamplingConfig) (sdktrace.Sampler, error) {
+	if cfg.Ratio < 0 || cfg.Ratio > 1 {
+		return nil, fmt.Errorf("sampling ratio must be between 0 and 1")
+	}
+	if cfg.SlowThresholdMS < 0 {
+		return nil, fmt.Errorf("slow threshold must not be negative")
+	}
+	switch cfg.Sampler {
+	case SamplerAlwaysOn:
+		return sdktrace.AlwaysSample(), nil
+	case SamplerAlwaysOff:
+		return sdktrace.NeverSample(), nil
+	case SamplerTraceIDRatio:
+		return sdktrace.TraceIDRatioBased(cfg.Ratio), nil
+	case SamplerParentBasedAlwaysOn:
+		return sdktrace.ParentBased(sdktrace.AlwaysSample()), nil
+	case SamplerParentBasedAlwaysOff:
+		return sdktrace.ParentBased(sdktrace.NeverSample()), nil
+	case SamplerParentBasedTraceIDRatio:
+		return sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.Ratio)), nil
+	case SamplerErrorsAndSlow:
+		return errorsAndSlowSampler{base: sdktrace.TraceIDRatioBased(cfg.Ratio)}, nil
+	}
+	return nil, fmt.Errorf("unsupported sampler %q", cfg.Sampler)
+}
+
+// LoadSamplingConfig loads the sampler settings from OTEL_TRACES_SAMPLER and OTEL_TRACES_SAMPLER_ARG,
+// defaulting to the SDK default of sampling every root span, and the slow threshold of
+// errors_and_slow from SLOW_TRACE_THRESHOLD_MS
+func LoadSamplingConfig() (SamplingConfig, error) {
+	cfg := SamplingConfig{Sampler: os.Getenv("OTEL_TRACES_SAMPLER"), Ratio: 1, SlowThresholdMS: defaultSlowThresholdMS}
+	if cfg.Sampler == "" {
+		cfg.Sampler = SamplerParentBasedAlwaysOn
+	}
+	if value := os.Getenv("OTEL_TRACES_SAMPLER_ARG"); value != "" {
+		ratio, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return cfg, fmt.Errorf("invalid OTEL_TRACES_SAMPLER_ARG: %v", err)
+		}
+		cfg.Ratio = ratio
+	}
+	if value := os.Getenv("SLOW_TRACE_THRESHOLD_MS"); value != "" {
+		threshold, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return cfg, fmt.Errorf("invalid SLOW_TRACE_THRESHOLD_MS: %v", err)
+		}
+		cfg.SlowThresholdMS = threshold
+	}
+	return cfg, nil
+}
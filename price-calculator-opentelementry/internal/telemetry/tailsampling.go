@@ -0,0 +1,248 @@
+package telemetry
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Reasons a trace left out by the sampler is exported anyway
+const (
+	ForcedError = "error" // A span of the trace failed
+	ForcedSlow  = "slow"  // The local root span took at least the slow threshold
+)
+
+// Limits of the spans held until their trace is decided
+const (
+	maxBufferedSpans = 10000       // Spans of undecided traces, more are dropped
+	maxDecidedTraces = 4096        // Decisions remembered for spans ending after their local root
+	maxPendingAge    = time.Minute // Time a trace waits for its local root to end before it is decided without it
+)
+
+// errorsAndSlowSampler samples a fraction of the root spans and the children of sampled spans.
+// Every other span is recorded without being sampled, so ForceSampler can still export its
+// trace once it turns out to have failed or been slow.
+type errorsAndSlowSampler struct {
+	base sdktrace.Sampler
+}
+
+func (s errorsAndSlowSampler) ShouldSample(p sdktrace.SamplingParameters) sdktrace.SamplingResult {
+	parent := trace.SpanContextFromContext(p.ParentContext)
+	if parent.IsSampled() {
+		return sdktrace.SamplingResult{Decision: sdktrace.RecordAndSample, Tracestate: parent.TraceState()}
+	}
+	if !parent.IsValid() {
+		if result := s.base.ShouldSample(p); result.Decision == sdktrace.RecordAndSample {
+			return result
+		}
+	}
+	return sdktrace.SamplingResult{Decision: sdktrace.RecordOnly, Tracestate: parent.TraceState()}
+}
+
+func (s errorsAndSlowSampler) Description() string {
+	return "ErrorsAndSlow{" + s.base.Description() + "}"
+}
+
+// ForceSampler is a span processor in front of the export processors that passes on the
+// sampled spans, and holds the spans recorded but not sampled until the local root span of
+// their trace ends. The held spans are then exported as sampled when a span of the trace
+// failed or the root took at least the slow threshold of the sampler, and dropped otherwise.
+// Spans ending after their root follow the decision made for the trace.
+type ForceSampler struct {
+	processors []sdktrace.SpanProcessor
+	sampler    *DynamicSampler
+
+	mu           sync.Mutex
+	pending      map[trace.TraceID]*pendingTrace // Undecided traces
+	pendingOrder []*pendingTrace                 // Undecided traces by their first span, oldest first
+	buffered     int                             // Spans in pending
+	decided      map[trace.TraceID]string        // Reason a trace was forced, empty when dropped
+	order        []trace.TraceID                 // Decided traces, oldest first
+
+	forcedErrors atomic.Int64
+	forcedSlow   atomic.Int64
+}
+
+// NewForceSampler creates the processor passing the spans to processors, with the slow threshold of sampler
+func NewForceSampler(sampler *DynamicSampler, processors ...sdktrace.SpanProcessor) *ForceSampler {
+	return &ForceSampler{
+		processors: processors,
+		sampler:    sampler,
+		pending:    map[trace.TraceID]*pendingTrace{},
+		decided:    map[trace.TraceID]string{},
+	}
+}
+
+// ForcedTraces returns the number of traces exported because they failed or were slow
+func (f *ForceSampler) ForcedTraces() (errors, slow int64) {
+	return f.forcedErrors.Load(), f.forcedSlow.Load()
+}
+
+func (f *ForceSampler) OnStart(parent context.Context, s sdktrace.ReadWriteSpan) {
+	for _, p := range f.processors {
+		p.OnStart(parent, s)
+	}
+}
+
+func (f *ForceSampler) OnEnd(s sdktrace.ReadOnlySpan) {
+	if s.SpanContext().IsSampled() {
+		f.export(s)
+		return
+	}
+
+	traceID := s.SpanContext().TraceID()
+	now := time.Now()
+	f.mu.Lock()
+	expired := f.expirePending(now)
+	if reason, ok := f.decided[traceID]; ok {
+		f.mu.Unlock()
+		f.exportDecided(expired)
+		if reason != "" {
+			f.export(forcedSpan{s, reason})
+		}
+		return
+	}
+	t, ok := f.pending[traceID]
+	if !ok {
+		t = &pendingTrace{traceID: traceID, since: now}
+		f.pending[traceID] = t
+		f.pendingOrder = append(f.pendingOrder, t)
+	}
+	if f.buffered < maxBufferedSpans {
+		t.spans = append(t.spans, s)
+		f.buffered++
+	}
+	if s.Parent().IsValid() && !s.Parent().IsRemote() {
+		f.mu.Unlock()
+		f.exportDecided(expired)
+		return
+	}
+
+	// The local root ended, decide for the whole trace
+	reason := ""
+	if s.EndTime().Sub(s.StartTime()) >= f.sampler.SlowThreshold() {
+		reason = ForcedSlow
+	}
+	if s.Status().Code == codes.Error {
+		reason = ForcedError // Even when the root did not fit in the buffer
+	}
+	decided := f.decide(t, reason)
+	f.mu.Unlock()
+	f.exportDecided(append(expired, decided))
+}
+
+// pendingTrace holds the spans of a trace until its local root span ends
+type pendingTrace struct {
+	traceID trace.TraceID
+	spans   []sdktrace.ReadOnlySpan
+	since   time.Time // When its first span ended
+}
+
+// decidedTrace is a trace decided with the spans held for it
+type decidedTrace struct {
+	spans  []sdktrace.ReadOnlySpan
+	reason string // Reason the trace was forced, empty when dropped
+}
+
+// Decides for a pending trace, forced for reason unless one of its spans failed, with f.mu held
+func (f *ForceSampler) decide(t *pendingTrace, reason string) decidedTrace {
+	delete(f.pending, t.traceID)
+	f.buffered -= len(t.spans)
+	for _, span := range t.spans {
+		if span.Status().Code == codes.Error {
+			reason = ForcedError
+		}
+	}
+	f.decided[t.traceID] = reason
+	f.order = append(f.order, t.traceID)
+	if len(f.order) > maxDecidedTraces {
+		delete(f.decided, f.order[0])
+		f.order = f.order[1:]
+	}
+	return decidedTrace{spans: t.spans, reason: reason}
+}
+
+// Decides for the traces whose local root has not ended within maxPendingAge, so roots
+// that never end do not hold their spans forever. They are exported only when one of
+// their spans failed. Called with f.mu held.
+func (f *ForceSampler) expirePending(now time.Time) []decidedTrace {
+	var expired []decidedTrace
+	for len(f.pendingOrder) > 0 {
+		t := f.pendingOrder[0]
+		if f.pending[t.traceID] == t {
+			if now.Sub(t.since) < maxPendingAge {
+				break
+			}
+			expired = append(expired, f.decide(t, ""))
+		}
+		f.pendingOrder[0] = nil
+		f.pendingOrder = f.pendingOrder[1:]
+	}
+	return expired
+}
+
+// Exports the spans of the decided traces that were forced
+func (f *ForceSampler) exportDecided(traces []decidedTrace) {
+	for _, t := range traces {
+		switch t.reason {
+		case ForcedError:
+			f.forcedErrors.Add(1)
+		case ForcedSlow:
+			f.forcedSlow.Add(1)
+		default:
+			continue
+		}
+		for _, span := range t.spans {
+			f.export(forcedSpan{span, t.reason})
+		}
+	}
+}
+
+// Passes an ended span to the export processors
+func (f *ForceSampler) export(s sdktrace.ReadOnlySpan) {
+	for _, p := range f.processors {
+		p.OnEnd(s)
+	}
+}
+
+func (f *ForceSampler) Shutdown(ctx context.Context) error {
+	var err error
+	for _, p := range f.processors {
+		if pErr := p.Shutdown(ctx); err == nil {
+			err = pErr
+		}
+	}
+	return err
+}
+
+func (f *ForceSampler) ForceFlush(ctx context.Context) error {
+	var err error
+	for _, p := range f.processors {
+		if pErr := p.ForceFlush(ctx); err == nil {
+			err = pErr
+		}
+	}
+	return err
+}
+
+// forcedSpan is a span left out by the sampler exported as sampled, with the reason in the
+// sampling.forced attribute
+type forcedSpan struct {
+	sdktrace.ReadOnlySpan
+	reason string
+}
+
+func (s forcedSpan) SpanContext() trace.SpanContext {
+	sc := s.ReadOnlySpan.SpanContext()
+	return sc.WithTraceFlags(sc.TraceFlags().WithSampled(true))
+}
+
+func (s forcedSpan) Attributes() []attribute.KeyValue {
+	return append(s.ReadOnlySpan.Attributes(), attribute.String("sampling.forced", s.reason))
+}
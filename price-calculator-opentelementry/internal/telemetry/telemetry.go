@@ -0,0 +1,162 @@
+// Package telemetry sets up the OpenTelemetry traces, metrics and logs of a service:
+// OTLP exporters configured like the SDK's environment variables, a sampler and span
+// export pipeline that can be inspected and changed at runtime, the propagators, and
+// slog logging carrying the trace context of every record.
+package telemetry
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"go.opentelemetry.io/contrib/bridges/otelslog"
+	"go.opentelemetry.io/contrib/instrumentation/host"
+	"go.opentelemetry.io/contrib/instrumentation/runtime"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/log/global"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+
+	"otpl/pricecalculator/internal/config"
+)
+
+// Options structure for the telemetry of a service
+type Options struct {
+	ServiceName    string
+	OTLP           OTLPConfig
+	Sampling       SamplingConfig
+	TraceExporters []config.TraceExporter   // Span exporters, the OTLP exporter when empty
+	SpanProcessors []sdktrace.SpanProcessor // Run before the exporters, such as one copying baggage onto spans
+	MetricReader   sdkmetric.Reader         // Reader of the measurements, pushing them to the collector when nil
+	MetricReaders  []sdkmetric.Reader       // Further readers, such as one evaluating the measurements in process
+}
+
+// Telemetry structure for the providers created by Setup
+type Telemetry struct {
+	TracerProvider *sdktrace.TracerProvider
+	MeterProvider  *sdkmetric.MeterProvider
+	LoggerProvider *sdklog.LoggerProvider
+	Sampler        *DynamicSampler // Sampler of the tracer provider, reconfigurable at runtime
+	Pipeline       *SpanPipeline   // Export pipeline of the first trace exporter
+	ForceSampler   *ForceSampler   // Exports the failed and slow traces the sampler left out
+
+	traceExporters []config.TraceExporter // Configured span exporters, whose settings are reused when switching exporters
+	otlp           OTLPConfig
+}
+
+// Setup creates the tracer, meter and logger providers sharing the service's resource,
+// sets them and the propagators of OTEL_PROPAGATORS as the globals, starts the runtime and
+// host metrics, and bridges slog into the logger provider. Call InitLogging first so the
+// records also go to stdout.
+func Setup(ctx context.Context, opts Options) (*Telemetry, error) {
+	t := &Telemetry{traceExporters: opts.TraceExporters, otlp: opts.OTLP}
+	var err error
+	if t.Sampler, err = NewDynamicSampler(opts.Sampling); err != nil {
+		return nil, fmt.Errorf("invalid sampling configuration: %v", err)
+	}
+
+	// Create the span exporters, batching the spans for the first one in the monitored pipeline
+	spanProcessors, pipeline, err := newSpanProcessors(ctx, opts.TraceExporters, opts.OTLP)
+	if err != nil {
+		return nil, err
+	}
+	t.Pipeline = pipeline
+
+	// Define the resource attributes (e.g., service name, version and environment)
+	res, err := NewResource(ctx, opts.ServiceName)
+	if err != nil {
+		return nil, err
+	}
+
+	// Create the trace provider
+	options := []sdktrace.TracerProviderOption{
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(t.Sampler),
+	}
+	t.ForceSampler = NewForceSampler(t.Sampler, spanProcessors...)
+	for _, processor := range append(opts.SpanProcessors, t.ForceSampler) {
+		options = append(options, sdktrace.WithSpanProcessor(processor))
+	}
+	t.TracerProvider = sdktrace.NewTracerProvider(options...)
+
+	// Set the global tracer provider, and the propagators for the trace context and baggage
+	// on incoming and outgoing requests
+	otel.SetTracerProvider(t.TracerProvider)
+	propagator, err := NewPropagator()
+	if err != nil {
+		return nil, fmt.Errorf("failed to configure propagators: %v", err)
+	}
+	otel.SetTextMapPropagator(propagator)
+
+	// Create the meter provider, sharing the resource with the trace provider,
+	// with exemplars linking the measurements to their traces
+	metricReader := opts.MetricReader
+	if metricReader == nil {
+		metricExporter, err := NewMetricExporter(ctx, opts.OTLP)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create metric exporter: %v", err)
+		}
+		metricReader = sdkmetric.NewPeriodicReader(metricExporter)
+	}
+	enableExemplars()
+	meterOptions := []sdkmetric.Option{
+		sdkmetric.WithReader(metricReader),
+		sdkmetric.WithResource(res),
+	}
+	for _, reader := range opts.MetricReaders {
+		meterOptions = append(meterOptions, sdkmetric.WithReader(reader))
+	}
+	t.MeterProvider = sdkmetric.NewMeterProvider(meterOptions...)
+	otel.SetMeterProvider(t.MeterProvider)
+
+	// Report the Go runtime (goroutines, GC, memory) and host (CPU, memory, network) metrics
+	// through the meter provider, so they share the service's resource
+	if err := runtime.Start(runtime.WithMeterProvider(t.MeterProvider)); err != nil {
+		return nil, fmt.Errorf("failed to start runtime metrics: %v", err)
+	}
+	if err := host.Start(host.WithMeterProvider(t.MeterProvider)); err != nil {
+		return nil, fmt.Errorf("failed to start host metrics: %v", err)
+	}
+
+	// Create the OTLP log exporter
+	logExporter, err := NewLogExporter(ctx, opts.OTLP)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OTLP log exporter: %v", err)
+	}
+
+	// Create the logger provider, sharing the resource with the other providers,
+	// and bridge the application logs into it
+	t.LoggerProvider = sdklog.NewLoggerProvider(
+		sdklog.WithProcessor(sdklog.NewBatchProcessor(logExporter)), // OTLP exporter
+		sdklog.WithResource(res),
+	)
+	global.SetLoggerProvider(t.LoggerProvider)
+	ExportLogs(otelslog.NewHandler(opts.ServiceName, otelslog.WithLoggerProvider(t.LoggerProvider)))
+	return t, nil
+}
+
+// Shutdown flushes and stops the tracer, meter and logger providers
+func (t *Telemetry) Shutdown(ctx context.Context) {
+	if err := t.TracerProvider.Shutdown(ctx); err != nil {
+		slog.ErrorContext(ctx, "Error shutting down tracer provider", "error", err)
+	}
+	if err := t.MeterProvider.Shutdown(ctx); err != nil {
+		slog.ErrorContext(ctx, "Error shutting down meter provider", "error", err)
+	}
+	if err := t.LoggerProvider.Shutdown(ctx); err != nil {
+		slog.ErrorContext(ctx, "Error shutting down logger provider", "error", err)
+	}
+}
+
+// Turns on the experimental exemplar support of the metric SDK unless OTEL_GO_X_EXEMPLAR
+// is set, so histogram buckets carry the trace and span ID of a request measured in them.
+// OTEL_METRICS_EXEMPLAR_FILTER selects the measurements offered as exemplars:
+// trace_based (sampled requests, the default), always_on or always_off.
+// Must be called before the meter provider is created.
+func enableExemplars() {
+	if _, ok := os.LookupEnv("OTEL_GO_X_EXEMPLAR"); !ok {
+		os.Setenv("OTEL_GO_X_EXEMPLAR", "true")
+	}
+}
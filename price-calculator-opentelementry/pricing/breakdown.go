@@ -0,0 +1,62 @@
+package pricing
+
+// Kinds of the breakdown lines
+const (
+	LineTier      = "tier"
+	LineDiscount  = "discount"
+	LineSurcharge = "surcharge"
+	LineTax       = "tax"
+	LineGuardrail = "guardrail"
+	LineProfit    = "profit"
+)
+
+// BreakdownLine structure for a single amount between the base amount and the total
+type BreakdownLine struct {
+	Kind   string `json:"kind"` // tier, discount, surcharge, tax, guardrail or profit
+	Name   string `json:"name"`
+	Amount Money  `json:"amount"` // Negative for reductions
+}
+
+// Breakdown structure for the audit trail of a calculated price. The base amount, the lines
+// and the rounding adjustment add up to the total.
+type Breakdown struct {
+	BaseAmount         Money           `json:"base_amount"` // Unit price times quantity, before the tier
+	Lines              []BreakdownLine `json:"lines"`       // In the order they were applied
+	RoundingAdjustment Money           `json:"rounding_adjustment"`
+	Total              Money           `json:"total"`
+}
+
+// Builds the breakdown of a finished calculation. The rounding adjustment is the difference
+// between the total and the sum of the rounded amounts, which covers the rounding of the lines
+// as well as the rounding of the total.
+func newBreakdown(calc *Calculation) Breakdown {
+	breakdown := Breakdown{BaseAmount: calc.Currency.Round(calc.BaseAmount, calc.Mode), Lines: []BreakdownLine{}, Total: calc.Total}
+	add := func(kind, name string, amount Money) {
+		breakdown.Lines = append(breakdown.Lines, BreakdownLine{Kind: kind, Name: name, Amount: amount})
+	}
+	if calc.Tier != nil {
+		tiered := calc.Currency.Round(calc.BasePrice.MulInt(calc.Quantity), calc.Mode)
+		add(LineTier, "volume tier", tiered.Sub(breakdown.BaseAmount))
+	}
+	for _, discount := range calc.Discounts {
+		add(LineDiscount, discount.Name, Money{}.Sub(discount.Amount))
+	}
+	for _, surcharge := range calc.Surcharges {
+		add(LineSurcharge, surcharge.Name, calc.Currency.Round(surcharge.Amount, calc.Mode))
+	}
+	for _, tax := range calc.AppliedTaxes {
+		add(LineTax, tax.Name, tax.Amount)
+	}
+
+	sum := breakdown.BaseAmount
+	for _, line := range breakdown.Lines {
+		sum = sum.Add(line.Amount)
+	}
+	// A clamped total moves the rounded total to the limit, leaving the rounding adjustment as it was
+	if calc.Guardrail != nil {
+		add(LineGuardrail, calc.Guardrail.Guardrail, calc.GuardrailAdjustment)
+		sum = sum.Add(calc.GuardrailAdjustment)
+	}
+	breakdown.RoundingAdjustment = calc.Total.Sub(sum)
+	return breakdown
+}
@@ -0,0 +1,293 @@
+package pricing
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/sync/errgroup"
+)
+
+// Source of an exchange rate given in the cart request
+const RateSourceProvided = "provided"
+
+// Carts with fewer lines are calculated sequentially unless CartOptions sets another threshold
+const DefaultParallelMinLines = 100
+
+// CartItem structure for a single line item in a cart
+// Discount and TaxRate are percentages, an omitted TaxRate falls back to the stored default
+type CartItem struct {
+	Name      string   `json:"name"`
+	UnitPrice Money    `json:"unit_price"`
+	Quantity  int      `json:"quantity"`
+	TaxRate   *float64 `json:"tax_rate,omitempty"`
+	Discount  float64  `json:"discount"`
+	Currency  string   `json:"currency,omitempty"` // ISO 4217 code of the unit price, defaults to the cart currency
+}
+
+// CartRequest structure for cart input data
+type CartRequest struct {
+	Items    []CartItem         `json:"items"`
+	Currency string             `json:"currency,omitempty"` // ISO 4217 settlement code, defaults to USD
+	Rates    map[string]float64 `json:"rates,omitempty"`    // Cart currency units per unit of a line currency, by code
+}
+
+// CartLine structure for the calculated totals of a single line item, in the cart currency
+type CartLine struct {
+	Name         string       `json:"name"`
+	Quantity     int          `json:"quantity"`
+	Subtotal     Money        `json:"subtotal"`
+	Discount     Money        `json:"discount"`
+	Tax          Money        `json:"tax"`
+	Total        Money        `json:"total"`
+	Original     *LineAmounts `json:"original,omitempty"`      // Totals in the line currency, for lines in another currency
+	ExchangeRate float64      `json:"exchange_rate,omitempty"` // Rate the original totals were converted at
+}
+
+// LineAmounts structure for the totals of a cart line in its own currency
+type LineAmounts struct {
+	Currency string `json:"currency"`
+	Subtotal Money  `json:"subtotal"`
+	Discount Money  `json:"discount"`
+	Tax      Money  `json:"tax"`
+	Total    Money  `json:"total"`
+}
+
+// ExchangeRate structure for a rate used to convert cart lines to the cart currency
+type ExchangeRate struct {
+	From   string  `json:"from"`
+	To     string  `json:"to"`
+	Rate   float64 `json:"rate"`
+	Source string  `json:"source"` // provided, or where the RateFunc found it
+}
+
+// CartResponse structure for cart output data
+type CartResponse struct {
+	Lines         []CartLine     `json:"lines"`
+	Subtotal      Money          `json:"subtotal"`
+	Discount      Money          `json:"discount"`
+	Tax           Money          `json:"tax"`
+	GrandTotal    Money          `json:"grand_total"`
+	Currency      string         `json:"currency"`
+	ExchangeRates []ExchangeRate `json:"exchange_rates,omitempty"` // Rates used, one per line currency
+}
+
+// RateFunc looks up the rate from one currency to another and where it was found
+type RateFunc func(ctx context.Context, from, to string) (float64, string, error)
+
+// CartOptions structure for the optional settings of a cart calculation
+type CartOptions struct {
+	Rates            RateFunc // Finds the rates not given in the request, nil to require them all
+	Parallelism      int      // Lines calculated concurrently in large carts, 1 or less is sequential
+	ParallelMinLines int      // Lines from which a cart is calculated concurrently, defaults to DefaultParallelMinLines
+}
+
+// exchangeRates remembers the rates used by a cart, safe for concurrent use
+type exchangeRates struct {
+	mu   sync.Mutex
+	used map[string]*ExchangeRate // By line currency
+}
+
+// Validates a cart line item
+func (item CartItem) validate() error {
+	if item.UnitPrice.IsNegative() {
+		return invalid("invalid unit price for %q", item.Name)
+	}
+	if item.Quantity <= 0 {
+		return invalid("invalid quantity for %q", item.Name)
+	}
+	if item.TaxRate != nil && ValidateTaxRate(*item.TaxRate) != nil {
+		return invalid("invalid tax rate for %q", item.Name)
+	}
+	if item.Discount < 0 || item.Discount > 100 {
+		return invalid("invalid discount for %q", item.Name)
+	}
+	return nil
+}
+
+// Returns the rate from a line currency to the cart currency: the rate in the request, then the
+// one found by rates. The rate is remembered in used, without holding its lock during the lookup,
+// so lines of the same currency priced at once may look it up twice and all use the first one found.
+func cartRate(ctx context.Context, request CartRequest, rates RateFunc, from, to string, used *exchangeRates) (*ExchangeRate, error) {
+	used.mu.Lock()
+	rate, ok := used.used[from]
+	used.mu.Unlock()
+	if ok {
+		return rate, nil
+	}
+	rate = &ExchangeRate{From: from, To: to, Source: RateSourceProvided}
+	if provided, ok := request.Rates[from]; ok {
+		rate.Rate = provided
+	} else if rates != nil {
+		var err error
+		if rate.Rate, rate.Source, err = rates(ctx, from, to); err != nil {
+			return nil, invalid("%v", err)
+		}
+	} else {
+		return nil, invalid("no exchange rate from %s to %s", from, to)
+	}
+
+	used.mu.Lock()
+	defer used.mu.Unlock()
+	if found, ok := used.used[from]; ok {
+		return found, nil
+	}
+	used.used[from] = rate
+	return rate, nil
+}
+
+// CalculateCart computes per-line totals and the cart totals, rounding every amount to the
+// cart currency. A line in another currency is calculated in its currency and its totals
+// converted at the rate in the request or found by opts.Rates.
+// Each line is calculated in its own child span of the span in ctx, concurrently in carts of
+// at least opts.ParallelMinLines lines when opts.Parallelism is above 1.
+func CalculateCart(ctx context.Context, request CartRequest, defaults Config, mode RoundingMode, opts CartOptions) (CartResponse, error) {
+	span := trace.SpanFromContext(ctx)
+
+	// Validate the cart
+	if len(request.Items) == 0 {
+		return CartResponse{}, invalid("cart has no items")
+	}
+	for _, item := range request.Items {
+		if err := item.validate(); err != nil {
+			return CartResponse{}, err
+		}
+	}
+	currency, err := LookupCurrency(request.Currency)
+	if err != nil {
+		return CartResponse{}, err
+	}
+	for code, rate := range request.Rates {
+		if _, err := LookupCurrency(code); err != nil {
+			return CartResponse{}, err
+		}
+		if rate <= 0 {
+			return CartResponse{}, invalid("exchange rate of %s must be positive", code)
+		}
+	}
+	span.SetAttributes(
+		attribute.Int("cart.item_count", len(request.Items)),
+		attribute.String("currency", currency.Code),
+	)
+
+	// Calculate each line in its own child span, concurrently in large carts.
+	// Every line is written to its own slot, so the lines keep the order of the items.
+	parallelism := 1
+	minLines := opts.ParallelMinLines
+	if minLines <= 0 {
+		minLines = DefaultParallelMinLines
+	}
+	if opts.Parallelism > 1 && len(request.Items) >= minLines {
+		parallelism = opts.Parallelism
+	}
+	lines := make([]CartLine, len(request.Items))
+	used := &exchangeRates{used: map[string]*ExchangeRate{}}
+	var lineDuration time.Duration // Sum of the line durations, the time a sequential calculation would take
+	var lineDurationMu sync.Mutex
+	group, groupCtx := errgroup.WithContext(ctx)
+	group.SetLimit(parallelism)
+	start := time.Now()
+	for i, item := range request.Items {
+		group.Go(func() error {
+			lineStart := time.Now()
+			lineCtx, lineSpan := tracerFrom(ctx).Start(groupCtx, "CalculateCartLine", trace.WithAttributes(
+				attribute.String("cart.item.name", item.Name),
+				attribute.Int("cart.item.quantity", item.Quantity),
+			))
+			defer lineSpan.End()
+			line, err := calculateCartLine(lineCtx, request, item, currency, defaults, mode, opts.Rates, used)
+			if err != nil {
+				lineSpan.RecordError(err)
+				return err
+			}
+			lineSpan.SetAttributes(attribute.Float64("cart.item.total", line.Total.Float64()))
+			lines[i] = line
+			lineDurationMu.Lock()
+			lineDuration += time.Since(lineStart)
+			lineDurationMu.Unlock()
+			return nil
+		})
+	}
+	if err := group.Wait(); err != nil {
+		return CartResponse{}, err
+	}
+	duration := time.Since(start)
+	span.SetAttributes(
+		attribute.Int("cart.line_count", len(lines)),
+		attribute.Int("cart.parallelism", parallelism),
+		attribute.Float64("cart.lines.duration_ms", float64(duration.Microseconds())/1000),
+		attribute.Float64("cart.lines.sequential_duration_ms", float64(lineDuration.Microseconds())/1000),
+	)
+	if duration > 0 {
+		span.SetAttributes(attribute.Float64("cart.lines.speedup", float64(lineDuration)/float64(duration)))
+	}
+
+	// Add up the lines, listing the rates in the order their currencies first appear
+	response := CartResponse{Lines: lines, Currency: currency.Code}
+	for _, line := range lines {
+		if line.Original != nil && !slices.ContainsFunc(response.ExchangeRates, func(rate ExchangeRate) bool { return rate.From == line.Original.Currency }) {
+			response.ExchangeRates = append(response.ExchangeRates, *used.used[line.Original.Currency])
+		}
+		response.Subtotal = response.Subtotal.Add(line.Subtotal)
+		response.Discount = response.Discount.Add(line.Discount)
+		response.Tax = response.Tax.Add(line.Tax)
+		response.GrandTotal = response.GrandTotal.Add(line.Total)
+	}
+	response.GrandTotal = currency.RoundTotal(response.GrandTotal, mode)
+	span.SetAttributes(
+		attribute.Float64("cart.grand_total", response.GrandTotal.Float64()),
+		attribute.Int("cart.currency_count", len(used.used)+1),
+		attribute.String("rounding.mode", string(mode)),
+	)
+	return response, nil
+}
+
+// Calculates a cart line in its currency, converting its totals to the cart currency
+func calculateCartLine(ctx context.Context, request CartRequest, item CartItem, currency Currency, defaults Config, mode RoundingMode, rates RateFunc, used *exchangeRates) (CartLine, error) {
+	lineCurrency := currency
+	if item.Currency != "" {
+		var err error
+		if lineCurrency, err = LookupCurrency(item.Currency); err != nil {
+			return CartLine{}, err
+		}
+	}
+	itemTaxRate := defaults.TaxRate
+	if item.TaxRate != nil {
+		itemTaxRate = *item.TaxRate
+	}
+	subtotal := lineCurrency.Round(item.UnitPrice.MulInt(item.Quantity), mode)
+	discount := lineCurrency.Round(subtotal.Percent(item.Discount), mode)
+	tax := lineCurrency.Round(subtotal.Sub(discount).Percent(itemTaxRate), mode)
+	line := CartLine{
+		Name:     item.Name,
+		Quantity: item.Quantity,
+		Subtotal: subtotal,
+		Discount: discount,
+		Tax:      tax,
+		Total:    subtotal.Sub(discount).Add(tax),
+	}
+	if lineCurrency.Code == currency.Code {
+		return line, nil
+	}
+
+	// Convert each amount, the converted total adds up from the converted amounts
+	rate, err := cartRate(ctx, request, rates, lineCurrency.Code, currency.Code, used)
+	if err != nil {
+		return CartLine{}, err
+	}
+	line.Original = &LineAmounts{Currency: lineCurrency.Code, Subtotal: subtotal, Discount: discount, Tax: tax, Total: line.Total}
+	line.ExchangeRate = rate.Rate
+	line.Subtotal = currency.Round(subtotal.MulRate(rate.Rate), mode)
+	line.Discount = currency.Round(discount.MulRate(rate.Rate), mode)
+	line.Tax = currency.Round(tax.MulRate(rate.Rate), mode)
+	line.Total = line.Subtotal.Sub(line.Discount).Add(line.Tax)
+	trace.SpanFromContext(ctx).SetAttributes(
+		attribute.String("cart.item.currency", lineCurrency.Code),
+		attribute.Float64("cart.item.exchange_rate", rate.Rate),
+		attribute.String("cart.item.exchange_rate_source", rate.Source),
+	)
+	return line, nil
+}
@@ -0,0 +1,41 @@
+package pricing
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"slices"
+	"testing"
+)
+
+// Lines of the benchmarked carts
+var benchmarkCart = CartRequest{Items: []CartItem{
+	{Name: "Book", UnitPrice: MoneyFromFloat(12.5), Quantity: 2},
+	{Name: "Pen", UnitPrice: MoneyFromFloat(1.99), Quantity: 10, Discount: 5},
+	{Name: "Bag", UnitPrice: MoneyFromFloat(29), Quantity: 1},
+}}
+
+func BenchmarkCalculateCart(b *testing.B) {
+	ctx := context.Background()
+	b.ReportAllocs()
+	for range b.N {
+		_, _ = CalculateCart(ctx, benchmarkCart, Config{TaxRate: 8.5}, RoundHalfEven, CartOptions{})
+	}
+}
+
+// Compares a 500-line cart calculated sequentially and concurrently
+func BenchmarkCalculateLargeCart(b *testing.B) {
+	ctx := context.Background()
+	cart := CartRequest{Items: make([]CartItem, 500)}
+	for i := range cart.Items {
+		cart.Items[i] = benchmarkCart.Items[i%len(benchmarkCart.Items)]
+	}
+	for _, parallelism := range slices.Compact([]int{1, runtime.GOMAXPROCS(0)}) {
+		b.Run(fmt.Sprintf("parallelism=%d", parallelism), func(b *testing.B) {
+			b.ReportAllocs()
+			for range b.N {
+				_, _ = CalculateCart(ctx, cart, Config{TaxRate: 8.5}, RoundHalfEven, CartOptions{Parallelism: parallelism})
+			}
+		})
+	}
+}
@@ -0,0 +1,56 @@
+package pricing
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Coupon structure for a discount redeemed with a code
+type Coupon struct {
+	ID             string     `json:"id"`
+	Code           string     `json:"code"`
+	Type           string     `json:"type"` // percentage or fixed
+	Value          float64    `json:"value"`
+	ExpiresAt      *time.Time `json:"expires_at,omitempty"`
+	MaxRedemptions int        `json:"max_redemptions,omitempty"` // Unlimited when zero
+	Redemptions    int        `json:"redemptions"`
+}
+
+// NormalizeCouponCode returns the canonical form of a coupon code, codes are case-insensitive
+func NormalizeCouponCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// Validate checks that the coupon is complete
+func (c Coupon) Validate() error {
+	if NormalizeCouponCode(c.Code) == "" {
+		return fmt.Errorf("coupon code is required")
+	}
+	if c.Type != DiscountPercentage && c.Type != DiscountFixed {
+		return fmt.Errorf("coupon type must be %s or %s", DiscountPercentage, DiscountFixed)
+	}
+	if err := c.Discount().Validate(); err != nil {
+		return err
+	}
+	if c.MaxRedemptions < 0 {
+		return fmt.Errorf("max_redemptions must not be negative")
+	}
+	return nil
+}
+
+// Redeemable checks that the coupon has not expired or been used up
+func (c Coupon) Redeemable(now time.Time) error {
+	if c.ExpiresAt != nil && !now.Before(*c.ExpiresAt) {
+		return invalid("coupon %s has expired", c.Code)
+	}
+	if c.MaxRedemptions > 0 && c.Redemptions >= c.MaxRedemptions {
+		return invalid("coupon %s has been fully redeemed", c.Code)
+	}
+	return nil
+}
+
+// Discount returns the discount rule applied when the coupon is redeemed
+func (c Coupon) Discount() Discount {
+	return Discount{ID: "coupon-" + c.ID, Name: c.Code, Type: c.Type, Value: c.Value}
+}
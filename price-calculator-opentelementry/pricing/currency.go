@@ -0,0 +1,84 @@
+package pricing
+
+import "strings"
+
+// DefaultCurrency is used when a request does not specify one
+const DefaultCurrency = "USD"
+
+// Currency describes an ISO 4217 currency and how amounts in it are rounded
+type Currency struct {
+	Code       string // ISO 4217 alphabetic code
+	MinorUnits int    // Number of decimal places, e.g. 2 for USD, 0 for JPY, 3 for BHD
+}
+
+// Registry of supported ISO 4217 currencies
+var currencies = map[string]Currency{
+	"AED": {Code: "AED", MinorUnits: 2},
+	"AUD": {Code: "AUD", MinorUnits: 2},
+	"BHD": {Code: "BHD", MinorUnits: 3},
+	"BRL": {Code: "BRL", MinorUnits: 2},
+	"CAD": {Code: "CAD", MinorUnits: 2},
+	"CHF": {Code: "CHF", MinorUnits: 2},
+	"CLP": {Code: "CLP", MinorUnits: 0},
+	"CNY": {Code: "CNY", MinorUnits: 2},
+	"CZK": {Code: "CZK", MinorUnits: 2},
+	"DKK": {Code: "DKK", MinorUnits: 2},
+	"EUR": {Code: "EUR", MinorUnits: 2},
+	"GBP": {Code: "GBP", MinorUnits: 2},
+	"HKD": {Code: "HKD", MinorUnits: 2},
+	"HUF": {Code: "HUF", MinorUnits: 2},
+	"IDR": {Code: "IDR", MinorUnits: 2},
+	"ILS": {Code: "ILS", MinorUnits: 2},
+	"INR": {Code: "INR", MinorUnits: 2},
+	"ISK": {Code: "ISK", MinorUnits: 0},
+	"JOD": {Code: "JOD", MinorUnits: 3},
+	"JPY": {Code: "JPY", MinorUnits: 0},
+	"KRW": {Code: "KRW", MinorUnits: 0},
+	"KWD": {Code: "KWD", MinorUnits: 3},
+	"MXN": {Code: "MXN", MinorUnits: 2},
+	"NOK": {Code: "NOK", MinorUnits: 2},
+	"NZD": {Code: "NZD", MinorUnits: 2},
+	"OMR": {Code: "OMR", MinorUnits: 3},
+	"PLN": {Code: "PLN", MinorUnits: 2},
+	"SAR": {Code: "SAR", MinorUnits: 2},
+	"SEK": {Code: "SEK", MinorUnits: 2},
+	"SGD": {Code: "SGD", MinorUnits: 2},
+	"THB": {Code: "THB", MinorUnits: 2},
+	"TND": {Code: "TND", MinorUnits: 3},
+	"TRY": {Code: "TRY", MinorUnits: 2},
+	"USD": {Code: "USD", MinorUnits: 2},
+	"VND": {Code: "VND", MinorUnits: 0},
+	"ZAR": {Code: "ZAR", MinorUnits: 2},
+}
+
+// LookupCurrency finds a currency by its ISO 4217 code, an empty code returns the default currency
+func LookupCurrency(code string) (Currency, error) {
+	if code == "" {
+		code = DefaultCurrency
+	}
+	currency, ok := currencies[strings.ToUpper(code)]
+	if !ok {
+		return Currency{}, invalid("unknown currency %q", code)
+	}
+	return currency, nil
+}
+
+// Round rounds an amount to the minor units of the currency
+func (c Currency) Round(amount Money, mode RoundingMode) Money {
+	return amount.Round(c.MinorUnits, mode)
+}
+
+// DecimalString returns the amount as a decimal string with at least the minor units of the
+// currency, e.g. "12.50" in USD, keeping any further decimals of the amount
+func (c Currency) DecimalString(amount Money) string {
+	return amount.d.StringFixed(int32(max(c.MinorUnits, int(-amount.d.Exponent()))))
+}
+
+// RoundTotal rounds a final total to the currency. Cash rounding goes to the nearest 0.05,
+// halves up, in currencies with cents and to the minor units in the others.
+func (c Currency) RoundTotal(amount Money, mode RoundingMode) Money {
+	if mode == RoundCash && c.MinorUnits >= 2 {
+		return Money{d: amount.d.Div(cashIncrement).Round(0).Mul(cashIncrement)}
+	}
+	return c.Round(amount, mode)
+}
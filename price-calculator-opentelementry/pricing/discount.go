@@ -0,0 +1,147 @@
+package pricing
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Supported discount types
+const (
+	DiscountPercentage = "percentage"  // Value percent off the running amount
+	DiscountFixed      = "fixed"       // Value off the running amount
+	DiscountBuyXGetY   = "buy_x_get_y" // Every BuyQuantity units get GetQuantity units free
+	DiscountTiered     = "tiered"      // Percentage off from the highest tier reached by quantity
+)
+
+// DiscountTier structure for a quantity break in a tiered discount or a volume price break
+type DiscountTier struct {
+	MinQuantity int     `json:"min_quantity"`
+	Percentage  float64 `json:"percentage"`
+}
+
+// Discount structure for a discount rule applied during a calculation
+type Discount struct {
+	ID          string         `json:"id"`
+	Name        string         `json:"name"`
+	Type        string         `json:"type"`
+	Value       float64        `json:"value,omitempty"`
+	BuyQuantity int            `json:"buy_quantity,omitempty"`
+	GetQuantity int            `json:"get_quantity,omitempty"`
+	Tiers       []DiscountTier `json:"tiers,omitempty"`
+	Priority    int            `json:"priority,omitempty"` // Higher first under the priority stacking policy
+}
+
+// AppliedDiscount structure for a discount rule that reduced the price
+type AppliedDiscount struct {
+	ID     string `json:"id"`
+	Name   string `json:"name"`
+	Amount Money  `json:"amount"`
+}
+
+// Validate checks that the rule is complete for its type
+func (d Discount) Validate() error {
+	switch d.Type {
+	case DiscountPercentage:
+		if d.Value < 0 || d.Value > 100 {
+			return fmt.Errorf("percentage must be between 0 and 100")
+		}
+	case DiscountFixed:
+		if d.Value < 0 {
+			return fmt.Errorf("fixed amount must not be negative")
+		}
+	case DiscountBuyXGetY:
+		if d.BuyQuantity <= 0 || d.GetQuantity <= 0 {
+			return fmt.Errorf("buy_quantity and get_quantity must be positive")
+		}
+	case DiscountTiered:
+		if len(d.Tiers) == 0 {
+			return fmt.Errorf("tiered discount needs at least one tier")
+		}
+		return ValidateTiers(d.Tiers)
+	default:
+		return fmt.Errorf("unknown discount type %q", d.Type)
+	}
+	return nil
+}
+
+// Calculates the amount the rule takes off the running amount for the given quantity
+func (d Discount) amount(running, unitPrice Money, quantity int) Money {
+	var amount Money
+	switch d.Type {
+	case DiscountPercentage:
+		amount = running.Percent(d.Value)
+	case DiscountFixed:
+		amount = MoneyFromFloat(d.Value)
+	case DiscountBuyXGetY:
+		free := quantity / (d.BuyQuantity + d.GetQuantity) * d.GetQuantity
+		amount = unitPrice.MulInt(free)
+	case DiscountTiered:
+		tier, _ := resolveTier(d.Tiers, quantity)
+		amount = running.Percent(tier.Percentage)
+	}
+	return amount.Min(running) // Never discount below zero
+}
+
+// Applies the discount rules allowed by the stacking policy and records the decision on each
+// rule, applied or skipped, as an event on the active span
+func applyDiscounts(ctx context.Context, rules []Discount, policy StackingPolicy, unitPrice Money, quantity int) (Money, []AppliedDiscount, []SkippedDiscount) {
+	span := trace.SpanFromContext(ctx)
+
+	total := unitPrice.MulInt(quantity)
+	running := total
+	applied := []AppliedDiscount{}
+	var skipped []SkippedDiscount
+	skip := func(rule Discount, reason string) {
+		skipped = append(skipped, SkippedDiscount{ID: rule.ID, Name: rule.Name, Reason: reason})
+		explainRule(ctx, ExplainRule{Kind: ExplainDiscount, ID: rule.ID, Name: rule.Name, Detail: "skipped: " + reason})
+		span.AddEvent("discount.skipped", trace.WithAttributes(
+			attribute.String("discount.id", rule.ID),
+			attribute.String("discount.type", rule.Type),
+			attribute.String("discount.skip_reason", reason),
+		))
+	}
+
+	rules = policy.order(rules)
+	best := -1
+	if policy.Policy == StackBestSingle {
+		best = bestDiscount(rules, total, unitPrice, quantity)
+	}
+	for i, rule := range rules {
+		amount := rule.amount(running, unitPrice, quantity)
+		switch {
+		case !amount.IsPositive():
+			skip(rule, SkipNoEffect)
+			continue
+		case policy.Policy == StackBestSingle && i != best:
+			skip(rule, SkipNotBest)
+			continue
+		case policy.Limit > 0 && policy.name() != StackAll && len(applied) == policy.Limit:
+			skip(rule, SkipLimitReached)
+			continue
+		}
+		running = running.Sub(amount)
+		applied = append(applied, AppliedDiscount{ID: rule.ID, Name: rule.Name, Amount: amount})
+		explainRule(ctx, ExplainRule{Kind: ExplainDiscount, ID: rule.ID, Name: rule.Name, Matched: true, Amount: explainAmount(amount),
+			Detail: fmt.Sprintf("%s discount, %v remaining", rule.Type, running)})
+		span.AddEvent("discount.applied", trace.WithAttributes(
+			attribute.String("discount.id", rule.ID),
+			attribute.String("discount.type", rule.Type),
+			attribute.Float64("discount.amount", amount.Float64()),
+		))
+	}
+
+	ids := make([]string, len(applied))
+	for i, a := range applied {
+		ids[i] = a.ID
+	}
+	span.SetAttributes(
+		attribute.String("discount.stacking_policy", policy.name()),
+		attribute.StringSlice("discount.applied_ids", ids),
+		attribute.Int("discount.skipped_count", len(skipped)),
+		attribute.Float64("discount.total", total.Sub(running).Float64()),
+	)
+	return total.Sub(running), applied, skipped
+}
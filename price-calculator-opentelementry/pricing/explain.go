@@ -0,0 +1,136 @@
+package pricing
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Kinds of the rules evaluated by a calculation
+const (
+	ExplainTier      = "tier"
+	ExplainDiscount  = "discount"
+	ExplainTaxRule   = "tax_rule"
+	ExplainTax       = "tax"
+	ExplainSurcharge = "surcharge"
+	ExplainRounding  = "rounding"
+	ExplainGuardrail = "guardrail"
+)
+
+// Explanation structure for the step by step account of a calculation, following its spans:
+// the inputs, then every pipeline step with the rules it evaluated and the amounts before and after it
+type Explanation struct {
+	Inputs ExplainInputs `json:"inputs"`
+	Steps  []ExplainStep `json:"steps"`
+
+	mu sync.Mutex
+}
+
+// ExplainInputs structure for what a calculation started from
+type ExplainInputs struct {
+	Request  PriceRequest `json:"request"`
+	Defaults Config       `json:"defaults"`
+	Rounding RoundingMode `json:"rounding,omitempty"` // Unless the request names one
+}
+
+// ExplainStep structure for a pipeline step of an explained calculation
+type ExplainStep struct {
+	Step       string        `json:"step"`
+	DurationMS float64       `json:"duration_ms"`
+	Before     ExplainValues `json:"before"`
+	After      ExplainValues `json:"after"` // Unset when the step failed
+	Rules      []ExplainRule `json:"rules,omitempty"`
+	Error      string        `json:"error,omitempty"`
+}
+
+// ExplainValues structure for the intermediate values of a calculation between two steps
+type ExplainValues struct {
+	BasePrice Money   `json:"base_price"`
+	Quantity  int     `json:"quantity"`
+	TaxRate   float64 `json:"tax_rate"`
+	Currency  string  `json:"currency,omitempty"`
+	Subtotal  Money   `json:"subtotal"`
+	Discount  Money   `json:"discount"`
+	Surcharge Money   `json:"surcharge"`
+	Tax       Money   `json:"tax"`
+	Total     Money   `json:"total"`
+}
+
+// ExplainRule structure for a rule evaluated by a step, whether it matched or not
+type ExplainRule struct {
+	Kind    string `json:"kind"` // tier, discount, tax_rule, tax, surcharge, rounding or guardrail
+	ID      string `json:"id,omitempty"`
+	Name    string `json:"name,omitempty"`
+	Matched bool   `json:"matched"`
+	Amount  *Money `json:"amount,omitempty"` // Added to or taken from the price
+	Detail  string `json:"detail,omitempty"` // Why the rule matched or not
+}
+
+// Context key of the explanation of the calculation in progress
+type explainKey struct{}
+
+// WithExplanation returns a context whose calculation is explained step by step in the returned explanation
+func WithExplanation(ctx context.Context) (context.Context, *Explanation) {
+	explanation := &Explanation{Steps: []ExplainStep{}}
+	return context.WithValue(ctx, explainKey{}, explanation), explanation
+}
+
+// ExplanationFrom returns the explanation requested for the calculation in ctx, nil if none was
+func ExplanationFrom(ctx context.Context) *Explanation {
+	explanation, _ := ctx.Value(explainKey{}).(*Explanation)
+	return explanation
+}
+
+// Returns the intermediate values of a calculation
+func explainValues(calc *Calculation) ExplainValues {
+	return ExplainValues{
+		BasePrice: calc.BasePrice,
+		Quantity:  calc.Quantity,
+		TaxRate:   calc.TaxRate,
+		Currency:  calc.Currency.Code,
+		Subtotal:  calc.Subtotal,
+		Discount:  calc.Discount,
+		Surcharge: calc.Surcharge,
+		Tax:       calc.Tax,
+		Total:     calc.Total,
+	}
+}
+
+// Records the start of a step, returning the function recording its end
+func (e *Explanation) startStep(name string, calc *Calculation) func(error) {
+	e.mu.Lock()
+	e.Steps = append(e.Steps, ExplainStep{Step: name, Before: explainValues(calc)})
+	i := len(e.Steps) - 1
+	e.mu.Unlock()
+	start := time.Now()
+	return func(err error) {
+		e.mu.Lock()
+		defer e.mu.Unlock()
+		step := &e.Steps[i]
+		step.DurationMS = float64(time.Since(start).Microseconds()) / 1000
+		if err != nil {
+			step.Error = err.Error()
+			return
+		}
+		step.After = explainValues(calc)
+	}
+}
+
+// Records a rule evaluated by the current step of the calculation in ctx, if it is explained
+func explainRule(ctx context.Context, rule ExplainRule) {
+	e := ExplanationFrom(ctx)
+	if e == nil {
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if len(e.Steps) > 0 {
+		step := &e.Steps[len(e.Steps)-1]
+		step.Rules = append(step.Rules, rule)
+	}
+}
+
+// Returns a pointer to an amount, for the optional amount of an explained rule
+func explainAmount(amount Money) *Money {
+	return &amount
+}
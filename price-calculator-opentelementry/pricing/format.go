@@ -0,0 +1,66 @@
+package pricing
+
+import (
+	"unicode"
+
+	"golang.org/x/text/currency"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+	"golang.org/x/text/number"
+)
+
+// Formatter formats amounts for display in a locale, e.g. 1234.56 EUR as "€1.234,56" in de-DE
+type Formatter struct {
+	Locale  string // Canonical BCP 47 tag of the locale
+	printer *message.Printer
+}
+
+// FormattedPrices structure for the amounts of a price response formatted in a locale
+type FormattedPrices struct {
+	Locale     string `json:"locale"`
+	TotalPrice string `json:"total_price"`
+	Discount   string `json:"discount"`
+	Surcharge  string `json:"surcharge"`
+	Tax        string `json:"tax"`
+}
+
+// NewFormatter creates a formatter for a BCP 47 locale such as "de-DE"
+func NewFormatter(locale string) (*Formatter, error) {
+	tag, err := language.Parse(locale)
+	if err != nil {
+		return nil, invalid("invalid locale %q", locale)
+	}
+	return &Formatter{Locale: tag.String(), printer: message.NewPrinter(tag)}, nil
+}
+
+// Format formats an amount with the locale's symbol for the currency and its separators,
+// to the minor units of the currency. Alphabetic symbols such as "CHF" are followed by a space.
+func (f *Formatter) Format(amount Money, c Currency) string {
+	symbol := c.Code
+	if unit, err := currency.ParseISO(c.Code); err == nil {
+		symbol = f.printer.Sprint(currency.Symbol(unit))
+	}
+	if last := []rune(symbol); unicode.IsLetter(last[len(last)-1]) {
+		symbol += " "
+	}
+	sign := ""
+	if amount.IsNegative() {
+		sign, amount = "-", Money{}.Sub(amount)
+	}
+	return sign + symbol + f.printer.Sprint(number.Decimal(amount.Float64(), number.Scale(c.MinorUnits)))
+}
+
+// FormatPrices formats the amounts of a price response in the formatter's locale
+func (f *Formatter) FormatPrices(response PriceResponse) (*FormattedPrices, error) {
+	c, err := LookupCurrency(response.Currency)
+	if err != nil {
+		return nil, err
+	}
+	return &FormattedPrices{
+		Locale:     f.Locale,
+		TotalPrice: f.Format(response.TotalPrice, c),
+		Discount:   f.Format(response.Discount, c),
+		Surcharge:  f.Format(response.Surcharge, c),
+		Tax:        f.Format(response.Tax, c),
+	}, nil
+}
@@ -0,0 +1,151 @@
+package pricing
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Actions taken when a total is outside the guardrails
+const (
+	GuardrailReject = "reject" // Fail the calculation, the default
+	GuardrailClamp  = "clamp"  // Raise or lower the total to the limit
+)
+
+// Names of the guardrails
+const (
+	GuardrailMinTotal  = "min_total"
+	GuardrailMaxTotal  = "max_total"
+	GuardrailMinMargin = "min_margin"
+	GuardrailMaxMargin = "max_margin"
+)
+
+// Guardrails structure for the limits a calculated price must stay within.
+// Margins are only checked for requests with a unit cost and are never clamped.
+type Guardrails struct {
+	MinTotal  *Money
+	MaxTotal  *Money
+	MinMargin *float64 // Percentage of the pre-tax price left over the cost
+	MaxMargin *float64
+	Action    string // Taken for totals outside the limits, reject or clamp
+}
+
+// Validate checks that the limits are not negative, the minimums are below the maximums
+// and the action is supported
+func (g Guardrails) Validate() error {
+	if (g.MinTotal != nil && g.MinTotal.IsNegative()) || (g.MaxTotal != nil && g.MaxTotal.IsNegative()) {
+		return fmt.Errorf("guardrail totals must not be negative")
+	}
+	if g.MinTotal != nil && g.MaxTotal != nil && g.MaxTotal.Sub(*g.MinTotal).IsNegative() {
+		return fmt.Errorf("guardrail min_total must not exceed max_total")
+	}
+	if g.MinMargin != nil && g.MaxMargin != nil && *g.MinMargin > *g.MaxMargin {
+		return fmt.Errorf("guardrail min_margin must not exceed max_margin")
+	}
+	switch g.Action {
+	case "", GuardrailReject, GuardrailClamp:
+	default:
+		return fmt.Errorf("unsupported guardrail action %q", g.Action)
+	}
+	return nil
+}
+
+// GuardrailViolation structure for a limit a calculated price crossed
+type GuardrailViolation struct {
+	Guardrail string  `json:"guardrail"` // min_total, max_total, min_margin or max_margin
+	Limit     float64 `json:"limit"`
+	Value     float64 `json:"value"` // Total or margin percentage of the calculation
+}
+
+// GuardrailError reports a calculated price outside the guardrails
+type GuardrailError struct {
+	Violations []GuardrailViolation
+}
+
+func (e *GuardrailError) Error() string {
+	parts := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		bound, quantity, _ := strings.Cut(v.Guardrail, "_")
+		direction := "below"
+		if bound == "max" {
+			direction = "above"
+		}
+		parts[i] = fmt.Sprintf("%s %g %s %s %g", quantity, v.Value, direction, v.Guardrail, v.Limit)
+	}
+	return "price outside guardrails: " + strings.Join(parts, ", ")
+}
+
+// GuardrailStep checks the rounded total and the margin against the guardrails of the rules.
+// Totals outside the limits are clamped or rejected per the guardrails' action, and every
+// violation is recorded as a guardrail.violation event on the step span.
+type GuardrailStep struct{}
+
+func (GuardrailStep) Name() string { return StepGuardrails }
+
+func (GuardrailStep) Apply(ctx context.Context, calc *Calculation) error {
+	g := calc.Rules.Guardrails
+	if g == nil {
+		return nil
+	}
+	span := trace.SpanFromContext(ctx)
+	var violations []GuardrailViolation
+	violate := func(v GuardrailViolation, action string) {
+		explainRule(ctx, ExplainRule{Kind: ExplainGuardrail, Name: v.Guardrail, Matched: true,
+			Detail: fmt.Sprintf("%v violates limit %v, %s", v.Value, v.Limit, action)})
+		span.AddEvent("guardrail.violation", trace.WithAttributes(
+			attribute.String("guardrail.name", v.Guardrail),
+			attribute.Float64("guardrail.limit", v.Limit),
+			attribute.Float64("guardrail.value", v.Value),
+			attribute.String("guardrail.action", action),
+		))
+		if action == GuardrailReject {
+			violations = append(violations, v)
+		}
+	}
+
+	// Margins compare the pre-tax price to the cost of the units, a free unit loses its whole cost
+	if calc.Rules.UnitCost != nil && (g.MinMargin != nil || g.MaxMargin != nil) {
+		cost := calc.Rules.UnitCost.MulInt(calc.Quantity)
+		margin := -100.0
+		if calc.Subtotal.IsPositive() {
+			margin = calc.Subtotal.Sub(cost).Float64() / calc.Subtotal.Float64() * 100
+		}
+		span.SetAttributes(attribute.Float64("pricing.margin", margin))
+		if g.MinMargin != nil && margin < *g.MinMargin {
+			violate(GuardrailViolation{Guardrail: GuardrailMinMargin, Limit: *g.MinMargin, Value: margin}, GuardrailReject)
+		}
+		if g.MaxMargin != nil && margin > *g.MaxMargin {
+			violate(GuardrailViolation{Guardrail: GuardrailMaxMargin, Limit: *g.MaxMargin, Value: margin}, GuardrailReject)
+		}
+	}
+
+	action := g.Action
+	if action == "" {
+		action = GuardrailReject
+	}
+	total := calc.Total
+	var limit *Money
+	var name string
+	switch {
+	case g.MinTotal != nil && total.Sub(*g.MinTotal).IsNegative():
+		limit, name = g.MinTotal, GuardrailMinTotal
+	case g.MaxTotal != nil && g.MaxTotal.Sub(total).IsNegative():
+		limit, name = g.MaxTotal, GuardrailMaxTotal
+	}
+	if limit != nil {
+		violation := GuardrailViolation{Guardrail: name, Limit: limit.Float64(), Value: total.Float64()}
+		violate(violation, action)
+		if action == GuardrailClamp {
+			calc.GuardrailAdjustment = limit.Sub(total)
+			calc.Total = *limit
+			calc.Guardrail = &violation
+		}
+	}
+	if len(violations) > 0 {
+		return &GuardrailError{Violations: violations}
+	}
+	return nil
+}
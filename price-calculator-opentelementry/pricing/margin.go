@@ -0,0 +1,113 @@
+package pricing
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// SellingPriceRequest structure for pricing a cost at a target margin or markup, exactly one of which is set
+type SellingPriceRequest struct {
+	Cost     Money        `json:"cost"`
+	Margin   *float64     `json:"margin,omitempty"` // Percentage of the price left over the cost, below 100
+	Markup   *float64     `json:"markup,omitempty"` // Percentage of the cost added to it
+	Currency string       `json:"currency,omitempty"`
+	Rounding RoundingMode `json:"rounding,omitempty"` // Rounding of the price, overriding the configured mode
+}
+
+// MarginRequest structure for deriving the margin and markup of a selling price
+type MarginRequest struct {
+	Cost     Money  `json:"cost"`
+	Price    Money  `json:"price"`
+	Currency string `json:"currency,omitempty"`
+}
+
+// MarginResponse structure for a price with its cost, profit, margin and markup
+type MarginResponse struct {
+	Currency  string    `json:"currency"`
+	Cost      Money     `json:"cost"`
+	Price     Money     `json:"price"`
+	Profit    Money     `json:"profit"`           // Price minus cost, negative for a loss
+	Margin    float64   `json:"margin"`           // Profit as a percentage of the price
+	Markup    *float64  `json:"markup,omitempty"` // Profit as a percentage of the cost, omitted for a zero cost
+	Breakdown Breakdown `json:"breakdown"`        // The cost plus a profit line add up to the price
+}
+
+// SellingPrice computes the price of a cost at a target margin, cost / (1 - margin), or markup,
+// cost * (1 + markup), rounded to the currency
+func SellingPrice(ctx context.Context, request SellingPriceRequest, mode RoundingMode) (MarginResponse, error) {
+	currency, err := LookupCurrency(request.Currency)
+	if err != nil {
+		return MarginResponse{}, err
+	}
+	if !request.Cost.IsPositive() {
+		return MarginResponse{}, invalid("cost must be positive")
+	}
+	if request.Rounding != "" {
+		if mode, err = ParseRoundingMode(string(request.Rounding)); err != nil {
+			return MarginResponse{}, invalid("%v", err)
+		}
+	}
+
+	var price Money
+	switch {
+	case (request.Margin == nil) == (request.Markup == nil):
+		return MarginResponse{}, invalid("exactly one of margin and markup is required")
+	case request.Margin != nil:
+		if *request.Margin >= 100 {
+			return MarginResponse{}, invalid("margin must be below 100")
+		}
+		price = request.Cost.DivRate(1 - *request.Margin/100)
+	default:
+		if *request.Markup <= -100 {
+			return MarginResponse{}, invalid("markup must be above -100")
+		}
+		price = request.Cost.MulRate(1 + *request.Markup/100)
+	}
+	return marginResponse(ctx, currency, request.Cost, currency.Round(price, mode))
+}
+
+// Margin derives the margin and markup of selling a cost at a price
+func Margin(ctx context.Context, request MarginRequest) (MarginResponse, error) {
+	currency, err := LookupCurrency(request.Currency)
+	if err != nil {
+		return MarginResponse{}, err
+	}
+	if request.Cost.IsNegative() {
+		return MarginResponse{}, invalid("cost must not be negative")
+	}
+	if !request.Price.IsPositive() {
+		return MarginResponse{}, invalid("price must be positive")
+	}
+	return marginResponse(ctx, currency, request.Cost, request.Price)
+}
+
+// Builds the response for selling a cost at a price, recording the margin and markup on the active span
+func marginResponse(ctx context.Context, currency Currency, cost, price Money) (MarginResponse, error) {
+	// A price rounded down to zero has no margin
+	if !price.IsPositive() {
+		return MarginResponse{}, invalid("price must be positive")
+	}
+	profit := price.Sub(cost)
+	response := MarginResponse{
+		Currency: currency.Code,
+		Cost:     cost,
+		Price:    price,
+		Profit:   profit,
+		Margin:   profit.PercentOf(price),
+		Breakdown: Breakdown{
+			BaseAmount: cost,
+			Lines:      []BreakdownLine{{Kind: LineProfit, Name: "profit", Amount: profit}},
+			Total:      price,
+		},
+	}
+	attrs := []attribute.KeyValue{attribute.Float64("pricing.margin", response.Margin), attribute.String("currency", currency.Code)}
+	if cost.IsPositive() {
+		markup := profit.PercentOf(cost)
+		response.Markup = &markup
+		attrs = append(attrs, attribute.Float64("pricing.markup", markup))
+	}
+	trace.SpanFromContext(ctx).SetAttributes(attrs...)
+	return response, nil
+}
@@ -0,0 +1,182 @@
+package pricing
+
+import (
+	"database/sql/driver"
+	"fmt"
+
+	"github.com/shopspring/decimal"
+)
+
+// Money is an exact decimal amount, encoded in JSON as a plain number
+type Money struct {
+	d decimal.Decimal
+}
+
+// MoneyFromFloat creates a Money value from a float, using its shortest decimal representation
+func MoneyFromFloat(f float64) Money {
+	return Money{d: decimal.NewFromFloat(f)}
+}
+
+// ParseMoney parses a decimal string such as "107.99" into a Money value
+func ParseMoney(s string) (Money, error) {
+	d, err := decimal.NewFromString(s)
+	if err != nil {
+		return Money{}, fmt.Errorf("invalid amount %q", s)
+	}
+	return Money{d: d}, nil
+}
+
+func (m Money) Add(o Money) Money {
+	return Money{d: m.d.Add(o.d)}
+}
+
+func (m Money) Sub(o Money) Money {
+	return Money{d: m.d.Sub(o.d)}
+}
+
+// MulInt multiplies the amount by a whole number, e.g. a quantity
+func (m Money) MulInt(n int) Money {
+	return Money{d: m.d.Mul(decimal.NewFromInt(int64(n)))}
+}
+
+// Percent returns rate percent of the amount
+func (m Money) Percent(rate float64) Money {
+	return Money{d: m.d.Mul(decimal.NewFromFloat(rate)).Div(decimal.NewFromInt(100))}
+}
+
+// MulRate multiplies the amount by a rate, e.g. an exchange rate
+func (m Money) MulRate(rate float64) Money {
+	return Money{d: m.d.Mul(decimal.NewFromFloat(rate))}
+}
+
+// DivRate divides the amount by a rate, e.g. to find the price a cost is a share of
+func (m Money) DivRate(rate float64) Money {
+	return Money{d: m.d.Div(decimal.NewFromFloat(rate))}
+}
+
+// PercentOf returns the amount as a percentage of o, rounded to 4 decimal places
+func (m Money) PercentOf(o Money) float64 {
+	return m.d.Div(o.d).Mul(decimal.NewFromInt(100)).Round(4).InexactFloat64()
+}
+
+// DivInt divides the amount by a whole number, e.g. to find the price of one unit
+func (m Money) DivInt(n int) Money {
+	return Money{d: m.d.Div(decimal.NewFromInt(int64(n)))}
+}
+
+// Prorate returns the share n/d of the amount, e.g. for the unused days of a billing cycle
+func (m Money) Prorate(n, d int) Money {
+	return Money{d: m.d.Mul(decimal.NewFromInt(int64(n))).Div(decimal.NewFromInt(int64(d)))}
+}
+
+// ProrateBy returns the share n/d of the amount for a share given by two amounts, e.g. for a credit out of a total
+func (m Money) ProrateBy(n, d Money) Money {
+	return Money{d: m.d.Mul(n.d).Div(d.d)}
+}
+
+// Min returns the smaller of the two amounts
+func (m Money) Min(o Money) Money {
+	if o.d.LessThan(m.d) {
+		return o
+	}
+	return m
+}
+
+// Equal reports whether the amounts are equal, regardless of their number of decimals
+func (m Money) Equal(o Money) bool {
+	return m.d.Equal(o.d)
+}
+
+func (m Money) IsZero() bool {
+	return m.d.IsZero()
+}
+
+func (m Money) IsNegative() bool {
+	return m.d.IsNegative()
+}
+
+func (m Money) IsPositive() bool {
+	return m.d.IsPositive()
+}
+
+// Float64 returns the nearest float, for span attributes and metrics only
+func (m Money) Float64() float64 {
+	f, _ := m.d.Float64()
+	return f
+}
+
+func (m Money) String() string {
+	return m.d.String()
+}
+
+// Round rounds the amount to the given number of decimal places
+func (m Money) Round(places int, mode RoundingMode) Money {
+	p := int32(places)
+	switch mode {
+	case RoundHalfUp, RoundCash:
+		return Money{d: m.d.Round(p)}
+	case RoundDown:
+		return Money{d: m.d.RoundDown(p)}
+	case RoundUp:
+		return Money{d: m.d.RoundUp(p)}
+	case RoundCeiling:
+		return Money{d: m.d.RoundCeil(p)}
+	case RoundFloor:
+		return Money{d: m.d.RoundFloor(p)}
+	default:
+		return Money{d: m.d.RoundBank(p)}
+	}
+}
+
+// Encodes the amount as a JSON number without losing precision
+func (m Money) MarshalJSON() ([]byte, error) {
+	return []byte(m.d.String()), nil
+}
+
+// Decodes the amount from a JSON number or string
+func (m *Money) UnmarshalJSON(data []byte) error {
+	return m.d.UnmarshalJSON(data)
+}
+
+// Stores the amount as its decimal string
+func (m Money) Value() (driver.Value, error) {
+	return m.d.String(), nil
+}
+
+// Reads the amount from a numeric or text column
+func (m *Money) Scan(value any) error {
+	if f, ok := value.(float64); ok {
+		m.d = decimal.NewFromFloat(f)
+		return nil
+	}
+	return m.d.Scan(value)
+}
+
+// RoundingMode selects how amounts are rounded to the minor units of a currency
+type RoundingMode string
+
+// Supported rounding modes
+const (
+	RoundHalfEven RoundingMode = "half_even" // Halves go to the nearest even digit (banker's rounding)
+	RoundHalfUp   RoundingMode = "half_up"   // Halves go away from zero
+	RoundDown     RoundingMode = "down"      // Toward zero
+	RoundUp       RoundingMode = "up"        // Away from zero
+	RoundCeiling  RoundingMode = "ceiling"   // Toward positive infinity
+	RoundFloor    RoundingMode = "floor"     // Toward negative infinity
+	RoundCash     RoundingMode = "cash"      // Totals to the nearest 0.05, other amounts half up
+)
+
+// Smallest coin totals are rounded to by cash rounding
+var cashIncrement = decimal.New(5, -2)
+
+// ParseRoundingMode parses a rounding mode name, an empty name selects half-even
+func ParseRoundingMode(name string) (RoundingMode, error) {
+	switch mode := RoundingMode(name); mode {
+	case "":
+		return RoundHalfEven, nil
+	case RoundHalfEven, RoundHalfUp, RoundDown, RoundUp, RoundCeiling, RoundFloor, RoundCash:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("unknown rounding mode %q", name)
+	}
+}
@@ -0,0 +1,301 @@
+package pricing
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Names of the built-in calculation steps, usable as insertion points for custom steps
+const (
+	StepBase              = "base"
+	StepTiers             = "tiers"
+	StepDiscounts         = "discounts"
+	StepSurcharges        = "surcharges"
+	StepTax               = "tax"
+	StepPostTaxSurcharges = "post_tax_surcharges"
+	StepRounding          = "rounding"
+	StepGuardrails        = "guardrails"
+)
+
+// Calculation holds the state of a price calculation as it passes through the pipeline steps
+type Calculation struct {
+	Request  PriceRequest
+	Defaults Config
+	Rules    Rules
+	Mode     RoundingMode
+
+	// Resolved by the base step
+	BaseAmount   Money // BasePrice times Quantity before any adjustment
+	BasePrice    Money
+	TaxRate      float64 // Single tax rate, zero when stacked taxes apply
+	Taxes        []Tax   // Taxes applied in order, a single one for a tax rate
+	Currency     Currency
+	Quantity     int
+	TaxExemption *TaxExemption // Normalized exemption zero-rating the taxes
+
+	// Set by the tiers step, which lowers BasePrice by the volume price break reached
+	Tier *DiscountTier
+
+	Subtotal         Money // Running amount before tax
+	Discount         Money
+	Discounts        []AppliedDiscount
+	SkippedDiscounts []SkippedDiscount // Eligible rules left out by the stacking policy
+	Surcharge        Money
+	Surcharges       []AppliedSurcharge
+	Tax              Money
+	AppliedTaxes     []AppliedTax
+	Total            Money
+
+	// Set by the guardrails step when it clamped the total
+	Guardrail           *GuardrailViolation
+	GuardrailAdjustment Money // Added to the rounded total to reach the limit
+}
+
+// PricingStep is a single stage of a price calculation
+type PricingStep interface {
+	Name() string
+	Apply(ctx context.Context, calc *Calculation) error
+}
+
+// Pipeline is the ordered list of steps a calculation runs through
+type Pipeline []PricingStep
+
+// DefaultPipeline returns the built-in steps: base, tiers, discounts, surcharges, tax, post_tax_surcharges,
+// rounding and guardrails
+func DefaultPipeline() Pipeline {
+	return Pipeline{BaseStep{}, TierStep{}, DiscountStep{}, SurchargeStep{}, TaxStep{}, SurchargeStep{AfterTax: true}, RoundingStep{}, GuardrailStep{}}
+}
+
+// Insert returns the pipeline with step added before the step named before,
+// or at the end when no step has that name
+func (p Pipeline) Insert(before string, step PricingStep) Pipeline {
+	i := slices.IndexFunc(p, func(s PricingStep) bool { return s.Name() == before })
+	if i < 0 {
+		i = len(p)
+	}
+	return slices.Insert(slices.Clone(p), i, step)
+}
+
+// Calculate runs the steps in order, each in its own span, and stops at the first failing step
+func (p Pipeline) Calculate(ctx context.Context, request PriceRequest, defaults Config, rules Rules, mode RoundingMode) (PriceResponse, error) {
+	calc := &Calculation{Request: request, Defaults: defaults, Rules: rules, Mode: mode, Surcharges: []AppliedSurcharge{}}
+	if explanation := ExplanationFrom(ctx); explanation != nil {
+		explanation.Inputs = ExplainInputs{Request: request, Defaults: defaults, Rounding: mode}
+	}
+	for _, step := range p {
+		// Stop between steps once the calculation is cancelled
+		if err := ctx.Err(); err != nil {
+			return PriceResponse{}, err
+		}
+		if err := runStep(ctx, step, calc); err != nil {
+			return PriceResponse{}, err
+		}
+	}
+	trace.SpanFromContext(ctx).SetAttributes(attribute.String("currency", calc.Currency.Code), attribute.String("rounding.mode", string(calc.Mode)))
+	return PriceResponse{
+		TotalPrice:       calc.Total,
+		Currency:         calc.Currency.Code,
+		Discount:         calc.Discount,
+		Discounts:        calc.Discounts,
+		SkippedDiscounts: calc.SkippedDiscounts,
+		Surcharge:        calc.Surcharge,
+		Surcharges:       calc.Surcharges,
+		Tax:              calc.Currency.Round(calc.Tax, calc.Mode),
+		Taxes:            calc.AppliedTaxes,
+		Tier:             calc.Tier,
+		TaxExemption:     calc.TaxExemption,
+		Guardrail:        calc.Guardrail,
+		Breakdown:        newBreakdown(calc),
+	}, nil
+}
+
+// Applies a single step in its own span
+func runStep(ctx context.Context, step PricingStep, calc *Calculation) error {
+	ctx, span := tracerFrom(ctx).Start(ctx, "PricingStep "+step.Name(), trace.WithAttributes(attribute.String("pricing.step", step.Name())))
+	defer span.End()
+
+	explained := func(error) {}
+	if explanation := ExplanationFrom(ctx); explanation != nil {
+		explained = explanation.startStep(step.Name(), calc)
+	}
+	err := step.Apply(ctx, calc)
+	explained(err)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return err
+	}
+	span.SetAttributes(attribute.Float64("pricing.subtotal", calc.Subtotal.Float64()), attribute.Float64("pricing.total", calc.Total.Float64()))
+	return nil
+}
+
+// BaseStep resolves the base price, taxes, currency and quantity, falling back to the defaults.
+// The taxes are taken from the request, then the tax rule matching the jurisdiction, then the default rate.
+type BaseStep struct{}
+
+func (BaseStep) Name() string { return StepBase }
+
+func (BaseStep) Apply(ctx context.Context, calc *Calculation) error {
+	request := calc.Request
+	calc.BasePrice, calc.TaxRate = calc.Defaults.BasePrice, calc.Defaults.TaxRate
+	if request.BasePrice != nil {
+		calc.BasePrice = *request.BasePrice
+	}
+	switch {
+	case len(request.Taxes) > 0:
+		if request.TaxRate != nil {
+			return invalid("tax_rate and taxes are mutually exclusive")
+		}
+		calc.TaxRate, calc.Taxes = 0, request.Taxes
+	case request.TaxRate != nil:
+		calc.TaxRate = *request.TaxRate
+	case request.Jurisdiction != nil:
+		if request.Jurisdiction.Country == "" {
+			return invalid("jurisdiction country is required")
+		}
+		if rule, ok := ResolveTaxRule(ctx, calc.Rules.TaxRules, *request.Jurisdiction); ok {
+			calc.TaxRate, calc.Taxes = rule.Rate, rule.Taxes
+		}
+	}
+	if request.TaxExemption != nil {
+		exemption, err := NormalizeTaxExemption(*request.TaxExemption)
+		if err != nil {
+			return err
+		}
+		calc.TaxRate, calc.Taxes, calc.TaxExemption = 0, nil, &exemption
+		explainRule(ctx, ExplainRule{Kind: ExplainTax, Name: "exemption", Matched: true, Detail: "taxes zero-rated: " + exemption.Reason})
+		trace.SpanFromContext(ctx).SetAttributes(
+			attribute.String("tax.exemption.country", exemption.Country()),
+			attribute.String("tax.exemption.reason", exemption.Reason),
+		)
+	}
+	if err := ValidateBasePrice(calc.BasePrice); err != nil {
+		return err
+	}
+	if err := ValidateTaxRate(calc.TaxRate); err != nil {
+		return err
+	}
+	if err := ValidateTaxes(calc.Taxes); err != nil {
+		return err
+	}
+	if len(calc.Taxes) == 0 {
+		calc.Taxes = []Tax{{Name: "tax", Rate: calc.TaxRate}}
+	}
+	currency, err := LookupCurrency(request.Currency)
+	if err != nil {
+		return err
+	}
+	calc.Currency = currency
+	if request.Quantity < 0 {
+		return invalid("invalid quantity")
+	}
+	if request.Weight < 0 {
+		return invalid("weight must not be negative")
+	}
+	if request.Rounding != "" {
+		mode, err := ParseRoundingMode(string(request.Rounding))
+		if err != nil {
+			return invalid("%v", err)
+		}
+		calc.Mode = mode
+	}
+	calc.Quantity = max(request.Quantity, 1)
+	calc.BaseAmount = calc.BasePrice.MulInt(calc.Quantity)
+	calc.Subtotal = calc.BaseAmount
+	calc.Total = calc.Subtotal
+	return nil
+}
+
+// DiscountStep applies the discount rules in order, followed by the redeemed coupon,
+// as far as the stacking policy of the rules allows
+type DiscountStep struct{}
+
+func (DiscountStep) Name() string { return StepDiscounts }
+
+func (DiscountStep) Apply(ctx context.Context, calc *Calculation) error {
+	rules := calc.Rules.Discounts
+	if calc.Rules.Coupon != nil {
+		rules = append(slices.Clone(rules), calc.Rules.Coupon.Discount())
+	}
+	discount, applied, skipped := applyDiscounts(ctx, rules, calc.Rules.Stacking, calc.BasePrice, calc.Quantity)
+	calc.Discount = calc.Currency.Round(discount, calc.Mode)
+	for i := range applied {
+		applied[i].Amount = calc.Currency.Round(applied[i].Amount, calc.Mode)
+	}
+	calc.Discounts = applied
+	calc.SkippedDiscounts = skipped
+	calc.Subtotal = calc.Subtotal.Sub(calc.Discount)
+	calc.Total = calc.Subtotal
+	return nil
+}
+
+// TaxStep applies the taxes to the discounted subtotal in order. A compound tax
+// is applied to the subtotal plus the taxes before it, any other tax to the subtotal.
+type TaxStep struct{}
+
+func (TaxStep) Name() string { return StepTax }
+
+func (TaxStep) Apply(ctx context.Context, calc *Calculation) error {
+	span := trace.SpanFromContext(ctx)
+	calc.Tax = Money{}
+	calc.AppliedTaxes = make([]AppliedTax, 0, len(calc.Taxes))
+	for _, tax := range calc.Taxes {
+		taxable := calc.Subtotal
+		if tax.Compound {
+			taxable = taxable.Add(calc.Tax)
+		}
+		amount := taxable.Percent(tax.Rate)
+		calc.Tax = calc.Tax.Add(amount)
+		calc.AppliedTaxes = append(calc.AppliedTaxes, AppliedTax{Name: tax.Name, Rate: tax.Rate, Amount: calc.Currency.Round(amount, calc.Mode)})
+		explainRule(ctx, ExplainRule{Kind: ExplainTax, Name: tax.Name, Matched: true, Amount: explainAmount(amount),
+			Detail: fmt.Sprintf("%v%% of %v", tax.Rate, taxable)})
+		span.AddEvent("tax.applied", trace.WithAttributes(
+			attribute.String("tax.name", tax.Name),
+			attribute.Float64("tax.rate", tax.Rate),
+			attribute.Bool("tax.compound", tax.Compound),
+		))
+	}
+	calc.Total = calc.Subtotal.Add(calc.Tax)
+	span.SetAttributes(attribute.Int("tax.count", len(calc.Taxes)), attribute.Float64("tax.total", calc.Tax.Float64()))
+	return nil
+}
+
+// Surcharge is a step adding a fixed, untaxed amount to the total, such as a handling fee.
+// It is not part of the default pipeline, insert it before the rounding step.
+// Configured surcharges are applied by SurchargeStep instead.
+type Surcharge struct {
+	Label  string
+	Amount Money
+}
+
+func (Surcharge) Name() string { return "surcharge" }
+
+func (s Surcharge) Apply(ctx context.Context, calc *Calculation) error {
+	calc.Total = calc.Total.Add(s.Amount)
+	calc.Surcharge = calc.Surcharge.Add(s.Amount)
+	calc.Surcharges = append(calc.Surcharges, AppliedSurcharge{Name: s.Label, Amount: s.Amount})
+	explainRule(ctx, ExplainRule{Kind: ExplainSurcharge, Name: s.Label, Matched: true, Amount: explainAmount(s.Amount), Detail: "fixed surcharge"})
+	trace.SpanFromContext(ctx).SetAttributes(
+		attribute.String("surcharge.label", s.Label),
+		attribute.Float64("surcharge.amount", s.Amount.Float64()),
+	)
+	return nil
+}
+
+// RoundingStep rounds the total to the currency with the rounding mode of the calculation
+type RoundingStep struct{}
+
+func (RoundingStep) Name() string { return StepRounding }
+
+func (RoundingStep) Apply(ctx context.Context, calc *Calculation) error {
+	rounded := calc.Currency.RoundTotal(calc.Total, calc.Mode)
+	explainRule(ctx, ExplainRule{Kind: ExplainRounding, Name: string(calc.Mode), Matched: !rounded.Equal(calc.Total), Amount: explainAmount(rounded.Sub(calc.Total)),
+		Detail: fmt.Sprintf("%v rounded to %v", calc.Total, rounded)})
+	calc.Total = rounded
+	return nil
+}
@@ -0,0 +1,45 @@
+package pricing
+
+import (
+	"context"
+	"testing"
+)
+
+// Benchmarks every step of the default pipeline on a calculation with discounts, stacked
+// taxes and surcharges, each step starting from the calculation the steps before it produce
+func BenchmarkPipelineSteps(b *testing.B) {
+	ctx := context.Background()
+	basePrice := MoneyFromFloat(19.99)
+	calc := Calculation{
+		Request: PriceRequest{
+			BasePrice: &basePrice,
+			Quantity:  3,
+			Taxes:     []Tax{{Name: "GST", Rate: 5}, {Name: "QST", Rate: 9.975, Compound: true}},
+		},
+		Rules: Rules{
+			Discounts: []Discount{
+				{ID: "1", Name: "Volume", Type: DiscountPercentage, Value: 10},
+				{ID: "2", Name: "Loyalty", Type: DiscountFixed, Value: 1.5},
+			},
+			Surcharges: []SurchargeRule{
+				{ID: "1", Name: "Handling", Type: SurchargeFlat, Amount: MoneyFromFloat(2)},
+				{ID: "2", Name: "Shipping", Type: SurchargePerUnit, Amount: MoneyFromFloat(0.5), AfterTax: true},
+			},
+		},
+		Mode: RoundHalfEven,
+	}
+
+	for _, step := range DefaultPipeline() {
+		input := calc
+		b.Run(step.Name(), func(b *testing.B) {
+			b.ReportAllocs()
+			for range b.N {
+				calc := input
+				_ = step.Apply(ctx, &calc)
+			}
+		})
+		if err := step.Apply(ctx, &calc); err != nil {
+			b.Fatalf("step %s: %v", step.Name(), err)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+// Package pricing implements the price calculations shared by the HTTP and gRPC APIs.
+// It does not depend on the rest of the service, so other services can embed the
+// engine through a Calculator.
+package pricing
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"go.opentelemetry.io/otel"
+)
+
+// Tracer for the spans created by the pricing engine outside a Calculator with a tracer of its own
+var tracer = otel.Tracer("otpl/pricecalculator/pricing")
+
+// Config holds the defaults used when a request omits a value
+type Config struct {
+	BasePrice Money        `json:"base_price"`
+	TaxRate   float64      `json:"tax_rate"`
+	Currency  string       `json:"currency,omitempty"` // Currency of requests naming none, USD when empty
+	Rounding  RoundingMode `json:"rounding,omitempty"` // Overrides the configured rounding mode when set
+}
+
+// Rules holds the configured rules a calculation is evaluated against
+type Rules struct {
+	Discounts  []Discount // Applied in order
+	TaxRules   []TaxRule
+	Surcharges []SurchargeRule
+	Tiers      []DiscountTier // Volume price breaks, the product's own or the global ones
+	Coupon     *Coupon        // Redeemed coupon, applied after the discount rules
+	Stacking   StackingPolicy // Which discounts and coupon combine, all of them by default
+	Guardrails *Guardrails    // Limits of the total and margin, unchecked when nil
+	UnitCost   *Money         // Cost of a unit for the margin guardrails, such as the product's
+}
+
+// PriceRequest structure for input data
+// Omitted fields fall back to the stored defaults
+type PriceRequest struct {
+	BasePrice    *Money        `json:"base_price,omitempty"`
+	TaxRate      *float64      `json:"tax_rate,omitempty"`
+	Taxes        []Tax         `json:"taxes,omitempty"`         // Stacked taxes applied instead of a single tax rate
+	Currency     string        `json:"currency,omitempty"`      // ISO 4217 code, defaults to USD
+	Quantity     int           `json:"quantity,omitempty"`      // Number of units, defaults to 1
+	Weight       float64       `json:"weight,omitempty"`        // Shipment weight for weight based surcharges
+	Jurisdiction *Jurisdiction `json:"jurisdiction,omitempty"`  // Resolves the tax rate from the tax rules when no tax_rate is given
+	CouponCode   string        `json:"coupon_code,omitempty"`   // Coupon to redeem
+	SKU          string        `json:"sku,omitempty"`           // Catalog product priced instead of base_price
+	CustomerID   string        `json:"customer_id,omitempty"`   // Customer whose negotiated prices and tax exemption apply
+	Rounding     RoundingMode  `json:"rounding,omitempty"`      // Overrides the configured rounding mode
+	TaxExemption *TaxExemption `json:"tax_exemption,omitempty"` // Zero-rates the tax for a buyer with a valid VAT ID
+	AsOf         *time.Time    `json:"as_of,omitempty"`         // Prices with the default tax rate in effect at the time
+}
+
+// PriceResponse structure for output data
+type PriceResponse struct {
+	TotalPrice       Money               `json:"total_price"`
+	Currency         string              `json:"currency"`
+	Discount         Money               `json:"discount"`
+	Discounts        []AppliedDiscount   `json:"discounts"`
+	SkippedDiscounts []SkippedDiscount   `json:"skipped_discounts,omitempty"` // Eligible rules left out by the stacking policy
+	Surcharge        Money               `json:"surcharge"`
+	Surcharges       []AppliedSurcharge  `json:"surcharges"`
+	Tax              Money               `json:"tax"`
+	Taxes            []AppliedTax        `json:"taxes"`                   // Breakdown of Tax by tax
+	Tier             *DiscountTier       `json:"tier,omitempty"`          // Volume price break applied to the unit price
+	TaxExemption     *TaxExemption       `json:"tax_exemption,omitempty"` // Exemption that zero-rated the tax
+	Guardrail        *GuardrailViolation `json:"guardrail,omitempty"`     // Limit the total was clamped to
+	Breakdown        Breakdown           `json:"breakdown"`
+	Formatted        *FormattedPrices    `json:"formatted,omitempty"`      // Amounts formatted in the requested locale
+	CalculationID    string              `json:"calculation_id,omitempty"` // Identifies the calculation in logs, history and events
+	TraceID          string              `json:"trace_id,omitempty"`       // Trace of the calculation
+	Explanation      *Explanation        `json:"explanation,omitempty"`    // Step by step account of the calculation, on request
+}
+
+// ValidationError reports a request that cannot be priced as given
+type ValidationError struct {
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	return e.Message
+}
+
+// Creates a ValidationError with a formatted message
+func invalid(format string, args ...any) error {
+	return &ValidationError{Message: fmt.Sprintf(format, args...)}
+}
+
+// ValidateBasePrice checks that a base price is not negative
+func ValidateBasePrice(basePrice Money) error {
+	if basePrice.IsNegative() {
+		return invalid("base price must not be negative")
+	}
+	return nil
+}
+
+// ValidateTaxRate checks that a tax rate is a finite percentage between 0 and 100
+func ValidateTaxRate(rate float64) error {
+	if math.IsNaN(rate) || math.IsInf(rate, 0) || rate < 0 || rate > 100 {
+		return invalid("tax rate must be between 0 and 100")
+	}
+	return nil
+}
+
+// Calculate computes the total price for a request with the default pipeline, falling back to
+// defaults for omitted fields. The discount rules are applied in order before tax, and each
+// applied rule is recorded on the span of the discounts step.
+func Calculate(ctx context.Context, request PriceRequest, defaults Config, rules Rules, mode RoundingMode) (PriceResponse, error) {
+	return NewCalculator(CalculatorOptions{}).Calculate(ctx, Request{PriceRequest: request, Defaults: defaults, Rules: rules, Rounding: mode})
+}
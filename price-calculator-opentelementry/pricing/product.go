@@ -0,0 +1,33 @@
+package pricing
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Product structure for a catalog entry that requests can reference by SKU
+type Product struct {
+	SKU         string         `json:"sku"`
+	Name        string         `json:"name"`
+	UnitPrice   Money          `json:"unit_price"`
+	UnitCost    *Money         `json:"unit_cost,omitempty"`    // Cost of a unit, checked by the margin guardrails
+	TaxCategory string         `json:"tax_category,omitempty"` // Matched against the category of the tax rules
+	Tiers       []DiscountTier `json:"tiers,omitempty"`        // Volume price breaks replacing the global ones
+}
+
+// Validate checks that the product is complete
+func (p Product) Validate() error {
+	if strings.TrimSpace(p.SKU) == "" {
+		return fmt.Errorf("sku is required")
+	}
+	if strings.TrimSpace(p.Name) == "" {
+		return fmt.Errorf("product name is required")
+	}
+	if p.UnitPrice.IsNegative() {
+		return fmt.Errorf("unit price must not be negative")
+	}
+	if p.UnitCost != nil && p.UnitCost.IsNegative() {
+		return fmt.Errorf("unit cost must not be negative")
+	}
+	return ValidateTiers(p.Tiers)
+}
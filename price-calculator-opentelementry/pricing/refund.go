@@ -0,0 +1,144 @@
+package pricing
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Ways of sizing a refund
+const (
+	RefundByQuantity = "quantity"
+	RefundByAmount   = "amount"
+)
+
+// RefundRequest structure for refunding part or all of a recorded calculation, either by the
+// units returned or by a credit amount
+type RefundRequest struct {
+	CalculationID string `json:"calculation_id"`     // Calculation refunded, as returned by /calculate
+	Quantity      int    `json:"quantity,omitempty"` // Units returned, negative
+	Amount        *Money `json:"amount,omitempty"`   // Credit including tax, negative
+	Reason        string `json:"reason,omitempty"`
+}
+
+// Refunded structure for what was refunded of a calculation so far, the amount credited being positive
+type Refunded struct {
+	Count    int   `json:"count"` // Refunds recorded
+	Quantity int   `json:"quantity"`
+	Amount   Money `json:"amount"`
+}
+
+// RefundResponse structure for a credit note. The amounts are signed from the customer's point
+// of view, so refunded amounts are negative and reversed discounts positive.
+type RefundResponse struct {
+	CalculationID         string        `json:"calculation_id,omitempty"`
+	OriginalCalculationID string        `json:"original_calculation_id"`
+	Mode                  string        `json:"mode"` // quantity or amount
+	Quantity              int           `json:"quantity,omitempty"`
+	Reason                string        `json:"reason,omitempty"`
+	Currency              string        `json:"currency"`
+	Share                 float64       `json:"share"`    // Percentage of the original calculation refunded
+	Subtotal              Money         `json:"subtotal"` // Total before tax
+	Tax                   Money         `json:"tax"`
+	Taxes                 []AppliedTax  `json:"taxes"` // With the rates of the original calculation
+	TotalPrice            Money         `json:"total_price"`
+	Breakdown             Breakdown     `json:"breakdown"`
+	Original              PriceResponse `json:"original"`
+	Refunded              Refunded      `json:"refunded"` // Of the original, this refund included
+	TraceID               string        `json:"trace_id,omitempty"`
+}
+
+// CalculateRefund reverses the share of an original calculation given by the returned units or the
+// credit amount, out of what remains after the refunds so far. Every amount of the original breakdown,
+// its taxes included, is reversed in the same share, so tax is refunded at the rates the original was
+// charged. The refund is recorded on the span in ctx.
+func CalculateRefund(ctx context.Context, request RefundRequest, original PriceRequest, response PriceResponse, refunded Refunded, mode RoundingMode) (RefundResponse, error) {
+	span := trace.SpanFromContext(ctx)
+	currency, err := LookupCurrency(response.Currency)
+	if err != nil {
+		return RefundResponse{}, err
+	}
+	if original.Rounding != "" {
+		mode = original.Rounding
+	}
+
+	// Size the refund as a share of the original calculation
+	refund := RefundResponse{
+		OriginalCalculationID: request.CalculationID,
+		Reason:                request.Reason,
+		Currency:              response.Currency,
+		Taxes:                 []AppliedTax{},
+		Original:              response,
+	}
+	remaining := response.TotalPrice.Sub(refunded.Amount)
+	var share func(Money) Money
+	switch {
+	case request.Quantity != 0 && request.Amount != nil:
+		return RefundResponse{}, invalid("quantity and amount are mutually exclusive")
+	case request.Quantity != 0:
+		quantity := max(original.Quantity, 1)
+		units := quantity - refunded.Quantity
+		if units < 1 {
+			return RefundResponse{}, invalid("all %d units were already refunded", quantity)
+		}
+		if request.Quantity > 0 || -request.Quantity > units {
+			return RefundResponse{}, invalid("quantity must be between -%d and -1", units)
+		}
+		refund.Mode, refund.Quantity = RefundByQuantity, request.Quantity
+		share = func(m Money) Money { return m.Prorate(-request.Quantity, quantity) }
+		credit := currency.RoundTotal(share(response.TotalPrice), mode)
+		if response.TotalPrice.IsPositive() && (-request.Quantity == units || remaining.Sub(credit).IsNegative()) {
+			// The last units get what remains, so rounding never credits more than the total
+			credit = remaining
+			share = func(m Money) Money { return m.ProrateBy(credit, response.TotalPrice) }
+		}
+		refund.TotalPrice = Money{}.Sub(credit)
+	case request.Amount != nil:
+		credit := Money{}.Sub(*request.Amount)
+		if !credit.IsPositive() || remaining.Sub(credit).IsNegative() {
+			return RefundResponse{}, invalid("amount must be negative and not exceed the %v remaining of the original total of %v", remaining, response.TotalPrice)
+		}
+		refund.Mode = RefundByAmount
+		share = func(m Money) Money { return m.ProrateBy(credit, response.TotalPrice) }
+		refund.TotalPrice = currency.Round(*request.Amount, mode)
+	default:
+		return RefundResponse{}, invalid("a negative quantity or amount is required")
+	}
+	refund.Refunded = Refunded{
+		Count:    refunded.Count + 1,
+		Quantity: refunded.Quantity - refund.Quantity,
+		Amount:   refunded.Amount.Sub(refund.TotalPrice),
+	}
+	if response.TotalPrice.IsPositive() {
+		refund.Share = Money{}.Sub(refund.TotalPrice).PercentOf(response.TotalPrice)
+	}
+
+	// Reverse every line of the original breakdown in that share
+	reverse := func(m Money) Money { return Money{}.Sub(currency.Round(share(m), mode)) }
+	refund.Breakdown = Breakdown{BaseAmount: reverse(response.Breakdown.BaseAmount), Lines: []BreakdownLine{}, Total: refund.TotalPrice}
+	sum := refund.Breakdown.BaseAmount
+	for _, line := range response.Breakdown.Lines {
+		line.Amount = reverse(line.Amount)
+		refund.Breakdown.Lines = append(refund.Breakdown.Lines, line)
+		sum = sum.Add(line.Amount)
+	}
+	refund.Breakdown.RoundingAdjustment = refund.TotalPrice.Sub(sum)
+	for _, tax := range response.Taxes {
+		tax.Amount = reverse(tax.Amount)
+		refund.Taxes = append(refund.Taxes, tax)
+		refund.Tax = refund.Tax.Add(tax.Amount)
+	}
+	refund.Subtotal = refund.TotalPrice.Sub(refund.Tax)
+
+	span.SetAttributes(
+		attribute.String("refund.mode", refund.Mode),
+		attribute.String("refund.original_calculation_id", request.CalculationID),
+		attribute.Float64("refund.share", refund.Share),
+		attribute.Int("refund.count", refund.Refunded.Count),
+		attribute.Float64("refund.tax", refund.Tax.Float64()),
+		attribute.Float64("refund.total", refund.TotalPrice.Float64()),
+		attribute.String("currency", refund.Currency),
+	)
+	return refund, nil
+}
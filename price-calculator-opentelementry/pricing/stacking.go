@@ -0,0 +1,82 @@
+package pricing
+
+import (
+	"fmt"
+	"slices"
+)
+
+// Promotion stacking policies, deciding which of the eligible discounts apply together
+const (
+	StackAll        = "stack_all"     // Every discount in order, the default
+	StackBestSingle = "best_single"   // Only the discount taking the most off
+	StackUpToN      = "stack_up_to_n" // The first Limit discounts in order
+	StackPriority   = "priority"      // In descending priority, up to Limit discounts when set
+)
+
+// Reasons a discount was skipped
+const (
+	SkipNoEffect     = "no_effect"     // It would not have lowered the price
+	SkipNotBest      = "not_best"      // Another discount took more off under best_single
+	SkipLimitReached = "limit_reached" // The policy's limit of discounts was reached
+)
+
+// StackingPolicy structure for how eligible discounts and the redeemed coupon combine
+type StackingPolicy struct {
+	Policy string `json:"policy,omitempty"` // stack_all when empty
+	Limit  int    `json:"limit,omitempty"`  // Most discounts applied by stack_up_to_n and priority
+}
+
+// SkippedDiscount structure for an eligible discount rule the stacking policy left out
+type SkippedDiscount struct {
+	ID     string `json:"id"`
+	Name   string `json:"name"`
+	Reason string `json:"reason"` // no_effect, not_best or limit_reached
+}
+
+// Validate checks that the policy is supported and stack_up_to_n has a limit
+func (p StackingPolicy) Validate() error {
+	switch p.Policy {
+	case "", StackAll, StackBestSingle, StackPriority:
+	case StackUpToN:
+		if p.Limit <= 0 {
+			return fmt.Errorf("stacking policy %s needs a positive limit", StackUpToN)
+		}
+	default:
+		return fmt.Errorf("unsupported stacking policy %q", p.Policy)
+	}
+	if p.Limit < 0 {
+		return fmt.Errorf("stacking limit must not be negative")
+	}
+	return nil
+}
+
+// Returns the policy name, stack_all when unset
+func (p StackingPolicy) name() string {
+	if p.Policy == "" {
+		return StackAll
+	}
+	return p.Policy
+}
+
+// Returns the rules in the order the policy considers them, by descending priority for
+// the priority policy and as given otherwise
+func (p StackingPolicy) order(rules []Discount) []Discount {
+	if p.Policy != StackPriority {
+		return rules
+	}
+	rules = slices.Clone(rules)
+	slices.SortStableFunc(rules, func(a, b Discount) int { return b.Priority - a.Priority })
+	return rules
+}
+
+// Returns the index of the rule taking the most off the total on its own, the first one on
+// a tie, or -1 when none lowers the price
+func bestDiscount(rules []Discount, total, unitPrice Money, quantity int) int {
+	best, bestAmount := -1, Money{}
+	for i, rule := range rules {
+		if amount := rule.amount(total, unitPrice, quantity); amount.Sub(bestAmount).IsPositive() {
+			best, bestAmount = i, amount
+		}
+	}
+	return best
+}
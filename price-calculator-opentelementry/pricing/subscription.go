@@ -0,0 +1,176 @@
+package pricing
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Supported billing intervals of a subscription
+const (
+	IntervalMonthly = "monthly"
+	IntervalAnnual  = "annual"
+)
+
+// Layout of the subscription dates
+const dateLayout = time.DateOnly
+
+// SubscriptionRequest structure for pricing a recurring charge, optionally changed mid-cycle.
+// Dates are YYYY-MM-DD, an omitted TaxRate falls back to the stored default.
+type SubscriptionRequest struct {
+	Price       Money    `json:"price"`                  // Recurring price per unit and interval of the current plan
+	Quantity    int      `json:"quantity,omitempty"`     // Number of seats, defaults to 1
+	Interval    string   `json:"interval"`               // monthly or annual
+	AnchorDate  string   `json:"anchor_date"`            // Start of the first billing cycle, later cycles start on the same day
+	ChangeDate  string   `json:"change_date,omitempty"`  // Day the plan changes, defaults to today
+	NewPrice    *Money   `json:"new_price,omitempty"`    // Recurring price per unit after the change
+	NewQuantity *int     `json:"new_quantity,omitempty"` // Number of seats after the change
+	TaxRate     *float64 `json:"tax_rate,omitempty"`
+	Currency    string   `json:"currency,omitempty"` // ISO 4217 code, defaults to USD
+}
+
+// SubscriptionResponse structure for the next invoice of a subscription. A change credits the
+// unused part of the cycle on the current plan and charges the rest of the cycle on the new plan.
+type SubscriptionResponse struct {
+	Currency        string  `json:"currency"`
+	PeriodStart     string  `json:"period_start"`      // Start of the billing cycle the change falls in
+	NextInvoiceDate string  `json:"next_invoice_date"` // Start of the next billing cycle
+	ProrationFactor float64 `json:"proration_factor"`  // Share of the cycle left from the change date
+	ProrationCredit Money   `json:"proration_credit"`  // Unused part of the current plan
+	ProrationCharge Money   `json:"proration_charge"`  // Rest of the cycle on the new plan
+	RecurringAmount Money   `json:"recurring_amount"`  // A full cycle on the new plan
+	Tax             Money   `json:"tax"`
+	NextInvoice     Money   `json:"next_invoice"`   // Recurring amount plus charge minus credit, with tax
+	CreditBalance   Money   `json:"credit_balance"` // Credit exceeding the next invoice, carried forward
+}
+
+// Returns the start of the nth billing cycle from anchor. Cycles anchored on a day a month
+// does not have start on the last day of that month.
+func cycleStart(anchor time.Time, interval string, n int) time.Time {
+	year, month := anchor.Year(), anchor.Month()
+	if interval == IntervalAnnual {
+		year += n
+	} else {
+		month += time.Month(n)
+	}
+	lastDay := time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC).Day()
+	return time.Date(year, month, min(anchor.Day(), lastDay), 0, 0, 0, 0, time.UTC)
+}
+
+// Returns the start and end of the billing cycle containing day
+func billingCycle(anchor time.Time, interval string, day time.Time) (time.Time, time.Time) {
+	// Start a cycle early, as the cycle of the day's month may start after the day
+	n := day.Year() - anchor.Year()
+	if interval == IntervalMonthly {
+		n = n*12 + int(day.Month()-anchor.Month())
+	}
+	n = max(n-1, 0)
+	for !cycleStart(anchor, interval, n+1).After(day) {
+		n++
+	}
+	return cycleStart(anchor, interval, n), cycleStart(anchor, interval, n+1)
+}
+
+// Parses a subscription date, naming the field in the error
+func parseDate(field, value string) (time.Time, error) {
+	day, err := time.Parse(dateLayout, value)
+	if err != nil {
+		return day, invalid("invalid %s %q, expected YYYY-MM-DD", field, value)
+	}
+	return day, nil
+}
+
+// CalculateSubscription prices the next invoice of a subscription, prorating a change of price
+// or quantity by the days left in the billing cycle. The proration is recorded on the span in ctx.
+func CalculateSubscription(ctx context.Context, request SubscriptionRequest, defaults Config, mode RoundingMode) (SubscriptionResponse, error) {
+	span := trace.SpanFromContext(ctx)
+
+	// Validate the subscription
+	if request.Interval != IntervalMonthly && request.Interval != IntervalAnnual {
+		return SubscriptionResponse{}, invalid("interval must be %s or %s", IntervalMonthly, IntervalAnnual)
+	}
+	if err := ValidateBasePrice(request.Price); err != nil {
+		return SubscriptionResponse{}, err
+	}
+	if request.NewPrice != nil {
+		if err := ValidateBasePrice(*request.NewPrice); err != nil {
+			return SubscriptionResponse{}, err
+		}
+	}
+	if request.Quantity < 0 || (request.NewQuantity != nil && *request.NewQuantity < 0) {
+		return SubscriptionResponse{}, invalid("invalid quantity")
+	}
+	taxRate := defaults.TaxRate
+	if request.TaxRate != nil {
+		taxRate = *request.TaxRate
+	}
+	if err := ValidateTaxRate(taxRate); err != nil {
+		return SubscriptionResponse{}, err
+	}
+	anchor, err := parseDate("anchor_date", request.AnchorDate)
+	if err != nil {
+		return SubscriptionResponse{}, err
+	}
+	change, err := parseDate("change_date", request.ChangeDate)
+	if err != nil {
+		return SubscriptionResponse{}, err
+	}
+	if change.Before(anchor) {
+		return SubscriptionResponse{}, invalid("change_date must not be before anchor_date")
+	}
+	currency, err := LookupCurrency(request.Currency)
+	if err != nil {
+		return SubscriptionResponse{}, err
+	}
+
+	// Recurring amounts of the current and the new plan
+	quantity := max(request.Quantity, 1)
+	current := request.Price.MulInt(quantity)
+	price, newQuantity := request.Price, quantity
+	if request.NewPrice != nil {
+		price = *request.NewPrice
+	}
+	if request.NewQuantity != nil {
+		newQuantity = *request.NewQuantity
+	}
+	recurring := price.MulInt(newQuantity)
+
+	// Prorate by the days left in the cycle, a change on the first day of a cycle replaces it completely
+	start, end := billingCycle(anchor, request.Interval, change)
+	days := int(end.Sub(start).Hours() / 24)
+	remaining := int(end.Sub(change).Hours() / 24)
+	response := SubscriptionResponse{
+		Currency:        currency.Code,
+		PeriodStart:     start.Format(dateLayout),
+		NextInvoiceDate: end.Format(dateLayout),
+		ProrationFactor: float64(remaining) / float64(days),
+		RecurringAmount: currency.Round(recurring, mode),
+	}
+	if request.NewPrice != nil || request.NewQuantity != nil {
+		response.ProrationCredit = currency.Round(current.Prorate(remaining, days), mode)
+		response.ProrationCharge = currency.Round(recurring.Prorate(remaining, days), mode)
+	}
+
+	// Credit beyond the invoice is carried forward instead of invoiced as a negative amount
+	subtotal := response.RecurringAmount.Add(response.ProrationCharge).Sub(response.ProrationCredit)
+	if subtotal.IsNegative() {
+		response.CreditBalance = Money{}.Sub(subtotal)
+		subtotal = Money{}
+	}
+	response.Tax = currency.Round(subtotal.Percent(taxRate), mode)
+	response.NextInvoice = currency.RoundTotal(subtotal.Add(response.Tax), mode)
+
+	span.SetAttributes(
+		attribute.String("subscription.interval", request.Interval),
+		attribute.String("subscription.period_start", response.PeriodStart),
+		attribute.Float64("subscription.proration_factor", response.ProrationFactor),
+		attribute.Float64("subscription.proration_credit", response.ProrationCredit.Float64()),
+		attribute.Float64("subscription.proration_charge", response.ProrationCharge.Float64()),
+		attribute.Float64("subscription.next_invoice", response.NextInvoice.Float64()),
+		attribute.String("currency", currency.Code),
+		attribute.String("rounding.mode", string(mode)),
+	)
+	return response, nil
+}
@@ -0,0 +1,116 @@
+package pricing
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Supported surcharge types
+const (
+	SurchargeFlat       = "flat"       // Amount per order
+	SurchargePercentage = "percentage" // Rate percent of the discounted subtotal
+	SurchargePerUnit    = "per_unit"   // Amount per unit, such as quantity based shipping
+	SurchargeWeight     = "weight"     // Amount per unit of the request's weight
+)
+
+// SurchargeRule structure for a fee added to every calculation, such as shipping or handling.
+// Surcharges are applied after the discounts, before tax so they are taxed, or after tax.
+type SurchargeRule struct {
+	ID       string  `json:"id"`
+	Name     string  `json:"name"`
+	Type     string  `json:"type"`
+	Amount   Money   `json:"amount,omitempty"`    // For flat, per_unit and weight surcharges
+	Rate     float64 `json:"rate,omitempty"`      // For percentage surcharges
+	AfterTax bool    `json:"after_tax,omitempty"` // Added to the taxed total, untaxed
+}
+
+// AppliedSurcharge structure for a surcharge added to the price
+type AppliedSurcharge struct {
+	ID     string `json:"id,omitempty"`
+	Name   string `json:"name"`
+	Amount Money  `json:"amount"`
+}
+
+// Validate checks that the rule is complete for its type
+func (s SurchargeRule) Validate() error {
+	if s.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	switch s.Type {
+	case SurchargeFlat, SurchargePerUnit, SurchargeWeight:
+		if s.Amount.IsNegative() {
+			return fmt.Errorf("amount must not be negative")
+		}
+	case SurchargePercentage:
+		if s.Rate < 0 || s.Rate > 100 {
+			return fmt.Errorf("rate must be between 0 and 100")
+		}
+	default:
+		return fmt.Errorf("unknown surcharge type %q", s.Type)
+	}
+	return nil
+}
+
+// Calculates the surcharge on the discounted subtotal for the given quantity and weight
+func (s SurchargeRule) amount(subtotal Money, quantity int, weight float64) Money {
+	switch s.Type {
+	case SurchargeFlat:
+		return s.Amount
+	case SurchargePercentage:
+		return subtotal.Percent(s.Rate)
+	case SurchargePerUnit:
+		return s.Amount.MulInt(quantity)
+	case SurchargeWeight:
+		return s.Amount.MulRate(weight)
+	}
+	return Money{}
+}
+
+// SurchargeStep applies the surcharge rules that go before tax, or those that go after tax,
+// each evaluated in its own span. Percentage surcharges are taken of the discounted subtotal.
+type SurchargeStep struct {
+	AfterTax bool
+}
+
+func (s SurchargeStep) Name() string {
+	if s.AfterTax {
+		return StepPostTaxSurcharges
+	}
+	return StepSurcharges
+}
+
+func (s SurchargeStep) Apply(ctx context.Context, calc *Calculation) error {
+	base := calc.Subtotal
+	for _, rule := range calc.Rules.Surcharges {
+		if rule.AfterTax != s.AfterTax {
+			continue
+		}
+		_, span := tracerFrom(ctx).Start(ctx, "SurchargeRule", trace.WithAttributes(
+			attribute.String("surcharge.id", rule.ID),
+			attribute.String("surcharge.name", rule.Name),
+			attribute.String("surcharge.type", rule.Type),
+			attribute.Bool("surcharge.after_tax", rule.AfterTax),
+		))
+		amount := calc.Currency.Round(rule.amount(base, calc.Quantity, calc.Request.Weight), calc.Mode)
+		span.SetAttributes(attribute.Float64("surcharge.amount", amount.Float64()))
+		span.End()
+		if !amount.IsPositive() {
+			explainRule(ctx, ExplainRule{Kind: ExplainSurcharge, ID: rule.ID, Name: rule.Name, Detail: rule.Type + " surcharge comes to zero"})
+			continue
+		}
+		explainRule(ctx, ExplainRule{Kind: ExplainSurcharge, ID: rule.ID, Name: rule.Name, Matched: true, Amount: explainAmount(amount), Detail: rule.Type + " surcharge"})
+
+		calc.Surcharge = calc.Surcharge.Add(amount)
+		calc.Surcharges = append(calc.Surcharges, AppliedSurcharge{ID: rule.ID, Name: rule.Name, Amount: amount})
+		if s.AfterTax {
+			calc.Total = calc.Total.Add(amount)
+		} else {
+			calc.Subtotal = calc.Subtotal.Add(amount)
+			calc.Total = calc.Subtotal
+		}
+	}
+	return nil
+}
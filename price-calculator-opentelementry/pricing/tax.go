@@ -0,0 +1,146 @@
+package pricing
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// Jurisdiction structure identifying where a sale is taxed and what is sold
+type Jurisdiction struct {
+	Country    string `json:"country"`
+	State      string `json:"state,omitempty"`
+	PostalCode string `json:"postal_code,omitempty"`
+	Category   string `json:"category,omitempty"` // Product category
+}
+
+// Tax structure for one of several named taxes stacked on a sale, such as GST and PST.
+// Taxes are applied in list order.
+type Tax struct {
+	Name     string  `json:"name"`
+	Rate     float64 `json:"rate"`
+	Compound bool    `json:"compound,omitempty"` // Also taxes the taxes applied before it
+}
+
+// AppliedTax structure for the amount of a single tax in a calculated price
+type AppliedTax struct {
+	Name   string  `json:"name"`
+	Rate   float64 `json:"rate"`
+	Amount Money   `json:"amount"`
+}
+
+// ValidateTaxes checks that every stacked tax has a name and a valid rate
+func ValidateTaxes(taxes []Tax) error {
+	for _, tax := range taxes {
+		if tax.Name == "" {
+			return invalid("tax name is required")
+		}
+		if err := ValidateTaxRate(tax.Rate); err != nil {
+			return invalid("tax %s: %v", tax.Name, err)
+		}
+	}
+	return nil
+}
+
+// TaxRule structure for a tax rate, or stacked taxes, that apply to a jurisdiction.
+// Empty fields match any value, PostalCode matches as a prefix.
+type TaxRule struct {
+	ID         string  `json:"id"`
+	Country    string  `json:"country"`
+	State      string  `json:"state,omitempty"`
+	PostalCode string  `json:"postal_code,omitempty"`
+	Category   string  `json:"category,omitempty"`
+	Rate       float64 `json:"rate"`
+	Taxes      []Tax   `json:"taxes,omitempty"` // Applied instead of Rate when set
+}
+
+// Validate checks that the rule has a country and a valid rate or valid taxes
+func (t TaxRule) Validate() error {
+	if t.Country == "" {
+		return fmt.Errorf("country is required")
+	}
+	if err := ValidateTaxRate(t.Rate); err != nil {
+		return err
+	}
+	if len(t.Taxes) > 0 && t.Rate != 0 {
+		return fmt.Errorf("rate and taxes are mutually exclusive")
+	}
+	return ValidateTaxes(t.Taxes)
+}
+
+// Reports whether the rule applies to the jurisdiction and how specific the match is
+func (t TaxRule) match(j Jurisdiction) (int, bool) {
+	if !strings.EqualFold(t.Country, j.Country) {
+		return 0, false
+	}
+	specificity := 0
+	if t.State != "" {
+		if !strings.EqualFold(t.State, j.State) {
+			return 0, false
+		}
+		specificity++
+	}
+	if t.PostalCode != "" {
+		if !strings.HasPrefix(j.PostalCode, t.PostalCode) {
+			return 0, false
+		}
+		specificity += len(t.PostalCode) // Longer prefixes are more specific
+	}
+	if t.Category != "" {
+		if !strings.EqualFold(t.Category, j.Category) {
+			return 0, false
+		}
+		specificity += 100 // A category rule overrides any location-only rule
+	}
+	return specificity, true
+}
+
+// Explains which tax rules matched the jurisdiction and the one applied
+func explainTaxRules(ctx context.Context, rules []TaxRule, j Jurisdiction, best TaxRule, found bool) {
+	for _, rule := range rules {
+		explained := ExplainRule{Kind: ExplainTaxRule, ID: rule.ID}
+		switch specificity, ok := rule.match(j); {
+		case !ok:
+			explained.Detail = "jurisdiction does not match"
+		case found && rule.ID == best.ID:
+			explained.Matched = true
+			explained.Detail = fmt.Sprintf("most specific match (specificity %d)", specificity)
+		default:
+			explained.Detail = fmt.Sprintf("outranked by %s (specificity %d)", best.ID, specificity)
+		}
+		explainRule(ctx, explained)
+	}
+}
+
+// ResolveTaxRule finds the most specific rule for the jurisdiction in its own span.
+// Rules earlier in the list win ties.
+func ResolveTaxRule(ctx context.Context, rules []TaxRule, j Jurisdiction) (TaxRule, bool) {
+	_, span := tracerFrom(ctx).Start(ctx, "ResolveTaxRule")
+	defer span.End()
+	span.SetAttributes(
+		attribute.String("tax.country", j.Country),
+		attribute.String("tax.state", j.State),
+		attribute.String("tax.postal_code", j.PostalCode),
+		attribute.String("tax.category", j.Category),
+		attribute.Int("tax.rule_count", len(rules)),
+	)
+
+	var best TaxRule
+	bestSpecificity, found := -1, false
+	for _, rule := range rules {
+		if specificity, ok := rule.match(j); ok && specificity > bestSpecificity {
+			best, bestSpecificity, found = rule, specificity, true
+		}
+	}
+	if ExplanationFrom(ctx) != nil {
+		explainTaxRules(ctx, rules, j, best, found)
+	}
+
+	span.SetAttributes(attribute.Bool("tax.rule_matched", found))
+	if found {
+		span.SetAttributes(attribute.String("tax.rule_id", best.ID), attribute.Float64("tax.rate", best.Rate))
+	}
+	return best, found
+}
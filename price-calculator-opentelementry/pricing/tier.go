@@ -0,0 +1,78 @@
+package pricing
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ValidateTiers checks that every tier has a positive minimum quantity, a percentage
+// between 0 and 100 and a minimum quantity of its own
+func ValidateTiers(tiers []DiscountTier) error {
+	seen := map[int]bool{}
+	for _, tier := range tiers {
+		if tier.MinQuantity <= 0 || tier.Percentage < 0 || tier.Percentage > 100 {
+			return fmt.Errorf("invalid tier %+v", tier)
+		}
+		if seen[tier.MinQuantity] {
+			return fmt.Errorf("duplicate tier for min_quantity %d", tier.MinQuantity)
+		}
+		seen[tier.MinQuantity] = true
+	}
+	return nil
+}
+
+// Returns the tier with the highest minimum quantity reached by quantity, false if none is reached
+func resolveTier(tiers []DiscountTier, quantity int) (DiscountTier, bool) {
+	var reached DiscountTier
+	for _, tier := range tiers {
+		if quantity >= tier.MinQuantity && tier.MinQuantity > reached.MinQuantity {
+			reached = tier
+		}
+	}
+	return reached, reached.MinQuantity > 0
+}
+
+// Explains which tiers the quantity reached and the one applied
+func explainTiers(ctx context.Context, tiers []DiscountTier, quantity int, applied DiscountTier) {
+	for _, tier := range tiers {
+		rule := ExplainRule{Kind: ExplainTier, Name: fmt.Sprintf("%d+", tier.MinQuantity)}
+		switch {
+		case quantity < tier.MinQuantity:
+			rule.Detail = fmt.Sprintf("quantity %d below min_quantity %d", quantity, tier.MinQuantity)
+		case tier.MinQuantity != applied.MinQuantity:
+			rule.Detail = fmt.Sprintf("superseded by the %d+ tier", applied.MinQuantity)
+		default:
+			rule.Matched = true
+			rule.Detail = fmt.Sprintf("%v%% off the unit price", tier.Percentage)
+		}
+		explainRule(ctx, rule)
+	}
+}
+
+// TierStep applies the volume price break reached by the quantity to the unit price,
+// before any discount. Quantities below the lowest tier pay the full price.
+type TierStep struct{}
+
+func (TierStep) Name() string { return StepTiers }
+
+func (TierStep) Apply(ctx context.Context, calc *Calculation) error {
+	tier, ok := resolveTier(calc.Rules.Tiers, calc.Quantity)
+	if ExplanationFrom(ctx) != nil {
+		explainTiers(ctx, calc.Rules.Tiers, calc.Quantity, tier)
+	}
+	if !ok {
+		return nil
+	}
+	calc.Tier = &tier
+	calc.BasePrice = calc.BasePrice.Sub(calc.BasePrice.Percent(tier.Percentage))
+	calc.Subtotal = calc.BasePrice.MulInt(calc.Quantity)
+	calc.Total = calc.Subtotal
+	trace.SpanFromContext(ctx).SetAttributes(
+		attribute.Int("pricing.tier.min_quantity", tier.MinQuantity),
+		attribute.Float64("pricing.tier.percentage", tier.Percentage),
+	)
+	return nil
+}
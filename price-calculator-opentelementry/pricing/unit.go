@@ -0,0 +1,126 @@
+package pricing
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Extra decimal places of the effective unit price over the minor units of the currency
+const unitPricePlaces = 2
+
+// UnitDiscount structure for a discount taken off every unit, percentage or fixed
+type UnitDiscount struct {
+	Name  string  `json:"name"`
+	Type  string  `json:"type"`
+	Value float64 `json:"value"` // Percent of the discounted unit price, or amount off each unit
+}
+
+// UnitPriceRequest structure for pricing a quantity of units with per-unit discounts.
+// An omitted TaxRate falls back to the stored default.
+type UnitPriceRequest struct {
+	UnitPrice Money          `json:"unit_price"`
+	Quantity  int            `json:"quantity,omitempty"` // Defaults to 1
+	TaxRate   *float64       `json:"tax_rate,omitempty"`
+	Discounts []UnitDiscount `json:"discounts,omitempty"` // Applied to each unit in order
+	Currency  string         `json:"currency,omitempty"`
+	Rounding  RoundingMode   `json:"rounding,omitempty"` // Overrides the configured rounding mode
+}
+
+// UnitPriceResponse structure for the total of a quantity of units and the effective price of one unit
+type UnitPriceResponse struct {
+	Currency           string    `json:"currency"`
+	Quantity           int       `json:"quantity"`
+	UnitPrice          Money     `json:"unit_price"`
+	NetUnitPrice       Money     `json:"net_unit_price"` // Unit price after the discounts, before tax
+	Discount           Money     `json:"discount"`       // Discounts on all units
+	Subtotal           Money     `json:"subtotal"`
+	Tax                Money     `json:"tax"`
+	TotalPrice         Money     `json:"total_price"`
+	EffectiveUnitPrice Money     `json:"effective_unit_price"` // Total price divided by the quantity
+	Breakdown          Breakdown `json:"breakdown"`
+}
+
+// CalculateUnitPrice prices a quantity of units, taking the discounts off each unit before tax.
+// The unit figures are rounded to two decimal places more than the currency, so that they
+// multiply back to the totals. The prices are recorded on the span in ctx.
+func CalculateUnitPrice(ctx context.Context, request UnitPriceRequest, defaults Config, mode RoundingMode) (UnitPriceResponse, error) {
+	if err := ValidateBasePrice(request.UnitPrice); err != nil {
+		return UnitPriceResponse{}, err
+	}
+	if request.Quantity < 0 {
+		return UnitPriceResponse{}, invalid("invalid quantity")
+	}
+	taxRate := defaults.TaxRate
+	if request.TaxRate != nil {
+		taxRate = *request.TaxRate
+	}
+	if err := ValidateTaxRate(taxRate); err != nil {
+		return UnitPriceResponse{}, err
+	}
+	for i, discount := range request.Discounts {
+		if discount.Type != DiscountPercentage && discount.Type != DiscountFixed {
+			return UnitPriceResponse{}, invalid("discount %d must be %s or %s", i, DiscountPercentage, DiscountFixed)
+		}
+		if err := (Discount{Type: discount.Type, Value: discount.Value}).Validate(); err != nil {
+			return UnitPriceResponse{}, invalid("discount %d: %v", i, err)
+		}
+	}
+	currency, err := LookupCurrency(request.Currency)
+	if err != nil {
+		return UnitPriceResponse{}, err
+	}
+	if request.Rounding != "" {
+		if mode, err = ParseRoundingMode(string(request.Rounding)); err != nil {
+			return UnitPriceResponse{}, invalid("%v", err)
+		}
+	}
+	quantity := max(request.Quantity, 1)
+	places := currency.MinorUnits + unitPricePlaces
+
+	// Take the discounts off one unit, never below zero
+	breakdown := Breakdown{BaseAmount: currency.Round(request.UnitPrice.MulInt(quantity), mode), Lines: []BreakdownLine{}}
+	net := request.UnitPrice
+	for _, discount := range request.Discounts {
+		amount := MoneyFromFloat(discount.Value)
+		if discount.Type == DiscountPercentage {
+			amount = net.Percent(discount.Value)
+		}
+		amount = amount.Min(net)
+		net = net.Sub(amount)
+		breakdown.Lines = append(breakdown.Lines, BreakdownLine{Kind: LineDiscount, Name: discount.Name, Amount: Money{}.Sub(currency.Round(amount.MulInt(quantity), mode))})
+	}
+
+	response := UnitPriceResponse{
+		Currency:     currency.Code,
+		Quantity:     quantity,
+		UnitPrice:    request.UnitPrice,
+		NetUnitPrice: net.Round(places, mode),
+		Subtotal:     currency.Round(net.MulInt(quantity), mode),
+	}
+	response.Discount = breakdown.BaseAmount.Sub(response.Subtotal)
+	response.Tax = currency.Round(response.Subtotal.Percent(taxRate), mode)
+	response.TotalPrice = currency.RoundTotal(response.Subtotal.Add(response.Tax), mode)
+	response.EffectiveUnitPrice = response.TotalPrice.DivInt(quantity).Round(places, mode)
+	breakdown.Lines = append(breakdown.Lines, BreakdownLine{Kind: LineTax, Name: "tax", Amount: response.Tax})
+
+	// The rounding adjustment covers the rounding of each discount line against the subtotal and of the total
+	sum := breakdown.BaseAmount
+	for _, line := range breakdown.Lines {
+		sum = sum.Add(line.Amount)
+	}
+	breakdown.Total = response.TotalPrice
+	breakdown.RoundingAdjustment = response.TotalPrice.Sub(sum)
+	response.Breakdown = breakdown
+
+	trace.SpanFromContext(ctx).SetAttributes(
+		attribute.Int("unit.quantity", quantity),
+		attribute.Float64("unit.net_price", response.NetUnitPrice.Float64()),
+		attribute.Float64("unit.effective_price", response.EffectiveUnitPrice.Float64()),
+		attribute.Float64("pricing.total", response.TotalPrice.Float64()),
+		attribute.String("currency", currency.Code),
+		attribute.String("rounding.mode", string(mode)),
+	)
+	return response, nil
+}
@@ -0,0 +1,283 @@
+package pricing
+
+import (
+	"strings"
+	"time"
+)
+
+// Shapes of the v2 API, which groups the amounts of a calculation by kind and carries every
+// amount with its currency. Requests are converted to the v1 shapes priced by the engine, and
+// the v1 results converted back, so both versions share the same calculations.
+
+// Amount structure for a sum of money in the v2 API
+type Amount struct {
+	Value    string `json:"value"` // Decimal string, e.g. "12.50"
+	Currency string `json:"currency"`
+}
+
+// PriceRequestV2 structure for a single price calculation in the v2 API
+type PriceRequestV2 struct {
+	Currency   string       `json:"currency,omitempty"`   // Defaults to the unit price currency, then the configured one
+	UnitPrice  *Amount      `json:"unit_price,omitempty"` // Defaults to the catalog product, then the configured base price
+	Quantity   int          `json:"quantity,omitempty"`
+	Weight     float64      `json:"weight,omitempty"`
+	SKU        string       `json:"sku,omitempty"`
+	CustomerID string       `json:"customer_id,omitempty"`
+	Tax        *TaxV2       `json:"tax,omitempty"`
+	Discounts  *DiscountsV2 `json:"discounts,omitempty"`
+	Rounding   RoundingMode `json:"rounding,omitempty"`
+	AsOf       *time.Time   `json:"as_of,omitempty"`
+}
+
+// TaxV2 structure for the tax of a v2 calculation: a rate, stacked taxes or a jurisdiction
+type TaxV2 struct {
+	Rate         *float64      `json:"rate,omitempty"`
+	Taxes        []Tax         `json:"taxes,omitempty"`
+	Jurisdiction *Jurisdiction `json:"jurisdiction,omitempty"`
+	Exemption    *TaxExemption `json:"exemption,omitempty"`
+}
+
+// DiscountsV2 structure for the discounts requested in a v2 calculation, on top of the discount rules
+type DiscountsV2 struct {
+	CouponCode string `json:"coupon_code,omitempty"`
+}
+
+// PriceResponseV2 structure for the result of a v2 calculation
+type PriceResponseV2 struct {
+	ID                 string              `json:"id,omitempty"` // Calculation ID
+	TraceID            string              `json:"trace_id,omitempty"`
+	Currency           string              `json:"currency"`
+	Subtotal           Amount              `json:"subtotal"` // Unit price times quantity, before the volume price break
+	Tier               *DiscountTier       `json:"tier,omitempty"`
+	Discounts          DiscountSummaryV2   `json:"discounts"`
+	Surcharges         SurchargeSummaryV2  `json:"surcharges"`
+	Tax                TaxSummaryV2        `json:"tax"`
+	Guardrail          *GuardrailViolation `json:"guardrail,omitempty"`
+	RoundingAdjustment Amount              `json:"rounding_adjustment"`
+	Total              Amount              `json:"total"`
+}
+
+// AdjustmentV2 structure for a discount or surcharge applied to a v2 calculation
+type AdjustmentV2 struct {
+	ID     string `json:"id,omitempty"`
+	Name   string `json:"name"`
+	Amount Amount `json:"amount"`
+}
+
+// DiscountSummaryV2 structure for the discounts of a v2 calculation
+type DiscountSummaryV2 struct {
+	Total   Amount            `json:"total"`
+	Applied []AdjustmentV2    `json:"applied"`
+	Skipped []SkippedDiscount `json:"skipped,omitempty"` // Eligible rules left out by the stacking policy
+}
+
+// SurchargeSummaryV2 structure for the surcharges of a v2 calculation
+type SurchargeSummaryV2 struct {
+	Total   Amount         `json:"total"`
+	Applied []AdjustmentV2 `json:"applied"`
+}
+
+// TaxSummaryV2 structure for the taxes of a v2 calculation
+type TaxSummaryV2 struct {
+	Total     Amount         `json:"total"`
+	Applied   []AppliedTaxV2 `json:"applied"`
+	Exemption *TaxExemption  `json:"exemption,omitempty"` // Exemption that zero-rated the tax
+}
+
+// AppliedTaxV2 structure for the amount of a single tax in a v2 calculation
+type AppliedTaxV2 struct {
+	Name   string  `json:"name"`
+	Rate   float64 `json:"rate"`
+	Amount Amount  `json:"amount"`
+}
+
+// CartRequestV2 structure for a cart in the v2 API, each line priced in the currency of its unit price
+type CartRequestV2 struct {
+	Currency string             `json:"currency,omitempty"` // Settlement currency, defaults to the configured one
+	Rates    map[string]float64 `json:"rates,omitempty"`    // Cart currency units per unit of a line currency, by code
+	Lines    []CartLineV2       `json:"lines"`
+}
+
+// CartLineV2 structure for a line of a v2 cart
+type CartLineV2 struct {
+	Name               string   `json:"name"`
+	UnitPrice          Amount   `json:"unit_price"` // An empty currency is the cart currency
+	Quantity           int      `json:"quantity"`
+	TaxRate            *float64 `json:"tax_rate,omitempty"` // Defaults to the configured tax rate
+	DiscountPercentage float64  `json:"discount_percentage,omitempty"`
+}
+
+// CartResponseV2 structure for the totals of a v2 cart
+type CartResponseV2 struct {
+	Currency      string           `json:"currency"`
+	Lines         []CartLineTotals `json:"lines"`
+	Totals        CartTotalsV2     `json:"totals"`
+	ExchangeRates []ExchangeRate   `json:"exchange_rates,omitempty"`
+}
+
+// CartLineTotals structure for the totals of a v2 cart line, in the cart currency
+type CartLineTotals struct {
+	Name         string        `json:"name"`
+	Quantity     int           `json:"quantity"`
+	Subtotal     Amount        `json:"subtotal"`
+	Discount     Amount        `json:"discount"`
+	Tax          Amount        `json:"tax"`
+	Total        Amount        `json:"total"`
+	Original     *CartTotalsV2 `json:"original,omitempty"` // Totals in the line currency, for lines in another currency
+	ExchangeRate float64       `json:"exchange_rate,omitempty"`
+}
+
+// CartTotalsV2 structure for the totals of a v2 cart or of a line in its own currency
+type CartTotalsV2 struct {
+	Subtotal Amount `json:"subtotal"`
+	Discount Amount `json:"discount"`
+	Tax      Amount `json:"tax"`
+	Total    Amount `json:"total"`
+}
+
+// Parses an amount of a v2 request, checking its currency
+func (a Amount) money(field string) (Money, string, error) {
+	amount, err := ParseMoney(a.Value)
+	if err != nil {
+		return Money{}, "", invalid("invalid %s: %v", field, err)
+	}
+	if a.Currency == "" {
+		return amount, "", nil
+	}
+	currency, err := LookupCurrency(a.Currency)
+	if err != nil {
+		return Money{}, "", err
+	}
+	return amount, currency.Code, nil
+}
+
+// Returns an amount of a v2 response in the currency
+func newAmount(amount Money, code string) Amount {
+	currency, err := LookupCurrency(code)
+	if err != nil {
+		return Amount{Value: amount.String(), Currency: code}
+	}
+	return Amount{Value: currency.DecimalString(amount), Currency: currency.Code}
+}
+
+// V1 converts the request to the v1 request priced by the engine. The unit price must be in
+// the request currency.
+func (r PriceRequestV2) V1() (PriceRequest, error) {
+	request := PriceRequest{
+		Currency:   strings.ToUpper(r.Currency),
+		Quantity:   r.Quantity,
+		Weight:     r.Weight,
+		SKU:        r.SKU,
+		CustomerID: r.CustomerID,
+		Rounding:   r.Rounding,
+		AsOf:       r.AsOf,
+	}
+	if r.UnitPrice != nil {
+		unitPrice, currency, err := r.UnitPrice.money("unit_price")
+		if err != nil {
+			return PriceRequest{}, err
+		}
+		if currency != "" && request.Currency != "" && currency != request.Currency {
+			return PriceRequest{}, invalid("unit_price currency %s differs from the request currency %s", currency, request.Currency)
+		}
+		if request.Currency == "" {
+			request.Currency = currency
+		}
+		request.BasePrice = &unitPrice
+	}
+	if r.Tax != nil {
+		request.TaxRate = r.Tax.Rate
+		request.Taxes = r.Tax.Taxes
+		request.Jurisdiction = r.Tax.Jurisdiction
+		request.TaxExemption = r.Tax.Exemption
+	}
+	if r.Discounts != nil {
+		request.CouponCode = r.Discounts.CouponCode
+	}
+	return request, nil
+}
+
+// NewPriceResponseV2 converts the result of a calculation to the v2 shape
+func NewPriceResponseV2(response PriceResponse) PriceResponseV2 {
+	currency := response.Currency
+	v2 := PriceResponseV2{
+		ID:                 response.CalculationID,
+		TraceID:            response.TraceID,
+		Currency:           currency,
+		Subtotal:           newAmount(response.Breakdown.BaseAmount, currency),
+		Tier:               response.Tier,
+		Discounts:          DiscountSummaryV2{Total: newAmount(response.Discount, currency), Applied: []AdjustmentV2{}, Skipped: response.SkippedDiscounts},
+		Surcharges:         SurchargeSummaryV2{Total: newAmount(response.Surcharge, currency), Applied: []AdjustmentV2{}},
+		Tax:                TaxSummaryV2{Total: newAmount(response.Tax, currency), Applied: []AppliedTaxV2{}, Exemption: response.TaxExemption},
+		Guardrail:          response.Guardrail,
+		RoundingAdjustment: newAmount(response.Breakdown.RoundingAdjustment, currency),
+		Total:              newAmount(response.TotalPrice, currency),
+	}
+	for _, d := range response.Discounts {
+		v2.Discounts.Applied = append(v2.Discounts.Applied, AdjustmentV2{ID: d.ID, Name: d.Name, Amount: newAmount(d.Amount, currency)})
+	}
+	for _, s := range response.Surcharges {
+		v2.Surcharges.Applied = append(v2.Surcharges.Applied, AdjustmentV2{ID: s.ID, Name: s.Name, Amount: newAmount(s.Amount, currency)})
+	}
+	for _, t := range response.Taxes {
+		v2.Tax.Applied = append(v2.Tax.Applied, AppliedTaxV2{Name: t.Name, Rate: t.Rate, Amount: newAmount(t.Amount, currency)})
+	}
+	return v2
+}
+
+// V1 converts the cart to the v1 cart priced by the engine
+func (r CartRequestV2) V1() (CartRequest, error) {
+	request := CartRequest{Currency: r.Currency, Rates: r.Rates, Items: make([]CartItem, 0, len(r.Lines))}
+	for _, line := range r.Lines {
+		unitPrice, currency, err := line.UnitPrice.money("unit_price of " + line.Name)
+		if err != nil {
+			return CartRequest{}, err
+		}
+		request.Items = append(request.Items, CartItem{
+			Name:      line.Name,
+			UnitPrice: unitPrice,
+			Quantity:  line.Quantity,
+			TaxRate:   line.TaxRate,
+			Discount:  line.DiscountPercentage,
+			Currency:  currency,
+		})
+	}
+	return request, nil
+}
+
+// NewCartResponseV2 converts the totals of a cart to the v2 shape
+func NewCartResponseV2(response CartResponse) CartResponseV2 {
+	currency := response.Currency
+	v2 := CartResponseV2{
+		Currency: currency,
+		Lines:    make([]CartLineTotals, 0, len(response.Lines)),
+		Totals: CartTotalsV2{
+			Subtotal: newAmount(response.Subtotal, currency),
+			Discount: newAmount(response.Discount, currency),
+			Tax:      newAmount(response.Tax, currency),
+			Total:    newAmount(response.GrandTotal, currency),
+		},
+		ExchangeRates: response.ExchangeRates,
+	}
+	for _, line := range response.Lines {
+		totals := CartLineTotals{
+			Name:         line.Name,
+			Quantity:     line.Quantity,
+			Subtotal:     newAmount(line.Subtotal, currency),
+			Discount:     newAmount(line.Discount, currency),
+			Tax:          newAmount(line.Tax, currency),
+			Total:        newAmount(line.Total, currency),
+			ExchangeRate: line.ExchangeRate,
+		}
+		if o := line.Original; o != nil {
+			totals.Original = &CartTotalsV2{
+				Subtotal: newAmount(o.Subtotal, o.Currency),
+				Discount: newAmount(o.Discount, o.Currency),
+				Tax:      newAmount(o.Tax, o.Currency),
+				Total:    newAmount(o.Total, o.Currency),
+			}
+		}
+		v2.Lines = append(v2.Lines, totals)
+	}
+	return v2
+}
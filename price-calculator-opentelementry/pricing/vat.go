@@ -0,0 +1,106 @@
+package pricing
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Reason recorded for an exemption that names none
+const defaultExemptionReason = "B2B reverse charge"
+
+// Formats of the VAT and GST numbers by country prefix, without the prefix
+var vatIDFormats = map[string]*regexp.Regexp{
+	"AT": regexp.MustCompile(`^U\d{8}$`),
+	"AU": regexp.MustCompile(`^\d{11}$`), // ABN of a GST registered business
+	"BE": regexp.MustCompile(`^[01]\d{9}$`),
+	"BG": regexp.MustCompile(`^\d{9,10}$`),
+	"CH": regexp.MustCompile(`^E\d{9}(MWST|TVA|IVA)?$`),
+	"CY": regexp.MustCompile(`^\d{8}[A-Z]$`),
+	"CZ": regexp.MustCompile(`^\d{8,10}$`),
+	"DE": regexp.MustCompile(`^\d{9}$`),
+	"DK": regexp.MustCompile(`^\d{8}$`),
+	"EE": regexp.MustCompile(`^\d{9}$`),
+	"EL": regexp.MustCompile(`^\d{9}$`), // Greece
+	"ES": regexp.MustCompile(`^[0-9A-Z]\d{7}[0-9A-Z]$`),
+	"FI": regexp.MustCompile(`^\d{8}$`),
+	"FR": regexp.MustCompile(`^[0-9A-HJ-NP-Z]{2}\d{9}$`),
+	"GB": regexp.MustCompile(`^(\d{9}|\d{12}|GD\d{3}|HA\d{3})$`),
+	"HR": regexp.MustCompile(`^\d{11}$`),
+	"HU": regexp.MustCompile(`^\d{8}$`),
+	"IE": regexp.MustCompile(`^\d[0-9A-Z+*]\d{5}[A-Z]{1,2}$`),
+	"IN": regexp.MustCompile(`^\d{2}[A-Z]{5}\d{4}[A-Z][1-9A-Z]Z[0-9A-Z]$`), // GSTIN
+	"IT": regexp.MustCompile(`^\d{11}$`),
+	"LT": regexp.MustCompile(`^(\d{9}|\d{12})$`),
+	"LU": regexp.MustCompile(`^\d{8}$`),
+	"LV": regexp.MustCompile(`^\d{11}$`),
+	"MT": regexp.MustCompile(`^\d{8}$`),
+	"NL": regexp.MustCompile(`^\d{9}B\d{2}$`),
+	"NO": regexp.MustCompile(`^\d{9}(MVA)?$`),
+	"PL": regexp.MustCompile(`^\d{10}$`),
+	"PT": regexp.MustCompile(`^\d{9}$`),
+	"RO": regexp.MustCompile(`^\d{2,10}$`),
+	"SE": regexp.MustCompile(`^\d{12}$`),
+	"SI": regexp.MustCompile(`^\d{8}$`),
+	"SK": regexp.MustCompile(`^\d{10}$`),
+}
+
+// EU member states, whose VAT numbers can be checked with VIES
+var viesCountries = map[string]bool{
+	"AT": true, "BE": true, "BG": true, "CY": true, "CZ": true, "DE": true, "DK": true, "EE": true, "EL": true,
+	"ES": true, "FI": true, "FR": true, "HR": true, "HU": true, "IE": true, "IT": true, "LT": true, "LU": true,
+	"LV": true, "MT": true, "NL": true, "PL": true, "PT": true, "RO": true, "SE": true, "SI": true, "SK": true,
+}
+
+// TaxExemption structure for a B2B sale exempt from tax by the buyer's VAT or GST number.
+// The number starts with its country prefix, such as DE123456789.
+type TaxExemption struct {
+	VATID  string `json:"vat_id"`
+	Reason string `json:"reason,omitempty"` // Defaults to B2B reverse charge
+}
+
+// Country returns the country prefix of the VAT ID
+func (e TaxExemption) Country() string {
+	if len(e.VATID) < 2 {
+		return ""
+	}
+	return e.VATID[:2]
+}
+
+// Number returns the VAT ID without its country prefix
+func (e TaxExemption) Number() string {
+	if len(e.VATID) < 2 {
+		return ""
+	}
+	return e.VATID[2:]
+}
+
+// ViesCheckable reports whether the VAT ID is an EU number VIES can confirm
+func (e TaxExemption) ViesCheckable() bool {
+	return viesCountries[e.Country()]
+}
+
+// NormalizeTaxExemption removes the separators from the VAT ID, upper cases it, checks its
+// format for its country and fills in the default reason
+func NormalizeTaxExemption(exemption TaxExemption) (TaxExemption, error) {
+	id := strings.ToUpper(strings.Map(func(r rune) rune {
+		if r == ' ' || r == '.' || r == '-' {
+			return -1
+		}
+		return r
+	}, exemption.VATID))
+	if id == "" {
+		return exemption, invalid("tax exemption vat_id is required")
+	}
+	exemption.VATID = id
+	format, ok := vatIDFormats[exemption.Country()]
+	if !ok {
+		return exemption, invalid("unsupported VAT ID country %q", exemption.Country())
+	}
+	if !format.MatchString(exemption.Number()) {
+		return exemption, invalid("invalid VAT ID %q for %s", id, exemption.Country())
+	}
+	if exemption.Reason == "" {
+		exemption.Reason = defaultExemptionReason
+	}
+	return exemption, nil
+}